@@ -0,0 +1,172 @@
+/*
+ * anchor.go
+ * Rows relative to a row matching a regex
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/* anchorPrefix starts an anchored range */
+const anchorPrefix = "after:"
+
+/* anchor allows rows relative to the first row matching a regex */
+type anchor struct {
+	re *regexp.Regexp
+	lo int /* First allowed row, relative to the matching row */
+	hi int /* Last allowed row, or -1 for no limit */
+	at int /* Matching row, or 0 if not yet found */
+}
+
+/* splitSpec splits spec on commas which aren't inside the /regex/ of an
+anchored range */
+func splitSpec(spec string) []string {
+	var (
+		ps     []string
+		start  int
+		inRE   bool
+		doneRE bool /* Already had this piece's regex */
+	)
+	for i := 0; i < len(spec); i++ {
+		switch spec[i] {
+		case '\\':
+			if inRE {
+				i++ /* Skip the escaped character */
+			}
+		case '/':
+			switch {
+			case inRE:
+				inRE = false
+				doneRE = true
+			case !doneRE && anchorPrefix ==
+				strings.TrimSpace(spec[start:i]):
+				inRE = true
+			}
+		case ',':
+			if !inRE {
+				ps = append(ps, spec[start:i])
+				start = i + 1
+				doneRE = false
+			}
+		}
+	}
+	return append(ps, spec[start:])
+}
+
+/* parseAnchor parses an anchored range of the form after:/REGEX/OFFSETS */
+func parseAnchor(s string) (*anchor, error) {
+	s = strings.TrimPrefix(s, anchorPrefix)
+	if !strings.HasPrefix(s, "/") {
+		return nil, errors.New("regular expression must start with /")
+	}
+
+	/* Find the end of the regex, unescaping slashes */
+	var (
+		re  strings.Builder
+		end = -1
+	)
+	for i := 1; i < len(s) && -1 == end; i++ {
+		switch {
+		case '\\' == s[i] && i+1 < len(s) && '/' == s[i+1]:
+			re.WriteByte('/')
+			i++
+		case '/' == s[i]:
+			end = i
+		default:
+			re.WriteByte(s[i])
+		}
+	}
+	if -1 == end {
+		return nil, errors.New("regular expression must end with /")
+	}
+	var (
+		a   anchor
+		err error
+	)
+	if a.re, err = regexp.Compile(re.String()); nil != err {
+		return nil, err
+	}
+
+	/* Work out the offsets */
+	off := strings.TrimSpace(s[end+1:])
+	if "" == off {
+		off = "+1-"
+	}
+	lo, hi, open := off, "", false
+	if i := strings.Index(off, "-"); -1 != i {
+		lo, hi, open = off[:i], off[i+1:], true
+	}
+	if a.lo, err = parseOffset(lo); nil != err {
+		return nil, err
+	}
+	switch {
+	case !open:
+		a.hi = a.lo
+	case "" == hi:
+		a.hi = -1
+	default:
+		if a.hi, err = parseOffset(hi); nil != err {
+			return nil, err
+		}
+		if a.hi < a.lo {
+			return nil, errors.New("end of range before start")
+		}
+	}
+	return &a, nil
+}
+
+/* parseOffset parses a non-negative offset, which may start with a + */
+func parseOffset(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+	if nil != err {
+		return 0, fmt.Errorf("invalid offset %q", s)
+	}
+	if 0 > n {
+		return 0, fmt.Errorf("negative offset %q", s)
+	}
+	return n, nil
+}
+
+/* allows returns whether row n, which is record, is allowed by the anchor.
+The fields of record are joined with sep before matching.  If final is true,
+the anchor will return the same for all rows after n. */
+func (a *anchor) allows(n int, record []string, sep string) (ok, final bool) {
+	/* Look for the matching row */
+	if 0 == a.at {
+		if !a.re.MatchString(strings.Join(record, sep)) {
+			return false, false
+		}
+		debug("Row %v matches anchor %v", n, a.re)
+		a.at = n
+	}
+	ok = n >= a.at+a.lo && (-1 == a.hi || n <= a.at+a.hi)
+	if -1 == a.hi {
+		final = ok
+	} else {
+		final = n > a.at+a.hi
+	}
+	return ok, final
+}
@@ -0,0 +1,108 @@
+/*
+ * anchor_test.go
+ * Tests for anchor.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAnchoredRows(t *testing.T) {
+	in := "junk,1\nHEADER2,x\nd1,2\nd2,3\nHEADER2,y\nd3,4\n"
+	rest := "d1,2\nd2,3\nHEADER2,y\nd3,4\n" /* After the first HEADER2 */
+	for _, c := range []struct {
+		rows RowSpec
+		want string
+		err  bool
+	}{
+		{rows: "after:/^HEADER2/+1-", want: rest},
+		{rows: "after:/^HEADER2/", want: rest},
+		{rows: "after:/HEADER2/2,1", want: "junk,1\nd2,3\n"},
+		{rows: "after:/HEADER2/1-2", want: "d1,2\nd2,3\n"},
+		{rows: "after:/HEADER2,y/+1", want: "d3,4\n"},
+		{rows: "after:/^d\\d,[23]/1", want: "d2,3\n"},
+		{rows: "after:/nothing/", want: ""},
+		{rows: "after:/(/", err: true},
+		{rows: "after:/unterminated", err: true},
+		{rows: "after:HEADER2", err: true},
+	} {
+		c := c
+		t.Run(string(c.rows), func(t *testing.T) {
+			s, err := NewSelector(c.rows, "")
+			if c.err {
+				if nil == err {
+					t.Errorf("No error")
+				}
+				return
+			}
+			if nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			got, err := s.SelectBytes([]byte(in))
+			if nil != err {
+				t.Fatalf("SelectBytes: %v", err)
+			}
+			err = CompareCSV(got, []byte(c.want), ',')
+			if nil != err {
+				t.Errorf("%v\ngot:\n%s", err, got)
+			}
+		})
+	}
+	if _, err := NewSelector("", "after:/a/"); nil == err {
+		t.Errorf("Anchored columns didn't fail")
+	}
+}
+
+func TestAnchoredRowsEscapedSlash(t *testing.T) {
+	s, err := NewSelector(`after:/a\/b/`, "")
+	if nil != err {
+		t.Fatalf("Error: %v", err)
+	}
+	got, err := s.SelectBytes([]byte("a/b\nc\nd\n"))
+	if nil != err {
+		t.Fatalf("SelectBytes: %v", err)
+	}
+	if "c\nd\n" != string(got) {
+		t.Errorf("Got %q, want %q", got, "c\nd\n")
+	}
+}
+
+func TestSplitSpec(t *testing.T) {
+	for _, c := range []struct {
+		spec string
+		want []string
+	}{
+		{"1,2-3", []string{"1", "2-3"}},
+		{"1-9/4,3", []string{"1-9/4", "3"}},
+		{"1-:2,5/1", []string{"1-:2", "5/1"}},
+		{"after:/a,b/1-,3", []string{"after:/a,b/1-", "3"}},
+		{`after:/a\/,b/,2/2`, []string{`after:/a\/,b/`, "2/2"}},
+		{" after:/,/ , 4", []string{" after:/,/ ", " 4"}},
+	} {
+		got := splitSpec(c.spec)
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", c.want) {
+			t.Errorf("splitSpec(%q) = %q, want %q",
+				c.spec, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ * calc.go
+ * Arithmetic and concatenation for computed columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* valueExpr is an expression which gives a value computed from a record */
+type valueExpr interface {
+	eval(record []string) string
+}
+
+/* binaryExpr is two values joined with an arithmetic or concatenation
+operator */
+type binaryExpr struct {
+	op   byte
+	l, r valueExpr
+}
+
+/* eval concatenates b's values or does arithmetic on them.  If arithmetic is
+impossible, eval returns the empty string. */
+func (b binaryExpr) eval(record []string) string {
+	l, r := b.l.eval(record), b.r.eval(record)
+	if '&' == b.op {
+		return l + r
+	}
+	lf, lerr := strconv.ParseFloat(strings.TrimSpace(l), 64)
+	rf, rerr := strconv.ParseFloat(strings.TrimSpace(r), 64)
+	if nil != lerr || nil != rerr {
+		return ""
+	}
+	var v float64
+	switch b.op {
+	case '+':
+		v = lf + rf
+	case '-':
+		v = lf - rf
+	case '*':
+		v = lf * rf
+	case '/':
+		v = lf / rf
+	case '%':
+		v = math.Mod(lf, rf)
+	}
+	return formatNumber(v)
+}
+
+/* negExpr is a negated value */
+type negExpr struct{ e valueExpr }
+
+/* eval returns the negation of n's value, or the empty string if it's not a
+number */
+func (n negExpr) eval(record []string) string {
+	f, err := strconv.ParseFloat(strings.TrimSpace(n.e.eval(record)), 64)
+	if nil != err {
+		return ""
+	}
+	return formatNumber(-f)
+}
+
+/* formatNumber formats f without an exponent and without the noise floating
+point arithmetic leaves in the last few digits, so 1.1*3 is 3.3.  Infinities
+and NaNs, e.g. from division by zero, are formatted as the empty string. */
+func formatNumber(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return ""
+	}
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	if 0 == f {
+		f = 0 /* No -0 */
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+/* valuePuncts are the operators and punctuation which may appear in a value
+expression */
+const valuePuncts = "+-*/%&()"
+
+/* tokenizeValue splits s into tokens.  Tokens are double-quoted strings,
+operators and parentheses, and runs of anything else not containing
+whitespace. */
+func tokenizeValue(s string) ([]exprToken, error) {
+	var ts []exprToken
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if "" == s {
+			return ts, nil
+		}
+		/* Quoted string */
+		if '"' == s[0] {
+			q, err := strconv.QuotedPrefix(s)
+			if nil != err {
+				return nil, fmt.Errorf("invalid quoted string "+
+					"at %q", s)
+			}
+			v, err := strconv.Unquote(q)
+			if nil != err {
+				return nil, fmt.Errorf("invalid quoted "+
+					"string %s: %v", q, err)
+			}
+			ts = append(ts, exprToken{s: v, quoted: true})
+			s = s[len(q):]
+			continue
+		}
+		/* Operators and parentheses */
+		if strings.ContainsRune(valuePuncts, rune(s[0])) {
+			ts = append(ts, exprToken{s: s[:1]})
+			s = s[1:]
+			continue
+		}
+		/* Anything else */
+		n := strings.IndexAny(s, " \t\""+valuePuncts)
+		if -1 == n {
+			n = len(s)
+		}
+		ts = append(ts, exprToken{s: s[:n]})
+		s = s[n:]
+	}
+}
+
+/* valueParser parses a list of tokens into a valueExpr.  Its grammar is
+
+	concat  = sum { "&" sum }
+	sum     = product { ( "+" | "-" ) product }
+	product = unary { ( "*" | "/" | "%" ) unary }
+	unary   = "-" unary | "(" concat ")" | operand
+	operand = column | quoted string | bare word
+*/
+type valueParser struct {
+	ts []exprToken
+}
+
+/* peekOp returns the next token if it's one of the unquoted single-character
+operators in ops, or 0 if not. */
+func (p *valueParser) peekOp(ops string) byte {
+	if 0 == len(p.ts) || p.ts[0].quoted || 1 != len(p.ts[0].s) ||
+		!strings.Contains(ops, p.ts[0].s) {
+		return 0
+	}
+	return p.ts[0].s[0]
+}
+
+/* valueFunc is one of valueParser's methods which parses part of an
+expression */
+type valueFunc func() (valueExpr, error)
+
+/* binary parses operands parsed with next joined with the operators in ops,
+which are left-associative */
+func (p *valueParser) binary(ops string, next valueFunc) (valueExpr, error) {
+	l, err := next()
+	if nil != err {
+		return nil, err
+	}
+	for {
+		op := p.peekOp(ops)
+		if 0 == op {
+			return l, nil
+		}
+		p.ts = p.ts[1:]
+		r, err := next()
+		if nil != err {
+			return nil, err
+		}
+		l = binaryExpr{op: op, l: l, r: r}
+	}
+}
+
+/* concat parses values joined with & */
+func (p *valueParser) concat() (valueExpr, error) {
+	return p.binary("&", p.sum)
+}
+
+/* sum parses values joined with + and - */
+func (p *valueParser) sum() (valueExpr, error) {
+	return p.binary("+-", p.product)
+}
+
+/* product parses values joined with *, /, and % */
+func (p *valueParser) product() (valueExpr, error) {
+	return p.binary("*/%", p.unary)
+}
+
+/* unary parses a negated value, a value in parentheses, or an operand */
+func (p *valueParser) unary() (valueExpr, error) {
+	if 0 == len(p.ts) {
+		return nil, fmt.Errorf("unexpected end of value")
+	}
+	t := p.ts[0]
+	p.ts = p.ts[1:]
+	switch {
+	case t.quoted:
+		return computedValue{value: t.s}, nil
+	case "-" == t.s:
+		e, err := p.unary()
+		if nil != err {
+			return nil, err
+		}
+		return negExpr{e: e}, nil
+	case "(" == t.s:
+		e, err := p.concat()
+		if nil != err {
+			return nil, err
+		}
+		if 0 == p.peekOp(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.ts = p.ts[1:]
+		return e, nil
+	case 1 == len(t.s) && strings.Contains(valuePuncts, t.s):
+		return nil, fmt.Errorf("unexpected %q", t.s)
+	}
+	if col, err := exprColumn(t); nil == err {
+		return computedValue{col: col}, nil
+	}
+	return computedValue{value: t.s}, nil
+}
@@ -0,0 +1,109 @@
+/*
+ * calc_test.go
+ * Tests for calc.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import "testing"
+
+func TestFormatNumber(t *testing.T) {
+	for _, c := range []struct {
+		f    float64
+		want string
+	}{
+		{3, "3"},
+		{1.1 * 3, "3.3"},
+		{0.1 + 0.2, "0.3"},
+		{-0.5, "-0.5"},
+		{1e21, "1000000000000000000000"},
+		{1e-7, "0.0000001"},
+		{-0.0, "0"},
+	} {
+		if got := formatNumber(c.f); got != c.want {
+			t.Errorf("%v: got %q, want %q", c.f, got, c.want)
+		}
+	}
+}
+
+func TestComputedArithmetic(t *testing.T) {
+	runComputedTests(t, []computedTest{{
+		spec: "total=col3*col4",
+		name: "total",
+		want: map[string]string{
+			"a,b,2,3":     "6",
+			"a,b,1.1,3":   "3.3",
+			"a,b, 2 ,3":   "6",
+			"a,b,x,3":     "",
+			"a,b,,3":      "",
+			"a,b,2":       "",
+			"a,b,1e3,1.5": "1500",
+		},
+	}, {
+		spec: "p = 1 + c1 * 2 - c2 / 4 % 3",
+		name: "p",
+		want: map[string]string{
+			"3,8":  "5",
+			"0,16": "0",
+		},
+	}, {
+		spec: "g=(c1+1)*-c2",
+		name: "g",
+		want: map[string]string{"2,3": "-9", "2,-3": "9", "2,x": ""},
+	}, {
+		spec: "neg=--c1",
+		name: "neg",
+		want: map[string]string{"4": "4", "0": "0", "-0": "0"},
+	}, {
+		spec: "div=c1/c2",
+		name: "div",
+		want: map[string]string{"1,0": "", "0,0": "", "1,4": "0.25"},
+	}, {
+		spec: `full=c2 & ", " & c1`,
+		name: "full",
+		want: map[string]string{"ann,smith": "smith, ann", "x": ", x"},
+	}, {
+		spec: `s=c1 & c2 + c3`,
+		name: "s",
+		want: map[string]string{"id-,1,2": "id-3"},
+	}, {
+		spec: `n=c1 & "" + 1`,
+		name: "n",
+		want: map[string]string{"a": "a"},
+	}, {
+		spec: `case=case(c1 > 10: c1 * 2, c1 & "!")`,
+		name: "case",
+		want: map[string]string{"20": "40", "5": "5!"},
+	}})
+	for _, s := range []string{
+		"x=",
+		"x=c1 +",
+		"x=(c1",
+		"x=c1)",
+		"x=c1 c2",
+		"x=*c1",
+		`x="a`,
+		"x=c1 + ()",
+	} {
+		if _, err := ParseComputed(s); nil == err {
+			t.Errorf("ParseComputed(%q) didn't fail", s)
+		}
+	}
+}
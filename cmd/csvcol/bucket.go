@@ -0,0 +1,116 @@
+/*
+ * bucket.go
+ * Grouping rows into time buckets
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* bucketSizes are the named sizes of time buckets */
+var bucketSizes = map[string]time.Duration{
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+/* timeLayouts are the layouts tried, in order, when parsing a timestamp */
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.UnixDate,
+}
+
+/* timeBucket puts rows into buckets by the time in one of their columns */
+type timeBucket struct {
+	col  int /* 1-indexed */
+	size time.Duration
+}
+
+/* parseTimeBucket parses a bucket specification of the form COL=SIZE, where
+COL is given as col:N or cN and SIZE is minute, hour, day, or a duration such
+as 15m. */
+func parseTimeBucket(s string) (timeBucket, error) {
+	var b timeBucket
+	c, size, ok := strings.Cut(s, "=")
+	if !ok {
+		return b, fmt.Errorf("missing =")
+	}
+	cols, err := csvcol.ParseGroupKey(c)
+	if nil != err {
+		return b, err
+	}
+	if 1 != len(cols) {
+		return b, fmt.Errorf("only one column may be given")
+	}
+	b.col = cols[0]
+	if d, ok := bucketSizes[size]; ok {
+		b.size = d
+	} else if b.size, err = time.ParseDuration(size); nil != err {
+		return b, fmt.Errorf("invalid size %q", size)
+	}
+	if 0 >= b.size {
+		return b, fmt.Errorf("size must be positive")
+	}
+	return b, nil
+}
+
+/* key returns the start of the bucket into which record falls, in UTC and
+formatted per RFC3339.  If the record's timestamp can't be parsed, key returns
+false. */
+func (b timeBucket) key(record []string) (string, bool) {
+	if b.col > len(record) {
+		return "", false
+	}
+	t, ok := parseTimestamp(record[b.col-1])
+	if !ok {
+		return "", false
+	}
+	return t.UTC().Truncate(b.size).Format(time.RFC3339), true
+}
+
+/* parseTimestamp parses s as a timestamp in any of timeLayouts, or as a
+number of seconds since the Unix epoch. */
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); nil == err {
+			return t, true
+		}
+	}
+	if f, err := strconv.ParseFloat(s, 64); nil == err {
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,110 @@
+/*
+ * bucket_test.go
+ * Tests for bucket.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeBucket(t *testing.T) {
+	for s, want := range map[string]timeBucket{
+		"c1=minute":  {1, time.Minute},
+		"col:3=hour": {3, time.Hour},
+		"c2=day":     {2, 24 * time.Hour},
+		"c4=15m":     {4, 15 * time.Minute},
+	} {
+		got, err := parseTimeBucket(s)
+		if nil != err {
+			t.Errorf("%q: %v", s, err)
+		} else if want != got {
+			t.Errorf("%q: got %+v, want %+v", s, got, want)
+		}
+	}
+	for _, s := range []string{
+		"c1",
+		"x=hour",
+		"c1,c2=hour",
+		"c1=week",
+		"c1=0s",
+		"c1=-1h",
+	} {
+		if _, err := parseTimeBucket(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
+
+func TestTimeBucketKey(t *testing.T) {
+	b := timeBucket{col: 2, size: 15 * time.Minute}
+	for in, want := range map[string]string{
+		"2026-01-02T10:14:59Z":            "2026-01-02T10:00:00Z",
+		"2026-01-02T10:15:00.5Z":          "2026-01-02T10:15:00Z",
+		"2026-01-02T12:31:00+02:00":       "2026-01-02T10:30:00Z",
+		" 2026-01-02 10:59:59 ":           "2026-01-02T10:45:00Z",
+		"2026-01-02 10:16":                "2026-01-02T10:15:00Z",
+		"2026-01-02":                      "2026-01-02T00:00:00Z",
+		"Fri, 02 Jan 2026 10:20:00 +0000": "2026-01-02T10:15:00Z",
+		"1767351600":                      "2026-01-02T11:00:00Z",
+		"1767351600.5":                    "2026-01-02T11:00:00Z",
+	} {
+		got, ok := b.key([]string{"x", in})
+		if !ok {
+			t.Errorf("%q: not parsed", in)
+		} else if want != got {
+			t.Errorf("%q: got %q, want %q", in, got, want)
+		}
+	}
+	for _, r := range [][]string{{"x"}, {"x", ""}, {"x", "yesterday"}} {
+		if k, ok := b.key(r); ok {
+			t.Errorf("%q: got key %q", r, k)
+		}
+	}
+}
+
+func TestTimeBucket(t *testing.T) {
+	in := "ts,v\n" +
+		"2026-01-02T11:05:00Z,4\n" +
+		"2026-01-02T10:05:00Z,1\n" +
+		"bad,9\n" +
+		"2026-01-02 10:59:59,2\n"
+	res := runCSVCol(
+		t,
+		in,
+		"-header", "-time-bucket", "c1=hour", "-cols", "2",
+	)
+	if 0 != res.code {
+		t.Fatalf("Exit status %d: %s", res.code, res.stderr)
+	}
+	want := "group,column,count,empty,numeric,min,max,sum,mean\n" +
+		"2026-01-02T10:00:00Z,v,2,0,2,1,2,3,1.5\n" +
+		"2026-01-02T11:00:00Z,v,1,0,1,4,4,4,4\n"
+	if want != res.stdout {
+		t.Errorf("Output incorrect:\ngot:\n%s\nwant:\n%s",
+			res.stdout, want)
+	}
+	if !strings.Contains(res.stderr, "Skipped 1 row(s)") {
+		t.Errorf("Skipped row not reported: %s", res.stderr)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+ * bundle.go
+ * Bundles of output files with a manifest
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/* manifestName is the name of the manifest in a bundle */
+const manifestName = "manifest.json"
+
+/* outBundle is the bundle to which output files go, for -bundle */
+var outBundle *bundle
+
+/* bundle is a directory or zip archive holding the output for each input
+file as well as a manifest describing them. */
+type bundle struct {
+	name  string            /* Directory or archive */
+	out   *output           /* Archive, if we're making one */
+	zip   *zip.Writer       /* Archive's contents */
+	names map[string]string /* Input file -> output file */
+	files []bundleEntry     /* Output files, once closed */
+}
+
+/* bundleEntry describes an output file in a bundle's manifest */
+type bundleEntry struct {
+	Name   string `json:"name"`
+	Input  string `json:"input"`
+	Rows   int    `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+/* bundleManifest is the manifest written to a bundle */
+type bundleManifest struct {
+	Created   string        `json:"created,omitempty"`
+	Arguments []string      `json:"arguments"`
+	Format    string        `json:"format"`
+	Files     []bundleEntry `json:"files"`
+}
+
+/* newBundle makes a bundle named name for the output for each of the input
+files named in inputs, which are named as per -output-per-file.  If name ends
+in .zip the bundle is a zip archive which replaces any existing file when the
+bundle is closed, otherwise it's a directory, which is created if it doesn't
+exist. */
+func newBundle(name string, inputs []string) (*bundle, error) {
+	b := &bundle{name: name, names: make(map[string]string)}
+	/* Work out and check the names of the output files */
+	seen := make(map[string]string) /* Output -> input */
+	for _, in := range inputs {
+		n := expandOutputTemplate(*gc.outputPerFile, in)
+		if !filepath.IsLocal(n) {
+			return nil, fmt.Errorf(
+				"output file %v for %v isn't inside the bundle",
+				n,
+				in,
+			)
+		}
+		n = filepath.ToSlash(filepath.Clean(n))
+		if manifestName == n {
+			return nil, fmt.Errorf(
+				"output file for %v may not be named %v",
+				in,
+				manifestName,
+			)
+		}
+		if o, ok := seen[n]; ok {
+			return nil, fmt.Errorf(
+				"output file %v is used for both %v and %v",
+				n,
+				o,
+				in,
+			)
+		}
+		seen[n] = in
+		b.names[in] = n
+	}
+
+	/* Archives are made in one go */
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		var err error
+		if b.out, err = openOutput(name, false); nil != err {
+			return nil, err
+		}
+		b.zip = zip.NewWriter(b.out)
+		return b, nil
+	}
+
+	/* Directories need to be made first, in case we're sandboxed */
+	if err := os.MkdirAll(name, 0755); nil != err {
+		return nil, err
+	}
+	for _, n := range b.names {
+		d := filepath.Join(name, filepath.Dir(filepath.FromSlash(n)))
+		if err := os.MkdirAll(d, 0755); nil != err {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+/* create creates the output file in b for the input file named in. */
+func (b *bundle) create(in string) (*bundleOutput, error) {
+	n, ok := b.names[in]
+	if !ok { /* Shouldn't happen */
+		return nil, fmt.Errorf("no output file for %v", in)
+	}
+	o := &bundleOutput{
+		b:     b,
+		h:     sha256.New(),
+		entry: bundleEntry{Name: n, Input: in},
+	}
+	var w io.Writer
+	if nil != b.zip {
+		h := &zip.FileHeader{Name: n, Method: zip.Deflate}
+		if !*gc.deterministic {
+			h.Modified = time.Now()
+		}
+		var err error
+		if w, err = b.zip.CreateHeader(h); nil != err {
+			return nil, err
+		}
+		verbose("Writing output for %v to %v in %v", in, n, b.name)
+	} else {
+		p := filepath.Join(b.name, filepath.FromSlash(n))
+		f, err := os.Create(p)
+		if nil != err {
+			return nil, err
+		}
+		verbose("Writing output for %v to %v", in, p)
+		w = f
+		o.c = f
+	}
+	o.w = io.MultiWriter(w, o.h)
+	return o, nil
+}
+
+/* Close writes b's manifest and, if b is an archive, finishes the archive
+and puts it in place. */
+func (b *bundle) Close() error {
+	m := bundleManifest{
+		Arguments: os.Args[1:],
+		Format:    *gc.format,
+		Files:     b.files,
+	}
+	if nil == m.Files {
+		m.Files = []bundleEntry{}
+	}
+	if !*gc.deterministic {
+		m.Created = time.Now().UTC().Format(time.RFC3339)
+	}
+	j, err := json.MarshalIndent(m, "", "\t")
+	if nil != err {
+		return err
+	}
+	j = append(j, '\n')
+
+	/* Directories just get another file */
+	if nil == b.zip {
+		return os.WriteFile(
+			filepath.Join(b.name, manifestName),
+			j,
+			0644,
+		)
+	}
+
+	/* Archives get another member and are done */
+	h := &zip.FileHeader{Name: manifestName, Method: zip.Deflate}
+	if !*gc.deterministic {
+		h.Modified = time.Now()
+	}
+	w, err := b.zip.CreateHeader(h)
+	if nil == err {
+		_, err = w.Write(j)
+	}
+	if nil == err {
+		err = b.zip.Close()
+	}
+	if nil != err {
+		b.out.f.Close()
+		os.Remove(b.out.f.Name())
+		return err
+	}
+	return b.out.Close()
+}
+
+/* bundleOutput is an output file in a bundle.  It keeps track of its size
+and checksum for the bundle's manifest. */
+type bundleOutput struct {
+	b     *bundle
+	w     io.Writer /* Output and hash */
+	c     io.Closer /* Output file, if not in an archive */
+	h     hash.Hash
+	entry bundleEntry
+}
+
+/* Write writes p to the output file */
+func (o *bundleOutput) Write(p []byte) (int, error) {
+	n, err := o.w.Write(p)
+	o.entry.Bytes += int64(n)
+	return n, err
+}
+
+/* setRows notes that n rows have been written, for the manifest */
+func (o *bundleOutput) setRows(n int) {
+	o.entry.Rows = n
+}
+
+/* Close closes the output file, if it's not in an archive, and adds it to the
+bundle's manifest. */
+func (o *bundleOutput) Close() error {
+	if nil != o.c {
+		if err := o.c.Close(); nil != err {
+			return err
+		}
+	}
+	o.entry.SHA256 = hex.EncodeToString(o.h.Sum(nil))
+	o.b.files = append(o.b.files, o.entry)
+	return nil
+}
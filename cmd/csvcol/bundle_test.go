@@ -0,0 +1,220 @@
+/*
+ * bundle_test.go
+ * Tests for bundle.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+/* readManifest unmarshals a bundle's manifest */
+func readManifest(t *testing.T, b []byte) bundleManifest {
+	t.Helper()
+	var m bundleManifest
+	if err := json.Unmarshal(b, &m); nil != err {
+		t.Fatalf("Unmarshalling manifest: %v\n%s", err, b)
+	}
+	return m
+}
+
+/* checkBundle checks that the files in the manifest m are the files in fs,
+which maps names to contents, with the right sizes and checksums, and the
+given numbers of rows. */
+func checkBundle(
+	t *testing.T,
+	m bundleManifest,
+	fs map[string][]byte,
+	rows map[string]int,
+) {
+	t.Helper()
+	if len(m.Files) != len(rows) {
+		t.Errorf(
+			"Manifest has %d files, want %d",
+			len(m.Files),
+			len(rows),
+		)
+	}
+	for _, e := range m.Files {
+		b, ok := fs[e.Name]
+		if !ok {
+			t.Errorf("%v in manifest but not in bundle", e.Name)
+			continue
+		}
+		sum := sha256.Sum256(b)
+		if want := hex.EncodeToString(sum[:]); want != e.SHA256 {
+			t.Errorf(
+				"%v: checksum %v, want %v",
+				e.Name,
+				e.SHA256,
+				want,
+			)
+		}
+		if int64(len(b)) != e.Bytes {
+			t.Errorf(
+				"%v: %d bytes, want %d",
+				e.Name,
+				e.Bytes,
+				len(b),
+			)
+		}
+		if want, ok := rows[e.Name]; !ok {
+			t.Errorf("Unexpected file %v", e.Name)
+		} else if want != e.Rows {
+			t.Errorf("%v: %d rows, want %d", e.Name, e.Rows, want)
+		}
+	}
+}
+
+func TestBundleDirectory(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one.csv", "h\n1\n2\n3\n")
+	two := writeTestFile(t, dir, "two.csv", "h\n4\n")
+	bdir := filepath.Join(dir, "bundle")
+	args := []string{
+		"-header",
+		"-bundle", bdir,
+		"-output-per-file", "out/{base}",
+		"-where", "c1 != 2",
+		one, two,
+	}
+	if got := mustRun(t, "", args...); "" != got {
+		t.Errorf("Unexpected standard output: %q", got)
+	}
+	fs := make(map[string][]byte)
+	for _, n := range []string{"out/one.csv", "out/two.csv"} {
+		b, err := os.ReadFile(filepath.Join(bdir, n))
+		if nil != err {
+			t.Fatalf("Reading %v: %v", n, err)
+		}
+		fs[n] = b
+	}
+	if want := "h\n1\n3\n"; want != string(fs["out/one.csv"]) {
+		t.Errorf("Got %q, want %q", fs["out/one.csv"], want)
+	}
+	mb, err := os.ReadFile(filepath.Join(bdir, manifestName))
+	if nil != err {
+		t.Fatalf("Reading manifest: %v", err)
+	}
+	m := readManifest(t, mb)
+	checkBundle(t, m, fs, map[string]int{
+		"out/one.csv": 2,
+		"out/two.csv": 1,
+	})
+	if !slices.Equal(args, m.Arguments) {
+		t.Errorf("Arguments %q, want %q", m.Arguments, args)
+	}
+	if "csv" != m.Format {
+		t.Errorf("Format %q", m.Format)
+	}
+	if "" == m.Created {
+		t.Errorf("Creation time missing")
+	}
+	if m.Files[0].Input != one || m.Files[1].Input != two {
+		t.Errorf("Inputs %q, %q", m.Files[0].Input, m.Files[1].Input)
+	}
+}
+
+func TestBundleZip(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one.csv", "a,b\nc,d\n")
+	two := writeTestFile(t, dir, "two.csv", "e,f\n")
+	var zips [2][]byte
+	for i := range zips {
+		n := filepath.Join(dir, "bundle.zip")
+		mustRun(
+			t,
+			"",
+			"-deterministic",
+			"-cols", "2",
+			"-bundle", n,
+			one, two,
+		)
+		var err error
+		if zips[i], err = os.ReadFile(n); nil != err {
+			t.Fatalf("Reading %v: %v", n, err)
+		}
+	}
+	if !bytes.Equal(zips[0], zips[1]) {
+		t.Errorf("Deterministic bundles differ")
+	}
+	checkDir(t, dir, "bundle.zip", "one.csv", "two.csv")
+
+	zr, err := zip.NewReader(
+		bytes.NewReader(zips[0]),
+		int64(len(zips[0])),
+	)
+	if nil != err {
+		t.Fatalf("Reading archive: %v", err)
+	}
+	fs := make(map[string][]byte)
+	for _, zf := range zr.File {
+		f, err := zf.Open()
+		if nil != err {
+			t.Fatalf("Opening %v: %v", zf.Name, err)
+		}
+		fs[zf.Name], err = io.ReadAll(f)
+		f.Close()
+		if nil != err {
+			t.Fatalf("Reading %v: %v", zf.Name, err)
+		}
+	}
+	m := readManifest(t, fs[manifestName])
+	delete(fs, manifestName)
+	checkBundle(t, m, fs, map[string]int{"one.csv": 2, "two.csv": 1})
+	if "b\nd\n" != string(fs["one.csv"]) {
+		t.Errorf("Got %q", fs["one.csv"])
+	}
+	if "" != m.Created {
+		t.Errorf("Deterministic bundle has creation time %q", m.Created)
+	}
+}
+
+func TestBundleFailure(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one.csv", "a\n")
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0700); nil != err {
+		t.Fatalf("Making %v: %v", sub, err)
+	}
+	again := writeTestFile(t, sub, "one.csv", "b\n")
+	bdir := filepath.Join(dir, "bundle")
+	for _, args := range [][]string{
+		{"-bundle", bdir, one, again},
+		{"-bundle", bdir, "-output-per-file", manifestName, one},
+		{"-bundle", bdir, "-output-per-file", "../{base}", one},
+		{"-bundle", bdir, "-in-place", one},
+	} {
+		res := runCSVCol(t, "", args...)
+		if -52 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
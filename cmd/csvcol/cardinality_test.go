@@ -0,0 +1,85 @@
+/*
+ * cardinality_test.go
+ * Tests for cardinality.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000, 50000, 200000} {
+		var h hyperLogLog
+		/* Each value is added twice, as duplicates don't count */
+		for i := 0; i < n; i++ {
+			h.add("v" + strconv.Itoa(i))
+			h.add("v" + strconv.Itoa(i))
+		}
+		/* Allow a few standard errors */
+		got := float64(h.estimate())
+		e := math.Abs(got-float64(n)) / float64(max(n, 1))
+		if 0.03 < e {
+			t.Errorf("%d values: estimated %v", n, got)
+		}
+	}
+}
+
+func TestCardinality(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("id,flag,mod\n")
+	for i := 0; i < 20000; i++ {
+		sb.WriteString(strconv.Itoa(i) + ",y,")
+		sb.WriteString(strconv.Itoa(i%100) + "\n")
+	}
+	res := runCSVCol(t, sb.String(), "-header", "-cardinality")
+	if 0 != res.code {
+		t.Fatalf("Failed: %s", res.stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(res.stdout), "\n")
+	if 4 != len(lines) || "column,name,count,distinct" != lines[0] {
+		t.Fatalf("Unexpected output:\n%s", res.stdout)
+	}
+	if "2,flag,20000,1" != lines[2] || "3,mod,20000,100" != lines[3] {
+		t.Errorf("Small counts incorrect:\n%s", res.stdout)
+	}
+	fs := strings.Split(lines[1], ",")
+	d, err := strconv.Atoi(fs[len(fs)-1])
+	if nil != err || "1,id,20000" != strings.Join(fs[:3], ",") ||
+		20000 < d || 19400 > d {
+		t.Errorf("Large count incorrect: %s", lines[1])
+	}
+
+	/* Should only use selected columns, and never more than the count */
+	runOutputTests(t, []outputTest{{
+		name:  "selected",
+		stdin: "a,b\n1,2\n3,4\n",
+		args:  []string{"-cardinality", "-cols", "2"},
+		want:  "column,name,count,distinct\n2,c2,3,3\n",
+	}})
+	res = runCSVCol(t, "a\n", "-cardinality", "-stats")
+	if -25 != int8(res.code) {
+		t.Errorf("-cardinality -stats exit code %d", int8(res.code))
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * collate.go
+ * Locale-aware string comparison
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"sync"
+
+	"github.com/magisterquis/csvcol"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+/* setCollation makes csvcol compare strings using the collation order of the
+given locale, e.g. de_DE or sv. */
+func setCollation(locale string) error {
+	tag, err := language.Parse(locale)
+	if nil != err {
+		return err
+	}
+	debug("Collating with %v", tag)
+	c := collate.New(tag)
+	/* Collators aren't safe to use concurrently */
+	var mu sync.Mutex
+	csvcol.SetStringCompare(func(a, b string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return c.CompareString(a, b)
+	})
+	return nil
+}
@@ -0,0 +1,134 @@
+/*
+ * console_windows.go
+ * Display output properly on a Windows console
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/sys/windows"
+)
+
+/* cpUTF8 is the UTF-8 code page */
+const cpUTF8 = 65001
+
+/* If the standard output is a console and the system's code page isn't
+UTF-8, text which isn't UTF-8 is likely in the system's code page.  Go already
+writes UTF-8 to a console properly, but anything else would end up as
+replacement characters. */
+func init() {
+	var m uint32
+	h := windows.Handle(os.Stdout.Fd())
+	if nil != windows.GetConsoleMode(h, &m) {
+		return
+	}
+	if cp := windows.GetACP(); cpUTF8 != cp {
+		stdout = &consoleWriter{w: os.Stdout, cp: cp}
+	}
+}
+
+/* consoleWriter is a writer which converts text which isn't UTF-8 from the
+code page cp to UTF-8 before writing it to w. */
+type consoleWriter struct {
+	w       io.Writer
+	cp      uint32
+	partial []byte /* Start of a rune which may be finished next Write */
+}
+
+/* Write converts p to UTF-8 as needed and writes it to c.w.  The last few
+bytes of p may be held until the next call to Write, if they might be the
+start of a UTF-8 rune split across writes. */
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	b := append(c.partial, p...)
+	c.partial = nil
+
+	/* Hold back what might be a split rune */
+	for i := len(b) - 1; 0 <= i && len(b)-utf8.UTFMax < i; i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+		if !utf8.FullRune(b[i:]) {
+			c.partial = append([]byte{}, b[i:]...)
+			b = b[:i]
+		}
+		break
+	}
+
+	/* Most of the time, there'll be nothing to convert */
+	if utf8.Valid(b) {
+		_, err := c.w.Write(b)
+		return len(p), err
+	}
+	out := make([]byte, 0, len(b)+len(b)/2)
+	for 0 != len(b) {
+		r, n := utf8.DecodeRune(b)
+		if utf8.RuneError != r || 1 != n {
+			out = append(out, b[:n]...)
+			b = b[n:]
+			continue
+		}
+		/* Convert the whole run of non-UTF-8 */
+		j := 1
+		for j < len(b) {
+			r, n := utf8.DecodeRune(b[j:])
+			if utf8.RuneError != r || 1 != n {
+				break
+			}
+			j++
+		}
+		out = append(out, c.decode(b[:j])...)
+		b = b[j:]
+	}
+	_, err := c.w.Write(out)
+	return len(p), err
+}
+
+/* decode converts b from c.cp to UTF-8.  If it can't be converted, b is
+returned as-is. */
+func (c *consoleWriter) decode(b []byte) []byte {
+	n, err := windows.MultiByteToWideChar(
+		c.cp,
+		0,
+		&b[0],
+		int32(len(b)),
+		nil,
+		0,
+	)
+	if nil != err || 0 >= n {
+		return b
+	}
+	u := make([]uint16, n)
+	if _, err := windows.MultiByteToWideChar(
+		c.cp,
+		0,
+		&b[0],
+		int32(len(b)),
+		&u[0],
+		n,
+	); nil != err {
+		return b
+	}
+	return []byte(string(utf16.Decode(u)))
+}
@@ -0,0 +1,81 @@
+/*
+ * console_windows_test.go
+ * Tests for console_windows.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+/* cpWindows1252 is the Western European Windows code page */
+const cpWindows1252 = 1252
+
+func TestConsoleWriter(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		writes []string
+		want   string
+	}{{
+		name:   "ascii",
+		writes: []string{"a,b\n"},
+		want:   "a,b\n",
+	}, {
+		name:   "utf8",
+		writes: []string{"café,über\n"},
+		want:   "café,über\n",
+	}, {
+		name:   "cp1252",
+		writes: []string{"caf\xe9,\x80\x96\n"},
+		want:   "café,€–\n",
+	}, {
+		name:   "mixed",
+		writes: []string{"é\xe9\n"},
+		want:   "éé\n",
+	}, {
+		name:   "split_rune",
+		writes: []string{"caf\xc3", "\xa9\n"},
+		want:   "café\n",
+	}, {
+		name:   "split_euro",
+		writes: []string{"\xe2\x82", "\xac\n"},
+		want:   "€\n",
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			var b bytes.Buffer
+			cw := &consoleWriter{w: &b, cp: cpWindows1252}
+			for _, w := range c.writes {
+				n, err := cw.Write([]byte(w))
+				if nil != err {
+					t.Fatalf("Write: %v", err)
+				}
+				if len(w) != n {
+					t.Errorf("Wrote %d/%d bytes", n, len(w))
+				}
+			}
+			if got := b.String(); got != c.want {
+				t.Errorf("Got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * constant.go
+ * Find constant and nearly-constant columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+/* constantCounters is the number of distinct values counted exactly per
+column when looking for constant columns.  Past this, counts are
+approximate, as for -top-k. */
+const constantCounters = 1000
+
+/* constantCols finds columns in which one value makes up at least threshold
+of the values.  It uses a topK to count values, so counts are exact unless
+a column has more than constantCounters distinct values, in which case it's
+unlikely to be nearly constant anyways. */
+type constantCols struct {
+	*topK
+	threshold float64
+	values    []int /* Values, per column */
+}
+
+/* newConstantCols returns a constantCols which flags columns in which one
+value makes up at least threshold of the values. */
+func newConstantCols(threshold float64, column func(i int) int) *constantCols {
+	return &constantCols{
+		topK:      newTopK(1, constantCounters, column),
+		threshold: threshold,
+	}
+}
+
+/* add counts the fields of orec.  Name returns the name of the ith field of
+orec.  Record is ignored. */
+func (c *constantCols) add(record, orec []string, name func(i int) string) {
+	c.topK.add(record, orec, name)
+	for len(c.values) < len(orec) {
+		c.values = append(c.values, 0)
+	}
+	for i := range orec {
+		c.values[i]++
+	}
+}
+
+/* each calls f with a header and then with a record for each column with
+the column's input column number and name, the number of values, the number
+of distinct values, the most common value, the fraction of values which are
+the most common value, the column's entropy in bits, and whether or not the
+column is constant enough.  If the column had too many distinct values to
+count exactly, the number of distinct values and entropy are empty and the
+fraction is a lower bound. */
+func (c *constantCols) each(f func([]string) error) error {
+	if err := f([]string{
+		"column", "name", "count", "distinct", "top", "top_fraction",
+		"entropy", "constant",
+	}); nil != err {
+		return err
+	}
+	for i, n := range c.names {
+		var (
+			top      string
+			topCount int
+			entropy  float64
+		)
+		/* Sorted, so floating point sums come out the same every
+		time */
+		vs := make([]string, 0, len(c.counts[i]))
+		for v := range c.counts[i] {
+			vs = append(vs, v)
+		}
+		sort.Strings(vs)
+		for _, v := range vs {
+			vc := c.counts[i][v]
+			if vc > topCount || (vc == topCount && v < top) {
+				top, topCount = v, vc
+			}
+			p := float64(vc) / float64(c.values[i])
+			entropy -= p * math.Log2(p)
+		}
+		frac := float64(topCount) / float64(c.values[i])
+		distinct := strconv.Itoa(len(c.counts[i]))
+		ent := strconv.FormatFloat(math.Abs(entropy), 'f', 3, 64)
+		if 0 != c.errs[i] {
+			distinct, ent = "", ""
+		}
+		if err := f([]string{
+			strconv.Itoa(c.cols[i]),
+			n,
+			strconv.Itoa(c.values[i]),
+			distinct,
+			top,
+			strconv.FormatFloat(frac, 'f', 3, 64),
+			ent,
+			strconv.FormatBool(frac >= c.threshold),
+		}); nil != err {
+			return err
+		}
+	}
+	return nil
+}
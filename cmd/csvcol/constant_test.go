@@ -0,0 +1,113 @@
+/*
+ * constant_test.go
+ * Tests for constant.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestConstantColsEach(t *testing.T) {
+	c := newConstantCols(0.75, func(i int) int { return i + 1 })
+	for _, r := range []string{"x,1,a", "x,1,b", "x,2,a", "x,2,a"} {
+		c.add(nil, strings.Split(r, ","), func(i int) string {
+			return fmt.Sprintf("n%d", i)
+		})
+	}
+	var rs []string
+	if err := c.each(func(r []string) error {
+		rs = append(rs, strings.Join(r, ","))
+		return nil
+	}); nil != err {
+		t.Fatalf("each: %v", err)
+	}
+	want := []string{
+		"column,name,count,distinct,top,top_fraction,entropy,constant",
+		"1,n0,4,1,x,1.000,0.000,true",
+		"2,n1,4,2,1,0.500,1.000,false",
+		"3,n2,4,2,a,0.750,0.811,true",
+	}
+	if fmt.Sprint(want) != fmt.Sprint(rs) {
+		t.Errorf(
+			"Got:\n%s\nwant:\n%s",
+			strings.Join(rs, "\n"),
+			strings.Join(want, "\n"),
+		)
+	}
+}
+
+func TestConstantColsManyValues(t *testing.T) {
+	/* Too many values to count exactly should leave out the distinct
+	count and entropy */
+	c := newConstantCols(0.5, func(i int) int { return i + 1 })
+	for i := 0; i < 2*constantCounters; i++ {
+		c.add(nil, []string{strconv.Itoa(i)}, func(int) string {
+			return "c1"
+		})
+	}
+	var rs [][]string
+	if err := c.each(func(r []string) error {
+		rs = append(rs, r)
+		return nil
+	}); nil != err {
+		t.Fatalf("each: %v", err)
+	}
+	if 2 != len(rs) {
+		t.Fatalf("Got %d records, expected 2", len(rs))
+	}
+	if "" != rs[1][3] || "" != rs[1][6] || "false" != rs[1][7] {
+		t.Errorf("Unexpected record: %q", rs[1])
+	}
+}
+
+func TestConstantCols(t *testing.T) {
+	in := "id,flag,kind\n1,y,a\n2,y,a\n3,y,b\n4,n,a\n"
+	runOutputTests(t, []outputTest{{
+		name:  "header",
+		stdin: in,
+		args:  []string{"-header", "-constant-cols", "0.75"},
+		want: "column,name,count,distinct,top,top_fraction,entropy," +
+			"constant\n" +
+			"1,id,4,4,1,0.250,2.000,false\n" +
+			"2,flag,4,2,y,0.750,0.811,true\n" +
+			"3,kind,4,2,a,0.750,0.811,true\n",
+	}, {
+		name:  "selected",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "2",
+			"-rows", "1-3",
+			"-constant-cols", "1",
+		},
+		want: "column,name,count,distinct,top,top_fraction,entropy," +
+			"constant\n" +
+			"2,flag,3,1,y,1.000,0.000,true\n",
+	}})
+	res := runCSVCol(t, "a\n", "-constant-cols", "0.9", "-stats")
+	if -25 != int8(res.code) {
+		t.Errorf("-constant-cols -stats exit code %d", int8(res.code))
+	}
+}
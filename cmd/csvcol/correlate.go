@@ -0,0 +1,132 @@
+/*
+ * correlate.go
+ * Pearson correlation between columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* comoment accumulates what's needed to work out the correlation between two
+columns, one pair of values at a time. */
+type comoment struct {
+	n      float64
+	mx, my float64 /* Means */
+	m2x    float64 /* Sums of squared differences from the means */
+	m2y    float64
+	c      float64 /* Sum of products of differences from the means */
+}
+
+/* add adds a pair of values, updating the means and sums as per Welford */
+func (m *comoment) add(x, y float64) {
+	m.n++
+	dx := x - m.mx
+	m.mx += dx / m.n
+	dy := y - m.my
+	m.my += dy / m.n
+	m.m2x += dx * (x - m.mx)
+	m.m2y += dy * (y - m.my)
+	m.c += dx * (y - m.my)
+}
+
+/* r returns Pearson's correlation coefficient, or false if there's not
+enough data or either column doesn't vary. */
+func (m *comoment) r() (float64, bool) {
+	if 2 > m.n || 0 == m.m2x || 0 == m.m2y {
+		return 0, false
+	}
+	return m.c / math.Sqrt(m.m2x*m.m2y), true
+}
+
+/* correlation works out the correlation between each pair of columns.  Only
+rows in which both columns are numbers count towards a pair's correlation. */
+type correlation struct {
+	names []string
+	pairs [][]*comoment /* pairs[i][j], j < i; pairs[i][i] is i with i */
+	vals  []float64     /* Reused for each row */
+	ok    []bool
+}
+
+/* add adds the numeric fields of orec to the correlations.  Name returns
+the name of the ith field of orec.  Record is ignored. */
+func (c *correlation) add(record, orec []string, name func(i int) string) {
+	for len(c.names) < len(orec) {
+		i := len(c.names)
+		c.names = append(c.names, name(i))
+		row := make([]*comoment, i+1)
+		for j := range row {
+			row[j] = &comoment{}
+		}
+		c.pairs = append(c.pairs, row)
+		c.vals = append(c.vals, 0)
+		c.ok = append(c.ok, false)
+	}
+	for i := range c.vals {
+		c.ok[i] = false
+		if i >= len(orec) {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(orec[i]), 64)
+		if nil == err && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			c.vals[i], c.ok[i] = f, true
+		}
+	}
+	for i, row := range c.pairs {
+		if !c.ok[i] {
+			continue
+		}
+		for j, m := range row {
+			if c.ok[j] {
+				m.add(c.vals[j], c.vals[i])
+			}
+		}
+	}
+}
+
+/* each calls f with a header of the columns' names and then with a row of
+the matrix of correlations for each column.  Correlations which can't be
+worked out are empty. */
+func (c *correlation) each(f func([]string) error) error {
+	if err := f(append([]string{""}, c.names...)); nil != err {
+		return err
+	}
+	for i, n := range c.names {
+		row := make([]string, len(c.names)+1)
+		row[0] = n
+		for j := range c.names {
+			a, b := i, j
+			if b > a {
+				a, b = b, a
+			}
+			m := c.pairs[a][b]
+			if r, ok := m.r(); ok {
+				row[j+1] = strconv.FormatFloat(r, 'f', 4, 64)
+			}
+		}
+		if err := f(row); nil != err {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+ * correlate_test.go
+ * Tests for correlate.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComoment(t *testing.T) {
+	xs := []float64{1, 2, 3, 4, 5, 6}
+	ys := []float64{2.5, 3, 7, 6.5, 11, 10}
+	var m comoment
+	for i := range xs {
+		m.add(xs[i], ys[i])
+	}
+	/* Work it out the long way */
+	var sx, sy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+	}
+	mx, my := sx/float64(len(xs)), sy/float64(len(ys))
+	var cxy, cxx, cyy float64
+	for i := range xs {
+		cxy += (xs[i] - mx) * (ys[i] - my)
+		cxx += (xs[i] - mx) * (xs[i] - mx)
+		cyy += (ys[i] - my) * (ys[i] - my)
+	}
+	want := cxy / math.Sqrt(cxx*cyy)
+	if got, ok := m.r(); !ok {
+		t.Errorf("No coefficient")
+	} else if 1e-12 < math.Abs(got-want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+
+	/* Too little data or no variation */
+	for _, ps := range [][][2]float64{
+		{},
+		{{1, 2}},
+		{{1, 2}, {1, 3}},
+		{{1, 2}, {2, 2}},
+	} {
+		var m comoment
+		for _, p := range ps {
+			m.add(p[0], p[1])
+		}
+		if r, ok := m.r(); ok {
+			t.Errorf("%v: got %v", ps, r)
+		}
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name: "matrix",
+		stdin: "x,y,z,k\n" +
+			"1,2,-1,5\n2,4,-2,5\n3,6,-3,5\n4,8,x,5\n",
+		args: []string{"-correlate", "-header"},
+		want: ",x,y,z,k\n" +
+			"x,1.0000,1.0000,-1.0000,\n" +
+			"y,1.0000,1.0000,-1.0000,\n" +
+			"z,-1.0000,-1.0000,1.0000,\n" +
+			"k,,,,\n",
+	}, {
+		name:  "pairwise",
+		stdin: "1,1,1\n2,,3\n3,3,2\n,4,4\n",
+		args:  []string{"-correlate", "-cols", "1,2"},
+		want:  ",c1,c2\nc1,1.0000,1.0000\nc2,1.0000,1.0000\n",
+	}, {
+		name:  "imperfect",
+		stdin: "1,1\n2,3\n3,2\n",
+		args:  []string{"-correlate"},
+		want:  ",c1,c2\nc1,1.0000,0.5000\nc2,0.5000,1.0000\n",
+	}})
+}
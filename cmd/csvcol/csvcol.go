@@ -0,0 +1,1962 @@
+/*
+ * csvcol.go
+ * Program to select columns (and rows) from a CSV file.
+ * by J. Stuart McMurray
+ * Created 20141119
+ * Last modified 20261017
+ *
+ * Copyright (c) 2014-2017 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/magisterquis/csvcol"
+	"github.com/magisterquis/ranges"
+)
+
+/* Global config */
+var gc struct {
+	csvfile       listFlag
+	rows          *string
+	notRows       *string
+	rowfile       listFlag
+	rowsEnv       *string
+	cols          *string
+	notCols       *string
+	colfile       listFlag
+	colsEnv       *string
+	colnames      *string
+	colre         listFlag
+	verbose       *bool
+	v             *bool
+	debug         *bool
+	d             *bool
+	commentChar   *string
+	trace         *string
+	outputPerFile *string
+	bundle        *string
+	output        *string
+	compress      *bool
+	inPlace       *bool
+	preserveMtime *bool
+	lockfile      *string
+	sandbox       *bool
+	estimate      *int
+	preview       *int
+	listCols      *bool
+	diagnose      *bool
+	compareSchema *bool
+	schemaSample  *int
+	idleTimeout   *time.Duration
+	timeout       *time.Duration
+	idleContinue  *bool
+	delim         *string
+	tab           *bool
+	comma         rune /* Parsed from -delim or -tab */
+	outDelim      *string
+	outComma      rune /* Parsed from -outdelim */
+	json          *bool
+	format        *string
+	emailRows     *int
+	emailWidth    *int
+	section       *int
+	sectionMarker *string
+	sectionRE     *regexp.Regexp /* Compiled -section-marker */
+	ordered       *bool
+	firstPerGroup *string
+	lastPerGroup  *string
+	statsBy       *string
+	correlate     *bool
+	piiScan       *bool
+	topK          *int
+	topKCounters  *int
+	cardinality   *bool
+	constantCols  *float64
+	stats         *bool
+	untrusted     *bool
+	maxRows       *int
+	maxFieldSize  *int
+	maxOutput     *string
+	maxTime       *time.Duration
+	uniq          *string
+	freq          *string
+	perfile       *bool
+	withFilename  *bool
+	filenameLast  *bool
+	withLinenum   *bool
+	sample        *float64
+	sampleN       *int
+	seed          *uint64
+	shuffle       *bool
+	deterministic *bool
+	outliers      *string
+	flagOutliers  bool /* -flag-outliers adds a column */
+	addCols       listFlag
+	transforms    listFlag
+	collate       *string
+	sheet         *string
+	xlsx          bool /* Recognize XLSX workbooks */
+	xlsxHeader    *bool
+	sqlite        *string
+	sqliteTable   *string
+	normalize     *string
+	stripInvis    *bool
+	normCols      *string
+	fixText       *bool
+	dedupWindow   *int
+	dedup         *bool
+	dedupKey      *string
+	join          *string
+	paste         *bool
+	transpose     *bool
+	transposeMax  *string
+	pasteEnd      *string
+	joinOn        *string
+	joinType      *string
+	joinCols      *string
+	maxMem        *string
+	maxMemBytes   int64 /* Parsed -max-mem */
+	maxTranspose  int64 /* Parsed -transpose-max */
+	jsonNested    *bool
+	template      *string
+	templateFile  *string
+	tmpl          *template.Template /* Parsed -template */
+	tmplNewline   bool               /* -template needs a newline */
+	fixTextCols   *string
+	pad           listFlag
+	computed      []*csvcol.Computed /* Parsed -add-col */
+	sampleWeight  *string
+	timeBucket    *string
+	groupSep      *string
+	groupSepText  *string
+	watermark     *int
+	grpc          *string
+	grpcMaxMsg    *string
+	maxGRPCMsg    int64 /* Parsed -grpc-max-message */
+	header        *bool
+	daemon        *string
+	job           *string
+	stages        listFlag
+	pseudonymize  listFlag
+	mask          listFlag
+	hash          listFlag
+	mapValues     listFlag
+	mapMissing    *string
+	tokenize      listFlag
+	detokenize    listFlag
+	tokenMap      *string
+	tokenKey      *string
+	strict        *bool
+	skipBad       *bool
+	workers       *int
+	whereAll      listFlag
+	whereAny      listFlag
+	in            listFlag
+	notIn         listFlag
+	match         listFlag
+	vmatch        listFlag
+}
+
+/* listFlag is a flag which may be given multiple times */
+type listFlag []string
+
+/* String satisfies flag.Value */
+func (l *listFlag) String() string { return strings.Join(*l, " ") }
+
+/* Set satisfies flag.Value */
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+/* inRecord is a record read from an input file */
+type inRecord struct {
+	record []string
+	file   string /* Printable name */
+	line   int
+	offset int64
+}
+
+/* traceRule is one comma-separated piece of a row or column specification,
+kept so that -trace can report which pieces matched each output row. */
+type traceRule struct {
+	Spec   string
+	filter ranges.Filter
+}
+
+/* traceRecord is written to the -trace file for every output row */
+type traceRecord struct {
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Offset int64    `json:"offset"`
+	Row    int      `json:"row"`
+	Rows   []string `json:"rows"`
+	Cols   []string `json:"cols"`
+}
+
+func main() {
+	/* Set flags and parse */
+	flag.Var(&gc.csvfile, "csvfile", "CSV file to read.  CSV-formatted data will be also be read from the file(s) listed on the command line (in the order listed).  Files compressed with gzip, bzip2, or zstd are decompressed automatically; zstd requires the zstd program, which is checked for before reading any input if a file's name ends in .zst.  If -csvfile or one of the files listed on the command line is - or no files are listed on the command line and -csvfile is not specified, CSV-formatted data will be read from standard input (in which case, neither rowfile nor colfile may be -), e.g. csvcol header.csv - footer.csv.  Named pipes and /dev/fd files, e.g. from the shell's <(...), may be used for any number of files.  If both -csvfile and additional files are given, the file named by -csvfile will be read first (even if it is -).  May be given multiple times, in which case the files are read in the order given.")
+	gc.rows = flag.String("rows", "", "The row(-number)s to output.  This is given as a comma-separated list of row numbers or ranges.  Either the starting or ending number may be omitted in a range to indicate the first or last row, respectively.  Example: -3,5-7,9,11-, which outputs rows 1, 2, 3, 5, 6, 7, 9, and all rows from the 11th row to the end of the data (inclusive of the 11th row).  Rows may be counted from the end with -N-, for the last N rows, or -N--M, for the Nth-from-last to the Mth-from-last rows; -1- is the last row.  This requires holding the last N rows in memory until the end of the input.  Any range may be followed by /S or :S to output only every Sth row, starting with the first in the range, e.g. 2-1000/10 for rows 2, 12, 22, and so on, or 1-:2 for every other row.  Rows may also be given relative to the first row matching a regular expression (matched against the row's fields joined by the delimiter) with after:/REGEX/OFFSETS, where OFFSETS is a number or range of numbers of rows after the matching row, e.g. after:/^HEADER2/+1- for all rows after the first row starting with HEADER2.  OFFSETS defaults to +1- and the + is optional.  By default, all rows are output if neither -ros nor -rowfile are specified.  The row counter is not reset between each file, unless -perfile is given.  It is as if all the files were concatenated.  Once no later row can be selected, e.g. after row 100 with -rows 5-100, no more input is read.")
+	gc.perfile = flag.Bool("perfile", false, "Restart the row counter at 1 for each input file, so that rows are selected from each file as if it were the only file, e.g. -perfile -rows 2- to skip every file's first line.  Rows counted from the end and anchored rows are also worked out separately for each file.")
+	gc.withFilename = flag.Bool("with-filename", false, "Add a column to the start of each output row containing the name of the file from which the row came, like grep -H.  The standard input is named \"standard input\".  The column is added after any -stage stages and its name, in the header and with -json, is filename.")
+	gc.filenameLast = flag.Bool("filename-last", false, "With -with-filename, add the file name to the end of each output row instead of the start.")
+	gc.withLinenum = flag.Bool("with-linenum", false, "Add a column to the start of each output row containing the row's 1-indexed row number in the input, before any rows were filtered out, as used by -rows.  With -with-filename, the file name comes first.  The column's name, in the header and with -json, is linenum.")
+	gc.notRows = flag.String("notrows", "", "The row(-number)s not to output, in the same format as -rows.  Rows specified here will not be output even if specified with -rows or -rowfile.  If neither -rows nor -rowfile is given, all other rows will be output.  A specification given to -rows (or a line in the -rowfile) which starts with a ! is treated as if it were given to -notrows.  Example: -notrows 3,7-9 or -rows '!3,7-9'")
+	flag.Var(&gc.rowfile, "rowfile", "If specified, 1-indexed row numbers to to indicate rows to output will be read from this file.  The format is the nearly the same as for -rows, but may be given on multiple lines.  Blank lines and everything after a # are ignored.  A line of the form @include otherfile reads more specifications from otherfile, which is relative to the directory containing the including file.  May be - to read from the standard input (in which case, neither csvfile nor colfile may be -) or a named pipe or /dev/fd file, e.g. -rowfile <(cut -f 1 -d : hits).  If both this and -rows are specified, rows specified by either this file or -rows will be output.  May be given multiple times, in which case rows specified by any of the files will be output.")
+	gc.rowsEnv = flag.String("rows-env", "", "If specified, also output the rows given in the environment variable with this `name`, in the same format as for -rows.  It's an error if the variable isn't set.  Example: -rows-env CSVCOL_ROWS")
+	gc.cols = flag.String("cols", "", "The column(-number)s to output.  This is given as a comma-separated list of column numbers or ranges.  Either the starting or ending number may be omitted in a range to indicate the first or last column, respectively.  Example: -3,5-7,9,11-, which outputs columns 1, 2, 3, 5, 6, 7, 9, and all columns from the 11th column to the end of the data (inclusive of the 11th column).  Columns may be counted from the end of each row with -N-, for the last N columns, or -N--M, for the Nth-from-last to the Mth-from-last columns; -1- is the last column.  Any range may be followed by /S or :S to output only every Sth column, e.g. 1-/2 for every other column.  By default, all columns are output if neither -cols nor -colfile are specified.")
+	gc.notCols = flag.String("notcols", "", "The column(-number)s not to output, in the same format as -cols.  Columns specified here will not be output even if specified with -cols, -colfile, or -colnames.  If none of those are given, all other columns will be output.  A specification given to -cols (or a line in the -colfile) which starts with a ! is treated as if it were given to -notcols.  Example: -notcols 3,7-9 or -cols '!3,7-9'")
+	flag.Var(&gc.colfile, "colfile", "If specified, 1-indexed column numbers to to indicate columns to output will be read from this file.  The format is the nearly the same as for -columns, but may be given on multiple lines.  Blank lines, everything after a #, and @include lines are handled as for -rowfile.  May be - to read from the standard input (in which case, neither csvfile nor rowfile may be -) or a named pipe or /dev/fd file.  If both this and -cols are specified, columns specified by either this file or -cols will be output.  May be given multiple times, in which case columns specified by any of the files will be output.")
+	gc.colsEnv = flag.String("cols-env", "", "If specified, also output the columns given in the environment variable with this `name`, in the same format as for -cols.  It's an error if the variable isn't set.")
+	gc.colnames = flag.String("colnames", "", "Comma-separated list of the names of columns to output.  The first row of the input which isn't a comment is taken to be a header containing the names of the columns.  The list is parsed as a line of CSV, so names containing commas may be double-quoted.  A range of columns may be given as FIRST-LAST, for the columns from the one named FIRST to the one named LAST, inclusive; either name may be omitted to mean the first or last column.  A name which is a column's name is never treated as a range.  If -cols or -colfile are also specified, columns specified by any of them will be output.  Example: -colnames 'email,timestamp-status'")
+	gc.delim = flag.String("delim", ",", "Input field delimiter.  Must be a single character, which may be given as \\t for a tab.  Example: -delim ';'")
+	gc.tab = flag.Bool("tab", false, "Same as -delim '\\t', for reading TSV files.")
+	gc.outDelim = flag.String("outdelim", ",", "Output field delimiter, independent of -delim.  Must be a single character, which may be given as \\t for a tab.  Example: -outdelim '\\t'")
+	gc.json = flag.Bool("json", false, "Output one JSON object per row (JSON Lines) instead of CSV.  If column names are known (e.g. with -colnames), they are used as the objects' keys and the header row is not output.  Otherwise, keys are of the form cN, where N is the 1-indexed input column number.  Same as -format json.")
+	gc.format = flag.String("format", "csv", "Output format, one of csv, json (see -json), json-array, table, markdown, text-email, xlsx, sql, or pgcopy.  json-array is the same as json, but the objects are written as a single JSON array.  Table and markdown output have the columns aligned, for reading in a terminal or pasting into a ticket, and are written once all of the input has been read.  The header, if there is one (e.g. with -header or -colnames), is underlined.  Markdown tables without a header get one with column names of the form cN.  Text-email output is a table cut down to fit in an email, as per -email-rows and -email-width, with long fields ending in ... and a column of ...'s in place of columns which don't fit.  XLSX output is a workbook with a single sheet, best written to a file with -o; numbers are written as numbers unless they'd lose something, like leading zeros.  SQL output is an INSERT statement per row and pgcopy output is a PostgreSQL COPY statement with its data in text format, both for the table named with -table and with column names from the header, if there is one, or of the form cN if not; empty fields become NULL.")
+	gc.emailRows = flag.Int("email-rows", 20, "Maximum number of rows, not counting the header, to output with -format text-email.  Rows past the limit are noted at the end of the table.")
+	gc.emailWidth = flag.Int("email-width", 72, "Maximum width, in characters, of the table output with -format text-email.  Columns which don't fit are left out.")
+	gc.sqlite = flag.String("sqlite", "", "Load the output into a table in the SQLite database in the named `file`, created if it doesn't exist, instead of writing it.  The table's column names come from the header, if there is one (e.g. with -header or -colnames), or are of the form cN if not.  If the table doesn't exist, it's created with each column's type (INTEGER, REAL, or TEXT) inferred from its values; empty fields in INTEGER and REAL columns are loaded as NULL.  Requires the sqlite3 command-line program, which must be in the PATH; csvcol checks for it before reading any input.")
+	gc.sqliteTable = flag.String("table", "data", "Name of the `table` into which to load the output with -sqlite, or for which to write -format sql or pgcopy")
+	gc.xlsxHeader = flag.Bool("xlsx-header", false, "Bold and freeze the header of XLSX output, if there is one (e.g. with -header or -colnames)")
+	gc.section = flag.Int("section", 0, "If non-zero, treat only this section of each input file as CSV data.  Sections are separated by one or more blank lines, unless -section-marker is given.  The first section is section 1.")
+	gc.sectionMarker = flag.String("section-marker", "", "If specified, sections (see -section) start with a line matching this regular expression instead of being separated by blank lines.  Marker lines are not treated as data.  Lines before the first marker are section 0, and -section defaults to 1.  Example: -section-marker '^\\[.*\\]$'")
+	flag.Var(&gc.colre, "colre", "Output the columns whose names match this regular expression.  Names are taken from the first row, as for -colnames.  May be given multiple times, and may be used with -cols, -colfile, and -colnames.  Example: -colre '^metric_'")
+	gc.ordered = flag.Bool("ordered", false, "Output columns in the order given by -cols, -colfile, -colnames, and -colre (in that order) rather than in the order in which they appear in the input.  Columns given more than once are output more than once.  Example: -ordered -cols 5,1,1,3")
+	gc.firstPerGroup = flag.String("first-per-group", "", "If specified, only output the first selected row for each distinct value of the given column or columns.  Columns are given as a comma-separated list of col:N or cN, e.g. col:1 or c1,c3.  Memory use grows with the number of distinct values.")
+	gc.lastPerGroup = flag.String("last-per-group", "", "Like -first-per-group, but output the last selected row for each distinct value.  Rows are held in memory and output at the end of the input, in the order in which they were read.")
+	gc.stats = flag.Bool("stats", false, "Instead of outputting the selected rows, output summary statistics for each selected column, in one pass over the input.  The statistics are output as CSV with the columns column, name, count, empty, numeric, distinct, min, max, mean, and stddev.  Distinct is an estimate of the number of distinct non-empty values, as for -cardinality.  Min and max compare numbers as numbers and other values as strings; mean and stddev (the sample standard deviation) are of the numeric values only.")
+	gc.statsBy = flag.String("stats-by", "", "If specified, output summary statistics of the selected columns instead of the selected rows, computed separately for each distinct value of the given column or columns, which are given as for -first-per-group.  The statistics are output as CSV with the columns group, column, count, empty, numeric, min, max, sum, and mean.  Min and max compare numbers as numbers and other values as strings; sum and mean are of the numeric values only.  Example: -stats-by col:1 -cols 3,4")
+	gc.timeBucket = flag.String("time-bucket", "", "If specified, output summary statistics of the selected columns, as for -stats-by, computed separately for each interval of time.  The interval into which a row falls is taken from a column containing a timestamp.  The column and interval size are given as COL=SIZE, where COL is col:N or cN and SIZE is minute, hour, day, or a duration such as 15m.  Timestamps may be in RFC 3339 or similar formats (e.g. 2006-01-02 15:04:05) or seconds since the Unix epoch.  The group column of the output is the start of the interval, in UTC.  Intervals are output in order.  Rows with unparseable timestamps are skipped.  Example: -time-bucket c1=hour -cols 3,4")
+	gc.outliers = flag.String("flag-outliers", "", "If specified, add a column named outlier to the end of each output row which is true if the number in the given column is an outlier and false if not.  The specification is of the form COL,METHOD=N[,MODE], where COL is col:N or cN, METHOD is zscore, for values more than N standard deviations from the mean, or iqr, for values more than N interquartile ranges below the first quartile or above the third, and MODE is flag, to add the column, drop, to output only rows which aren't outliers, or only, to output only the outliers.  Values which aren't numbers are never outliers.  All of the selected rows are held in memory until the end of the input.  Outliers are worked out after any -stage stages and before sampling.  Example: -flag-outliers 'col:5,zscore=4'")
+	gc.sample = flag.Float64("sample", 0, "If non-zero, output each of the selected rows with this probability, e.g. 0.01 for roughly 1% of the rows, without holding any rows in memory.  Rows are sampled after any -stage stages and before -sample-n.")
+	gc.sampleN = flag.Int("sample-n", 0, "If non-zero, output a random sample of exactly this many of the selected rows (or all of them, if there are fewer), in the order in which they were read.  Only the sampled rows are held in memory.  Rows are sampled after any -stage stages.  With -output-per-file, each output file gets its own sample.")
+	gc.sampleWeight = flag.String("sample-weight", "", "With -sample-n, sample rows with a probability proportional to the number in this column, given as col:N or cN.  Rows whose weight isn't a positive number are never sampled.  Example: -sample-n 1000 -sample-weight col:7")
+	gc.shuffle = flag.Bool("shuffle", false, "Output the selected rows in a random order, after any -stage stages and sampling.  Rows are held in memory until all of the input has been read (or, with -output-per-file or -in-place, each file), or spilled to temporary files as per -max-mem.")
+	gc.seed = flag.Uint64("seed", 0, "If non-zero, seed the random choices made by -sample, -sample-n, and -shuffle with this number, so that the same input gives the same sample every time")
+	gc.deterministic = flag.Bool("deterministic", false, "Exit with an error before reading any input if anything requested on the command line could make the output differ from run to run given the same input, such as -sample without -seed or -max-time.  Zip archive members written with -o ARCHIVE::MEMBER are given no modification time.")
+	gc.correlate = flag.Bool("correlate", false, "Output a matrix of Pearson's correlation coefficients between each pair of selected columns instead of the selected rows.  Only rows in which both columns are numbers count towards a pair's coefficient.  Coefficients which can't be worked out, e.g. because a column is constant, are left empty.  Example: -correlate -cols 3-5")
+	gc.topK = flag.Int("top-k", 0, "If greater than 0, instead of outputting the selected rows, report approximately the given `number` of most frequent values in each selected column, using a fixed amount of memory regardless of the number of distinct values.  The report is CSV with the columns column, name, value, count, and error, with the most frequent values first.  A count may be too low by at most error.  Any value which makes up more than 1/(C+1) of a column, where C is -top-k-counters, is sure to be reported.")
+	gc.topKCounters = flag.Int("top-k-counters", 0, "Use the given `number` of counters per column for -top-k; more is more accurate but uses more memory (default 10 times -top-k)")
+	gc.cardinality = flag.Bool("cardinality", false, "Instead of outputting the selected rows, report the approximate number of distinct values in each selected column, using a fixed amount of memory per column regardless of the number of distinct values.  The report is CSV with the columns column, name, count, and distinct.  Estimates are usually within a few percent; small numbers of distinct values are usually exact.")
+	gc.constantCols = flag.Float64("constant-cols", 0, "If greater than 0, instead of outputting the selected rows, report which of the selected columns are constant or nearly so, i.e. in which the most common value makes up at least the given `fraction` of the values (e.g. 1 for strictly constant columns or 0.99 for nearly constant ones).  The report is CSV with the columns column, name, count, distinct, top, top_fraction, entropy (in bits), and constant, which is true for columns which meet the fraction.  Columns with more than "+strconv.Itoa(constantCounters)+" distinct values are counted approximately and have an empty distinct and entropy.")
+	gc.uniq = flag.String("uniq", "", "Instead of outputting the selected rows, output the distinct values of the given input `column` or columns, in the order first seen.  Columns are given as a comma-separated list of col:N, cN, or N.  The output has a header with the columns' names, from the header if there is one.  Memory use grows with the number of distinct values.  Example: -uniq 3")
+	gc.freq = flag.String("freq", "", "Like -uniq, but also output how many times each distinct value of the given `column` or columns appears, most frequent first, in a column named count.  Example: -freq c2,c3")
+	gc.piiScan = flag.Bool("pii-scan", false, "Instead of outputting the selected rows, report which of the selected columns look like they contain personal information: email addresses, credit card numbers (which pass the Luhn check), US Social Security numbers, UK National Insurance numbers, or phone numbers.  A column is reported if at least half of its non-empty values look like the same kind of information.  The report is CSV with the columns column, name, kind, matches, values, and fraction.  Detection is heuristic; an unreported column may still contain personal information.")
+	gc.groupSep = flag.String("group-sep", "", "If specified, output a separator line between consecutive output rows with different values in the given column or columns, given as for -first-per-group.  Example: -group-sep col:1")
+	gc.groupSepText = flag.String("group-sep-text", "", "Separator line for -group-sep.  By default, a blank line is used.  Starting the separator with the comment character allows the output to be read by csvcol again.  Example: -group-sep-text '# ----'")
+	gc.watermark = flag.Int("watermark", 0, "If positive, output a comment line noting the number of rows output so far after every this many rows, e.g. # 1,000,000 rows.  The comment starts with the comment character (or # if -commentchar is empty) so the output may still be read by csvcol.  When used with -output-per-file or -in-place, the count is per output file.")
+	gc.grpc = flag.String("grpc", "", "If specified, serve the Select RPC described in csvcol.proto over unencrypted HTTP/2 (h2c) on this address instead of processing files.  Clients stream chunks of CSV along with a selection and receive the selected records.  Streams are read as plain CSV, never decompressed or converted from XLSX.  Flags controlling how CSV is read (e.g. -delim, -commentchar) apply to all streams.  Example: -grpc 127.0.0.1:5050")
+	gc.daemon = flag.String("daemon", "", "If specified, listen on a Unix socket at this path for jobs instead of processing files.  Each job is a JSON object with the keys files (a list of paths), rows, cols, not_rows, not_cols, where (a list of conditions), ordered, and output, which correspond to the flags of similar names.  If output is given, selected records are written to that file; otherwise, they are sent back.  For each job, a JSON object is sent back with the keys rows (the number of records selected), output (the records, if not written to a file), and error (if something went wrong).  Jobs are run by a pool of workers.  Flags controlling how CSV is read and written apply to all jobs.  Example: -daemon /tmp/csvcol.sock")
+	gc.workers = flag.Int("workers", runtime.NumCPU(), "Number of jobs to run at once with -daemon")
+	gc.grpcMaxMsg = flag.String("grpc-max-message", "4M", "Maximum `size` of a message a client may send with -grpc, e.g. 512K or 16M.  Larger messages end the stream with RESOURCE_EXHAUSTED.")
+	gc.header = flag.Bool("header", false, "Treat the first row of each file as a header.  The header is always output, regardless of -rows and -where, and is only output once when reading multiple files (or once per output file with -output-per-file).  Row numbers for -rows start after the header, so -rows 1 is the first row after the header.  Column names for -colnames and -json are taken from the header.")
+	gc.job = flag.String("job", "", "If specified, run the job described in this file instead of processing files named on the command line.  The file is a small subset of YAML with the keys inputs (a list of files to read), header (true to treat the first row of each input as a header, as with -header), and outputs (a list of outputs).  Each input is read once and each row is passed to every output.  Each output has a path (- for the standard output) and may have the keys rows, not_rows, cols, not_cols, colnames, where, where_any, in, not_in, match, vmatch, ordered, and first_per_group, which correspond to the flags of similar names; all but ordered, colnames, and first_per_group may be a list.  Relative paths are relative to the directory containing the job file.  Flags controlling how CSV is read and written apply to all inputs and outputs.")
+	gc.sheet = flag.String("sheet", "", "Read the named `sheet` of XLSX workbooks, or the sheet with the given 1-indexed number, instead of the first sheet.  XLSX workbooks are recognized automatically and read as if they were CSV, except with -untrusted or -daemon, where -sheet must be given to read them, and with -grpc, where they never are; dates are converted to YYYY-MM-DD or YYYY-MM-DD HH:MM:SS.")
+	gc.normalize = flag.String("normalize", "", "Apply Unicode normalization `form` nfc, nfd, nfkc, or nfkd to every field, or those given with -normalize-cols, before anything else, so that visually-identical but differently-composed strings compare equal")
+	gc.stripInvis = flag.Bool("strip-invisible", false, "Remove control and other invisible characters, such as zero-width spaces and joiners, byte order marks, and directional marks, from every field, or those given with -normalize-cols, before anything else.  This removes embedded newlines and tabs as well.")
+	gc.normCols = flag.String("normalize-cols", "", "Only apply -normalize and -strip-invisible to the given comma-separated `columns`, each of the form col:N or cN")
+	gc.template = flag.String("template", "", "Write each selected row through the given Go text/template instead of as CSV.  The template's dot is the row's fields, so {{index . 0}} is the first output column, and {{field \"name\"}} gets a field by its column name, from the header (e.g. with -header) or of the form cN.  A newline is written after each row unless the template ends with one.  The header itself isn't written.  Example: -template '{{index . 0}} -> {{index . 2}}'")
+	gc.templateFile = flag.String("template-file", "", "Read the template for -template from the named `file`")
+	gc.jsonNested = flag.Bool("json-nested", false, "With -json or -format json-array, split column names with dots in them into nested objects, e.g. so user.name and user.id make {\"user\":{\"name\":...,\"id\":...}}.  Names which can't be split, like a.b when there's also a column named a, are used as-is.")
+	gc.maxMem = flag.String("max-mem", "", "Limit the memory used by sort and rsort stages to roughly the given `size`, e.g. 512M or 2G, by sorting rows in chunks in temporary files and merging them, so inputs larger than memory can be sorted.  Also limits the memory used by -dedup and -shuffle.  Temporary files are put in $TMPDIR, or %TMP% on Windows, and removed when they're no longer needed.  By default, everything is kept in memory.")
+	gc.dedup = flag.Bool("dedup", false, "Don't output rows which are the same as any previous selected row, keeping the first.  A 128-bit hash of each row is remembered, so memory use grows with the number of distinct rows; with -max-mem, hashes are spilled to temporary files, at the cost of speed.")
+	gc.dedupKey = flag.String("dedup-key", "", "Like -dedup, but only compare the given `columns`, given as a comma-separated list of col:N, cN, or N, numbered as they're output, e.g. 1,4.  Implies -dedup.")
+	gc.transpose = flag.Bool("transpose", false, "Swap the rows and columns of the output, so that the Nth field of each output row becomes the Nth output row.  Short rows are padded with empty fields.  Output is held in memory until all of the input has been read (or, with -output-per-file or -in-place, each file), up to -transpose-max.  May not be used with -group-sep or -watermark.")
+	gc.transposeMax = flag.String("transpose-max", "64M", "Give up with an error if -transpose would hold more than roughly this `size` of output in memory, e.g. 1G, or 0 for no limit")
+	gc.paste = flag.Bool("paste", false, "Read the input files side-by-side instead of one after the other: row N of the input is row N of the first file, followed by row N of the second file, and so on.  With -header, the first rows of the files make up the header.  May not be used with -output-per-file, -in-place, or -bundle.")
+	gc.pasteEnd = flag.String("paste-end", "stop", "What to do with -paste when one file has fewer rows than the others: stop, to stop at the end of the shortest file, or pad, to carry on to the end of the longest file, with empty fields in place of the files which have ended")
+	gc.join = flag.String("join", "", "If specified, join each selected row with the rows of this CSV `file` which have the same key (see -on), appending the lookup file's columns (see -join-cols).  A row is output once for each matching row in the lookup file.  The smaller of the input and the lookup file is held in memory; if it's the input, rows are output once all of the input has been read.  If -header (or -colnames or -colre) is given, the lookup file's first row is its header.  Joined columns come after -add columns.")
+	gc.joinOn = flag.String("on", "1=1", "Key columns for -join, given as `IN=LOOKUP`, where IN is a column of the input (numbered as read, not as output) and LOOKUP is a column of the lookup file, each col:N, cN, or N.  Example: -join users.csv -on 2=1")
+	gc.joinType = flag.String("join-type", "inner", "Type of -join: inner, left (also output input rows without a match), right (also output lookup rows without a match, with the input's key), or outer (both)")
+	gc.joinCols = flag.String("join-cols", "", "Columns of the lookup file to append for -join, in the same format as -cols; by default, all but the key column")
+	gc.dedupWindow = flag.Int("dedup-window", 0, "If positive, don't output rows which are the same as one of the previous `N` selected rows, whether or not they were output.  Only N rows are remembered, so this works on endless streams where duplicates arrive close together.")
+	gc.fixText = flag.Bool("fix-text", false, "Fix UTF-8 which was mistakenly decoded as Windows-1252 (e.g. â€™ for ’), replace smart quotes with plain quotes, and replace non-breaking spaces with spaces, in every field, or those given with -fix-text-cols.  This happens before anything else but -normalize and -strip-invisible.  The number of fields changed is reported when all of the input has been read.")
+	gc.fixTextCols = flag.String("fix-text-cols", "", "Only apply -fix-text to the given comma-separated `columns`, each of the form col:N or cN")
+	flag.Var(&gc.addCols, "add", "Add a column computed from the input columns, given as NAME=EXPR, where EXPR is made of double-quoted strings, input columns (e.g. c3 or col3), numbers and bare words, joined with the arithmetic operators +, -, *, /, and %, the concatenation operator &, and parentheses.  Arithmetic on anything but numbers, including empty fields, or division by zero gives an empty value.  Expressions containing spaces or operators usually need shell quoting.  The column is added after the selected columns, with NAME in the header, and can be mixed with -add-col.  May be given multiple times.  Example: -add 'total=c3*c4' -add 'name=c2 & \", \" & c1'")
+	flag.Var(&gc.addCols, "add-col", "Add a column computed from the input columns, given as NAME=VALUE or NAME=case(COND:VALUE, COND:VALUE, ..., DEFAULT), where each COND is a condition as for -where and each VALUE is a double-quoted string, an input column (e.g. c3), a bare word, or an expression as for -add.  The column's value is the VALUE for the first COND which is true, or DEFAULT (or nothing) if none are.  The column is added after the selected columns, with NAME in the header.  May be given multiple times.  Example: -add-col 'tier=case(c5>1000:\"gold\", c5>100:\"silver\", \"bronze\")'")
+	gc.collate = flag.String("collate", "", "Compare strings, in conditions (e.g. -where) and when sorting, using the collation order of the given `locale` (e.g. de_DE or sv) rather than byte by byte")
+	flag.Var(&gc.transforms, "transform", "Transform the values in a column, given as COL:OP[:ARGS], where COL is col:N or cN and OP is one of trim, ltrim, rtrim, upper, lower, squeeze (trim and replace runs of whitespace with a single space), or replace, which takes REGEX:REPLACEMENT as its ARGS.  A : in REGEX may be escaped with a backslash and REPLACEMENT may use $1 and such for submatches.  The header isn't transformed.  Transforms happen before -map and are applied in the order given.  May be given multiple times.  Example: -transform 'c2:trim' -transform 'c2:lower' -transform 'c4:replace:[^0-9]+:'")
+	flag.Var(&gc.pad, "pad", "Pad the values in a column to a fixed width, given as COL,width=N[,side=left|right][,char=C][,truncate], where COL is col:N or cN.  Values are padded on the right with spaces unless side=left or char=C is given.  With truncate, longer values are cut to N characters, so every value is exactly N characters long.  The header isn't padded.  Padding happens after -map, -pseudonymize, -tokenize, -mask, and -hash.  May be given multiple times.  Example: -pad 'c3,width=8,side=left,char=0,truncate'")
+	flag.Var(&gc.mapValues, "map", "Translate the values in a column using a mapping file, given as COL=FILE, where COL is col:N or cN.  FILE is CSV; the first field of each row is a value and the second is what it's translated to.  The header isn't translated.  Translation happens before -pseudonymize and -tokenize.  May be given multiple times.  Example: -map 'col:4=codes.csv'")
+	gc.mapMissing = flag.String("map-missing", "keep", "What to do with values not in a -map mapping file: keep, to leave them as-is, empty, to replace them with nothing, error, to stop with an error, or default=TEXT, to replace them with TEXT.")
+	flag.Var(&gc.pseudonymize, "pseudonymize", "Replace the values in a column with their hex-encoded HMAC-SHA256, so that each value is consistently replaced by the same pseudonym across files and runs but can't be recovered without the key.  Given as COL,key=KEY, where COL is col:N or cN and KEY is the key or @FILE to read the key from a file.  Empty values and the header are left as-is.  May be given multiple times.  Example: -pseudonymize 'col:2,key=@keyfile'")
+	flag.Var(&gc.mask, "mask", "Replace the values in the given columns, given as col:N or cN (or a comma-separated list of them), with "+maskValue+".  Masking happens after column selection and after -pseudonymize and -tokenize.  Empty values and the header are left as-is.  May be given multiple times.  Example: -mask c2,c5")
+	flag.Var(&gc.hash, "hash", "Replace the values in a column with their hex-encoded hash, given as COL:ALG[:SALT], where COL is col:N or cN, ALG is sha256, sha384, or sha512, and SALT, if given, is put before each value before it's hashed.  Unlike -pseudonymize, anybody who knows the salt can check a guessed value.  Hashing happens along with -mask.  Empty values and the header are left as-is.  May be given multiple times.  Example: -hash 'c3:sha256:s3kr1t'")
+	flag.Var(&gc.tokenize, "tokenize", "Replace the values in the given columns, given as col:N or cN (or a comma-separated list of them), with random tokens, and save which token replaced which value in the file given with -token-map, encrypted with the key given with -token-key.  If the token map file already exists, its tokens are reused and it's updated with any new ones.  The same value always gets the same token.  Empty values and the header are left as-is.  May be given multiple times.  Example: -tokenize c2 -token-map tokens.enc -token-key @keyfile")
+	flag.Var(&gc.detokenize, "detokenize", "Replace tokens made with -tokenize in the given columns with the values they replaced, using -token-map and -token-key.  Values which aren't known tokens are left as-is.  May be given multiple times.")
+	gc.tokenMap = flag.String("token-map", "", "Encrypted file which maps tokens to the values they replaced, for -tokenize and -detokenize.")
+	gc.tokenKey = flag.String("token-key", "", "Passphrase used to encrypt the -token-map file, or @FILE to read the passphrase from a file.")
+	flag.Var(&gc.stages, "stage", "Pass output rows through a further stage of processing before they're output.  May be specified multiple times to make a pipeline; rows pass through the stages in the order given.  Stages are of the form KIND=SPEC, where KIND is one of rows, notrows, cols, notcols, where, match, or vmatch, which work like the flags of the same names on the output of the previous stage, or sort or rsort, which sort rows by the given comma-separated columns in ascending or descending order.  Later columns break ties in earlier ones; a column preceded by a - is sorted the other way, and order may be given as a key to sort by the order in which rows reached the stage.  Sorting is stable, so rows which are still tied keep their order.  Columns in each stage are numbered as they are output by the previous stage.  Sorting holds all rows in memory, unless -max-mem is given.  Example: -stage 'cols=1-5' -stage 'where=c3>0' -stage 'sort=c2,-c4'")
+	gc.strict = flag.Bool("strict", false, "Exit with an error if a file can't be read or contains invalid CSV.  By default, the error is reported, reading continues with the next file, and csvcol exits with an error once the rest of the input has been read.  Also disables the lenient handling of quotes in unquoted fields and stray quotes in quoted fields, which are otherwise accepted.")
+	gc.skipBad = flag.Bool("skip-bad", false, "Skip records which aren't valid CSV and carry on reading the file.  The number of records skipped is reported before exiting.  As with -strict, quotes must be used correctly.")
+	gc.commentChar = flag.String("commentchar", "#", "Comment character.  If a line starts with this character, it will be ignored.  Set to \"\" to disable ignoring comments.")
+	gc.trace = flag.String("trace", "", "If specified, one JSON object per output row will be written to this file, recording the source file, the line in the source file on which the row started, the byte offset in the source file at which reading the row started, the row number, and which pieces of the row and column specifications matched the row.  Useful for auditing where output came from.")
+	flag.Var(&gc.whereAll, "where", "Only output rows for which the given condition is true.  Conditions are of the form col:N OP VALUE, where N is a 1-indexed column number, OP is one of ==, =, !=, <, <=, >, or >=, and VALUE is the value against which to compare the field.  Comparisons are numeric if both the field and the value are numbers and lexical otherwise.  VALUE may be double-quoted, or may be another column (e.g. col:3 > col:4) to compare two fields of the same row.  Columns may also be written as cN, e.g. c3 > c4.  Conditions may also be of the form col:N is empty or col:N is not empty, where a field is empty if it is missing or contains only whitespace.  In any condition, len(col:N) may be used in place of col:N to use the length of the field in characters rather than its value.  Columns may also be tested for membership in a set with col:N in (a, \"b c\", ...) or col:N not in (...).  Identifiers may be checked for validity with luhn(col:N) (e.g. credit card numbers), iban(col:N), uuid(col:N), or email(col:N).  Conditions may be combined with && and ||, negated with !, and grouped with parentheses; && binds more tightly than ||.  Columns may also be written colN.  May be specified multiple times, in which case all conditions must be true.  Examples: -where 'col:3 >= 100', -where 'len(col:4) > 35', -where 'col7 > 100 && (col2 != \"\" || col3 == \"ERROR\")'")
+	flag.Var(&gc.whereAll, "where-all", "Same as -where")
+	flag.Var(&gc.whereAll, "filter", "Same as -where")
+	flag.Var(&gc.whereAny, "where-any", "Like -where, but if specified one or more times at least one of the -where-any conditions must be true for a row to be output (in addition to all of the -where and -where-all conditions).")
+	flag.Var(&gc.in, "in", "Only output rows for which the given column's value is in a list of values.  The list is given in the form col:N=V1,V2,V3,... and is parsed as a line of CSV, so values containing commas may be double-quoted.  May be specified multiple times, in which case all must match.  Example: -in 'col:2=red,green,blue'")
+	flag.Var(&gc.notIn, "not-in", "Like -in, but only output rows for which the given column's value is not in the list.")
+	flag.Var(&gc.match, "match", "Only output rows for which the given column matches a regular expression.  The column and regular expression are given in the form N:REGEX, where N is a 1-indexed column number (which may also be written as col:N or cN).  The regular expression need only match part of the field; use ^ and $ to match the whole field.  May be specified multiple times, in which case all must match.  Example: -match '3:^(GET|POST) /admin'")
+	flag.Var(&gc.vmatch, "vmatch", "Like -match, but only output rows for which the given column does not match the regular expression, like grep -v.")
+	gc.outputPerFile = flag.String("output-per-file", "", "If specified, the output for each input file will be written to its own file instead of the standard output.  The name of each output file is made from this template, in which {dir} is replaced by the directory containing the input file, {base} by the input file's name, {name} by the input file's name without its extension, and {ext} by the input file's extension (including the dot).  The standard input is treated as a file named stdin in the current directory.  Example: -output-per-file '{dir}/{name}.filtered.csv'")
+	gc.bundle = flag.String("bundle", "", "Write the output for each input file, named as per -output-per-file (by default {base}), to this directory, or to this zip archive if the name ends in .zip, along with a manifest.json listing each output file's name, input file, number of rows, size, and SHA-256 checksum, and the command line used to make them.  Output file names must be relative to the bundle and may not leave it.  The directory is created if it doesn't exist; an existing archive is replaced.  May not be used with -in-place or -sqlite.  Example: -bundle delivery.zip -output-per-file '{name}.filtered.csv'")
+	gc.inPlace = flag.Bool("in-place", false, "Replace each input file with its output.  Same as -output-per-file '{dir}/{base}'.  Output is written to a temporary file which replaces the input file once the input file has been processed.  The input file's permissions and, if possible, owner are preserved.")
+	gc.output = flag.String("o", "", "If specified, write output to this file instead of the standard output.  Output is written to a temporary file in the same directory which replaces the file once all output has been written, so the file is never left half-written.  If the name ends in .gz, output is gzipped.  If the name is of the form ARCHIVE::MEMBER, output is written, compressed, to the named member of a zip archive, which is created if it doesn't exist.  Other members of an existing archive are kept, and a member of the same name is replaced.  Example: -o delivery.zip::result.csv")
+	gc.compress = flag.Bool("compress", false, "Gzip output, whether or not it's written to a file with -o.")
+	gc.preserveMtime = flag.Bool("preserve-mtime", false, "When an input file is replaced by its output (e.g. with -in-place), also preserve the input file's modification time.")
+	gc.lockfile = flag.String("lockfile", "", "If specified, an exclusive advisory lock will be taken on this file (which will be created if it doesn't exist) before any output is written, and held until csvcol exits.  If another process holds the lock, csvcol will wait for it to be released.  This prevents concurrent invocations which use the same lockfile from interleaving or clobbering each other's output.")
+	gc.estimate = flag.Int("estimate", 0, "If non-zero, read only the first this many megabytes of each input file, print an estimate of the number of rows which would be read and output, the size of the output, and how long processing all of the input would take, and exit without writing any output.")
+	gc.listCols = flag.Bool("list-cols", false, "Print the 1-indexed number and value of each field of the first row of the input, one per line, and exit.  Handy for working out what to give to -cols.")
+	gc.compareSchema = flag.Bool("compare-schema", false, "Compare the schemas of two input files, e.g. successive deliveries of the same data, and exit.  Each file's first row is taken as its header and the types (integer, real, boolean, timestamp, text, or empty) of its columns are inferred from a sample of its rows.  Differences are output as CSV with the columns column, change, old, and new, where change is added, removed, moved, retyped, or empty, for a column whose fraction of empty values changed by at least "+strconv.FormatFloat(schemaEmptyDrift, 'f', -1, 64)+".  csvcol exits with status -44 (212) if there were any differences.")
+	gc.schemaSample = flag.Int("schema-sample", 1000, "Infer column types for -compare-schema from the first `N` rows of each file, or all of the rows if 0")
+	gc.diagnose = flag.Bool("diagnose", false, "Print each input file's line endings, byte order mark, control characters, invalid UTF-8, and longest line, and exit.  Handy for working out how to read a file about which nothing is known.")
+	gc.preview = flag.Int("preview", 0, "If non-zero, print the first row of the input (as a header) and the first this many selected rows, with the columns aligned for reading, and exit.  Other output settings are ignored.")
+	gc.timeout = flag.Duration("timeout", 30*time.Second, "Maximum time to wait to connect to a server and to receive the response headers when reading from an HTTP or HTTPS URL.  Any file name, including for -csvfile, -rowfile, and -colfile, may be such a URL, or an S3 or GCS object given as s3://BUCKET/KEY or gs://BUCKET/OBJECT.  Reading the response body isn't limited; see -idle-timeout.")
+	gc.idleTimeout = flag.Duration("idle-timeout", 0, "If non-zero and CSV data is being read from the standard input or another stream, such as a named pipe, give up on the stream if no data arrives for this long.  Output is flushed and csvcol exits with an error unless -idle-continue is given.  Example: -idle-timeout 30s")
+	gc.idleContinue = flag.Bool("idle-continue", false, "If the standard input or another stream times out (see -idle-timeout), flush output and carry on with the next input file instead of exiting.")
+	gc.untrusted = flag.Bool("untrusted", false, "Treat the input as hostile: refuse to use flags which write files or serve requests (-o, -output-per-file, -in-place, -bundle, -sqlite, -trace, -tokenize, -lockfile, -max-mem, -daemon, -grpc, and -job) don't recognize XLSX workbooks unless -sheet is given, and, unless given, set -max-rows to "+strconv.Itoa(untrustedMaxRows)+", -max-field-size to "+strconv.Itoa(untrustedMaxFieldSize)+", -max-output to "+untrustedMaxOutput+", and -max-time to "+untrustedMaxTime.String()+".")
+	gc.sandbox = flag.Bool("sandbox", false, "Before reading any input, restrict csvcol to reading the input files and writing the output files named on the command line, using pledge(2) and unveil(2) on OpenBSD and Landlock on Linux, where available (programs built with cgo can't be restricted on Linux).  Elsewhere, this does nothing.  While restricted, other programs can't be run, so gcloud won't be used for GCS access tokens, and -sandbox may not be used with -sqlite or input files with names ending in .zst; other zstd-compressed input is an error.  Not used with -daemon, -grpc, or -job.")
+	gc.maxRows = flag.Int("max-rows", 0, "If positive, give up with an error after reading more than the given `number` of rows, in total")
+	gc.maxFieldSize = flag.Int("max-field-size", 0, "If positive, give up with an error on reading a field longer than the given number of `bytes`.  As a record is read whole before its fields are checked, a record may be no longer than "+strconv.Itoa(recordSizeFactor)+" times this.")
+	gc.maxOutput = flag.String("max-output", "", "If given, give up with an error before writing more than the given `size` of output, e.g. 10M")
+	gc.maxTime = flag.Duration("max-time", 0, "If positive, give up with an error after the given `duration`, e.g. 30s")
+	gc.verbose = flag.Bool("verbose", false, "Print informational messages to the standard error stream.")
+	gc.v = flag.Bool("v", false, "Same as -verbose")
+	gc.debug = flag.Bool("debug", false, "Print debugging messages to the standard error stream.")
+	gc.d = flag.Bool("d", false, "Same as debug")
+	flag.Parse()
+
+	/* Handle -v and -d */
+	*gc.verbose = *gc.verbose || *gc.v
+	*gc.debug = *gc.debug || *gc.d
+	csvcol.Verbose = verbose
+	csvcol.Debug = debug
+
+	/* Work out the input delimiter */
+	if *gc.tab {
+		*gc.delim = `\t`
+	}
+	var err error
+	if gc.comma, err = parseDelim(*gc.delim); nil != err {
+		inform("Invalid delimiter %q: %v", *gc.delim, err)
+		exit(-17)
+	}
+	if gc.outComma, err = parseDelim(*gc.outDelim); nil != err {
+		inform("Invalid output delimiter %q: %v", *gc.outDelim, err)
+		exit(-17)
+	}
+	if strings.HasPrefix(*gc.commentChar, string(gc.comma)) {
+		inform("The delimiter and comment character must differ.")
+		exit(-17)
+	}
+
+	if *gc.strict && *gc.skipBad {
+		inform("Only one of -strict and -skip-bad may be specified.")
+		exit(-24)
+	}
+
+	/* Work out how to find sections */
+	if "" != *gc.sectionMarker {
+		if gc.sectionRE, err = regexp.Compile(
+			*gc.sectionMarker,
+		); nil != err {
+			inform("Invalid section marker: %v", err)
+			exit(-18)
+		}
+		if 0 == *gc.section {
+			*gc.section = 1
+		}
+	}
+
+	/* Hostile input and input from other programs is only read as XLSX
+	if -sheet says to */
+	gc.xlsx = !*gc.untrusted && "" == *gc.daemon && "" == *gc.grpc
+	flag.Visit(func(f *flag.Flag) {
+		if "sheet" == f.Name {
+			gc.xlsx = true
+		}
+	})
+
+	/* Serve other programs, if asked */
+	if "" != *gc.grpc {
+		if gc.maxGRPCMsg, err = parseSize(*gc.grpcMaxMsg); nil != err {
+			inform("Invalid -grpc-max-message %q: %v",
+				*gc.grpcMaxMsg, err)
+			exit(-20)
+		}
+		if err := serveGRPC(*gc.grpc); nil != err {
+			inform("Error serving gRPC: %v", err)
+			exit(-20)
+		}
+		return
+	}
+
+	if "" != *gc.job {
+		if err := runJobFile(*gc.job); nil != err {
+			inform("Error running job %v: %v", *gc.job, err)
+			exit(-22)
+		}
+		return
+	}
+	if "" != *gc.daemon {
+		if err := serveDaemon(*gc.daemon, *gc.workers); nil != err {
+			inform("Error running daemon: %v", err)
+			exit(-21)
+		}
+		return
+	}
+
+	/* -in-place is a shorthand for writing over the input */
+	if *gc.inPlace {
+		if "" != *gc.outputPerFile {
+			inform("Only one of -in-place and -output-per-file " +
+				"may be specified.")
+			exit(-13)
+		}
+		*gc.outputPerFile = "{dir}/{base}"
+	}
+	if "" != *gc.bundle {
+		if *gc.inPlace || "" != *gc.sqlite {
+			inform("-bundle may not be used with -in-place or " +
+				"-sqlite.")
+			exit(-52)
+		}
+		if "" == *gc.outputPerFile {
+			*gc.outputPerFile = "{base}"
+		}
+	}
+	if *gc.transpose {
+		if "" != *gc.groupSep || 0 < *gc.watermark {
+			inform("-transpose may not be used with -group-sep " +
+				"or -watermark.")
+			exit(-50)
+		}
+		var err error
+		gc.maxTranspose, err = parseSize(*gc.transposeMax)
+		if nil != err {
+			inform("Invalid -transpose-max %q: %v",
+				*gc.transposeMax, err)
+			exit(-50)
+		}
+	}
+	if *gc.paste {
+		if "" != *gc.outputPerFile {
+			inform("-paste may not be used with " +
+				"-output-per-file, -in-place, or -bundle.")
+			exit(-49)
+		}
+		if "stop" != *gc.pasteEnd && "pad" != *gc.pasteEnd {
+			inform("-paste-end must be stop or pad.")
+			exit(-49)
+		}
+	}
+	switch *gc.format {
+	case "csv", "table", "markdown", "sql", "pgcopy":
+	case "text-email":
+		if 0 >= *gc.emailRows || 0 >= *gc.emailWidth {
+			inform("-email-rows and -email-width must be positive.")
+			exit(-27)
+		}
+	case "xlsx":
+		if *gc.compress {
+			inform("-compress may not be used with -format xlsx.")
+			exit(-27)
+		}
+	case "json", "json-array":
+		*gc.json = true
+	default:
+		inform("Unknown output format %q", *gc.format)
+		exit(-27)
+	}
+	if *gc.json && "csv" != *gc.format && "json" != *gc.format &&
+		"json-array" != *gc.format {
+		inform("Only one of -json and -format may be given.")
+		exit(-27)
+	}
+	if "" != *gc.template || "" != *gc.templateFile {
+		if "csv" != *gc.format || *gc.json || "" != *gc.sqlite {
+			inform("-template may not be used with -format, " +
+				"-json, or -sqlite.")
+			exit(-39)
+		}
+		var err error
+		if gc.tmpl, gc.tmplNewline, err = parseTemplate(
+			*gc.template,
+			*gc.templateFile,
+		); nil != err {
+			inform("Invalid template: %v", err)
+			exit(-39)
+		}
+	}
+	if "" != *gc.sqlite && ("" != *gc.output || *gc.compress ||
+		"" != *gc.outputPerFile || "csv" != *gc.format || *gc.json) {
+		inform("-sqlite may not be used with -o, -compress, " +
+			"-output-per-file, -in-place, -format, or -json.")
+		exit(-36)
+	}
+	if "" != *gc.sqlite {
+		if err := checkSQLite(); nil != err {
+			inform("-sqlite needs the sqlite3 program: %v", err)
+			exit(-36)
+		}
+	}
+	if err := setupLimits(); nil != err {
+		inform("%v", err)
+		exit(-46)
+	}
+	if *gc.deterministic {
+		rs := nondeterminism()
+		for _, r := range rs {
+			inform("Output may not be deterministic: %s", r)
+		}
+		if 0 != len(rs) {
+			exit(-51)
+		}
+	}
+	if "" != *gc.outputPerFile && ("" != *gc.output || *gc.compress) {
+		inform("Neither -o nor -compress may be used with " +
+			"-output-per-file or -in-place.")
+		exit(-26)
+	}
+
+	/* Ensure that only one of the files is stdin */
+	s := false /* Using stdin */
+	for _, f := range gc.rowfile {
+		checkStdin(&s, "-" == f)
+	}
+	for _, f := range gc.colfile {
+		checkStdin(&s, "-" == f)
+	}
+	checkStdin(&s, 0 == len(gc.csvfile) && 0 == flag.NArg())
+	for _, f := range gc.csvfile {
+		checkStdin(&s, "-" == f)
+	}
+	for _, f := range flag.Args() {
+		checkStdin(&s, "-" == f)
+	}
+	checkStdin(&s, "-" == *gc.join)
+
+	/* Find out now if we can't decompress something */
+	checkZstd(append(append(
+		[]string{*gc.join},
+		gc.csvfile...,
+	), flag.Args()...))
+
+	/* Ranges may also come from the environment */
+	for _, e := range []struct {
+		flag string
+		name string
+		spec *string
+	}{
+		{"-rows-env", *gc.rowsEnv, gc.rows},
+		{"-cols-env", *gc.colsEnv, gc.cols},
+	} {
+		if "" == e.name {
+			continue
+		}
+		v, ok := os.LookupEnv(e.name)
+		if !ok {
+			inform("Environment variable %s for %s is not set.",
+				e.name, e.flag)
+			exit(-3)
+		}
+		if "" == strings.TrimSpace(*e.spec) {
+			*e.spec = v
+		} else if "" != strings.TrimSpace(v) {
+			*e.spec += "," + v
+		}
+	}
+
+	/* Work out which rows to print */
+	rFilter, rRules := mkFilter(*gc.rows, *gc.notRows, gc.rowfile,
+		"row")
+	/* Work out which columns to print */
+	cFilter, cRules := mkFilter(*gc.cols, *gc.notCols, gc.colfile,
+		"column")
+	if cFilter.Anchored() {
+		inform("Anchored ranges may only be used for rows.")
+		exit(-3)
+	}
+
+	/* Work out which rows to print by content */
+	wFilter, err := mkWhere(gc.whereAll, gc.whereAny, gc.in,
+		gc.notIn, gc.match, gc.vmatch)
+	if nil != err {
+		inform("Unable to process conditions: %v", err)
+		exit(-11)
+	}
+
+	debug("Row Filter: %v", rFilter)
+	debug("Colunm Filter: %v", cFilter)
+
+	/* Make an array of filenames to read. */
+	csvfile := []string{}
+	/* Only stdin */
+	if 0 == len(gc.csvfile) && 0 == flag.NArg() {
+		csvfile = []string{"-"}
+	} else {
+		/* First -csvfile */
+		csvfile = append(csvfile, gc.csvfile...)
+		/* Then command-line files */
+		if flag.NArg() > 0 {
+			csvfile = append(csvfile, flag.Args()...)
+		}
+	}
+
+	/* The sandbox doesn't let us run other programs */
+	if *gc.sandbox {
+		checkSandbox(csvfile)
+	}
+
+	sel := csvcol.Selector{
+		Rows:    rFilter,
+		Cols:    cFilter,
+		Where:   wFilter,
+		Ordered: *gc.ordered,
+		Comma:   gc.comma,
+	}
+
+	/* Only the first of each group, if asked */
+	if "" != *gc.firstPerGroup {
+		cols, err := csvcol.ParseGroupKey(*gc.firstPerGroup)
+		if nil != err {
+			inform("Invalid -first-per-group key: %v", err)
+			exit(-19)
+		}
+		sel.SetFirstPerGroup(cols)
+	}
+
+	/* Unicode may need normalizing */
+	normer, err := newNormalizer(
+		*gc.normalize,
+		*gc.stripInvis,
+		*gc.normCols,
+	)
+	if nil != err {
+		inform("Unable to set up normalization: %v", err)
+		exit(-35)
+	}
+
+	/* Text might need cleaning up */
+	var fixer *textFixer
+	if *gc.fixText {
+		if fixer, err = newTextFixer(*gc.fixTextCols); nil != err {
+			inform("Invalid -fix-text-cols: %v", err)
+			exit(-37)
+		}
+	} else if "" != *gc.fixTextCols {
+		inform("-fix-text-cols requires -fix-text.")
+		exit(-37)
+	}
+
+	/* Some columns may be computed */
+	for _, a := range gc.addCols {
+		cc, err := csvcol.ParseComputed(a)
+		if nil != err {
+			inform("Invalid -add or -add-col %q: %v", a, err)
+			exit(-33)
+		}
+		gc.computed = append(gc.computed, cc)
+	}
+
+	/* Some columns may need transforming */
+	trans := make(transformer)
+	for _, t := range gc.transforms {
+		if err := trans.add(t); nil != err {
+			inform("Invalid -transform %q: %v", t, err)
+			exit(-40)
+		}
+	}
+
+	/* Strings might need to be compared in a locale's order */
+	if "" != *gc.collate {
+		if err := setCollation(*gc.collate); nil != err {
+			inform("Invalid -collate %q: %v", *gc.collate, err)
+			exit(-41)
+		}
+	}
+
+	/* Some columns may need translating */
+	var mapper *valueMapper
+	if 0 != len(gc.mapValues) {
+		var err error
+		if mapper, err = newValueMapper(*gc.mapMissing); nil != err {
+			inform("Invalid -map-missing: %v", err)
+			exit(-32)
+		}
+		for _, m := range gc.mapValues {
+			if err := mapper.add(m); nil != err {
+				inform("Invalid -map %q: %v", m, err)
+				exit(-32)
+			}
+		}
+	}
+
+	/* Some columns may need pseudonyms */
+	pseudo := make(pseudonymizer)
+	for _, p := range gc.pseudonymize {
+		if err := pseudo.add(p); nil != err {
+			inform("Invalid -pseudonymize %q: %v", p, err)
+			exit(-30)
+		}
+	}
+
+	/* Some columns may need masking or hashing */
+	mask := make(masker)
+	for _, m := range gc.mask {
+		if err := mask.addMask(m); nil != err {
+			inform("Invalid -mask %q: %v", m, err)
+			exit(-42)
+		}
+	}
+	for _, h := range gc.hash {
+		if err := mask.addHash(h); nil != err {
+			inform("Invalid -hash %q: %v", h, err)
+			exit(-42)
+		}
+	}
+
+	/* Some columns may need padding */
+	pad := make(padder)
+	for _, p := range gc.pad {
+		if err := pad.add(p); nil != err {
+			inform("Invalid -pad %q: %v", p, err)
+			exit(-34)
+		}
+	}
+
+	/* Or tokens */
+	var tokens *tokenizer
+	if 0 != len(gc.tokenize) && 0 != len(gc.detokenize) {
+		inform("Only one of -tokenize and -detokenize may be given.")
+		exit(-31)
+	}
+	if 0 != len(gc.tokenize) || 0 != len(gc.detokenize) {
+		var err error
+		if tokens, err = newTokenizer(
+			append(gc.tokenize, gc.detokenize...),
+			*gc.tokenMap,
+			*gc.tokenKey,
+		); nil != err {
+			inform("Unable to set up tokenization: %v", err)
+			exit(-31)
+		}
+	}
+
+	/* Column names will be resolved once we've read the header */
+	if "" != *gc.colnames {
+		names, err := csv.NewReader(strings.NewReader(
+			*gc.colnames,
+		)).Read()
+		if nil != err {
+			inform("Unable to parse column names: %v", err)
+			exit(-15)
+		}
+		sel.SetColumnNames(names)
+	}
+	if 0 != len(gc.colre) {
+		res := make([]*regexp.Regexp, len(gc.colre))
+		for i, r := range gc.colre {
+			var err error
+			if res[i], err = regexp.Compile(r); nil != err {
+				inform("Invalid -colre %q: %v", r, err)
+				exit(-15)
+			}
+		}
+		sel.SetColumnRegexps(res)
+	}
+
+	/* If we're only estimating, do that and exit */
+	if 0 < *gc.estimate {
+		estimate(csvfile, sel, *gc.estimate)
+		return
+	}
+
+	/* Or if we're just having a quick look */
+	if 0 < *gc.preview {
+		if err := preview(csvfile, sel, *gc.preview); nil != err {
+			inform("Error writing preview: %v", err)
+			exit(-8)
+		}
+		return
+	}
+	if *gc.listCols {
+		if err := listCols(csvfile); nil != err {
+			inform("Error listing columns: %v", err)
+			exit(-8)
+		}
+		return
+	}
+	if *gc.diagnose {
+		if err := diagnose(csvfile); nil != err {
+			inform("Error diagnosing input: %v", err)
+			exit(-8)
+		}
+		return
+	}
+	if *gc.compareSchema {
+		n, err := compareSchema(csvfile, *gc.schemaSample)
+		if nil != err {
+			inform("Error comparing schemas: %v", err)
+			exit(-8)
+		}
+		if 0 != n {
+			verbose("Found %d difference(s)", n)
+			exit(-44)
+		}
+		return
+	}
+
+	/* Wait our turn, if we're asked to */
+	if "" != *gc.lockfile {
+		lf, err := lockFile(*gc.lockfile)
+		if nil != err {
+			inform("Unable to lock %v: %v", *gc.lockfile, err)
+			exit(-14)
+		}
+		defer lf.Close()
+		debug("Locked %v", *gc.lockfile)
+	}
+
+	/* Set up stdout or -o as a CSV writer */
+	out, err := openOutput(*gc.output, *gc.compress)
+	if nil != err {
+		inform("Unable to open output: %v", err)
+		exit(-26)
+	}
+	if 0 < limits.maxOutput {
+		out.Writer = &limitedWriter{
+			w:   out.Writer,
+			max: limits.maxOutput,
+		}
+	}
+	w := newRecordWriter(out, &sel)
+
+	/* Set up the trace file, if we have one */
+	var (
+		tw *bufio.Writer
+		te *json.Encoder
+	)
+	if "" != *gc.trace {
+		tf, err := os.Create(*gc.trace)
+		if err != nil {
+			inform("Unable to create trace file %v: %v",
+				*gc.trace, err)
+			exit(-9)
+		}
+		defer tf.Close()
+		tw = bufio.NewWriter(tf)
+		te = json.NewEncoder(tw)
+	}
+
+	/* Groups may need separating */
+	var (
+		sepCols []int
+		lastKey *string /* Key of the previous row, if any */
+		nOut    int     /* Rows output, for -watermark */
+	)
+	if "" != *gc.groupSep {
+		if sepCols, err = csvcol.ParseGroupKey(
+			*gc.groupSep,
+		); nil != err {
+			inform("Invalid -group-sep key: %v", err)
+			exit(-19)
+		}
+	}
+
+	/* emit outputs orec, selected from record, and notes where it came
+	from */
+	emit := func(record, orec []string, tr *traceRecord) {
+		/* Separate groups */
+		if nil != sepCols {
+			k := csvcol.GroupKey(sepCols, record)
+			if nil != lastKey && k != *lastKey {
+				err := w.WriteLine(*gc.groupSepText)
+				if nil != err {
+					inform("Error writing separator: "+
+						"%v", err)
+					exit(-8)
+				}
+			}
+			lastKey = &k
+		}
+		if err := w.Write(orec); err != nil {
+			inform("Error writing %v: %v", orec, err)
+			exit(-8)
+		}
+		/* Note how far we've gotten */
+		nOut++
+		if 0 < *gc.watermark && 0 == nOut%*gc.watermark {
+			err := w.WriteLine(watermark(nOut))
+			if nil != err {
+				inform("Error writing watermark: %v", err)
+				exit(-8)
+			}
+		}
+		if nil == tr {
+			return
+		}
+		if err := te.Encode(tr); err != nil {
+			inform("Error writing trace: %v", err)
+			exit(-10)
+		}
+	}
+
+	/* With -last-per-group, we don't know what to output until the end */
+	var lastPer *lastPerGroup
+	if "" != *gc.lastPerGroup {
+		cols, err := csvcol.ParseGroupKey(*gc.lastPerGroup)
+		if nil != err {
+			inform("Invalid -last-per-group key: %v", err)
+			exit(-19)
+		}
+		if "" != *gc.outputPerFile {
+			inform("-last-per-group may not be used with " +
+				"-output-per-file or -in-place.")
+			exit(-19)
+		}
+		lastPer = newLastPerGroup(cols)
+	}
+
+	/* With -stats-by and friends, we output statistics instead of rows */
+	var (
+		stats     summary
+		statsFlag []string /* Summary flags given */
+	)
+	for _, m := range []struct {
+		flag string
+		on   bool
+	}{
+		{"-stats", *gc.stats},
+		{"-stats-by", "" != *gc.statsBy},
+		{"-time-bucket", "" != *gc.timeBucket},
+		{"-correlate", *gc.correlate},
+		{"-pii-scan", *gc.piiScan},
+		{"-top-k", 0 < *gc.topK},
+		{"-cardinality", *gc.cardinality},
+		{"-constant-cols", 0 < *gc.constantCols},
+		{"-uniq", "" != *gc.uniq},
+		{"-freq", "" != *gc.freq},
+	} {
+		if m.on {
+			statsFlag = append(statsFlag, m.flag)
+		}
+	}
+	if 1 < len(statsFlag) {
+		inform("Only one of %s may be given.",
+			strings.Join(statsFlag, " and "))
+		exit(-25)
+	}
+	if *gc.stats {
+		stats = &columnSummary{column: sel.InputColumn}
+	}
+	if "" != *gc.statsBy {
+		cols, err := csvcol.ParseGroupKey(*gc.statsBy)
+		if nil != err {
+			inform("Invalid -stats-by key: %v", err)
+			exit(-25)
+		}
+		stats = newColumnStats(cols)
+	}
+	if "" != *gc.timeBucket {
+		b, err := parseTimeBucket(*gc.timeBucket)
+		if nil != err {
+			inform("Invalid -time-bucket: %v", err)
+			exit(-25)
+		}
+		stats = newBucketStats(b)
+	}
+	if *gc.correlate {
+		stats = &correlation{}
+	}
+	if *gc.piiScan {
+		stats = &piiScan{column: sel.InputColumn}
+	}
+	if 0 < *gc.topK {
+		stats = newTopK(*gc.topK, *gc.topKCounters, sel.InputColumn)
+	}
+	if *gc.cardinality {
+		stats = &cardinality{column: sel.InputColumn}
+	}
+	if 0 < *gc.constantCols {
+		stats = newConstantCols(*gc.constantCols, sel.InputColumn)
+	}
+	for _, u := range []struct {
+		flag *string
+		freq bool
+	}{{gc.uniq, false}, {gc.freq, true}} {
+		if "" == *u.flag {
+			continue
+		}
+		var err error
+		if stats, err = newValueCounts(*u.flag, u.freq); nil != err {
+			inform("Invalid %s: %v", statsFlag[0], err)
+			exit(-25)
+		}
+	}
+	if nil != stats &&
+		("" != *gc.outputPerFile || nil != lastPer || *gc.json) {
+		inform("%s may not be used with -output-per-file, "+
+			"-in-place, -last-per-group, or -json.", statsFlag[0])
+		exit(-25)
+	}
+
+	/* Further stages, if we have any */
+	pipe := &pipeline{out: func(r stageRow) {
+		/* Actually output line, or hold on to it for later */
+		if nil != stats {
+			if !r.header {
+				stats.add(r.in, r.out, sel.ColumnName)
+			} else if hs, ok := stats.(headerSummary); ok {
+				hs.header(r.in)
+			}
+			return
+		}
+		/* The header goes out first, whatever else is going on */
+		if r.header {
+			r.out = addOrigin(r.out, linenumColumn, filenameColumn)
+			if err := w.WriteHeader(r.out); nil != err {
+				inform("Error writing header: %v", err)
+				exit(-8)
+			}
+			return
+		}
+		r.out = addOrigin(r.out, strconv.Itoa(r.row), r.file)
+		if nil != lastPer {
+			lastPer.add(r.in, r.out, r.tr)
+			return
+		}
+		emit(r.in, r.out, r.tr)
+	}}
+	if "" != *gc.maxMem {
+		var err error
+		if gc.maxMemBytes, err = parseSize(*gc.maxMem); nil != err {
+			inform("Invalid -max-mem %q: %v", *gc.maxMem, err)
+			exit(-43)
+		}
+	}
+
+	/* Joins happen before other stages, which may use joined columns */
+	var join *joinStage
+	if "" != *gc.join {
+		var err error
+		if join, err = newJoinStage(
+			*gc.join,
+			*gc.header || "" != *gc.colnames || 0 != len(gc.colre),
+			inputSize(csvfile),
+			"" == *gc.outputPerFile,
+			sel.InputColumn,
+		); nil != err {
+			inform("Unable to join with %v: %v", *gc.join, err)
+			exit(-48)
+		}
+		pipe.stages = append(pipe.stages, join)
+	}
+
+	for _, s := range gc.stages {
+		st, err := parseStage(s)
+		if nil != err {
+			inform("Invalid stage %q: %v", s, err)
+			exit(-23)
+		}
+		pipe.stages = append(pipe.stages, st)
+	}
+
+	/* Duplicates might need removing */
+	if *gc.dedup || "" != *gc.dedupKey {
+		d := &dedupStage{
+			seen: newHashSet(gc.maxMemBytes),
+		}
+		if "" != *gc.dedupKey {
+			var err error
+			d.key, err = parseDedupKey(*gc.dedupKey)
+			if nil != err {
+				inform("Invalid -dedup-key %q: %v",
+					*gc.dedupKey, err)
+				exit(-45)
+			}
+		}
+		pipe.stages = append(pipe.stages, d)
+	}
+
+	/* Duplicates close together might need removing */
+	if 0 > *gc.dedupWindow {
+		inform("-dedup-window must not be negative.")
+		exit(-38)
+	} else if 0 < *gc.dedupWindow {
+		pipe.stages = append(pipe.stages, newDedupWindowStage(
+			*gc.dedupWindow,
+		))
+	}
+
+	/* Outliers are found once we've seen everything */
+	var outliers *outlierStage
+	if "" != *gc.outliers {
+		var err error
+		if outliers, err = parseOutlierStage(
+			*gc.outliers,
+		); nil != err {
+			inform("Invalid -flag-outliers %q: %v",
+				*gc.outliers, err)
+			exit(-29)
+		}
+		gc.flagOutliers = outliers.flagging()
+		pipe.stages = append(pipe.stages, outliers)
+	}
+
+	/* Sampling happens after everything else */
+	if 0 != *gc.sample {
+		if !(0 < *gc.sample && 1 >= *gc.sample) {
+			inform("-sample must be more than 0 and at most 1.")
+			exit(-28)
+		}
+		pipe.stages = append(pipe.stages, newFractionStage(
+			*gc.sample,
+		))
+	}
+	if 0 != *gc.sampleN || "" != *gc.sampleWeight {
+		if 0 >= *gc.sampleN {
+			inform("-sample-n must be positive.")
+			exit(-28)
+		}
+		col := 0
+		if "" != *gc.sampleWeight {
+			cols, err := csvcol.ParseGroupKey(*gc.sampleWeight)
+			if nil == err && 1 != len(cols) {
+				err = errors.New("only one column may be given")
+			}
+			if nil != err {
+				inform("Invalid -sample-weight column: %v", err)
+				exit(-28)
+			}
+			col = cols[0]
+		}
+		pipe.stages = append(pipe.stages, newSampleStage(
+			*gc.sampleN,
+			col,
+		))
+	}
+
+	/* Shuffling happens last, so sampled rows are shuffled as well */
+	if *gc.shuffle {
+		pipe.stages = append(pipe.stages, newShuffleStage())
+	}
+
+	/* process selects from and outputs a single record */
+	process := func(ir inRecord) {
+		/* Work out whether to ignore it */
+		if nil != normer {
+			normer.apply(ir.record)
+		}
+		if nil != fixer {
+			fixer.apply(ir.record)
+		}
+		orec, ok, err := sel.Select(ir.record)
+		if nil != err {
+			inform("Unable to select columns by name: %v", err)
+			exit(-15)
+		}
+		if !ok {
+			return
+		}
+		header := sel.IsHeaderRow()
+		if 0 != len(trans) && !header {
+			trans.apply(orec, &sel)
+		}
+		if nil != mapper && !header {
+			if err := mapper.apply(orec, &sel); nil != err {
+				inform("Unable to translate row %v of %v: %v",
+					sel.Row(), ir.file, err)
+				w.Close()
+				exit(-32)
+			}
+		}
+		if 0 != len(pseudo) && !header {
+			pseudo.apply(orec, &sel)
+		}
+		switch {
+		case nil == tokens || header:
+		case 0 != len(gc.tokenize):
+			if err := tokens.tokenize(orec, &sel); nil != err {
+				inform("Unable to make token: %v", err)
+				exit(-31)
+			}
+		default:
+			tokens.detokenize(orec, &sel)
+		}
+		if 0 != len(mask) && !header {
+			mask.apply(orec, &sel)
+		}
+		if 0 != len(pad) && !header {
+			pad.apply(orec, &sel)
+		}
+		orec = addComputed(orec, ir.record, header)
+
+		/* Note where it came from */
+		var tr *traceRecord
+		if nil != te {
+			tr = &traceRecord{
+				File:   ir.file,
+				Line:   ir.line,
+				Offset: ir.offset,
+				Row:    sel.Row(),
+			}
+			tr.Rows = matchingRules(rRules, sel.Row(), sel.Row())
+			tr.Cols = matchingRules(cRules, 1, len(ir.record))
+		}
+
+		pipe.push(stageRow{
+			in:     ir.record,
+			out:    orec,
+			row:    sel.Row(),
+			file:   ir.file,
+			header: header,
+			tr:     tr,
+		})
+	}
+
+	/* Rows counted from the end need the last few rows held back */
+	window := sel.Rows.Window()
+	var held []inRecord
+	if 0 != window && "" != *gc.outputPerFile && !*gc.perfile {
+		inform("Rows counted from the end may only be used with " +
+			"-output-per-file or -in-place if -perfile is given.")
+		exit(-3)
+	}
+	/* drainHeld processes held rows, now that we know how many rows
+	there are */
+	drainHeld := func() {
+		sel.SetTotal(sel.Row() + len(held))
+		for _, ir := range held {
+			process(ir)
+		}
+		held = nil
+	}
+
+	/* Output files may go in a bundle */
+	if "" != *gc.bundle {
+		var err error
+		if outBundle, err = newBundle(*gc.bundle, csvfile); nil != err {
+			inform("Unable to make bundle %v: %v", *gc.bundle, err)
+			exit(-52)
+		}
+	}
+
+	/* Don't let hostile input do more than we need */
+	startSandbox(csvfile)
+
+	/* Read data from each file */
+	sentHeader := false /* Header's been output, for -header */
+	nBad := 0           /* Bad records skipped, for -skip-bad */
+	nFailed := 0        /* Files we couldn't finish reading */
+	for i, f := range csvfile {
+		/* Pasted files are read all at once */
+		if *gc.paste && 0 != i {
+			break
+		}
+		/* No point in reading more files if we've no more rows to
+		select and they'd not get their own row numbers or output */
+		if sel.Exhausted() && !*gc.perfile && "" == *gc.outputPerFile {
+			verbose("No more rows to select, skipping %v",
+				strings.Join(csvfile[i:], ", "))
+			break
+		}
+		fp, fname := openInput(f)
+		if *gc.paste {
+			fname = strings.Join(csvfile, " + ")
+		}
+		verbose("Parsing %v", fname)
+		needHeader := *gc.header
+		if *gc.perfile {
+			sel.Reset()
+		}
+		/* Each file might get its own output */
+		var of io.WriteCloser
+		if *gc.inPlace && isStream(fp) {
+			inform("Unable to replace %v in place: not a "+
+				"regular file", fname)
+			exit(-13)
+		}
+		if "" != *gc.outputPerFile {
+			var err error
+			if of, err = openPerFileOutput(f, fp); nil != err {
+				inform("Unable to open output for %v: %v",
+					fname, err)
+				exit(-12)
+			}
+			w = newRecordWriter(of, &sel)
+			lastKey = nil
+			nOut = 0
+			sentHeader = false
+		}
+		/* Make a CSV reader, maybe reading the other files too */
+		cr, sr := inputReader(fp)
+		var r recordReader = cr
+		if *gc.paste {
+			r = newPasteReader(
+				cr,
+				csvfile[1:],
+				"pad" == *gc.pasteEnd,
+			)
+		}
+		/* Lines and offsets are in the file, not the section */
+		if nil != sr {
+			r = sectionPos{recordReader: r, sr: sr}
+		}
+
+		/* Parse lines until the file is done or there's nothing more
+		to select from it */
+		for {
+			if sel.Exhausted() && !needHeader {
+				verbose("No more rows to select from %v", fname)
+				break
+			}
+			/* Get a line */
+			offset := r.InputOffset()
+			record, e := r.Read()
+			if nil != record {
+				debug("%v) Got %v fields: %#v", sel.Row()+1,
+					len(record), record)
+			}
+			/* Give up if we have an error */
+			if e != nil {
+				/* If it's EOF, go to the next file */
+				if errors.Is(e, io.EOF) {
+					break
+				}
+				debug("Got error reading %v (%T): %v", fname,
+					e, e)
+				/* Input stalled, maybe give up */
+				if errors.Is(e, errIdle) {
+					inform("No input from %v for %v",
+						fname, *gc.idleTimeout)
+					if !*gc.idleContinue {
+						w.Close()
+						exit(-16)
+					}
+					break
+				}
+				/* Hostile input is fatal */
+				if errors.Is(e, errLimit) {
+					inform("Error reading %v: %v", fname, e)
+					w.Close()
+					exit(-46)
+				}
+				/* Bad CSV might not be fatal */
+				if handleReadError(fname, e, &nBad) {
+					continue
+				}
+				if *gc.strict {
+					w.Close()
+					exit(-24)
+				}
+				nFailed++
+				break
+			}
+			if err := checkLimits(record); nil != err {
+				inform("Error reading %v: %v", fname, err)
+				w.Close()
+				exit(-46)
+			}
+			/* The first row of each file may be a header, which
+			we only output once */
+			if needHeader {
+				needHeader = false
+				h, err := sel.SelectHeader(record)
+				if nil != err {
+					inform("Unable to select columns by "+
+						"name: %v", err)
+					exit(-15)
+				}
+				if hs, ok := stats.(headerSummary); ok {
+					hs.header(record)
+				}
+				if sentHeader || nil != stats {
+					continue
+				}
+				sentHeader = true
+				/* Stages may change the header's columns */
+				pipe.push(stageRow{
+					in:     record,
+					out:    addComputed(h, nil, true),
+					file:   fname,
+					header: true,
+				})
+				continue
+			}
+			ir := inRecord{
+				record: record,
+				file:   fname,
+				offset: offset,
+			}
+			ir.line, _ = r.FieldPos(0)
+			/* If we need to know how many rows there are, hold on
+			to the last few rows until we do */
+			if 0 != window {
+				held = append(held, ir)
+				if len(held) <= window {
+					continue
+				}
+				ir, held = held[0], held[1:]
+			}
+			process(ir)
+		}
+		/* Rows counted from the end of this file */
+		if 0 != window && *gc.perfile {
+			drainHeld()
+		}
+		/* Each output file gets its own run through the stages */
+		if nil != of {
+			pipe.flush()
+		}
+		/* Flush output after each file */
+		w.Flush()
+		if err := w.Error(); err != nil {
+			inform("Error flushing output: %v", err)
+			exit(-6)
+		}
+		if os.Stdin != fp {
+			fp.Close()
+		}
+		if nil != of {
+			if err := w.Close(); nil != err {
+				inform("Error writing output for %v: %v",
+					fname, err)
+				exit(-8)
+			}
+			if bo, ok := of.(*bundleOutput); ok {
+				bo.setRows(nOut)
+			}
+			if err := of.Close(); nil != err {
+				inform("Error closing output for %v: %v",
+					fname, err)
+				exit(-6)
+			}
+		}
+	}
+
+	/* Now we know how many rows there are, deal with the held rows */
+	if 0 != window && !*gc.perfile {
+		drainHeld()
+	}
+
+	/* Flush anything held by later stages */
+	if "" == *gc.outputPerFile {
+		pipe.flush()
+		w.Flush()
+		if err := w.Error(); err != nil {
+			inform("Error flushing output: %v", err)
+			exit(-6)
+		}
+	}
+
+	/* Output the last row in each group */
+	if nil != lastPer {
+		lastPer.each(emit)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			inform("Error flushing output: %v", err)
+			exit(-6)
+		}
+	}
+
+	/* Output statistics */
+	if nil != stats {
+		if err := stats.each(w.Write); nil != err {
+			inform("Error writing statistics: %v", err)
+			exit(-8)
+		}
+		if g, ok := stats.(*groupStats); ok && 0 != g.skipped {
+			inform("Skipped %v row(s) without a valid timestamp",
+				g.skipped)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			inform("Error flushing output: %v", err)
+			exit(-6)
+		}
+	}
+
+	/* Save any new tokens */
+	if nil != tokens {
+		if err := tokens.save(); nil != err {
+			inform("Unable to save tokens to %v: %v",
+				*gc.tokenMap, err)
+			exit(-31)
+		}
+	}
+
+	/* Make sure all the output's made it out */
+	if err := w.Close(); nil != err {
+		inform("Error writing output: %v", err)
+		exit(-8)
+	}
+	if err := out.Close(); nil != err {
+		inform("Error closing output: %v", err)
+		exit(-6)
+	}
+	if nil != outBundle {
+		if err := outBundle.Close(); nil != err {
+			inform("Error finishing bundle %v: %v", *gc.bundle, err)
+			exit(-52)
+		}
+	}
+
+	/* Note what we fixed */
+	if nil != fixer {
+		inform("Fixed text in %v field(s)", fixer.fixed)
+	}
+
+	/* Note if we skipped anything */
+	if 0 != nBad {
+		inform("Skipped %v bad record(s)", nBad)
+	}
+
+	/* Flush the trace as well */
+	if nil != tw {
+		if err := tw.Flush(); err != nil {
+			inform("Error flushing trace: %v", err)
+			exit(-10)
+		}
+	}
+
+	/* Don't let unreadable input go unnoticed */
+	if 0 != nFailed {
+		inform("Unable to read %v file(s)", nFailed)
+		exit(-24)
+	}
+}
+
+/* handleReadError reports e, an error reading the file named fname.  If e is
+a CSV parse error and -skip-bad was given, handleReadError increments *nBad
+and returns true to indicate reading should continue with the next record. */
+func handleReadError(fname string, e error, nBad *int) bool {
+	var pe *csv.ParseError
+	if !errors.As(e, &pe) {
+		inform("Error reading %v: %v", fname, e)
+		return false
+	}
+	where := fmt.Sprintf("line %v, column %v", pe.Line, pe.Column)
+	if pe.StartLine != pe.Line {
+		where = fmt.Sprintf("record starting on line %v, %v",
+			pe.StartLine, where)
+	}
+	if *gc.skipBad {
+		*nBad++
+		verbose("Skipping bad record in %v: %v: %v", fname, where,
+			pe.Err)
+		return true
+	}
+	inform("Error parsing %v: %v: %v", fname, where, pe.Err)
+	return false
+}
+
+/* openInput opens the input file named f, which may be - for the standard
+input.  It returns the opened file and a printable name for it.  If the file
+can't be opened, the program exits. */
+func openInput(f string) (*os.File, string) {
+	if "-" == f {
+		return os.Stdin, "standard input"
+	}
+	fp, err := openFile(f)
+	if err != nil {
+		inform("Unable to open %v: %v", f, err)
+		exit(-5)
+	}
+	return fp, f
+}
+
+/* inputReader returns a CSV reader which reads from the input file fp, which
+gives up if fp is a stream and -idle-timeout is exceeded.  If -section was
+given, the sectionReader from which the CSV reader reads is returned as well. */
+func inputReader(fp *os.File) (*csv.Reader, *sectionReader) {
+	var in io.Reader = fp
+	if 0 < *gc.idleTimeout && isStream(fp) {
+		in = newIdleReader(fp, *gc.idleTimeout)
+	}
+	return newSectionedReader(in)
+}
+
+/* newReader returns a CSV reader which reads from r, configured as per the
+command line. */
+func newReader(r io.Reader) *csv.Reader {
+	cr, _ := newSectionedReader(r)
+	return cr
+}
+
+/* newSectionedReader is like newReader, but also returns the sectionReader
+from which the CSV reader reads, if -section was given. */
+func newSectionedReader(r io.Reader) (*csv.Reader, *sectionReader) {
+	r = fromXLSX(decompress(r))
+	var sr *sectionReader
+	if 0 != *gc.section {
+		sr = newSectionReader(r, *gc.section, gc.sectionRE)
+		r = sr
+	}
+	return newCSVReader(r), sr
+}
+
+/* newCSVReader returns a CSV reader which reads CSV straight from r, without
+decompressing it, converting it from XLSX, or looking for a section, but
+otherwise configured as per the command line. */
+func newCSVReader(r io.Reader) *csv.Reader {
+	var lr *recordLimiter
+	if 0 < *gc.maxFieldSize {
+		lr = &recordLimiter{
+			r:   r,
+			max: int64(recordSizeFactor) * int64(*gc.maxFieldSize),
+		}
+		r = lr
+	}
+	cr := csv.NewReader(r)
+	if nil != lr {
+		lr.cr = cr
+	}
+	if len(*gc.commentChar) > 0 {
+		cr.Comment = []rune(*gc.commentChar)[0]
+	}
+	cr.Comma = gc.comma
+	cr.FieldsPerRecord = -1
+	/* Being strict about bad records means finding them */
+	cr.LazyQuotes = !*gc.strict && !*gc.skipBad
+	return cr
+}
+
+/* newWriter returns a CSV writer which writes to w, configured as per the
+command line. */
+func newWriter(w io.Writer) *csv.Writer {
+	cw := csv.NewWriter(w)
+	cw.Comma = gc.outComma
+	return cw
+}
+
+/* parseDelim parses a field delimiter, which must be a single character.  \t
+may be used for a tab. */
+func parseDelim(d string) (rune, error) {
+	if `\t` == d {
+		return '\t', nil
+	}
+	rs := []rune(d)
+	if 1 != len(rs) {
+		return 0, errors.New("must be a single character")
+	}
+	switch rs[0] {
+	case '\r', '\n', '"', utf8.RuneError:
+		return 0, errors.New("not allowed as a delimiter")
+	}
+	return rs[0], nil
+}
+
+/* Check if is is true.  If it is and s is true, die with an error.  If is is
+true and s isn't, make s true.  If it's false all is good. */
+func checkStdin(s *bool, is bool) {
+	/* Don't care if is isn't */
+	if !is {
+		return
+	}
+	/* Set s if it's not set */
+	if !*s {
+		*s = true
+		return
+	}
+	/* If both are set, die with an error. */
+	inform("The standard input may only be read once, so only one " +
+		"of -csvfile, -rowfile, -colfile, -join, and the files on " +
+		"the command line may be -.")
+	if haveDevFD() {
+		inform("The others may be named pipes or given with the " +
+			"shell's process substitution, e.g. -rowfile <(cmd).")
+	}
+	exit(-1)
+}
+
+/* mkFilter makes a filter from the specified flagfiles (i.e. rowfiles), flag
+(i.e. rows), and notflag (i.e. notrows).  Name is passed in for error
+reporting.  If -trace was given, the individual pieces of the specification
+are returned as well. */
+func mkFilter(
+	flag string,
+	notflag string,
+	flagfiles []string,
+	name string,
+) (csvcol.Filter, []traceRule) {
+	debug("Making %v filter from flag [%v], negated flag [%v], and "+
+		"files %q", name, flag, notflag, flagfiles)
+	/* Filter to return */
+	var (
+		f     csvcol.Filter
+		rules []traceRule
+	)
+	/* If we have nothing to set, return a permissive filter */
+	if "" == flag && "" == notflag && 0 == len(flagfiles) {
+		return f, addTraceRules(rules, "-")
+	}
+
+	/* update adds the ranges in spec to the filter, or excludes them if
+	spec starts with a ! */
+	haveIn := false
+	update := func(spec string) error {
+		t := strings.TrimSpace(spec)
+		if "" != t && !strings.HasPrefix(t, "!") {
+			haveIn = true
+		}
+		/* Only simple ranges are traced */
+		simple, err := f.Add(spec)
+		if nil != err {
+			return err
+		}
+		rules = addTraceRules(rules, simple)
+		return nil
+	}
+
+	/* Process ranges on the command line */
+	if "" != flag {
+		verbose("Processing %v ranges from the commandline (%v)", name, flag)
+		if err := update(flag); err != nil {
+			inform("Unable to process %v ranges (%v): %v", name,
+				flag, err)
+			exit(-3)
+		}
+
+	}
+	if "" != notflag {
+		verbose("Processing %v ranges to exclude from the "+
+			"commandline (%v)", name, notflag)
+		if err := f.Exclude(notflag); err != nil {
+			inform("Unable to process %v ranges to exclude "+
+				"(%v): %v", name, notflag, err)
+			exit(-3)
+		}
+	}
+
+	/* Read ranges from each file */
+	for _, flagfile := range flagfiles {
+		var in *os.File
+		if "-" == flagfile {
+			in = os.Stdin
+		} else {
+			i, err := openFile(flagfile)
+			if err != nil {
+				inform("Unable to open %v file %v: %v",
+					name, flagfile, err)
+				exit(-2)
+			}
+			in = i
+		}
+		fname := flagfile
+		if "-" == fname {
+			fname = "standard input"
+		}
+		/* Read lines from the file */
+		err := readSpecFile(in, fname, update, []string{})
+		if os.Stdin != in {
+			in.Close()
+		}
+		if err != nil {
+			inform("Unable to process %v ranges from %v: %v", name,
+				fname, err)
+			exit(-7)
+		}
+	}
+
+	/* If we've only got exclusions, everything else is included */
+	if !haveIn {
+		rules = addTraceRules(rules, "-")
+	}
+
+	return f, rules
+}
+
+/* mkWhere makes a Where from the -where*, -in, -not-in, -match, and -vmatch
+flags */
+func mkWhere(
+	all, any, in, notIn, match, vmatch []string,
+) (csvcol.Where, error) {
+	var w csvcol.Where
+	for _, f := range []struct {
+		name  string
+		specs []string
+		add   func(string) error
+	}{
+		{"-where", all, w.AddAll},
+		{"-where-any", any, w.AddAny},
+		{"-in", in, w.AddIn},
+		{"-not-in", notIn, w.AddNotIn},
+		{"-match", match, w.AddMatch},
+		{"-vmatch", vmatch, w.AddNotMatch},
+	} {
+		for _, s := range f.specs {
+			if err := f.add(s); nil != err {
+				return w, fmt.Errorf("%v: %v", f.name, err)
+			}
+		}
+	}
+	return w, nil
+}
+
+/* readSpecFile reads row or column specifications from r, which is the file
+named name, and passes them to update.  Blank lines are ignored, as is
+everything from a # to the end of a line.  A line of the form
+@include otherfile causes specifications to be read from otherfile, which is
+relative to the directory containing name unless it is absolute.  Parents
+holds the names of the files which included this one, to detect loops. */
+func readSpecFile(
+	r io.Reader,
+	name string,
+	update func(string) error,
+	parents []string,
+) error {
+	scanner := bufio.NewScanner(r)
+	for ln := 1; scanner.Scan(); ln++ {
+		t := scanner.Text()
+		if i := strings.Index(t, "#"); -1 != i {
+			t = t[:i]
+		}
+		t = strings.TrimSpace(t)
+		if "" == t {
+			continue
+		}
+
+		/* Not an include, just process the line */
+		if !strings.HasPrefix(t, "@include") {
+			verbose("Processing %v from %v", t, name)
+			if err := update(t); err != nil {
+				return fmt.Errorf("line %v: %v", ln, err)
+			}
+			continue
+		}
+
+		/* Work out which file to include */
+		inc := strings.TrimSpace(strings.TrimPrefix(t, "@include"))
+		if "" == inc {
+			return fmt.Errorf("line %v: missing file to include",
+				ln)
+		}
+		switch {
+		case isURL(inc), !isRelative(inc), "standard input" == name:
+		case isURL(name):
+			var err error
+			if inc, err = resolveURL(name, inc); nil != err {
+				return fmt.Errorf("line %v: %v", ln, err)
+			}
+		default:
+			inc = filepath.Join(filepath.Dir(name), inc)
+		}
+		for _, p := range append(parents, name) {
+			if filepath.Clean(inc) == filepath.Clean(p) {
+				return fmt.Errorf("line %v: %v includes itself",
+					ln, inc)
+			}
+		}
+		verbose("Including %v from %v", inc, name)
+		f, err := openFile(inc)
+		if nil != err {
+			return fmt.Errorf("line %v: %v", ln, err)
+		}
+		err = readSpecFile(f, inc, update, append(parents, name))
+		f.Close()
+		if nil != err {
+			return fmt.Errorf("%v: %v", inc, err)
+		}
+	}
+	return scanner.Err()
+}
+
+/* addTraceRules splits spec into its comma-separated pieces and appends a
+traceRule for each to rules, if -trace was given. */
+func addTraceRules(rules []traceRule, spec string) []traceRule {
+	if "" == *gc.trace {
+		return rules
+	}
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		if "" == p {
+			continue
+		}
+		f := ranges.New(verbose, debug)
+		if err := f.Update(p); err != nil {
+			/* Already checked by the caller */
+			continue
+		}
+		rules = append(rules, traceRule{Spec: p, filter: f})
+	}
+	return rules
+}
+
+/* matchingRules returns the specs of the rules which allow any number from lo
+to hi, inclusive. */
+func matchingRules(rules []traceRule, lo, hi int) []string {
+	ms := []string{}
+	for _, r := range rules {
+		for i := lo; i <= hi; i++ {
+			if a, _ := r.filter.AllowsOut(i); a {
+				ms = append(ms, r.Spec)
+				break
+			}
+		}
+	}
+	return ms
+}
+
+/* verbose prints a message if -v */
+func verbose(f string, a ...interface{}) {
+	if *gc.verbose || *gc.debug {
+		inform(f, a...)
+	}
+}
+
+/* debug prints a message if -v or -d */
+func debug(f string, a ...interface{}) {
+	f = "D: " + f /* Note as a debug string */
+	if *gc.debug {
+		inform(f, a...)
+	}
+}
+
+/* exit removes any temporary output files and exits with the given code.  It
+should be used instead of os.Exit. */
+func exit(code int) {
+	removeTempOutputs()
+	os.Exit(code)
+}
+
+/* inform prints informational mesages to stderr */
+func inform(f string, a ...interface{}) {
+	if !strings.HasSuffix(f, "\n") {
+		f += "\n"
+	}
+	fmt.Fprintf(os.Stderr, f, a...)
+}
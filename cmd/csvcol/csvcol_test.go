@@ -0,0 +1,1407 @@
+/*
+ * csvcol_test.go
+ * Helpers for running csvcol in tests
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* runMainEnv, if set in the environment, makes the test binary run csvcol
+instead of the tests, so tests can run csvcol with its own flags and exit
+status. */
+const runMainEnv = "CSVCOL_TEST_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if "" != os.Getenv(runMainEnv) {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+/* runResult is what came of running csvcol */
+type runResult struct {
+	stdout string
+	stderr string
+	code   int /* Exit status */
+}
+
+/* runCSVCol runs csvcol with the given arguments, with stdin as its
+standard input, in a new directory, and returns what it output. */
+func runCSVCol(t *testing.T, stdin string, args ...string) runResult {
+	t.Helper()
+	return runCSVColIn(t, t.TempDir(), stdin, args...)
+}
+
+/* runCSVColIn is like runCSVCol, but runs csvcol in dir. */
+func runCSVColIn(
+	t *testing.T,
+	dir string,
+	stdin string,
+	args ...string,
+) runResult {
+	t.Helper()
+	cmd := csvcolCommand(t, args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	var res runResult
+	if err := cmd.Run(); nil != err {
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) {
+			t.Fatalf("Running csvcol %q: %v", args, err)
+		}
+		res.code = ee.ExitCode()
+	}
+	res.stdout = stdout.String()
+	res.stderr = stderr.String()
+	return res
+}
+
+/* csvcolCommand returns a Cmd which runs csvcol with the given arguments.
+It's killed if it's still running after a minute. */
+func csvcolCommand(t *testing.T, args ...string) *exec.Cmd {
+	t.Helper()
+	exe, err := os.Executable()
+	if nil != err {
+		t.Fatalf("Finding test binary: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	t.Cleanup(cancel)
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Env = append(os.Environ(), runMainEnv+"=1")
+	return cmd
+}
+
+/* mustRun runs csvcol as with runCSVCol and fails the test if csvcol didn't
+exit happily.  It returns csvcol's standard output. */
+func mustRun(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	res := runCSVCol(t, stdin, args...)
+	if 0 != res.code {
+		t.Fatalf(
+			"csvcol %q exited with status %d: %s",
+			args,
+			res.code,
+			res.stderr,
+		)
+	}
+	return res.stdout
+}
+
+/* writeTestFile writes contents to the file named name in dir and returns
+its path. */
+func writeTestFile(t *testing.T, dir, name, contents string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(contents), 0644); nil != err {
+		t.Fatalf("Writing %v: %v", p, err)
+	}
+	return p
+}
+
+/* outputTest is a run of csvcol and the output it should give */
+type outputTest struct {
+	name  string
+	stdin string
+	args  []string
+	want  string
+}
+
+/* runOutputTests runs csvcol for each of tests, each in a subtest, and checks
+its output. */
+func runOutputTests(t *testing.T, tests []outputTest) {
+	t.Helper()
+	for _, c := range tests {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := mustRun(t, c.stdin, c.args...)
+			if c.want != got {
+				t.Errorf("Output incorrect:\n"+
+					"got:\n%s\nwant:\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestTrace(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestFile(
+		t,
+		dir,
+		"in.csv",
+		"h1,h2\na,b\n\"c\nd\",e\nf,g\n",
+	)
+	tf := filepath.Join(dir, "trace.jsonl")
+	got := mustRun(t, "x,y\n", "-rows", "2-3,5", "-cols", "2",
+		"-trace", tf, in, "-")
+	if want := "b\ne\ny\n"; want != got {
+		t.Errorf("Output %q, want %q", got, want)
+	}
+	checkTrace(t, tf, []traceLine{
+		{in, 2, 6, 2, []string{"2-3"}, []string{"2"}},
+		{in, 3, 10, 3, []string{"2-3"}, []string{"2"}},
+		{"standard input", 1, 0, 5, []string{"5"}, []string{"2"}},
+	})
+}
+
+func TestTraceSection(t *testing.T) {
+	dir := t.TempDir()
+	in := writeTestFile(t, dir, "in.csv", "x\ny\n\na,b\nc,d\n")
+	tf := filepath.Join(dir, "trace.jsonl")
+	got := mustRun(t, "", "-section", "2", "-trace", tf, in)
+	if want := "a,b\nc,d\n"; want != got {
+		t.Errorf("Output %q, want %q", got, want)
+	}
+	checkTrace(t, tf, []traceLine{
+		{in, 4, 5, 1, []string{"-"}, []string{"-"}},
+		{in, 5, 9, 2, []string{"-"}, []string{"-"}},
+	})
+}
+
+/* traceLine is a line of a -trace file */
+type traceLine struct {
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Offset int64    `json:"offset"`
+	Row    int      `json:"row"`
+	Rows   []string `json:"rows"`
+	Cols   []string `json:"cols"`
+}
+
+/* checkTrace checks that the trace file tf has the lines in want */
+func checkTrace(t *testing.T, tf string, want []traceLine) {
+	t.Helper()
+	b, err := os.ReadFile(tf)
+	if nil != err {
+		t.Fatalf("Reading trace: %v", err)
+	}
+	ls := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(ls) != len(want) {
+		t.Fatalf("Got %d trace lines, want %d:\n%s",
+			len(ls), len(want), b)
+	}
+	for i, l := range ls {
+		var got traceLine
+		if err := json.Unmarshal([]byte(l), &got); nil != err {
+			t.Fatalf("Trace line %q: %v", l, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want[i]) {
+			t.Errorf("Trace line %d:\ngot:  %+v\nwant: %+v",
+				i+1, got, want[i])
+		}
+	}
+}
+
+func TestWhereAnyAll(t *testing.T) {
+	in := "a,1,x\nb,2,y\nc,3,z\n"
+	runOutputTests(t, []outputTest{{
+		name:  "any",
+		stdin: in,
+		args: []string{
+			"-where-any", "col:1 == a",
+			"-where-any", "col:2 >= 3",
+		},
+		want: "a,1,x\nc,3,z\n",
+	}, {
+		name:  "all",
+		stdin: in,
+		args: []string{
+			"-where", "col:2 > 1",
+			"-where-all", "col:3 != z",
+		},
+		want: "b,2,y\n",
+	}, {
+		name:  "all_and_any",
+		stdin: in,
+		args: []string{
+			"-where-all", "col:2 > 1",
+			"-where-any", "col:1 == a",
+			"-where-any", "col:3 == z",
+		},
+		want: "c,3,z\n",
+	}, {
+		name:  "none_match",
+		stdin: in,
+		args: []string{
+			"-where-any", "col:1 == q",
+			"-where-any", "col:2 > 9",
+		},
+		want: "",
+	}})
+}
+
+func TestOutputPerFile(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0700); nil != err {
+		t.Fatalf("Making %v: %v", sub, err)
+	}
+	one := writeTestFile(t, dir, "one.csv", "a,b\nc,d\n")
+	two := writeTestFile(t, sub, "two.txt", "e,f\ng,h\n")
+	res := runCSVColIn(
+		t,
+		dir,
+		"i,j\n",
+		"-cols", "2",
+		"-output-per-file", "{dir}/{name}.filtered{ext}",
+		one, two, "-",
+	)
+	if 0 != res.code {
+		t.Fatalf("Exit status %d: %s", res.code, res.stderr)
+	}
+	if "" != res.stdout {
+		t.Errorf("Unexpected standard output: %q", res.stdout)
+	}
+	for _, c := range []struct {
+		name string
+		want string
+	}{
+		{filepath.Join(dir, "one.filtered.csv"), "b\nd\n"},
+		{filepath.Join(sub, "two.filtered.txt"), "f\nh\n"},
+		{filepath.Join(dir, "stdin.filtered"), "j\n"},
+	} {
+		b, err := os.ReadFile(c.name)
+		if nil != err {
+			t.Errorf("Reading output: %v", err)
+			continue
+		}
+		if got := string(b); c.want != got {
+			t.Errorf("%v: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestInPlaceMetadata(t *testing.T) {
+	old := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	for _, c := range []struct {
+		name      string
+		args      []string
+		keepMtime bool
+	}{
+		{"in_place", []string{"-in-place"}, false},
+		{
+			"preserve_mtime",
+			[]string{"-in-place", "-preserve-mtime"},
+			true,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fn := writeTestFile(t, dir, "in.csv", "a,b\nc,d\n")
+			if err := os.Chmod(fn, 0640); nil != err {
+				t.Fatalf("Chmod: %v", err)
+			}
+			if err := os.Chtimes(fn, old, old); nil != err {
+				t.Fatalf("Chtimes: %v", err)
+			}
+			mustRun(t, "", append(c.args, "-cols", "2", fn)...)
+			b, err := os.ReadFile(fn)
+			if nil != err {
+				t.Fatalf("Reading output: %v", err)
+			}
+			if "b\nd\n" != string(b) {
+				t.Errorf("Output %q, want %q", b, "b\nd\n")
+			}
+			fi, err := os.Stat(fn)
+			if nil != err {
+				t.Fatalf("Stat: %v", err)
+			}
+			if 0640 != fi.Mode().Perm() {
+				t.Errorf("Permissions %v, want %v",
+					fi.Mode().Perm(), os.FileMode(0640))
+			}
+			kept := fi.ModTime().Equal(old)
+			if kept != c.keepMtime {
+				t.Errorf("Modification time %v, "+
+					"preserved: %v", fi.ModTime(), kept)
+			}
+			/* No temporary files left behind */
+			if des, err := os.ReadDir(dir); nil != err {
+				t.Errorf("ReadDir: %v", err)
+			} else if 1 != len(des) {
+				t.Errorf("Got %d files, want 1", len(des))
+			}
+		})
+	}
+}
+
+func TestInPlaceFailure(t *testing.T) {
+	dir := t.TempDir()
+	in := "a,b\nc\"d,e\n"
+	fn := writeTestFile(t, dir, "in.csv", in)
+	res := runCSVCol(t, "", "-strict", "-in-place", fn)
+	if 0 == res.code {
+		t.Fatalf("Succeeded with bad input")
+	}
+	if b, err := os.ReadFile(fn); nil != err {
+		t.Errorf("Reading input: %v", err)
+	} else if in != string(b) {
+		t.Errorf("Input changed to %q", b)
+	}
+	checkDir(t, dir, "in.csv")
+}
+
+func TestColnames(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestFile(
+		t,
+		dir,
+		"first.csv",
+		"# comment\nid,email,\"last, login\"\n1,a@x,mon\n",
+	)
+	second := writeTestFile(t, dir, "second.csv", "2,b@x,tue\n")
+	runOutputTests(t, []outputTest{{
+		name: "names",
+		args: []string{
+			"-commentchar", "#",
+			"-colnames", `email,"last, login"`,
+			first, second,
+		},
+		want: "email,\"last, login\"\na@x,mon\nb@x,tue\n",
+	}, {
+		name: "ordered",
+		args: []string{
+			"-commentchar", "#",
+			"-colnames", `"last, login",id`,
+			"-ordered",
+			first,
+		},
+		want: "\"last, login\",id\nmon,1\n",
+	}, {
+		name: "with_cols",
+		args: []string{
+			"-commentchar", "#",
+			"-colnames", "id",
+			"-cols", "3",
+			first,
+		},
+		want: "id,\"last, login\"\n1,mon\n",
+	}})
+
+	res := runCSVCol(t, "a,b\n1,2\n", "-colnames", "a,nope")
+	if 0 == res.code {
+		t.Errorf("Unknown column name didn't fail")
+	}
+	if !strings.Contains(res.stderr, `no column named "nope"`) {
+		t.Errorf("Unexpected error: %s", res.stderr)
+	}
+}
+
+func TestNegatedRanges(t *testing.T) {
+	in := "1,2,3,4,5\na,b,c,d,e\nf,g,h,i,j\nk,l,m,n,o\n"
+	runOutputTests(t, []outputTest{{
+		name:  "notcols",
+		stdin: in,
+		args:  []string{"-notcols", "2,4-"},
+		want:  "1,3\na,c\nf,h\nk,m\n",
+	}, {
+		name:  "cols_bang",
+		stdin: in,
+		args:  []string{"-cols", "!2,4-"},
+		want:  "1,3\na,c\nf,h\nk,m\n",
+	}, {
+		name:  "notrows",
+		stdin: in,
+		args:  []string{"-notrows", "2-3"},
+		want:  "1,2,3,4,5\nk,l,m,n,o\n",
+	}, {
+		name:  "rows_bang",
+		stdin: in,
+		args:  []string{"-rows", "!1", "-cols", "1"},
+		want:  "a\nf\nk\n",
+	}, {
+		name:  "rows_and_notrows",
+		stdin: in,
+		args:  []string{"-rows", "2-", "-notrows", "3", "-cols", "5"},
+		want:  "e\no\n",
+	}, {
+		name:  "cols_and_notcols",
+		stdin: in,
+		args:  []string{"-cols", "2-4", "-notcols", "3", "-rows", "1"},
+		want:  "2,4\n",
+	}})
+}
+
+func TestSpecFileIncludes(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0700); nil != err {
+		t.Fatalf("Making %v: %v", sub, err)
+	}
+	writeTestFile(t, sub, "more", "# More rows\n4 # and a comment\n")
+	rows := writeTestFile(
+		t,
+		dir,
+		"rows",
+		"# Rows we want\n\n1\n  \n@include sub/more\n2 # trailing\n",
+	)
+	cols := writeTestFile(t, dir, "cols", "3\n\n# c1\n1\n")
+	runOutputTests(t, []outputTest{{
+		name:  "rowfile_colfile",
+		stdin: "a,b,c\nd,e,f\ng,h,i\nj,k,l\nm,n,o\n",
+		args:  []string{"-rowfile", rows, "-colfile", cols},
+		want:  "a,c\nd,f\nj,l\n",
+	}})
+
+	/* Includes which loop shouldn't loop forever */
+	loop := writeTestFile(t, dir, "loop", "1\n@include loop\n")
+	if res := runCSVCol(t, "a\n", "-rowfile", loop); 0 == res.code {
+		t.Errorf("Include loop didn't fail")
+	}
+}
+
+func TestDelim(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "semicolon",
+		stdin: "a;\"b;c\";d\ne;f;g\n",
+		args:  []string{"-delim", ";", "-cols", "2-"},
+		want:  "b;c,d\nf,g\n",
+	}, {
+		name:  "tab",
+		stdin: "a\tb,c\td\n",
+		args:  []string{"-tab", "-cols", "2"},
+		want:  "\"b,c\"\n",
+	}, {
+		name:  "escaped_tab",
+		stdin: "a\tb\n",
+		args:  []string{"-delim", `\t`, "-cols", "2"},
+		want:  "b\n",
+	}, {
+		name:  "pipe",
+		stdin: "a|b|c\n",
+		args:  []string{"-delim", "|", "-cols", "1,3"},
+		want:  "a,c\n",
+	}})
+	for _, d := range []string{"ab", "", "\n", `"`} {
+		if res := runCSVCol(t, "a\n", "-delim", d); 0 == res.code {
+			t.Errorf("Invalid delimiter %q didn't fail", d)
+		}
+	}
+}
+
+func TestOutdelim(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "tsv",
+		stdin: "a,b c,d\n",
+		args:  []string{"-outdelim", `\t`},
+		want:  "a\tb c\td\n",
+	}, {
+		name:  "quoting",
+		stdin: "a,\"b;c\",d\n",
+		args:  []string{"-outdelim", ";"},
+		want:  "a;\"b;c\";d\n",
+	}, {
+		name:  "convert",
+		stdin: "a;b,c\n",
+		args:  []string{"-delim", ";", "-outdelim", "|"},
+		want:  "a|b,c\n",
+	}})
+	if res := runCSVCol(t, "a\n", "-outdelim", "||"); 0 == res.code {
+		t.Errorf("Invalid output delimiter didn't fail")
+	}
+}
+
+func TestFilterColumns(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "end_before_start",
+		stdin: "id,start,end\n1,5,9\n2,7,3\n3,10,9\n",
+		args:  []string{"-header", "-filter", "c3 < c2", "-cols", "1"},
+		want:  "id\n2\n3\n",
+	}})
+}
+
+func TestRemoveTempOutputs(t *testing.T) {
+	defer func(d *bool) { gc.debug = d }(gc.debug)
+	gc.debug = new(bool)
+	dir := t.TempDir()
+	tmp := writeTestFile(t, dir, "tmp", "a\n")
+	kept := writeTestFile(t, dir, "kept", "b\n")
+	addTempOutput(tmp)
+	addTempOutput(kept)
+	doneTempOutput(kept)
+	removeTempOutputs()
+	if _, err := os.Stat(tmp); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Temporary file not removed: %v", err)
+	}
+	if _, err := os.Stat(kept); nil != err {
+		t.Errorf("Renamed file removed: %v", err)
+	}
+}
+
+func TestOrdered(t *testing.T) {
+	in := "1,2,3,4,5\na,b,c,d,e\n"
+	runOutputTests(t, []outputTest{{
+		name:  "reorder_duplicate",
+		stdin: in,
+		args:  []string{"-ordered", "-cols", "5,1,1,3"},
+		want:  "5,1,1,3\ne,a,a,c\n",
+	}, {
+		name:  "ranges",
+		stdin: in,
+		args:  []string{"-ordered", "-cols", "4-,-2"},
+		want:  "4,5,1,2\nd,e,a,b\n",
+	}, {
+		name:  "from_end",
+		stdin: in,
+		args:  []string{"-ordered", "-cols", "-1-,1"},
+		want:  "5,1\ne,a\n",
+	}, {
+		name:  "notcols",
+		stdin: in,
+		args:  []string{"-ordered", "-cols", "5,1-", "-notcols", "3"},
+		want:  "5,1,2,4,5\ne,a,b,d,e\n",
+	}, {
+		name:  "short_row",
+		stdin: "1,2,3\n4\n",
+		args:  []string{"-ordered", "-cols", "3,1"},
+		want:  "3,1\n4\n",
+	}, {
+		name:  "unordered",
+		stdin: in,
+		args:  []string{"-cols", "5,1,1,3"},
+		want:  "1,3,5\na,c,e\n",
+	}})
+}
+
+func TestFromEndFiles(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one", "1\n2\n3\n4\n5\n")
+	two := writeTestFile(t, dir, "two", "6\n7\n8\n")
+	runOutputTests(t, []outputTest{{
+		name: "across_files",
+		args: []string{"-rows", "-2-", one, two},
+		want: "7\n8\n",
+	}, {
+		name: "perfile",
+		args: []string{"-rows", "-2-", "-perfile", one, two},
+		want: "4\n5\n7\n8\n",
+	}, {
+		name: "long_window",
+		args: []string{"-rows", "-4-", one, two},
+		want: "5\n6\n7\n8\n",
+	}})
+}
+
+func TestMatch(t *testing.T) {
+	in := "1.2.3.4,GET /admin\n" +
+		"5.6.7.8,POST /\n" +
+		"1.2.3.5,POST /admin/x\n"
+	runOutputTests(t, []outputTest{{
+		name:  "match",
+		stdin: in,
+		args:  []string{"-match", "2:^(GET|POST) /admin"},
+		want:  "1.2.3.4,GET /admin\n1.2.3.5,POST /admin/x\n",
+	}, {
+		name:  "repeated",
+		stdin: in,
+		args:  []string{"-match", "c2:admin", "-match", "col:1:5$"},
+		want:  "1.2.3.5,POST /admin/x\n",
+	}, {
+		name:  "vmatch",
+		stdin: in,
+		args:  []string{"-vmatch", "2:admin"},
+		want:  "5.6.7.8,POST /\n",
+	}, {
+		name:  "both",
+		stdin: in,
+		args:  []string{"-match", "2:POST", "-vmatch", "2:admin"},
+		want:  "5.6.7.8,POST /\n",
+	}, {
+		name:  "with_rows",
+		stdin: in,
+		args: []string{
+			"-rows", "2-",
+			"-match", "2:admin",
+			"-cols", "1",
+		},
+		want: "1.2.3.5\n",
+	}})
+
+	for _, s := range []string{"2", "2:(", "0:x"} {
+		if res := runCSVCol(t, in, "-match", s); 0 == res.code {
+			t.Errorf("-match %q succeeded", s)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one", "h1,h2\n1,a\n2,b\n3,c\n")
+	two := writeTestFile(t, dir, "two", "h1,h2\n4,d\n5,e\n")
+	runOutputTests(t, []outputTest{{
+		name: "rows_after_header",
+		args: []string{"-header", "-rows", "1", one},
+		want: "h1,h2\n1,a\n",
+	}, {
+		name: "without_header",
+		args: []string{"-rows", "1", one},
+		want: "h1,h2\n",
+	}, {
+		name: "once_across_files",
+		args: []string{"-header", one, two},
+		want: "h1,h2\n1,a\n2,b\n3,c\n4,d\n5,e\n",
+	}, {
+		name: "numbered_across_files",
+		args: []string{"-header", "-rows", "3-4", one, two},
+		want: "h1,h2\n3,c\n4,d\n",
+	}, {
+		name: "perfile",
+		args: []string{"-header", "-rows", "2", "-perfile", one, two},
+		want: "h1,h2\n2,b\n5,e\n",
+	}, {
+		name: "where",
+		args: []string{"-header", "-where", "c1 > 4", one, two},
+		want: "h1,h2\n5,e\n",
+	}, {
+		name: "nothing_selected",
+		args: []string{
+			"-header",
+			"-where", "c1 > 9",
+			"-cols", "2",
+			one,
+		},
+		want: "h2\n",
+	}, {
+		name: "from_end",
+		args: []string{"-header", "-rows", "-1-", one, two},
+		want: "h1,h2\n5,e\n",
+	}, {
+		name: "colnames",
+		args: []string{"-header", "-colnames", "h2", one},
+		want: "h2\na\nb\nc\n",
+	}, {
+		name:  "empty",
+		stdin: "",
+		args:  []string{"-header"},
+		want:  "",
+	}})
+
+	/* The header is output once per output file */
+	res := runCSVCol(
+		t,
+		"",
+		"-header",
+		"-output-per-file", filepath.Join(dir, "{name}.out"),
+		one, two,
+	)
+	if 0 != res.code {
+		t.Fatalf("Exit status %d: %s", res.code, res.stderr)
+	}
+	for name, want := range map[string]string{
+		"one.out": "h1,h2\n1,a\n2,b\n3,c\n",
+		"two.out": "h1,h2\n4,d\n5,e\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if nil != err {
+			t.Errorf("Reading output: %v", err)
+		} else if want != string(b) {
+			t.Errorf("%s: got %q, want %q", name, b, want)
+		}
+	}
+}
+
+func TestBadInput(t *testing.T) {
+	dir := t.TempDir()
+	bad := writeTestFile(t, dir, "bad", "a,b\nc,d\"\n\"e\"x,f\ng,h\n")
+	good := writeTestFile(t, dir, "good", "i,j\n")
+	/* Gzipped, but with the end missing */
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte("k,l\nm,n\n"))
+	zw.Close()
+	short := writeTestFile(t, dir, "short.gz", b.String()[:b.Len()-8])
+
+	for _, c := range []struct {
+		name   string
+		args   []string
+		want   string
+		code   int
+		stderr []string
+	}{{
+		name: "lenient",
+		args: []string{
+			writeTestFile(t, dir, "bare", "a,b\nc,d\"\n"),
+			good,
+		},
+		want: "a,b\nc,\"d\"\"\"\ni,j\n",
+	}, {
+		name:   "strict",
+		args:   []string{"-strict", bad, good},
+		want:   "a,b\n",
+		code:   -24,
+		stderr: []string{bad + ": line 2, column 4: bare \""},
+	}, {
+		name: "skip_bad",
+		args: []string{"-skip-bad", bad, good},
+		want: "a,b\ng,h\ni,j\n",
+		stderr: []string{
+			"Skipped 2 bad record(s)",
+		},
+	}, {
+		name: "multiline_record",
+		args: []string{"-skip-bad", writeTestFile(
+			t,
+			dir,
+			"open",
+			"a,b\n\"c\nd\"x,e\n",
+		)},
+		want:   "a,b\n",
+		stderr: []string{"Skipped 1 bad record(s)"},
+	}, {
+		name: "unreadable_continues",
+		args: []string{short, good},
+		want: "k,l\nm,n\ni,j\n",
+		code: -24,
+		stderr: []string{
+			"Error reading " + short,
+			"Unable to read 1 file(s)",
+		},
+	}, {
+		name:   "unreadable_strict",
+		args:   []string{"-strict", short, good},
+		want:   "k,l\nm,n\n",
+		code:   -24,
+		stderr: []string{"Error reading " + short},
+	}, {
+		name: "strict_and_skip_bad",
+		args: []string{"-strict", "-skip-bad", good},
+		code: -24,
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			res := runCSVCol(t, "", c.args...)
+			if c.want != res.stdout {
+				t.Errorf(
+					"Output incorrect:\n"+
+						"got:\n%s\nwant:\n%s",
+					res.stdout,
+					c.want,
+				)
+			}
+			if int8(c.code) != int8(res.code) {
+				t.Errorf(
+					"Exit status %d, want %d",
+					int8(res.code),
+					c.code,
+				)
+			}
+			for _, s := range c.stderr {
+				if !strings.Contains(res.stderr, s) {
+					t.Errorf("Stderr missing %q: %s", s,
+						res.stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestPerfile(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one", "h,a\n1,b\n2,c\n")
+	two := writeTestFile(t, dir, "two", "h,d\n3,e\n")
+	marked := writeTestFile(t, dir, "marked", "x\nSTART\ny\nz\n")
+	start := writeTestFile(t, dir, "start", "START\nq\n")
+	runOutputTests(t, []outputTest{{
+		name: "skip_first_lines",
+		args: []string{"-perfile", "-rows", "2-", one, two},
+		want: "1,b\n2,c\n3,e\n",
+	}, {
+		name: "without_perfile",
+		args: []string{"-rows", "2-", one, two},
+		want: "1,b\n2,c\nh,d\n3,e\n",
+	}, {
+		name:  "stdin",
+		stdin: "h,f\n4,g\n",
+		args: []string{
+			"-perfile",
+			"-rows", "1",
+			"-cols", "2",
+			one, "-",
+		},
+		want: "a\nf\n",
+	}, {
+		name: "not_rows",
+		args: []string{"-perfile", "-notrows", "1", one, two},
+		want: "1,b\n2,c\n3,e\n",
+	}, {
+		name: "anchored",
+		args: []string{
+			"-perfile",
+			"-rows", "after:/START/",
+			marked, one, start,
+		},
+		want: "y\nz\nq\n",
+	}, {
+		name: "anchored_without_perfile",
+		args: []string{"-rows", "after:/START/", marked, start},
+		want: "y\nz\nSTART\nq\n",
+	}})
+}
+
+func TestWithFilename(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "one", "h,a\n1,b\n")
+	writeTestFile(t, dir, "two", "h,a\n2,c\n")
+	for _, c := range []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+	}{{
+		name: "plain",
+		args: []string{"one", "two"},
+		want: "one,h,a\none,1,b\ntwo,h,a\ntwo,2,c\n",
+	}, {
+		name: "header",
+		args: []string{"-header", "one", "two"},
+		want: "filename,h,a\none,1,b\ntwo,2,c\n",
+	}, {
+		name:  "stdin",
+		stdin: "x\n",
+		args:  []string{"-rows", "2-", "one", "-"},
+		want:  "one,1,b\nstandard input,x\n",
+	}, {
+		name: "json",
+		args: []string{"-header", "-json", "two"},
+		want: `{"filename":"two","h":"2","a":"c"}` + "\n",
+	}, {
+		name: "after_stages",
+		args: []string{
+			"-header",
+			"-stage", "rsort=c1",
+			"-cols", "2",
+			"one", "two",
+		},
+		want: "filename,a\ntwo,c\none,b\n",
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			args := append([]string{"-with-filename"}, c.args...)
+			res := runCSVColIn(t, dir, c.stdin, args...)
+			if 0 != res.code {
+				t.Fatalf("Exit status %d: %s", res.code,
+					res.stderr)
+			}
+			if c.want != res.stdout {
+				t.Errorf(
+					"Output incorrect:\n"+
+						"got:\n%s\nwant:\n%s",
+					res.stdout,
+					c.want,
+				)
+			}
+		})
+	}
+}
+
+func TestWithLinenum(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "one", "h,a\n1,b\n2,c\n")
+	writeTestFile(t, dir, "two", "h,a\n3,d\n")
+	for _, c := range []struct {
+		name string
+		args []string
+		want string
+	}{{
+		name: "rows",
+		args: []string{"-rows", "2-", "one", "two"},
+		want: "2,1,b\n3,2,c\n4,h,a\n5,3,d\n",
+	}, {
+		name: "where_header",
+		args: []string{"-header", "-where", "c1 > 1", "one", "two"},
+		want: "linenum,h,a\n2,2,c\n3,3,d\n",
+	}, {
+		name: "perfile_filename",
+		args: []string{
+			"-with-filename",
+			"-perfile",
+			"-rows", "2",
+			"one", "two",
+		},
+		want: "one,2,1,b\ntwo,2,3,d\n",
+	}, {
+		name: "json",
+		args: []string{"-header", "-json", "-rows", "2", "one"},
+		want: `{"linenum":"2","h":"2","a":"c"}` + "\n",
+	}, {
+		name: "sorted",
+		args: []string{"-header", "-stage", "sort=-c1", "one", "two"},
+		want: "linenum,h,a\n3,3,d\n2,2,c\n1,1,b\n",
+	}, {
+		name: "from_end",
+		args: []string{"-rows", "-1-", "one", "two"},
+		want: "5,3,d\n",
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			args := append([]string{"-with-linenum"}, c.args...)
+			res := runCSVColIn(t, dir, "", args...)
+			if 0 != res.code {
+				t.Fatalf("Exit status %d: %s", res.code,
+					res.stderr)
+			}
+			if c.want != res.stdout {
+				t.Errorf(
+					"Output incorrect:\n"+
+						"got:\n%s\nwant:\n%s",
+					res.stdout,
+					c.want,
+				)
+			}
+		})
+	}
+}
+
+func TestListCols(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "header",
+		stdin: "name,age,\"a, city\"\nx,y,z\n",
+		args:  []string{"-list-cols"},
+		want:  "1\tname\n2\tage\n3\ta, city\n",
+	}, {
+		name:  "delim_comment",
+		stdin: "# c\na;b\n",
+		args: []string{
+			"-list-cols",
+			"-delim", ";",
+			"-commentchar", "#",
+		},
+		want: "1\ta\n2\tb\n",
+	}, {
+		name:  "all_columns",
+		stdin: "a,b\n",
+		args:  []string{"-list-cols", "-cols", "2"},
+		want:  "1\ta\n2\tb\n",
+	}, {
+		name:  "empty",
+		stdin: "",
+		args:  []string{"-list-cols"},
+		want:  "",
+	}})
+}
+
+func TestColre(t *testing.T) {
+	in := "id,metric_a,x,metric_b\n1,2,3,4\n"
+	runOutputTests(t, []outputTest{{
+		name:  "prefix",
+		stdin: in,
+		args:  []string{"-colre", "^metric_"},
+		want:  "metric_a,metric_b\n2,4\n",
+	}, {
+		name:  "with_cols",
+		stdin: in,
+		args:  []string{"-colre", "^metric_", "-cols", "1"},
+		want:  "id,metric_a,metric_b\n1,2,4\n",
+	}, {
+		name:  "with_colnames",
+		stdin: in,
+		args:  []string{"-colre", "_b$", "-colnames", "x"},
+		want:  "x,metric_b\n3,4\n",
+	}, {
+		name:  "ordered",
+		stdin: in,
+		args: []string{
+			"-colre", "^x$",
+			"-colre", "^metric_",
+			"-ordered",
+		},
+		want: "x,metric_a,metric_b\n3,2,4\n",
+	}, {
+		name:  "json",
+		stdin: in,
+		args:  []string{"-colre", "a$", "-json", "-header"},
+		want:  `{"metric_a":"2"}` + "\n",
+	}})
+	for _, re := range []string{"(", "zzz"} {
+		if res := runCSVCol(t, in, "-colre", re); 0 == res.code {
+			t.Errorf("-colre %q succeeded", re)
+		}
+	}
+}
+
+func TestColnameRanges(t *testing.T) {
+	in := "id,timestamp,a-b,status,z\n1,2,3,4,5\n"
+	runOutputTests(t, []outputTest{{
+		name:  "range",
+		stdin: in,
+		args:  []string{"-colnames", "timestamp-status"},
+		want:  "timestamp,a-b,status\n2,3,4\n",
+	}, {
+		name:  "inserted_column",
+		stdin: "new,id,timestamp,a-b,status,z\n0,1,2,3,4,5\n",
+		args:  []string{"-colnames", "timestamp-status"},
+		want:  "timestamp,a-b,status\n2,3,4\n",
+	}, {
+		name:  "open_start",
+		stdin: in,
+		args:  []string{"-colnames", "-a-b"},
+		want:  "id,timestamp,a-b\n1,2,3\n",
+	}, {
+		name:  "open_end",
+		stdin: in,
+		args:  []string{"-colnames", "id,status-"},
+		want:  "id,status,z\n1,4,5\n",
+	}, {
+		name:  "hyphenated_name",
+		stdin: in,
+		args:  []string{"-colnames", "a-b"},
+		want:  "a-b\n3\n",
+	}})
+	for _, n := range []string{"status-timestamp", "nope-z"} {
+		if res := runCSVCol(t, in, "-colnames", n); 0 == res.code {
+			t.Errorf("-colnames %q succeeded", n)
+		}
+	}
+}
+
+func TestWhereValidators(t *testing.T) {
+	in := "card,email\n" +
+		"4111111111111111,a@example.com\n" +
+		"4111111111111112,b@example.com\n" +
+		"5500 0000 0000 0004,c@localhost\n"
+	runOutputTests(t, []outputTest{{
+		name:  "luhn",
+		stdin: in,
+		args:  []string{"-header", "-where", "luhn(c1)"},
+		want: "card,email\n" +
+			"4111111111111111,a@example.com\n" +
+			"5500 0000 0000 0004,c@localhost\n",
+	}, {
+		name:  "not_email",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-where", "!email(c2)",
+			"-cols", "1",
+		},
+		want: "card\n5500 0000 0000 0004\n",
+	}})
+}
+
+func TestAddCol(t *testing.T) {
+	in := "name,total\nann,5000\nbob,500\ncat,5\n"
+	tier := `tier=case(c2>1000:"gold", c2>100:"silver", "bronze")`
+	runOutputTests(t, []outputTest{{
+		name:  "case",
+		stdin: in,
+		args:  []string{"-header", "-add-col", tier},
+		want: "name,total,tier\n" +
+			"ann,5000,gold\n" +
+			"bob,500,silver\n" +
+			"cat,5,bronze\n",
+	}, {
+		name:  "unselected_column",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "1",
+			"-add-col", tier,
+			"-add-col", "src=x",
+		},
+		want: "name,tier,src\nann,gold,x\nbob,silver,x\ncat,bronze,x\n",
+	}, {
+		name:  "where",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-where", "c2 < 1000",
+			"-add-col", tier,
+		},
+		want: "name,total,tier\nbob,500,silver\ncat,5,bronze\n",
+	}})
+	res := runCSVCol(t, in, "-add-col", "tier=case(")
+	if -33 != int8(res.code) {
+		t.Errorf("Bad -add-col exit code %d, want -33", int8(res.code))
+	}
+}
+
+func TestCollate(t *testing.T) {
+	in := "name\nZebra\nÖl\nÄpfel\nOber\nApfel\n"
+	runOutputTests(t, []outputTest{{
+		name:  "bytes",
+		stdin: in,
+		args:  []string{"-header", "-stage", "sort=c1"},
+		want:  "name\nApfel\nOber\nZebra\nÄpfel\nÖl\n",
+	}, {
+		name:  "german",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-collate", "de_DE",
+			"-stage", "sort=c1",
+		},
+		want: "name\nApfel\nÄpfel\nOber\nÖl\nZebra\n",
+	}, {
+		name:  "swedish",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-collate", "sv",
+			"-stage", "rsort=c1",
+		},
+		want: "name\nÖl\nÄpfel\nZebra\nOber\nApfel\n",
+	}, {
+		name:  "where",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-collate", "de",
+			"-where", "c1 < B",
+		},
+		want: "name\nÄpfel\nApfel\n",
+	}})
+	res := runCSVCol(t, in, "-collate", "not a locale")
+	if -41 != int8(res.code) {
+		t.Errorf("Bad -collate exit code %d", int8(res.code))
+	}
+}
+
+func TestAdd(t *testing.T) {
+	in := "first,last,qty,price\nann,smith,2,1.10\nbob,jones,x,3\n"
+	runOutputTests(t, []outputTest{{
+		name:  "arithmetic_and_concatenation",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "1",
+			"-add", "total=col3*col4",
+			"-add", `name=c2 & ", " & c1`,
+		},
+		want: "first,total,name\n" +
+			"ann,2.2,\"smith, ann\"\n" +
+			"bob,,\"jones, bob\"\n",
+	}, {
+		name:  "with_add_col",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "1",
+			"-add-col", `big=case(c4 > 2: yes, no)`,
+			"-add", "double=c4*2",
+		},
+		want: "first,big,double\nann,no,2.2\nbob,yes,6\n",
+	}})
+	if res := runCSVCol(t, in, "-add", "x=c1 +"); -33 != int8(res.code) {
+		t.Errorf("Bad -add exit code %d", int8(res.code))
+	}
+}
+
+func TestStdinAmongFiles(t *testing.T) {
+	dir := t.TempDir()
+	header := writeTestFile(t, dir, "header.csv", "name,n\n")
+	footer := writeTestFile(t, dir, "footer.csv", "total,3\n")
+	in := "a,1\nb,2\n"
+	runOutputTests(t, []outputTest{{
+		name:  "middle",
+		stdin: in,
+		args:  []string{header, "-", footer},
+		want:  "name,n\na,1\nb,2\ntotal,3\n",
+	}, {
+		name:  "last",
+		stdin: in,
+		args:  []string{header, "-"},
+		want:  "name,n\na,1\nb,2\n",
+	}, {
+		name:  "after_csvfile",
+		stdin: in,
+		args:  []string{"-csvfile", header, "-", footer},
+		want:  "name,n\na,1\nb,2\ntotal,3\n",
+	}, {
+		name:  "with_filename",
+		stdin: in,
+		args:  []string{"-with-filename", "-cols", "1", "-", footer},
+		want: "standard input,a\nstandard input,b\n" +
+			footer + ",total\n",
+	}})
+
+	/* The standard input can only be read once */
+	cols := writeTestFile(t, dir, "cols", "1\n")
+	for _, args := range [][]string{
+		{"-", "-"},
+		{header, "-", footer, "-"},
+		{"-csvfile", "-", header, "-"},
+		{"-rowfile", "-", header, "-"},
+		{"-colfile", "-", "-"},
+		{"-colfile", cols, "-join", "-", "-"},
+	} {
+		res := runCSVCol(t, in, args...)
+		if -1 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
+
+func TestRepeatedSpecFiles(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one.csv", "a,b,c\nd,e,f\n")
+	two := writeTestFile(t, dir, "two.csv", "g,h,i\n")
+	three := writeTestFile(t, dir, "three.csv", "j,k,l\nm,n,o\n")
+	rows1 := writeTestFile(t, dir, "rows1", "1\n")
+	rows2 := writeTestFile(t, dir, "rows2", "# More\n4-\n")
+	cols1 := writeTestFile(t, dir, "cols1", "3\n")
+	cols2 := writeTestFile(t, dir, "cols2", "1\n")
+	runOutputTests(t, []outputTest{{
+		name: "csvfiles",
+		args: []string{"-csvfile", one, "-csvfile", two},
+		want: "a,b,c\nd,e,f\ng,h,i\n",
+	}, {
+		name: "csvfiles_and_args",
+		args: []string{"-csvfile", two, "-csvfile", one, three},
+		want: "g,h,i\na,b,c\nd,e,f\nj,k,l\nm,n,o\n",
+	}, {
+		name:  "csvfiles_stdin",
+		stdin: "p,q,r\n",
+		args:  []string{"-csvfile", one, "-csvfile", "-"},
+		want:  "a,b,c\nd,e,f\np,q,r\n",
+	}, {
+		name: "rowfiles",
+		args: []string{
+			"-rowfile", rows1,
+			"-rowfile", rows2,
+			one, two, three,
+		},
+		want: "a,b,c\nj,k,l\nm,n,o\n",
+	}, {
+		name: "rowfiles_and_rows",
+		args: []string{"-rowfile", rows1, "-rows", "3", one, two},
+		want: "a,b,c\ng,h,i\n",
+	}, {
+		name: "colfiles",
+		args: []string{
+			"-colfile", cols1,
+			"-colfile", cols2,
+			"-ordered",
+			one,
+		},
+		want: "c,a\nf,d\n",
+	}})
+}
+
+func TestRangesFromEnv(t *testing.T) {
+	t.Setenv("CSVCOL_TEST_ROWS", "2-3")
+	t.Setenv("CSVCOL_TEST_COLS", " 3 ")
+	t.Setenv("CSVCOL_TEST_EMPTY", "")
+	in := "a,b,c\nd,e,f\ng,h,i\nj,k,l\n"
+	runOutputTests(t, []outputTest{{
+		name:  "rows",
+		stdin: in,
+		args:  []string{"-rows-env", "CSVCOL_TEST_ROWS"},
+		want:  "d,e,f\ng,h,i\n",
+	}, {
+		name:  "cols",
+		stdin: in,
+		args:  []string{"-cols-env", "CSVCOL_TEST_COLS"},
+		want:  "c\nf\ni\nl\n",
+	}, {
+		name:  "with_flags",
+		stdin: in,
+		args: []string{
+			"-rows", "1",
+			"-rows-env", "CSVCOL_TEST_ROWS",
+			"-cols", "1",
+			"-cols-env", "CSVCOL_TEST_COLS",
+		},
+		want: "a,c\nd,f\ng,i\n",
+	}, {
+		name:  "empty_with_flags",
+		stdin: in,
+		args: []string{
+			"-rows", "4",
+			"-rows-env", "CSVCOL_TEST_EMPTY",
+		},
+		want: "j,k,l\n",
+	}})
+
+	res := runCSVCol(t, in, "-rows-env", "CSVCOL_TEST_UNSET")
+	if -3 != int8(res.code) {
+		t.Errorf("Unset variable: exit code %d", int8(res.code))
+	}
+	if !strings.Contains(res.stderr, "CSVCOL_TEST_UNSET") {
+		t.Errorf("Unset variable not named: %s", res.stderr)
+	}
+	t.Setenv("CSVCOL_TEST_BAD", "x-y")
+	res = runCSVCol(t, in, "-cols-env", "CSVCOL_TEST_BAD")
+	if 0 == res.code {
+		t.Errorf("Invalid columns accepted")
+	}
+}
+
+func TestEarlyExit(t *testing.T) {
+	/* Input which never ends shouldn't matter once we're past the last
+	row we want */
+	pr, pw, err := os.Pipe()
+	if nil != err {
+		t.Fatalf("Making pipe: %v", err)
+	}
+	defer pw.Close()
+	if _, err := pw.WriteString("a\nb\nc\nd\n"); nil != err {
+		t.Fatalf("Writing to pipe: %v", err)
+	}
+	cmd := csvcolCommand(t, "-rows", "2-3", "-idle-timeout", "10s")
+	cmd.Stdin = pr
+	out, err := cmd.Output()
+	pr.Close()
+	if nil != err {
+		t.Fatalf("Error: %v", err)
+	}
+	if "b\nc\n" != string(out) {
+		t.Errorf("Got %q", out)
+	}
+
+	/* Files after the last row we want shouldn't be opened, unless they
+	get their own rows */
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one.csv", "a\nb\nc\n")
+	missing := filepath.Join(dir, "missing.csv")
+	res := runCSVCol(t, "", "-verbose", "-rows", "2", one, missing)
+	if 0 != res.code || "b\n" != res.stdout {
+		t.Errorf(
+			"Missing file read: %d, %q: %s",
+			int8(res.code),
+			res.stdout,
+			res.stderr,
+		)
+	}
+	if !strings.Contains(res.stderr, "skipping "+missing) {
+		t.Errorf("Skipped file not noted:\n%s", res.stderr)
+	}
+	res = runCSVCol(t, "", "-perfile", "-rows", "2", one, missing)
+	if 0 == res.code {
+		t.Errorf("Missing file not read with -perfile")
+	}
+}
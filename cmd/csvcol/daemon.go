@@ -0,0 +1,232 @@
+/*
+ * daemon.go
+ * Run jobs submitted over a Unix socket
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* daemonJob is a job submitted to the daemon, as a line of JSON */
+type daemonJob struct {
+	Files   []string `json:"files"`
+	Rows    string   `json:"rows"`
+	Cols    string   `json:"cols"`
+	NotRows string   `json:"not_rows"`
+	NotCols string   `json:"not_cols"`
+	Where   []string `json:"where"`
+	Ordered bool     `json:"ordered"`
+	Output  string   `json:"output"` /* Empty to send output back */
+
+	done chan daemonResult
+}
+
+/* daemonResult is sent back for every job, as a line of JSON */
+type daemonResult struct {
+	Rows   int    `json:"rows"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+/* serveDaemon accepts jobs on a Unix socket at path and runs them with
+nWorkers workers. */
+func serveDaemon(path string, nWorkers int) error {
+	l, err := net.Listen("unix", path)
+	if nil != err {
+		return err
+	}
+	verbose("Listening for jobs on %v", l.Addr())
+
+	/* Clean up the socket when we're told to go away */
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-ch
+		verbose("Caught %v, removing %v", s, path)
+		l.Close()
+	}()
+
+	/* Start the workers */
+	if 1 > nWorkers {
+		nWorkers = 1
+	}
+	jobs := make(chan daemonJob)
+	for i := 0; i < nWorkers; i++ {
+		go func() {
+			for j := range jobs {
+				j.done <- runJob(j)
+			}
+		}()
+	}
+	debug("Started %v workers", nWorkers)
+
+	/* Handle clients */
+	for {
+		c, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if nil != err {
+			return err
+		}
+		go handleDaemonClient(c, jobs)
+	}
+}
+
+/* handleDaemonClient reads jobs from c, one per line, submits them to be run,
+and sends back the results in order. */
+func handleDaemonClient(c net.Conn, jobs chan<- daemonJob) {
+	defer c.Close()
+	debug("New client")
+	dec := json.NewDecoder(bufio.NewReader(c))
+	enc := json.NewEncoder(c)
+	for {
+		var j daemonJob
+		if err := dec.Decode(&j); nil != err {
+			if io.EOF != err {
+				enc.Encode(daemonResult{Error: fmt.Sprintf(
+					"invalid job: %v", err,
+				)})
+			}
+			return
+		}
+		j.done = make(chan daemonResult, 1)
+		jobs <- j
+		res := <-j.done
+		if "" != res.Error {
+			verbose("Job failed: %v", res.Error)
+		}
+		if err := enc.Encode(res); nil != err {
+			debug("Error sending result: %v", err)
+			return
+		}
+	}
+}
+
+/* runJob runs a single job */
+func runJob(j daemonJob) daemonResult {
+	var res daemonResult
+	debug("Running job %#v", j)
+	sel, err := requestSelection(selectRequest{
+		rows:    j.Rows,
+		cols:    j.Cols,
+		notRows: j.NotRows,
+		notCols: j.NotCols,
+		where:   j.Where,
+		ordered: j.Ordered,
+	})
+	if nil != err {
+		res.Error = err.Error()
+		return res
+	}
+	if 0 == len(j.Files) {
+		res.Error = "no files"
+		return res
+	}
+
+	/* Work out where the output goes */
+	var (
+		buf bytes.Buffer
+		out io.Writer = &buf
+		of  *os.File
+	)
+	if "" != j.Output {
+		if of, err = os.Create(j.Output); nil != err {
+			res.Error = err.Error()
+			return res
+		}
+		defer of.Close()
+		out = of
+	}
+	w := newRecordWriter(out, sel)
+
+	/* Select from each file in turn */
+	for _, f := range j.Files {
+		if err := selectFromFile(f, sel, w, &res.Rows); nil != err {
+			res.Error = fmt.Sprintf("%v: %v", f, err)
+			return res
+		}
+	}
+	if err := w.Close(); nil != err {
+		res.Error = err.Error()
+		return res
+	}
+	if nil != of {
+		if err := of.Close(); nil != err {
+			res.Error = err.Error()
+		}
+		return res
+	}
+	res.Output = buf.String()
+	return res
+}
+
+/* selectFromFile writes the records selected by sel from the file named f to
+w, and adds the number written to n. */
+func selectFromFile(
+	f string,
+	sel *csvcol.Selector,
+	w recordWriter,
+	n *int,
+) error {
+	fp, err := openFile(f)
+	if nil != err {
+		return err
+	}
+	defer fp.Close()
+	r := newReader(fp)
+	for {
+		record, err := r.Read()
+		if io.EOF == err {
+			return nil
+		} else if nil != err {
+			return err
+		}
+		orec, ok, err := sel.Select(record)
+		if nil != err {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if sel.IsHeaderRow() {
+			if err := w.WriteHeader(orec); nil != err {
+				return err
+			}
+			continue
+		}
+		if err := w.Write(orec); nil != err {
+			return err
+		}
+		*n++
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * daemon_test.go
+ * Tests for daemon.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+/* startDaemon starts csvcol as a daemon and returns the path to its socket */
+func startDaemon(t *testing.T, args ...string) string {
+	t.Helper()
+	/* Socket paths can't be very long, so not t.TempDir() */
+	dir, err := os.MkdirTemp("", "csvcol")
+	if nil != err {
+		t.Fatalf("Making socket directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "s")
+
+	cmd := csvcolCommand(t, append([]string{"-daemon", sock}, args...)...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); nil != err {
+		t.Fatalf("Starting csvcol: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	/* Wait for it to listen */
+	for start := time.Now(); time.Since(start) < 10*time.Second; {
+		c, err := net.Dial("unix", sock)
+		if nil == err {
+			c.Close()
+			return sock
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("csvcol didn't start listening on %v", sock)
+	return ""
+}
+
+/* daemonClient is a connection to the daemon */
+type daemonClient struct {
+	t   *testing.T
+	c   net.Conn
+	dec *json.Decoder
+}
+
+/* dialDaemon connects to the daemon listening on sock */
+func dialDaemon(t *testing.T, sock string) daemonClient {
+	t.Helper()
+	c, err := net.Dial("unix", sock)
+	if nil != err {
+		t.Fatalf("Connecting to daemon: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.SetDeadline(time.Now().Add(10 * time.Second))
+	return daemonClient{
+		t:   t,
+		c:   c,
+		dec: json.NewDecoder(bufio.NewReader(c)),
+	}
+}
+
+/* send sends a line to the daemon and returns the result */
+func (d daemonClient) send(line string) daemonResult {
+	d.t.Helper()
+	if _, err := fmt.Fprintf(d.c, "%s\n", line); nil != err {
+		d.t.Fatalf("Sending job: %v", err)
+	}
+	var res daemonResult
+	if err := d.dec.Decode(&res); nil != err {
+		d.t.Fatalf("Reading result: %v", err)
+	}
+	return res
+}
+
+/* run sends j to the daemon and returns the result */
+func (d daemonClient) run(j daemonJob) daemonResult {
+	d.t.Helper()
+	b, err := json.Marshal(j)
+	if nil != err {
+		d.t.Fatalf("Marshalling job: %v", err)
+	}
+	return d.send(string(b))
+}
+
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	one := writeTestFile(t, dir, "one.csv", "a,1\nb,2\nc,3\n")
+	two := writeTestFile(t, dir, "two.csv", "d,4\ne,5\n")
+	sock := startDaemon(t, "-workers", "2")
+	d := dialDaemon(t, sock)
+
+	/* Several jobs on one connection come back in order */
+	for _, c := range []struct {
+		name string
+		job  daemonJob
+		want daemonResult
+	}{{
+		name: "cols",
+		job:  daemonJob{Files: []string{one}, Cols: "1"},
+		want: daemonResult{Rows: 3, Output: "a\nb\nc\n"},
+	}, {
+		name: "rows_files",
+		job: daemonJob{
+			Files:   []string{one, two},
+			Rows:    "2-",
+			NotRows: "4",
+		},
+		want: daemonResult{Rows: 3, Output: "b,2\nc,3\ne,5\n"},
+	}, {
+		name: "where_ordered",
+		job: daemonJob{
+			Files:   []string{two, one},
+			Cols:    "2,1",
+			Where:   []string{"c2 >= 3"},
+			Ordered: true,
+		},
+		want: daemonResult{Rows: 3, Output: "4,d\n5,e\n3,c\n"},
+	}, {
+		name: "not_cols",
+		job:  daemonJob{Files: []string{two}, NotCols: "1"},
+		want: daemonResult{Rows: 2, Output: "4\n5\n"},
+	}} {
+		if got := d.run(c.job); c.want != got {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+
+	/* Output to a file */
+	out := filepath.Join(dir, "out.csv")
+	res := d.run(daemonJob{Files: []string{one}, Rows: "3", Output: out})
+	if want := (daemonResult{Rows: 1}); want != res {
+		t.Errorf("Output to file: got %+v, want %+v", res, want)
+	}
+	if b, err := os.ReadFile(out); nil != err {
+		t.Errorf("Reading output: %v", err)
+	} else if want := "c,3\n"; want != string(b) {
+		t.Errorf("Output file: got %q, want %q", b, want)
+	}
+
+	/* Failed jobs don't kill the connection */
+	for _, j := range []daemonJob{
+		{},
+		{Files: []string{filepath.Join(dir, "missing")}},
+		{Files: []string{one}, Rows: "x"},
+		{Files: []string{one}, Where: []string{"c1 =="}},
+		{Files: []string{one}, Output: filepath.Join(dir, "no", "x")},
+	} {
+		if res := d.run(j); "" == res.Error {
+			t.Errorf("Job %+v didn't fail: %+v", j, res)
+		}
+	}
+	if res := d.run(daemonJob{Files: []string{two}}); 2 != res.Rows {
+		t.Errorf("Job after failures: %+v", res)
+	}
+
+	/* Invalid JSON gets an error and the connection closed */
+	if res := d.send(`{"files":"x"}`); "" == res.Error {
+		t.Errorf("Invalid JSON didn't fail: %+v", res)
+	}
+}
+
+func TestDaemonClients(t *testing.T) {
+	dir := t.TempDir()
+	f := writeTestFile(t, dir, "in.csv", "a,1\nb,2\nc,3\n")
+	sock := startDaemon(t, "-workers", "2")
+
+	var wg sync.WaitGroup
+	for i, want := range []string{"a,1\n", "b,2\n", "c,3\n"} {
+		i := i + 1
+		want := want
+		d := dialDaemon(t, sock)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				b, _ := json.Marshal(daemonJob{
+					Files: []string{f},
+					Rows:  fmt.Sprint(i),
+				})
+				fmt.Fprintf(d.c, "%s\n", b)
+				var res daemonResult
+				if err := d.dec.Decode(&res); nil != err {
+					t.Errorf("Client %d: %v", i, err)
+					return
+				}
+				if 1 != res.Rows || want != res.Output {
+					t.Errorf("Client %d: got %+v", i, res)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,140 @@
+/*
+ * decompress.go
+ * Transparently decompress input
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+/* Magic bytes at the start of compressed files */
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+/* errReader returns an error for every read */
+type errReader struct{ err error }
+
+/* Read returns r.err */
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+/* decompress returns a reader which reads r, decompressed if it starts with
+the magic bytes of a gzip, bzip2, or zstd stream.  Zstd streams are
+decompressed with the zstd program, which must be in the PATH.  Errors
+starting decompression are returned by the first read. */
+func decompress(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		debug("Decompressing gzip")
+		zr, err := gzip.NewReader(br)
+		if nil != err {
+			return errReader{fmt.Errorf("gzip: %w", err)}
+		}
+		return zr
+	case bytes.HasPrefix(magic, bzip2Magic):
+		debug("Decompressing bzip2")
+		return bzip2.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		debug("Decompressing zstd")
+		zr, err := newZstdReader(br)
+		if nil != err {
+			return errReader{fmt.Errorf("zstd: %w", err)}
+		}
+		return zr
+	}
+	return br
+}
+
+/* checkZstd makes sure the zstd program is in the PATH if any of the files
+named in files end in .zst, so we don't find out after reading the files
+before them.  If zstd is needed but not found, the program exits. */
+func checkZstd(files []string) {
+	for _, f := range files {
+		if !strings.HasSuffix(f, ".zst") {
+			continue
+		}
+		if _, err := exec.LookPath("zstd"); nil != err {
+			inform("Reading %v needs the zstd program: %v", f, err)
+			exit(-53)
+		}
+		return
+	}
+}
+
+/* zstdReader reads the output of zstd -dc */
+type zstdReader struct {
+	cmd    *exec.Cmd
+	out    io.Reader
+	stderr strings.Builder
+	err    error /* Set once zstd's finished */
+}
+
+/* newZstdReader starts zstd to decompress r */
+func newZstdReader(r io.Reader) (*zstdReader, error) {
+	if sandboxed {
+		return nil, errors.New(
+			"zstd can't be run in the sandbox, see -sandbox",
+		)
+	}
+	z := &zstdReader{cmd: exec.Command("zstd", "-dc")}
+	z.cmd.Stdin = r
+	z.cmd.Stderr = &z.stderr
+	var err error
+	if z.out, err = z.cmd.StdoutPipe(); nil != err {
+		return nil, err
+	}
+	if err := z.cmd.Start(); nil != err {
+		return nil, err
+	}
+	return z, nil
+}
+
+/* Read reads decompressed data.  When zstd's output has been read, Read
+returns io.EOF if zstd exited happily or an error if not. */
+func (z *zstdReader) Read(p []byte) (int, error) {
+	if nil != z.err {
+		return 0, z.err
+	}
+	n, err := z.out.Read(p)
+	if !errors.Is(err, io.EOF) {
+		return n, err
+	}
+	/* Make sure it all went well */
+	z.err = io.EOF
+	if err := z.cmd.Wait(); nil != err {
+		z.err = fmt.Errorf("zstd: %w (%v)", err,
+			strings.TrimSpace(z.stderr.String()))
+	}
+	return n, z.err
+}
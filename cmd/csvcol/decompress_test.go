@@ -0,0 +1,120 @@
+/*
+ * decompress_test.go
+ * Tests for decompress.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/* decompressTestCSV is compressed for the decompression tests */
+const decompressTestCSV = "a,b,c\nd,e,f\n"
+
+/* compressWith compresses decompressTestCSV with the named program, or
+skips the test if it's not available. */
+func compressWith(t *testing.T, prog string) []byte {
+	t.Helper()
+	if _, err := exec.LookPath(prog); nil != err {
+		t.Skipf("No %s: %v", prog, err)
+	}
+	cmd := exec.Command(prog, "-c")
+	cmd.Stdin = strings.NewReader(decompressTestCSV)
+	b, err := cmd.Output()
+	if nil != err {
+		t.Fatalf("Compressing with %s: %v", prog, err)
+	}
+	return b
+}
+
+func TestDecompress(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(decompressTestCSV))
+	zw.Close()
+	for _, c := range []struct {
+		name string
+		data func(t *testing.T) []byte
+	}{
+		{"plain", func(*testing.T) []byte {
+			return []byte(decompressTestCSV)
+		}},
+		{"gz", func(*testing.T) []byte { return gz.Bytes() }},
+		{"bz2", func(t *testing.T) []byte {
+			return compressWith(t, "bzip2")
+		}},
+		{"zst", func(t *testing.T) []byte {
+			return compressWith(t, "zstd")
+		}},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fn := writeTestFile(t, dir, "in."+c.name,
+				string(c.data(t)))
+			/* From a file and from the standard input */
+			got := mustRun(t, "", "-cols", "3,1", "-ordered", fn)
+			if want := "c,a\nf,d\n"; want != got {
+				t.Errorf("From file got %q, want %q",
+					got, want)
+			}
+			got = mustRun(t, string(c.data(t)), "-rows", "2")
+			if want := "d,e,f\n"; want != got {
+				t.Errorf("From stdin got %q, want %q",
+					got, want)
+			}
+		})
+	}
+}
+
+func TestZstdMissing(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	first := writeTestFile(t, dir, "first.csv", decompressTestCSV)
+	zst := writeTestFile(t, dir, "second.csv.zst", "not really zstd")
+	res := runCSVColIn(
+		t,
+		dir,
+		"",
+		"-rowfile", filepath.Join(dir, "nonexistent"),
+		first,
+		zst,
+	)
+	if 0 == res.code {
+		t.Fatalf("No error without zstd")
+	}
+	if !strings.Contains(res.stderr, "needs the zstd program") {
+		t.Errorf("Unexpected error: %s", res.stderr)
+	}
+	if "" != res.stdout {
+		t.Errorf("Input read before checking for zstd: %q",
+			res.stdout)
+	}
+	if strings.Contains(res.stderr, "nonexistent") {
+		t.Errorf("Rowfile read before checking for zstd: %s",
+			res.stderr)
+	}
+}
@@ -0,0 +1,310 @@
+/*
+ * dedup.go
+ * Removing duplicate rows
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* dedupWindowStage is a stage which drops rows which are the same as one of
+the n rows before them, whether or not those were dropped.  Only the last n
+rows are remembered, so memory use is bounded however much input there is.
+The header is passed on without being remembered. */
+type dedupWindowStage struct {
+	window []string       /* Last n rows, as a ring */
+	next   int            /* Next slot in window */
+	seen   map[string]int /* Number of times each row is in window */
+}
+
+/* newDedupWindowStage returns a dedupWindowStage which remembers n rows */
+func newDedupWindowStage(n int) *dedupWindowStage {
+	return &dedupWindowStage{
+		window: make([]string, 0, n),
+		seen:   make(map[string]int),
+	}
+}
+
+/* push passes on r if it's not in the window, and adds it to the window */
+func (d *dedupWindowStage) push(r stageRow, next func(stageRow)) {
+	if r.header {
+		next(r)
+		return
+	}
+	k := strings.Join(r.out, "\x00")
+	dup := 0 != d.seen[k]
+
+	/* Remember this one, forgetting the oldest if the window's full */
+	if len(d.window) < cap(d.window) {
+		d.window = append(d.window, k)
+	} else {
+		old := d.window[d.next]
+		if d.seen[old]--; 0 == d.seen[old] {
+			delete(d.seen, old)
+		}
+		d.window[d.next] = k
+		d.next = (d.next + 1) % len(d.window)
+	}
+	d.seen[k]++
+
+	if dup {
+		debug("Dropping duplicate row %v", r.row)
+		return
+	}
+	next(r)
+}
+
+/* flush does nothing, as rows aren't held */
+func (d *dedupWindowStage) flush(func(stageRow)) {}
+
+/* dedupStage is a stage which drops rows which are the same as any row before
+them, or which have the same values in the key columns, if there are any.
+The header is passed on without being remembered. */
+type dedupStage struct {
+	key  []int /* 1-indexed output columns, or all if empty */
+	seen *hashSet
+}
+
+/* parseDedupKey parses a comma-separated list of columns, each col:N, cN,
+or just N. */
+func parseDedupKey(s string) ([]int, error) {
+	ps := strings.Split(s, ",")
+	for i, p := range ps {
+		p = strings.TrimSpace(p)
+		if "" != p && "" == strings.Trim(p, "0123456789") {
+			ps[i] = "c" + p
+		}
+	}
+	return csvcol.ParseGroupKey(strings.Join(ps, ","))
+}
+
+/* push passes on r if its key hasn't been seen before */
+func (d *dedupStage) push(r stageRow, next func(stageRow)) {
+	if r.header {
+		next(r)
+		return
+	}
+	var k string
+	if 0 == len(d.key) {
+		k = strings.Join(r.out, "\x00")
+	} else {
+		k = csvcol.GroupKey(d.key, r.out)
+	}
+	if d.seen.add(k) {
+		debug("Dropping duplicate row %v", r.row)
+		return
+	}
+	next(r)
+}
+
+/* flush removes any temporary files, as rows aren't held */
+func (d *dedupStage) flush(func(stageRow)) { d.seen.close() }
+
+/* hashSize is the number of bytes of a key's hash a hashSet keeps.  128 bits
+makes collisions vanishingly unlikely. */
+const hashSize = 16
+
+/* hashEntrySize is roughly how much memory a hash takes in a hashSet's map */
+const hashEntrySize = 48
+
+/* hashSetMaxRuns is how many files a hashSet spills to before they're merged
+into one */
+const hashSetMaxRuns = 8
+
+/* hashSet is a set of keys' hashes.  If maxMem isn't 0 and the hashes in
+memory would take more than about maxMem bytes, they're sorted and spilled
+to a temporary file, which is binary-searched when checking for keys. */
+type hashSet struct {
+	mem    map[[hashSize]byte]struct{}
+	maxMem int64
+	runs   []*os.File /* Sorted hashes */
+}
+
+/* newHashSet returns a new hashSet which uses about maxMem bytes, or as much
+as it needs if maxMem is 0. */
+func newHashSet(maxMem int64) *hashSet {
+	return &hashSet{
+		mem:    make(map[[hashSize]byte]struct{}),
+		maxMem: maxMem,
+	}
+}
+
+/* add adds k to the set.  It returns true if k was already in the set. */
+func (s *hashSet) add(k string) bool {
+	sum := sha256.Sum256([]byte(k))
+	var h [hashSize]byte
+	copy(h[:], sum[:])
+	if _, ok := s.mem[h]; ok {
+		return true
+	}
+	for _, f := range s.runs {
+		if s.inRun(f, h) {
+			return true
+		}
+	}
+	s.mem[h] = struct{}{}
+	if 0 != s.maxMem && s.maxMem < int64(len(s.mem)*hashEntrySize) {
+		s.spill()
+	}
+	return false
+}
+
+/* inRun returns true if h is in the sorted hashes in f */
+func (s *hashSet) inRun(f *os.File, h [hashSize]byte) bool {
+	fi, err := f.Stat()
+	if nil != err {
+		inform("Unable to get size of %v: %v", f.Name(), err)
+		exit(-45)
+	}
+	var (
+		b    [hashSize]byte
+		rerr error
+	)
+	n := int(fi.Size() / hashSize)
+	i := sort.Search(n, func(i int) bool {
+		if _, err := f.ReadAt(b[:], int64(i)*hashSize); nil != err {
+			rerr = err
+			return true
+		}
+		return 0 <= bytes.Compare(b[:], h[:])
+	})
+	if nil != rerr {
+		inform("Unable to read hashes from %v: %v", f.Name(), rerr)
+		exit(-45)
+	}
+	if i == n {
+		return false
+	}
+	if _, err := f.ReadAt(b[:], int64(i)*hashSize); nil != err {
+		inform("Unable to read hashes from %v: %v", f.Name(), err)
+		exit(-45)
+	}
+	return b == h
+}
+
+/* spill writes the hashes in memory to a temporary file, sorted, merging
+all of the files into one if there are too many */
+func (s *hashSet) spill() {
+	hs := make([][hashSize]byte, 0, len(s.mem))
+	for h := range s.mem {
+		hs = append(hs, h)
+	}
+	sort.Slice(hs, func(i, j int) bool {
+		return 0 > bytes.Compare(hs[i][:], hs[j][:])
+	})
+	srcs := []io.Reader{hashesReader(hs)}
+	if hashSetMaxRuns <= len(s.runs) {
+		for _, f := range s.runs {
+			if _, err := f.Seek(0, io.SeekStart); nil != err {
+				inform("Unable to rewind %v: %v", f.Name(), err)
+				exit(-45)
+			}
+			srcs = append(srcs, bufio.NewReader(f))
+		}
+	}
+	f, err := os.CreateTemp("", "csvcol-dedup-*")
+	if nil != err {
+		inform("Unable to make temporary file for -dedup: %v", err)
+		exit(-45)
+	}
+	debug("Spilling %d hashes to %v", len(hs), f.Name())
+	bw := bufio.NewWriter(f)
+	if err := mergeHashes(bw, srcs); nil != err {
+		inform("Unable to spill hashes to %v: %v", f.Name(), err)
+		exit(-45)
+	}
+	if err := bw.Flush(); nil != err {
+		inform("Unable to spill hashes to %v: %v", f.Name(), err)
+		exit(-45)
+	}
+	if 1 != len(srcs) {
+		s.close()
+	}
+	s.runs = append(s.runs, f)
+	s.mem = make(map[[hashSize]byte]struct{})
+}
+
+/* hashesReader returns a reader which reads the hashes in hs */
+func hashesReader(hs [][hashSize]byte) io.Reader {
+	b := make([]byte, 0, len(hs)*hashSize)
+	for _, h := range hs {
+		b = append(b, h[:]...)
+	}
+	return bytes.NewReader(b)
+}
+
+/* mergeHashes merges sorted streams of hashes, none of which are in more
+than one stream, into w */
+func mergeHashes(w io.Writer, srcs []io.Reader) error {
+	heads := make([][]byte, len(srcs))
+	next := func(i int) error {
+		b := make([]byte, hashSize)
+		if _, err := io.ReadFull(srcs[i], b); io.EOF == err {
+			b = nil
+		} else if nil != err {
+			return err
+		}
+		heads[i] = b
+		return nil
+	}
+	for i := range srcs {
+		if err := next(i); nil != err {
+			return err
+		}
+	}
+	for {
+		/* Find the lowest hash */
+		low := -1
+		for i, h := range heads {
+			if nil != h && (-1 == low ||
+				0 > bytes.Compare(h, heads[low])) {
+				low = i
+			}
+		}
+		if -1 == low {
+			return nil
+		}
+		if _, err := w.Write(heads[low]); nil != err {
+			return err
+		}
+		if err := next(low); nil != err {
+			return err
+		}
+	}
+}
+
+/* close closes and removes the temporary files */
+func (s *hashSet) close() {
+	for _, f := range s.runs {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	s.runs = nil
+}
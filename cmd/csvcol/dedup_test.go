@@ -0,0 +1,235 @@
+/*
+ * dedup_test.go
+ * Tests for dedup.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDedupWindowStage(t *testing.T) {
+	old := gc.debug
+	defer func() { gc.debug = old }()
+	gc.debug = new(bool)
+	for _, c := range []struct {
+		n    int
+		in   string
+		want string
+	}{
+		{n: 1, in: "aabba", want: "aba"},
+		{n: 2, in: "abab", want: "ab"},
+		{n: 2, in: "abca", want: "abca"},
+		{n: 2, in: "abcbca", want: "abca"},
+		{n: 3, in: "abcabcdxd", want: "abcdx"},
+		{n: 2, in: "aaaaa", want: "a"},
+		{n: 2, in: "abaacaa", want: "abc"},
+	} {
+		d := newDedupWindowStage(c.n)
+		var got []string
+		for _, r := range strings.Split(c.in, "") {
+			d.push(stageRow{out: []string{r}}, func(r stageRow) {
+				got = append(got, r.out[0])
+			})
+		}
+		if g := strings.Join(got, ""); g != c.want {
+			t.Errorf(
+				"%d, %q: got %q, want %q",
+				c.n,
+				c.in,
+				g,
+				c.want,
+			)
+		}
+		if c.n < len(d.window) || c.n < len(d.seen) {
+			t.Errorf(
+				"%d, %q: remembered %d rows (%d distinct)",
+				c.n,
+				c.in,
+				len(d.window),
+				len(d.seen),
+			)
+		}
+	}
+
+	/* Headers aren't remembered, and rows are compared whole */
+	d := newDedupWindowStage(5)
+	var got [][]string
+	for _, r := range []stageRow{
+		{out: []string{"h"}, header: true},
+		{out: []string{"h"}},
+		{out: []string{"a", "b"}},
+		{out: []string{"a,b"}},
+		{out: []string{"ab"}},
+		{out: []string{"a", "b"}},
+	} {
+		d.push(r, func(r stageRow) { got = append(got, r.out) })
+	}
+	want := [][]string{{"h"}, {"h"}, {"a", "b"}, {"a,b"}, {"ab"}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	in := "k,v\na,1\na,2\nb,1\na,1\nc,1\nb,1\na,1\n"
+	runOutputTests(t, []outputTest{{
+		name:  "window",
+		stdin: in,
+		args:  []string{"-header", "-dedup-window", "3"},
+		want:  "k,v\na,1\na,2\nb,1\nc,1\n",
+	}, {
+		name:  "selected_columns",
+		stdin: in,
+		args:  []string{"-header", "-cols", "1", "-dedup-window", "2"},
+		want:  "k\na\nb\nc\nb\na\n",
+	}, {
+		name:  "where_first",
+		stdin: in,
+		args: []string{
+			"-where", "c1 != b",
+			"-cols", "1",
+			"-dedup-window", "1",
+		},
+		want: "k\na\nc\na\n",
+	}})
+	res := runCSVCol(t, in, "-dedup-window", "-1")
+	if -38 != int8(res.code) {
+		t.Errorf("-dedup-window -1 exit code %d", int8(res.code))
+	}
+}
+
+func TestParseDedupKey(t *testing.T) {
+	for _, c := range []struct {
+		have string
+		want []int
+		err  bool
+	}{
+		{have: "1", want: []int{1}},
+		{have: "1,4", want: []int{1, 4}},
+		{have: "c2, col:3 ,4", want: []int{2, 3, 4}},
+		{have: "", err: true},
+		{have: "x", err: true},
+		{have: "1,", err: true},
+	} {
+		got, err := parseDedupKey(c.have)
+		if c.err {
+			if nil == err {
+				t.Errorf(
+					"%q: expected error, got %d",
+					c.have,
+					got,
+				)
+			}
+			continue
+		}
+		if nil != err {
+			t.Errorf("%q: error: %v", c.have, err)
+		} else if !slices.Equal(got, c.want) {
+			t.Errorf("%q: got %d, want %d", c.have, got, c.want)
+		}
+	}
+}
+
+func TestHashSet(t *testing.T) {
+	old := gc.debug
+	defer func() { gc.debug = old }()
+	gc.debug = new(bool)
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+
+	/* Small enough to spill often, and to merge spilled hashes */
+	const n = 1000
+	for _, maxMem := range []int64{0, 10 * hashEntrySize} {
+		s := newHashSet(maxMem)
+		for i := 0; i < n; i++ {
+			if s.add(strconv.Itoa(i)) {
+				t.Errorf("%d: %d added twice", maxMem, i)
+			}
+		}
+		if 0 != maxMem && 0 == len(s.runs) {
+			t.Errorf("%d: hashes not spilled", maxMem)
+		}
+		if hashSetMaxRuns < len(s.runs) {
+			t.Errorf("%d: %d spilled files", maxMem, len(s.runs))
+		}
+		for i := n - 1; 0 <= i; i-- {
+			if !s.add(strconv.Itoa(i)) {
+				t.Errorf("%d: %d not remembered", maxMem, i)
+			}
+		}
+		if s.add("new") || !s.add("new") {
+			t.Errorf("%d: new key incorrect", maxMem)
+		}
+		s.close()
+	}
+	des, err := os.ReadDir(tmp)
+	if nil != err {
+		t.Fatalf("Reading %s: %v", tmp, err)
+	}
+	for _, de := range des {
+		t.Errorf("Temporary file not removed: %s", de.Name())
+	}
+}
+
+func TestDedup(t *testing.T) {
+	in := "k,v,w\na,1,x\nb,1,x\na,1,x\na,2,x\nb,1,y\na,1,x\n"
+	runOutputTests(t, []outputTest{{
+		name:  "whole_rows",
+		stdin: in,
+		args:  []string{"-header", "-dedup"},
+		want:  "k,v,w\na,1,x\nb,1,x\na,2,x\nb,1,y\n",
+	}, {
+		name:  "key",
+		stdin: in,
+		args:  []string{"-header", "-dedup-key", "1,2"},
+		want:  "k,v,w\na,1,x\nb,1,x\na,2,x\n",
+	}, {
+		name:  "key_output_columns",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "2,3",
+			"-ordered",
+			"-dedup-key", "c2",
+		},
+		want: "v,w\n1,x\n1,y\n",
+	}, {
+		name:  "selected_columns",
+		stdin: in,
+		args:  []string{"-header", "-cols", "1", "-dedup"},
+		want:  "k\na\nb\n",
+	}, {
+		name:  "max_mem",
+		stdin: in,
+		args:  []string{"-header", "-dedup", "-max-mem", "1"},
+		want:  "k,v,w\na,1,x\nb,1,x\na,2,x\nb,1,y\n",
+	}})
+	res := runCSVCol(t, in, "-dedup-key", "x")
+	if -45 != int8(res.code) {
+		t.Errorf("Invalid -dedup-key exit code %d", int8(res.code))
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * deterministic.go
+ * Make sure output is deterministic
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+/* nondeterminism returns the reasons, if any, that the output of this
+invocation might differ from run to run given the same input, for
+-deterministic.  It should be called once the command line has been
+checked and limits have been set. */
+func nondeterminism() []string {
+	var rs []string
+	for _, c := range []struct {
+		is     bool
+		reason string
+	}{{
+		0 != *gc.sample && 0 == *gc.seed,
+		"-sample is random without -seed",
+	}, {
+		0 != *gc.sampleN && 0 == *gc.seed,
+		"-sample-n is random without -seed",
+	}, {
+		*gc.shuffle && 0 == *gc.seed,
+		"-shuffle is random without -seed",
+	}, {
+		0 != len(gc.tokenize),
+		"-tokenize makes random tokens",
+	}, {
+		0 < *gc.maxTime,
+		"-max-time (set by -untrusted unless given) may stop " +
+			"output at any point",
+	}, {
+		0 < *gc.idleTimeout,
+		"-idle-timeout may stop output at any point",
+	}, {
+		0 != *gc.estimate,
+		"-estimate depends on how quickly input is read",
+	}} {
+		if c.is {
+			rs = append(rs, c.reason)
+		}
+	}
+	return rs
+}
@@ -0,0 +1,107 @@
+/*
+ * deterministic_test.go
+ * Tests for deterministic.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDeterministic(t *testing.T) {
+	in := "a,1\nb,2\nc,3\n"
+	for _, c := range []struct {
+		args []string
+		want string /* In standard error, or empty for success */
+	}{
+		{args: nil},
+		{args: []string{"-sample", "0.5", "-seed", "1"}},
+		{args: []string{"-sample-n", "2", "-seed", "1"}},
+		{args: []string{"-shuffle", "-seed", "1"}},
+		{args: []string{"-untrusted", "-max-time", "0"}},
+		{args: []string{"-stage", "sort=c2"}},
+		{args: []string{"-freq", "1"}},
+		{
+			args: []string{"-sample", "0.5"},
+			want: "-sample is random without -seed",
+		},
+		{
+			args: []string{"-sample-n", "2"},
+			want: "-sample-n is random without -seed",
+		},
+		{
+			args: []string{"-shuffle"},
+			want: "-shuffle is random without -seed",
+		},
+		{
+			args: []string{"-tokenize", "c1"},
+			want: "-tokenize makes random tokens",
+		},
+		{
+			args: []string{"-max-time", "1m"},
+			want: "-max-time",
+		},
+		{
+			args: []string{"-untrusted"},
+			want: "-max-time (set by -untrusted unless given)",
+		},
+		{
+			args: []string{"-idle-timeout", "1m"},
+			want: "-idle-timeout may stop output",
+		},
+		{
+			args: []string{"-estimate", "1"},
+			want: "-estimate depends on",
+		},
+	} {
+		args := append([]string{"-deterministic"}, c.args...)
+		res := runCSVCol(t, in, args...)
+		if "" == c.want {
+			if 0 != res.code {
+				t.Errorf(
+					"%q: exit code %d: %s",
+					args,
+					int8(res.code),
+					res.stderr,
+				)
+			}
+			continue
+		}
+		if -51 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+		if !strings.Contains(res.stderr, c.want) {
+			t.Errorf(
+				"%q: standard error missing %q:\n%s",
+				args,
+				c.want,
+				res.stderr,
+			)
+		}
+	}
+
+	/* Every reason should be given */
+	res := runCSVCol(t, in, "-deterministic", "-shuffle", "-sample", "0.5")
+	if 2 != strings.Count(res.stderr, "may not be deterministic") {
+		t.Errorf("Not every reason given:\n%s", res.stderr)
+	}
+}
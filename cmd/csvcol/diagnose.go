@@ -0,0 +1,244 @@
+/*
+ * diagnose.go
+ * Reporting on the structure of input files
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+/* diagnosis holds what we've found out about a file */
+type diagnosis struct {
+	bom          string
+	bytes        int64
+	lines        int
+	lf           int
+	crlf         int
+	cr           int
+	sawCR        bool /* Last byte was a \r */
+	lineLen      int  /* Bytes in the current line */
+	longest      int
+	longestLine  int
+	controls     int
+	firstControl string /* Where and what */
+	invalid      int    /* Invalid UTF-8 sequences */
+	firstInvalid int    /* Line */
+}
+
+/* boms are the byte order marks we know about */
+var boms = []struct {
+	name string
+	b    []byte
+}{
+	{"UTF-8", []byte{0xef, 0xbb, 0xbf}},
+	{"UTF-16LE", []byte{0xff, 0xfe}},
+	{"UTF-16BE", []byte{0xfe, 0xff}},
+}
+
+/* diagnose prints the line endings, byte order mark, control characters,
+invalid UTF-8, and longest line of each of the files, for working out how to
+read a file about which nothing is known. */
+func diagnose(files []string) error {
+	for i, f := range files {
+		fp, fname := openInput(f)
+		verbose("Diagnosing %v", fname)
+		d, err := diagnoseReader(decompress(fp))
+		if os.Stdin != fp {
+			fp.Close()
+		}
+		if nil != err {
+			return fmt.Errorf("reading %v: %w", fname, err)
+		}
+		if 0 != i {
+			fmt.Fprintf(stdout, "\n")
+		}
+		if err := writeAligned(
+			stdout,
+			d.report(fname),
+			false,
+		); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+/* diagnoseReader reads r and works out what it can about it */
+func diagnoseReader(r io.Reader) (*diagnosis, error) {
+	d := &diagnosis{bom: "none"}
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	/* Byte order mark */
+	start, _ := br.Peek(3)
+	for _, b := range boms {
+		if bytes.HasPrefix(start, b.b) {
+			d.bom = b.name
+			br.Discard(len(b.b))
+			d.bytes += int64(len(b.b))
+			break
+		}
+	}
+
+	/* Everything else */
+	for {
+		b, err := br.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if nil != err {
+			return nil, err
+		}
+		d.bytes++
+		if d.sawCR {
+			d.sawCR = false
+			if '\n' == b {
+				d.crlf++
+				d.endLine()
+				continue
+			}
+			d.cr++
+			d.endLine()
+		}
+		switch {
+		case '\r' == b:
+			d.sawCR = true
+		case '\n' == b:
+			d.lf++
+			d.endLine()
+		case utf8.RuneSelf > b:
+			d.lineLen++
+			if ('\t' != b && ' ' > b) || 0x7f == b {
+				d.control(rune(b))
+			}
+		default:
+			d.multibyte(br, b)
+		}
+	}
+	if d.sawCR {
+		d.cr++
+		d.endLine()
+	}
+	if 0 != d.lineLen {
+		d.endLine()
+	}
+	return d, nil
+}
+
+/* multibyte handles a UTF-8 sequence starting with b, the rest of which is
+to be read from br. */
+func (d *diagnosis) multibyte(br *bufio.Reader, b byte) {
+	seq := []byte{b}
+	for !utf8.FullRune(seq) {
+		n, _ := br.Peek(1) /* Errors will come from the next read */
+		if 0 == len(n) || utf8.RuneStart(n[0]) {
+			break
+		}
+		br.Discard(1)
+		seq = append(seq, n[0])
+	}
+	d.bytes += int64(len(seq) - 1)
+	d.lineLen += len(seq)
+	r, size := utf8.DecodeRune(seq)
+	switch {
+	case utf8.RuneError == r && 1 == size:
+		d.invalid++
+		if 1 == d.invalid {
+			d.firstInvalid = d.lines + 1
+		}
+	case 0x80 <= r && 0x9f >= r: /* C1 controls */
+		d.control(r)
+	}
+}
+
+/* control notes a control character */
+func (d *diagnosis) control(r rune) {
+	d.controls++
+	if 1 == d.controls {
+		d.firstControl = fmt.Sprintf("line %v: %U", d.lines+1, r)
+	}
+}
+
+/* endLine notes the end of a line */
+func (d *diagnosis) endLine() {
+	d.lines++
+	if d.lineLen > d.longest {
+		d.longest = d.lineLen
+		d.longestLine = d.lines
+	}
+	d.lineLen = 0
+}
+
+/* report returns what we know about the file named name, as rows of
+property and value. */
+func (d *diagnosis) report(name string) [][]string {
+	/* Line endings */
+	var les []string
+	for _, le := range []struct {
+		name string
+		n    int
+	}{{"LF", d.lf}, {"CRLF", d.crlf}, {"CR", d.cr}} {
+		if 0 != le.n {
+			les = append(les, fmt.Sprintf("%s (%d)", le.name, le.n))
+		}
+	}
+	endings := "none"
+	switch len(les) {
+	case 0:
+	case 1:
+		endings = les[0]
+	default:
+		endings = "mixed: " + strings.Join(les, ", ")
+	}
+	final := "no"
+	if 0 == d.lines || d.lf+d.crlf+d.cr == d.lines {
+		final = "yes"
+	}
+
+	controls := "none"
+	if 0 != d.controls {
+		controls = fmt.Sprintf("%d (first on %s)", d.controls,
+			d.firstControl)
+	}
+	invalid := "none"
+	if 0 != d.invalid {
+		invalid = fmt.Sprintf("%d (first on line %d)", d.invalid,
+			d.firstInvalid)
+	}
+	return [][]string{
+		{"file", name},
+		{"bytes", fmt.Sprint(d.bytes)},
+		{"lines", fmt.Sprint(d.lines)},
+		{"line endings", endings},
+		{"final newline", final},
+		{"byte order mark", d.bom},
+		{"control characters", controls},
+		{"invalid UTF-8", invalid},
+		{"longest line", fmt.Sprintf("%d bytes (line %d)", d.longest,
+			d.longestLine)},
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * diagnose_test.go
+ * Tests for diagnose.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		writeTestFile(t, dir, "crlf.csv", "a,b\r\nc,d\r\n"),
+		writeTestFile(
+			t,
+			dir,
+			"ctrl.csv",
+			"\xef\xbb\xbfa,b\n\x01c\xff\n",
+		),
+	}
+	got := mustRun(t, "", append([]string{"-diagnose"}, files...)...)
+	reports := strings.Split(got, "\n\n")
+	if 2 != len(reports) {
+		t.Fatalf("Got %d reports, want 2:\n%s", len(reports), got)
+	}
+	for i, want := range [][]string{{
+		"line endings        CRLF (2)",
+		"byte order mark     none",
+		"control characters  none",
+	}, {
+		"line endings        LF (2)",
+		"byte order mark     UTF-8",
+		"control characters  1 (first on line 2: U+0001)",
+		"invalid UTF-8       1 (first on line 2)",
+	}} {
+		for _, w := range want {
+			if !strings.Contains(reports[i], w) {
+				t.Errorf("Report %d missing %q:\n%s",
+					i+1, w, reports[i])
+			}
+		}
+	}
+}
+
+func TestDiagnoseStdout(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		writeTestFile(t, dir, "one.csv", "a\n"),
+		writeTestFile(t, dir, "two.csv", "b\n"),
+	}
+	/* Everything should go to stdout, which may not be os.Stdout */
+	no := false
+	ov, od, osw := gc.verbose, gc.debug, stdout
+	defer func() { gc.verbose, gc.debug, stdout = ov, od, osw }()
+	var b bytes.Buffer
+	gc.verbose, gc.debug, stdout = &no, &no, &b
+	if err := diagnose(files); nil != err {
+		t.Fatalf("Error: %v", err)
+	}
+	if n := strings.Count(b.String(), "\n\n"); 1 != n {
+		t.Errorf("Got %d blank lines, want 1:\n%s", n, b.String())
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * estimate.go
+ * Estimate the cost of a run from a sample
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* countingWriter counts the bytes written to it and discards them */
+type countingWriter int64
+
+/* Write satisfies io.Writer */
+func (c *countingWriter) Write(b []byte) (int, error) {
+	*c += countingWriter(len(b))
+	return len(b), nil
+}
+
+/* estimate reads up to mb megabytes from each of the files and prints an
+estimate of how many rows and bytes would be output and how long it would
+take to process all of the files.  The selection s is used to process the
+samples. */
+func estimate(files []string, s csvcol.Selector, mb int) {
+	var (
+		totRows  float64 /* Estimated rows read */
+		totOut   float64 /* Estimated rows output */
+		totBytes float64 /* Estimated bytes output */
+		totTime  float64 /* Estimated seconds */
+		unknown  bool    /* Size of some input unknown */
+	)
+	for _, f := range files {
+		fp, fname := openInput(f)
+
+		/* Work out how big the file is, if we can */
+		size := int64(-1)
+		if fi, err := fp.Stat(); nil == err && fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+
+		/* Process the sample, starting after the previous files */
+		if *gc.perfile {
+			s.Reset()
+		} else {
+			s.SetRow(int(totRows))
+		}
+		var cw countingWriter
+		w := newWriter(&cw)
+		r := newReader(io.LimitReader(fp, int64(mb)<<20))
+		nin, nout := 0, 0
+		start := time.Now()
+		for !s.Exhausted() {
+			record, err := r.Read()
+			if nil != err {
+				break
+			}
+			nin++
+			if orec, ok, _ := s.Select(record); ok {
+				nout++
+				w.Write(orec)
+			}
+		}
+		w.Flush()
+		elapsed := time.Since(start)
+		read := r.InputOffset()
+		if os.Stdin != fp {
+			fp.Close()
+		}
+
+		/* Scale up to the whole file, unless we've already read all
+		we would have */
+		scale := 1.0
+		switch {
+		case s.Exhausted():
+		case 0 < read && 0 <= size:
+			scale = float64(size) / float64(read)
+		default:
+			unknown = true
+		}
+		inform("%v: size %v, sampled %v bytes: %.0f rows in, "+
+			"%.0f rows out, %.0f bytes out, %v", fname,
+			sizeString(size), read, float64(nin)*scale,
+			float64(nout)*scale, float64(cw)*scale,
+			scaleDuration(elapsed, scale))
+		totRows += float64(nin) * scale
+		totOut += float64(nout) * scale
+		totBytes += float64(cw) * scale
+		totTime += elapsed.Seconds() * scale
+
+		/* Once no more rows can be selected, csvcol stops reading */
+		if s.Exhausted() && !*gc.perfile && "" == *gc.outputPerFile {
+			break
+		}
+	}
+
+	/* Print the grand total */
+	note := ""
+	if unknown {
+		note = " (some input sizes unknown, estimate is a lower bound)"
+	}
+	inform("Total: %.0f rows in, %.0f rows out, %.0f bytes out, %v%v",
+		totRows, totOut, totBytes,
+		scaleDuration(time.Second, totTime), note)
+}
+
+/* sizeString returns n as a string, or "unknown" if n is negative */
+func sizeString(n int64) string {
+	if 0 > n {
+		return "unknown"
+	}
+	return fmt.Sprintf("%v bytes", n)
+}
+
+/* scaleDuration multiplies d by f, rounded to the nearest millisecond */
+func scaleDuration(d time.Duration, f float64) time.Duration {
+	return time.Duration(float64(d) * f).Round(time.Millisecond)
+}
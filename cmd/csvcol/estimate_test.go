@@ -0,0 +1,122 @@
+/*
+ * estimate_test.go
+ * Tests for estimate.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+/* estimateTotal returns the numbers in the Total line printed by -estimate */
+func estimateTotal(t *testing.T, stderr string) (in, out, bytes float64) {
+	t.Helper()
+	for _, l := range strings.Split(stderr, "\n") {
+		i := strings.Index(l, "Total: ")
+		if -1 == i {
+			continue
+		}
+		if _, err := fmt.Sscanf(
+			l[i:],
+			"Total: %f rows in, %f rows out, %f bytes out",
+			&in, &out, &bytes,
+		); nil != err {
+			t.Fatalf("Parsing %q: %v", l, err)
+		}
+		return in, out, bytes
+	}
+	t.Fatalf("No total in %q", stderr)
+	return 0, 0, 0
+}
+
+func TestEstimate(t *testing.T) {
+	dir := t.TempDir()
+	var sb strings.Builder
+	for i := 0; i < 200000; i++ {
+		fmt.Fprintf(&sb, "%06d,name\n", i) /* 2.4MB */
+	}
+	big := writeTestFile(t, dir, "big.csv", sb.String())
+	small := writeTestFile(t, dir, "small.csv", "a,b\nc,d\ne,f\n")
+
+	for _, c := range []struct {
+		name      string
+		args      []string
+		in, out   float64
+		bytes     float64
+		tolerance float64 /* Fraction */
+	}{
+		{
+			name: "small",
+			args: []string{"-rows", "2-", small},
+			in:   3, out: 2, bytes: 8,
+		}, {
+			name: "two_files",
+			args: []string{"-cols", "1", small, small},
+			in:   6, out: 6, bytes: 12,
+		}, {
+			name: "scaled",
+			args: []string{big},
+			in:   200000, out: 200000, bytes: float64(sb.Len()),
+			tolerance: 0.05,
+		}, {
+			name: "exhausted",
+			args: []string{"-rows", "1-10", big, big},
+			in:   11, out: 10, bytes: 120,
+		}, {
+			name: "perfile",
+			args: []string{"-rows", "1-10", "-perfile", big, big},
+			in:   22, out: 20, bytes: 240,
+		},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			args := append([]string{"-estimate", "1"}, c.args...)
+			res := runCSVCol(t, "", args...)
+			if 0 != res.code {
+				t.Fatalf("Exit status %d: %s",
+					res.code, res.stderr)
+			}
+			if "" != res.stdout {
+				t.Errorf("Unexpected output: %q", res.stdout)
+			}
+			in, out, bytes := estimateTotal(t, res.stderr)
+			for _, v := range []struct {
+				what      string
+				got, want float64
+			}{
+				{"rows in", in, c.in},
+				{"rows out", out, c.out},
+				{"bytes out", bytes, c.bytes},
+			} {
+				d := v.got - v.want
+				if 0 > d {
+					d = -d
+				}
+				if d > v.want*c.tolerance {
+					t.Errorf("Estimated %v %v, want %v",
+						v.what, v.got, v.want)
+				}
+			}
+		})
+	}
+}
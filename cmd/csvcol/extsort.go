@@ -0,0 +1,224 @@
+/*
+ * extsort.go
+ * Disk-backed sorting for the sort stage
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* sizeSuffixes are the multipliers for the suffixes parseSize understands */
+var sizeSuffixes = map[string]int64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+/* parseSize parses a size in bytes, optionally followed by K, M, G, or T, for
+kibibytes and so on, and optionally a B. */
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+	n := len(s)
+	for 0 < n && ('0' > s[n-1] || '9' < s[n-1]) {
+		n--
+	}
+	m, ok := sizeSuffixes[s[n:]]
+	if !ok {
+		return 0, fmt.Errorf("unknown suffix %q", s[n:])
+	}
+	v, err := strconv.ParseInt(s[:n], 10, 64)
+	if nil != err {
+		return 0, err
+	}
+	if 0 > v {
+		return 0, fmt.Errorf("negative size")
+	}
+	return v * m, nil
+}
+
+/* rowSize returns roughly how much memory r takes */
+func rowSize(r stageRow) int64 {
+	n := int64(128 + len(r.file))
+	for _, rec := range [][]string{r.in, r.out} {
+		for _, f := range rec {
+			n += int64(16 + len(f))
+		}
+	}
+	if nil != r.tr {
+		n += int64(128 + len(r.tr.File))
+	}
+	return n
+}
+
+/* spilledRow is a stageRow in a temporary file */
+type spilledRow struct {
+	In    []string
+	Out   []string
+	Row   int
+	File  string
+	Trace *traceRecord
+	Order int
+}
+
+/* sortRun is a temporary file full of sorted rows */
+type sortRun struct {
+	f    *os.File
+	dec  *gob.Decoder
+	head spilledRow /* Next row */
+}
+
+/* next reads the run's next row into head.  It returns false at the end of
+the run. */
+func (r *sortRun) next() bool {
+	r.head = spilledRow{}
+	err := r.dec.Decode(&r.head)
+	if io.EOF == err {
+		return false
+	} else if nil != err {
+		inform("Unable to read sorted rows from %v: %v",
+			r.f.Name(), err)
+		exit(-43)
+	}
+	return true
+}
+
+/* spill sorts the held rows and writes them to a temporary file */
+func (s *sortStage) spill() {
+	sort.Stable(sortRows{s, s.order})
+	f, err := os.CreateTemp("", "csvcol-sort-*")
+	if nil != err {
+		inform("Unable to make temporary file for sorting: %v", err)
+		exit(-43)
+	}
+	debug("Spilling %d rows to %v", len(s.rows), f.Name())
+	s.runs = append(s.runs, &sortRun{f: f})
+	bw := bufio.NewWriter(f)
+	enc := gob.NewEncoder(bw)
+	for i, r := range s.rows {
+		if err := enc.Encode(spilledRow{
+			In:    r.in,
+			Out:   r.out,
+			Row:   r.row,
+			File:  r.file,
+			Trace: r.tr,
+			Order: s.order[i],
+		}); nil != err {
+			inform("Unable to write sorted rows to %v: %v",
+				f.Name(), err)
+			exit(-43)
+		}
+	}
+	if err := bw.Flush(); nil != err {
+		inform("Unable to write sorted rows to %v: %v", f.Name(), err)
+		exit(-43)
+	}
+	s.rows, s.order, s.mem = nil, nil, 0
+}
+
+/* merge spills any held rows and merges the temporary files, passing the
+rows to next in order.  The temporary files are removed. */
+func (s *sortStage) merge(next func(stageRow)) {
+	if 0 != len(s.rows) {
+		s.spill()
+	}
+	defer func() {
+		for _, r := range s.runs {
+			r.f.Close()
+			os.Remove(r.f.Name())
+		}
+		s.runs = nil
+	}()
+
+	/* Start reading each run */
+	h := &runHeap{s: s}
+	for _, r := range s.runs {
+		if _, err := r.f.Seek(0, io.SeekStart); nil != err {
+			inform("Unable to rewind %v: %v", r.f.Name(), err)
+			exit(-43)
+		}
+		r.dec = gob.NewDecoder(bufio.NewReader(r.f))
+		if r.next() {
+			h.runs = append(h.runs, r)
+		}
+	}
+	heap.Init(h)
+
+	/* Pass on the lowest row until we run out */
+	for 0 != h.Len() {
+		r := h.runs[0]
+		next(stageRow{
+			in:   r.head.In,
+			out:  r.head.Out,
+			row:  r.head.Row,
+			file: r.head.File,
+			tr:   r.head.Trace,
+		})
+		if r.next() {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+}
+
+/* runHeap is a heap of sortRuns, ordered by their next rows */
+type runHeap struct {
+	s    *sortStage
+	runs []*sortRun
+}
+
+/* Len returns the number of runs */
+func (h *runHeap) Len() int { return len(h.runs) }
+
+/* Swap swaps two runs */
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+/* Less compares the next rows of two runs, falling back on the order in which
+they arrived, to keep the sort stable */
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.runs[i].head, h.runs[j].head
+	if c := h.s.compare(a.Out, b.Out, a.Order, b.Order); 0 != c {
+		return 0 > c
+	}
+	return a.Order < b.Order
+}
+
+/* Push adds a run */
+func (h *runHeap) Push(x interface{}) { h.runs = append(h.runs, x.(*sortRun)) }
+
+/* Pop removes the last run */
+func (h *runHeap) Pop() interface{} {
+	r := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return r
+}
@@ -0,0 +1,142 @@
+/*
+ * extsort_test.go
+ * Tests for extsort.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for _, c := range []struct {
+		have string
+		want int64
+		err  bool
+	}{
+		{have: "0", want: 0},
+		{have: "100", want: 100},
+		{have: "100B", want: 100},
+		{have: "2K", want: 2 << 10},
+		{have: "2kb", want: 2 << 10},
+		{have: " 512M ", want: 512 << 20},
+		{have: "3G", want: 3 << 30},
+		{have: "1T", want: 1 << 40},
+		{have: "", err: true},
+		{have: "M", err: true},
+		{have: "10X", err: true},
+		{have: "-5", err: true},
+		{have: "1.5G", err: true},
+	} {
+		c := c
+		t.Run(c.have, func(t *testing.T) {
+			got, err := parseSize(c.have)
+			if c.err {
+				if nil == err {
+					t.Errorf("Expected error, got %d", got)
+				}
+				return
+			}
+			if nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("Got %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestExternalSort(t *testing.T) {
+	/* Enough rows with few enough distinct keys that there's several
+	temporary files with ties between them */
+	const n = 2000
+	var (
+		sb   strings.Builder
+		rows [][2]string
+	)
+	sb.WriteString("key,n\n")
+	for i := 0; i < n; i++ {
+		r := [2]string{fmt.Sprintf("k%02d", (i*37)%50), fmt.Sprint(i)}
+		rows = append(rows, r)
+		fmt.Fprintf(&sb, "%s,%s\n", r[0], r[1])
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i][0] < rows[j][0]
+	})
+	var want strings.Builder
+	want.WriteString("key,n\n")
+	for _, r := range rows {
+		fmt.Fprintf(&want, "%s,%s\n", r[0], r[1])
+	}
+
+	/* Temporary files should end up in $TMPDIR (%TMP% on Windows), and
+	be removed */
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+	res := runCSVCol(
+		t,
+		sb.String(),
+		"-header",
+		"-debug",
+		"-max-mem", "8K",
+		"-stage", "sort=c1",
+	)
+	if 0 != res.code {
+		t.Fatalf("Failed (%d): %s", res.code, res.stderr)
+	}
+	if got := res.stdout; got != want.String() {
+		t.Errorf("Sort incorrect")
+	}
+	if sc := strings.Count(res.stderr, "Spilling"); 2 > sc {
+		t.Errorf("Only spilled %d times:\n%s", sc, res.stderr)
+	}
+	if !strings.Contains(res.stderr, tmp) {
+		t.Errorf("Temporary files not in %s:\n%s", tmp, res.stderr)
+	}
+	des, err := os.ReadDir(tmp)
+	if nil != err {
+		t.Fatalf("Reading %s: %v", tmp, err)
+	}
+	for _, de := range des {
+		t.Errorf("Temporary file not removed: %s", de.Name())
+	}
+
+	/* Without -max-mem, it should come out the same */
+	if got := mustRun(
+		t,
+		sb.String(),
+		"-header",
+		"-stage", "sort=c1",
+	); got != want.String() {
+		t.Errorf("In-memory sort incorrect")
+	}
+
+	res = runCSVCol(t, "a\n", "-max-mem", "lots", "-stage", "sort=c1")
+	if -43 != int8(res.code) {
+		t.Errorf("Invalid -max-mem exit code %d", int8(res.code))
+	}
+}
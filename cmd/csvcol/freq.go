@@ -0,0 +1,109 @@
+/*
+ * freq.go
+ * Distinct values and their frequencies
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* headerSummary is a summary which wants to see the input header, if there
+is one */
+type headerSummary interface {
+	summary
+	header(record []string)
+}
+
+/* valueCounts counts the distinct values of one or more input columns, for
+-uniq and -freq */
+type valueCounts struct {
+	cols   []int /* 1-indexed input columns */
+	freq   bool  /* Output counts, most frequent first */
+	names  []string
+	counts map[string]int
+	order  []string /* Values, in the order first seen */
+}
+
+/* newValueCounts returns a valueCounts which counts the values of the
+columns in spec, a comma-separated list of col:N, cN, or N. */
+func newValueCounts(spec string, freq bool) (*valueCounts, error) {
+	cols, err := parseDedupKey(spec)
+	if nil != err {
+		return nil, err
+	}
+	v := &valueCounts{cols: cols, freq: freq, counts: make(map[string]int)}
+	for _, c := range cols {
+		v.names = append(v.names, "c"+strconv.Itoa(c))
+	}
+	return v, nil
+}
+
+/* header names the counted columns after their names in the header */
+func (v *valueCounts) header(record []string) {
+	for i, c := range v.cols {
+		if c <= len(record) {
+			v.names[i] = record[c-1]
+		}
+	}
+}
+
+/* add counts record's value.  Orec and name are ignored. */
+func (v *valueCounts) add(record, orec []string, name func(i int) string) {
+	k := csvcol.GroupKey(v.cols, record)
+	if _, ok := v.counts[k]; !ok {
+		v.order = append(v.order, k)
+	}
+	v.counts[k]++
+}
+
+/* each calls f with a header and then with a record for each distinct value,
+in the order first seen or, if v.freq is true, with its count, most frequent
+first.  Ties are in the order first seen. */
+func (v *valueCounts) each(f func([]string) error) error {
+	h := append([]string(nil), v.names...)
+	if v.freq {
+		h = append(h, "count")
+		sort.SliceStable(v.order, func(i, j int) bool {
+			return v.counts[v.order[i]] > v.counts[v.order[j]]
+		})
+	}
+	if err := f(h); nil != err {
+		return err
+	}
+	for _, k := range v.order {
+		rec := []string{k}
+		if 1 < len(v.cols) {
+			rec = strings.Split(k, "\x00")
+		}
+		if v.freq {
+			rec = append(rec, strconv.Itoa(v.counts[k]))
+		}
+		if err := f(rec); nil != err {
+			return err
+		}
+	}
+	return nil
+}
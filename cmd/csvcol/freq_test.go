@@ -0,0 +1,127 @@
+/*
+ * freq_test.go
+ * Tests for freq.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValueCounts(t *testing.T) {
+	for _, c := range []struct {
+		spec   string
+		freq   bool
+		header []string
+		want   string
+	}{{
+		spec: "1",
+		want: "[[c1] [a] [b] [c]]",
+	}, {
+		spec:   "1",
+		header: []string{"k", "v"},
+		want:   "[[k] [a] [b] [c]]",
+	}, {
+		spec: "c1",
+		freq: true,
+		want: "[[c1 count] [b 3] [a 2] [c 1]]",
+	}, {
+		spec:   "col:2,1",
+		freq:   true,
+		header: []string{"k"},
+		want: "[[c2 k count] [x a 2] [x b 2] " +
+			"[y b 1] [ c 1]]",
+	}} {
+		v, err := newValueCounts(c.spec, c.freq)
+		if nil != err {
+			t.Fatalf("%q: error: %v", c.spec, err)
+		}
+		if nil != c.header {
+			v.header(c.header)
+		}
+		for _, r := range []string{
+			"a,x", "b,x", "a,x", "b,y", "c", "b,x",
+		} {
+			v.add(strings.Split(r, ","), nil, nil)
+		}
+		var got [][]string
+		if err := v.each(func(r []string) error {
+			got = append(got, r)
+			return nil
+		}); nil != err {
+			t.Fatalf("%q: each: %v", c.spec, err)
+		}
+		if g := fmt.Sprint(got); g != c.want {
+			t.Errorf("%q: got %s, want %s", c.spec, g, c.want)
+		}
+	}
+	if _, err := newValueCounts("x", false); nil == err {
+		t.Errorf("Invalid column accepted")
+	}
+}
+
+func TestUniqFreq(t *testing.T) {
+	/* Quoted fields shouldn't be mangled */
+	in := "name,city\n" +
+		"\"Smith, J\",\"New York, NY\"\n" +
+		"bob,Boston\n" +
+		"\"Smith, J\",\"New York, NY\"\n" +
+		"amy,Boston\n" +
+		"carl,Boston\n"
+	runOutputTests(t, []outputTest{{
+		name:  "uniq",
+		stdin: in,
+		args:  []string{"-header", "-uniq", "2"},
+		want:  "city\n\"New York, NY\"\nBoston\n",
+	}, {
+		name:  "freq",
+		stdin: in,
+		args:  []string{"-header", "-freq", "2"},
+		want:  "city,count\nBoston,3\n\"New York, NY\",2\n",
+	}, {
+		name:  "freq_columns_no_header",
+		stdin: in,
+		args:  []string{"-freq", "c1,c2"},
+		want: "c1,c2,count\n" +
+			"\"Smith, J\",\"New York, NY\",2\n" +
+			"name,city,1\n" +
+			"bob,Boston,1\n" +
+			"amy,Boston,1\n" +
+			"carl,Boston,1\n",
+	}, {
+		name:  "selected_rows",
+		stdin: in,
+		args:  []string{"-header", "-rows", "2-4", "-uniq", "2"},
+		want:  "city\nBoston\n\"New York, NY\"\n",
+	}})
+	for _, args := range [][]string{
+		{"-uniq", "x"},
+		{"-freq", "1", "-uniq", "1"},
+		{"-freq", "1", "-json"},
+	} {
+		res := runCSVCol(t, in, args...)
+		if -25 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * group.go
+ * Hold the last selected row of each group
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "github.com/magisterquis/csvcol"
+
+/* heldRow is a selected row held until the end of the input */
+type heldRow struct {
+	record []string
+	orec   []string
+	tr     *traceRecord
+	ok     bool /* False if superseded by a later row */
+}
+
+/* lastPerGroup keeps the last row seen for each group */
+type lastPerGroup struct {
+	cols []int
+	idx  map[string]int /* Key -> index in rows */
+	rows []heldRow
+}
+
+/* newLastPerGroup returns a lastPerGroup which groups by the given
+columns. */
+func newLastPerGroup(cols []int) *lastPerGroup {
+	return &lastPerGroup{cols: cols, idx: make(map[string]int)}
+}
+
+/* add holds orec, selected from record, replacing any previously-held row
+in the same group. */
+func (l *lastPerGroup) add(record, orec []string, tr *traceRecord) {
+	k := csvcol.GroupKey(l.cols, record)
+	if i, ok := l.idx[k]; ok {
+		l.rows[i] = heldRow{}
+	}
+	l.idx[k] = len(l.rows)
+	l.rows = append(l.rows, heldRow{
+		record: record,
+		orec:   orec,
+		tr:     tr,
+		ok:     true,
+	})
+}
+
+/* each calls f for each held row, in the order in which they were added */
+func (l *lastPerGroup) each(f func([]string, []string, *traceRecord)) {
+	for _, r := range l.rows {
+		if r.ok {
+			f(r.record, r.orec, r.tr)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * group_test.go
+ * Tests for group.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestPerGroup(t *testing.T) {
+	in := "c1,a\nc2,b\nc1,c\nc3,d\nc2,e\n"
+	runOutputTests(t, []outputTest{{
+		name:  "first",
+		stdin: in,
+		args:  []string{"-first-per-group", "col:1"},
+		want:  "c1,a\nc2,b\nc3,d\n",
+	}, {
+		name:  "last",
+		stdin: in,
+		args:  []string{"-last-per-group", "c1"},
+		want:  "c1,c\nc3,d\nc2,e\n",
+	}, {
+		name:  "two_columns",
+		stdin: "a,1,x\na,1,y\na,2,z\n",
+		args:  []string{"-first-per-group", "c1,c2"},
+		want:  "a,1,x\na,2,z\n",
+	}, {
+		name:  "selected_rows_only",
+		stdin: in,
+		args:  []string{"-first-per-group", "c1", "-rows", "2-"},
+		want:  "c2,b\nc1,c\nc3,d\n",
+	}, {
+		name:  "header",
+		stdin: "k,v\na,1\na,2\nb,3\n",
+		args:  []string{"-header", "-last-per-group", "c1"},
+		want:  "k,v\na,2\nb,3\n",
+	}, {
+		name:  "missing_column",
+		stdin: "a\nb,1\na,2\n",
+		args:  []string{"-first-per-group", "c2"},
+		want:  "a\nb,1\na,2\n",
+	}})
+	for _, k := range []string{"x", "c0", "1"} {
+		res := runCSVCol(t, "a\n", "-first-per-group", k)
+		if 0 == res.code {
+			t.Errorf("Invalid key %q didn't fail", k)
+		}
+	}
+}
+
+func TestGroupSep(t *testing.T) {
+	in := "a,1\na,2\nb,3\nb,4\nc,5\n"
+	runOutputTests(t, []outputTest{{
+		name:  "blank",
+		stdin: in,
+		args:  []string{"-group-sep", "col:1"},
+		want:  "a,1\na,2\n\nb,3\nb,4\n\nc,5\n",
+	}, {
+		name:  "text",
+		stdin: in,
+		args: []string{
+			"-group-sep", "c1",
+			"-group-sep-text", "# ----",
+			"-rows", "2-4",
+		},
+		want: "a,2\n# ----\nb,3\nb,4\n",
+	}, {
+		name:  "unselected_column",
+		stdin: in,
+		args:  []string{"-group-sep", "c1", "-cols", "2"},
+		want:  "1\n2\n\n3\n4\n\n5\n",
+	}, {
+		name:  "header",
+		stdin: "k,v\na,1\nb,2\n",
+		args:  []string{"-header", "-group-sep", "c1"},
+		want:  "k,v\na,1\n\nb,2\n",
+	}})
+
+	/* Separators which are comments can be read back in */
+	sep := mustRun(t, in, "-group-sep", "c1", "-group-sep-text", "#")
+	if got := mustRun(t, sep, "-commentchar", "#"); in != got {
+		t.Errorf("Reading back with separators gave %q, want %q",
+			got, in)
+	}
+}
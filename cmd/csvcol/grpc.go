@@ -0,0 +1,341 @@
+/*
+ * grpc.go
+ * Serve selections over gRPC
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+/* The gRPC service is implemented by hand on top of net/http's HTTP/2 support
+rather than with generated code, to avoid the dependencies.  The service is
+described in csvcol.proto. */
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* grpcPath is the path of the Select RPC */
+const grpcPath = "/csvcol.Csvcol/Select"
+
+/* gRPC status codes we use */
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcExhausted       = 8 /* RESOURCE_EXHAUSTED */
+	grpcUnimplemented   = 12
+	grpcInternal        = 13
+)
+
+/* grpcError is an error with a gRPC status code */
+type grpcError struct {
+	code int
+	msg  string
+}
+
+/* Error satisfies the error interface */
+func (e grpcError) Error() string { return e.msg }
+
+/* selectRequest is a SelectRequest message.  Only the first message in a
+stream need have the selection; later messages need only have chunks. */
+type selectRequest struct {
+	rows    string
+	cols    string
+	notRows string
+	notCols string
+	where   []string
+	chunk   []byte
+	ordered bool
+}
+
+/* serveGRPC serves the Select RPC on addr over unencrypted HTTP/2 */
+func serveGRPC(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+grpcPath, handleSelect)
+	var p http.Protocols
+	p.SetUnencryptedHTTP2(true)
+	s := &http.Server{Addr: addr, Handler: mux, Protocols: &p}
+	verbose("Serving gRPC on %v", addr)
+	return s.ListenAndServe()
+}
+
+/* handleSelect handles a stream of SelectRequests, sending back a stream of
+Records */
+func handleSelect(w http.ResponseWriter, r *http.Request) {
+	verbose("[%v] New Select stream", r.RemoteAddr)
+	w.Header().Set("Content-Type", "application/grpc")
+	err := doSelect(w, r)
+	/* Send back the status */
+	code, msg := grpcOK, ""
+	if nil != err {
+		var ge grpcError
+		if errors.As(err, &ge) {
+			code, msg = ge.code, ge.msg
+		} else {
+			code, msg = grpcInternal, err.Error()
+		}
+		verbose("[%v] Select failed: %v", r.RemoteAddr, err)
+	} else {
+		verbose("[%v] Select finished", r.RemoteAddr)
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if "" != msg {
+		w.Header().Set(
+			http.TrailerPrefix+"Grpc-Message",
+			url.PathEscape(msg),
+		)
+	}
+}
+
+/* doSelect reads the requests in r's body, applies the selection in the first
+to the CSV in all of the chunks, and writes the selected records to w. */
+func doSelect(w http.ResponseWriter, r *http.Request) error {
+	if e := r.Header.Get("Grpc-Encoding"); "" != e && "identity" != e {
+		return grpcError{grpcUnimplemented, "unsupported encoding " + e}
+	}
+	rc := http.NewResponseController(w)
+
+	/* The first message tells us what to select.  No messages is an
+	empty request. */
+	req, err := readSelectRequest(r.Body)
+	if io.EOF == err {
+		return nil
+	} else if nil != err {
+		return err
+	}
+	sel, err := requestSelection(req)
+	if nil != err {
+		return grpcError{grpcInvalidArgument, err.Error()}
+	}
+
+	/* Feed chunks to a CSV reader.  If we stop reading early, closing
+	the pipe and body stops the feeding. */
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	defer func() {
+		pr.Close()
+		r.Body.Close()
+		<-done
+	}()
+	go func() {
+		defer close(done)
+		var err error
+		for nil == err {
+			if _, err = pw.Write(req.chunk); nil != err {
+				break
+			}
+			req, err = readSelectRequest(r.Body)
+		}
+		if io.EOF == err {
+			err = nil
+		}
+		pw.CloseWithError(err)
+	}()
+
+	/* Send back what's selected.  Requests are plain CSV; there's no
+	need to run anything clients send through decompressors. */
+	cr := newCSVReader(pr)
+	for {
+		record, err := cr.Read()
+		if io.EOF == err {
+			return nil
+		} else if nil != err {
+			return err
+		}
+		orec, ok, err := sel.Select(record)
+		if nil != err {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := writeGRPCMessage(w, encodeRecord(orec)); nil != err {
+			return err
+		}
+		if err := rc.Flush(); nil != err {
+			return err
+		}
+	}
+}
+
+/* requestSelection makes a Selector from the specs in req */
+func requestSelection(req selectRequest) (*csvcol.Selector, error) {
+	s, err := csvcol.NewSelector(
+		csvcol.RowSpec(req.rows),
+		csvcol.ColSpec(req.cols),
+	)
+	if nil != err {
+		return nil, err
+	}
+	s.Ordered = req.ordered
+	s.Comma = gc.comma
+	if 0 != s.Rows.Window() {
+		return nil, fmt.Errorf("rows: rows counted from the end are " +
+			"not supported")
+	}
+	if "" != strings.TrimSpace(req.notRows) {
+		if err := s.Rows.Exclude(req.notRows); nil != err {
+			return nil, fmt.Errorf("rows: %v", err)
+		}
+	}
+	if "" != strings.TrimSpace(req.notCols) {
+		if err := s.Cols.Exclude(req.notCols); nil != err {
+			return nil, fmt.Errorf("columns: %v", err)
+		}
+	}
+	for _, w := range req.where {
+		if err := s.Where.AddAll(w); nil != err {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+/* readGRPCMessage reads a length-prefixed message from r.  It returns io.EOF
+if there are no more messages.  Messages larger than -grpc-max-message aren't
+read. */
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); nil != err {
+		if io.ErrUnexpectedEOF == err {
+			return nil, grpcError{grpcInvalidArgument,
+				"truncated message"}
+		}
+		return nil, err
+	}
+	if 0 != hdr[0] {
+		return nil, grpcError{grpcUnimplemented,
+			"compressed messages are not supported"}
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if int64(n) > gc.maxGRPCMsg {
+		return nil, grpcError{grpcExhausted, fmt.Sprintf(
+			"message of %d bytes is larger than the maximum of %d",
+			n,
+			gc.maxGRPCMsg,
+		)}
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); nil != err {
+		return nil, grpcError{grpcInvalidArgument, "truncated message"}
+	}
+	return b, nil
+}
+
+/* writeGRPCMessage writes a length-prefixed message to w */
+func writeGRPCMessage(w io.Writer, m []byte) error {
+	b := make([]byte, 5, 5+len(m))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(m)))
+	_, err := w.Write(append(b, m...))
+	return err
+}
+
+/* readSelectRequest reads and decodes a SelectRequest from r */
+func readSelectRequest(r io.Reader) (selectRequest, error) {
+	var req selectRequest
+	b, err := readGRPCMessage(r)
+	if nil != err {
+		return req, err
+	}
+	if err := decodeSelectRequest(b, &req); nil != err {
+		return req, grpcError{grpcInvalidArgument,
+			"invalid SelectRequest: " + err.Error()}
+	}
+	return req, nil
+}
+
+/* decodeSelectRequest decodes the protobuf-encoded SelectRequest in b into
+req.  Unknown fields are ignored. */
+func decodeSelectRequest(b []byte, req *selectRequest) error {
+	for 0 != len(b) {
+		/* Field number and wire type */
+		k, n := binary.Uvarint(b)
+		if 0 >= n {
+			return fmt.Errorf("invalid key")
+		}
+		b = b[n:]
+		field, wtype := k>>3, k&7
+		/* Field's value */
+		var (
+			v []byte
+			u uint64
+		)
+		switch wtype {
+		case 0: /* Varint */
+			if u, n = binary.Uvarint(b); 0 >= n {
+				return fmt.Errorf("invalid varint")
+			}
+			b = b[n:]
+		case 1: /* 64-bit */
+			if 8 > len(b) {
+				return fmt.Errorf("short 64-bit field")
+			}
+			b = b[8:]
+		case 2: /* Length-delimited */
+			l, n := binary.Uvarint(b)
+			if 0 >= n || uint64(len(b)-n) < l {
+				return fmt.Errorf("invalid length")
+			}
+			v, b = b[n:n+int(l)], b[n+int(l):]
+		case 5: /* 32-bit */
+			if 4 > len(b) {
+				return fmt.Errorf("short 32-bit field")
+			}
+			b = b[4:]
+		default:
+			return fmt.Errorf("unknown wire type %v", wtype)
+		}
+		switch field {
+		case 1:
+			req.rows = string(v)
+		case 2:
+			req.cols = string(v)
+		case 3:
+			req.notRows = string(v)
+		case 4:
+			req.notCols = string(v)
+		case 5:
+			req.where = append(req.where, string(v))
+		case 6:
+			req.chunk = v
+		case 7:
+			req.ordered = 0 != u
+		}
+	}
+	return nil
+}
+
+/* encodeRecord encodes fields as a protobuf Record message */
+func encodeRecord(fields []string) []byte {
+	var b []byte
+	for _, f := range fields {
+		b = append(b, 1<<3|2)
+		b = binary.AppendUvarint(b, uint64(len(f)))
+		b = append(b, f...)
+	}
+	return b
+}
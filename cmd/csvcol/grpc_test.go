@@ -0,0 +1,266 @@
+/*
+ * grpc_test.go
+ * Tests for grpc.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+/* startGRPC starts csvcol serving gRPC with the given extra arguments and
+returns the address on which it's listening. */
+func startGRPC(t *testing.T, args ...string) string {
+	t.Helper()
+	/* Find a free port */
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatalf("Finding a free port: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cmd := csvcolCommand(t, append([]string{"-grpc", addr}, args...)...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); nil != err {
+		t.Fatalf("Starting csvcol: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	/* Wait for it to listen */
+	for start := time.Now(); time.Since(start) < 10*time.Second; {
+		c, err := net.Dial("tcp", addr)
+		if nil == err {
+			c.Close()
+			return addr
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("csvcol didn't start listening on %v", addr)
+	return ""
+}
+
+/* grpcCall starts a Select call to the server on addr.  The returned writer
+is the request body.  The response comes back on the returned channel. */
+func grpcCall(
+	t *testing.T,
+	addr string,
+) (*io.PipeWriter, <-chan *http.Response) {
+	t.Helper()
+	var p http.Protocols
+	p.SetUnencryptedHTTP2(true)
+	c := &http.Client{
+		Transport: &http.Transport{Protocols: &p},
+		Timeout:   10 * time.Second,
+	}
+	pr, pw := io.Pipe()
+	req, err := http.NewRequest(
+		http.MethodPost,
+		"http://"+addr+grpcPath,
+		pr,
+	)
+	if nil != err {
+		t.Fatalf("Making request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	ch := make(chan *http.Response, 1)
+	go func() {
+		res, err := c.Do(req)
+		if nil != err {
+			t.Errorf("Request failed: %v", err)
+			close(ch)
+			return
+		}
+		ch <- res
+	}()
+	t.Cleanup(func() { pw.Close() })
+	return pw, ch
+}
+
+/* grpcFrame frames m as a gRPC message */
+func grpcFrame(m []byte) []byte {
+	b := make([]byte, 5, 5+len(m))
+	binary.BigEndian.PutUint32(b[1:], uint32(len(m)))
+	return append(b, m...)
+}
+
+/* encodeTestRequest encodes a SelectRequest with the given columns and
+chunk */
+func encodeTestRequest(cols, chunk string) []byte {
+	var b []byte
+	for _, f := range []struct {
+		n byte
+		v string
+	}{{2, cols}, {6, chunk}} {
+		if "" == f.v {
+			continue
+		}
+		b = append(b, f.n<<3|2)
+		b = binary.AppendUvarint(b, uint64(len(f.v)))
+		b = append(b, f.v...)
+	}
+	return b
+}
+
+/* grpcResult reads the records and status from res */
+func grpcResult(t *testing.T, res *http.Response) ([][]string, string) {
+	t.Helper()
+	if nil == res {
+		t.FailNow()
+	}
+	defer res.Body.Close()
+	var recs [][]string
+	for {
+		m, err := readTestMessage(res.Body)
+		if io.EOF == err {
+			break
+		} else if nil != err {
+			t.Fatalf("Reading response: %v", err)
+		}
+		var rec []string
+		for 0 != len(m) { /* Only field 1, length-delimited */
+			l, n := binary.Uvarint(m[1:])
+			m = m[1+n:]
+			rec = append(rec, string(m[:l]))
+			m = m[l:]
+		}
+		recs = append(recs, rec)
+	}
+	return recs, res.Trailer.Get("Grpc-Status")
+}
+
+/* readTestMessage reads a framed message from r */
+func readTestMessage(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); nil != err {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint32(hdr[1:]))
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+/* grpcResponse waits for the response on ch */
+func grpcResponse(t *testing.T, ch <-chan *http.Response) *http.Response {
+	t.Helper()
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(10 * time.Second):
+		t.Fatalf("No response")
+	}
+	return nil
+}
+
+func TestGRPCSelect(t *testing.T) {
+	addr := startGRPC(t)
+	pw, ch := grpcCall(t, addr)
+	go func() {
+		pw.Write(grpcFrame(encodeTestRequest("2", "a,b\nc,")))
+		pw.Write(grpcFrame(encodeTestRequest("", "d\n")))
+		pw.Close()
+	}()
+	recs, status := grpcResult(t, grpcResponse(t, ch))
+	if "0" != status {
+		t.Errorf("Status %q, not 0", status)
+	}
+	if want := [][]string{{"b"}, {"d"}}; !slices.EqualFunc(
+		recs,
+		want,
+		slices.Equal,
+	) {
+		t.Errorf("Records incorrect:\ngot: %q\nwant: %q", recs, want)
+	}
+}
+
+func TestGRPCEmptyStream(t *testing.T) {
+	pw, ch := grpcCall(t, startGRPC(t))
+	pw.Close()
+	recs, status := grpcResult(t, grpcResponse(t, ch))
+	if "0" != status {
+		t.Errorf("Status %q, not 0", status)
+	}
+	if 0 != len(recs) {
+		t.Errorf("Unexpected records: %q", recs)
+	}
+}
+
+func TestGRPCMaxMessage(t *testing.T) {
+	pw, ch := grpcCall(t, startGRPC(t, "-grpc-max-message", "1K"))
+	/* Only the header, claiming a huge message, and the body's left
+	open */
+	go pw.Write([]byte{0, 0x7f, 0xff, 0xff, 0xff})
+	_, status := grpcResult(t, grpcResponse(t, ch))
+	if "8" != status {
+		t.Errorf("Status %q, not 8 (RESOURCE_EXHAUSTED)", status)
+	}
+}
+
+func TestGRPCPlainCSV(t *testing.T) {
+	/* Input which looks compressed or like XLSX is still just CSV */
+	for _, magic := range []string{"BZh", "\x1f\x8b", "PK\x03\x04"} {
+		pw, ch := grpcCall(t, startGRPC(t))
+		go func() {
+			pw.Write(grpcFrame(encodeTestRequest("", magic+",b\n")))
+			pw.Close()
+		}()
+		recs, status := grpcResult(t, grpcResponse(t, ch))
+		if "0" != status {
+			t.Errorf("%q: status %q, not 0", magic, status)
+		}
+		want := [][]string{{magic, "b"}}
+		if !slices.EqualFunc(recs, want, slices.Equal) {
+			t.Errorf("%q: records incorrect:\ngot: %q\nwant: %q",
+				magic, recs, want)
+		}
+	}
+}
+
+func TestGRPCEarlyError(t *testing.T) {
+	/* -strict makes a stray quote an error, before the stream's done */
+	pw, ch := grpcCall(t, startGRPC(t, "-strict"))
+	go pw.Write(grpcFrame(encodeTestRequest("", "a\"b\n")))
+	_, status := grpcResult(t, grpcResponse(t, ch))
+	if "13" != status {
+		t.Errorf("Status %q, not 13 (INTERNAL)", status)
+	}
+	/* The server should have stopped reading */
+	done := make(chan error, 1)
+	go func() {
+		_, err := pw.Write(grpcFrame(encodeTestRequest("", "c\n")))
+		done <- err
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Errorf("Server still reading after error")
+	}
+}
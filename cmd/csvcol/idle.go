@@ -0,0 +1,97 @@
+/*
+ * idle.go
+ * Give up on input which stops arriving
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+/* errIdle is returned by idleReader.Read when no data arrives in time */
+var errIdle = errors.New("no input received before timeout")
+
+/* readResult is the result of a single read in idleReader's goroutine */
+type readResult struct {
+	b   []byte
+	err error
+}
+
+/* idleReader wraps a reader and returns errIdle if a read takes longer than
+its timeout. */
+type idleReader struct {
+	timeout time.Duration
+	results chan readResult
+	pending []byte
+	err     error
+}
+
+/* newIdleReader returns an idleReader which reads from r.  Reads from r are
+made in a separate goroutine, which will be blocked forever if r never returns
+once a timeout has happened. */
+func newIdleReader(r io.Reader, timeout time.Duration) *idleReader {
+	ir := &idleReader{
+		timeout: timeout,
+		results: make(chan readResult),
+	}
+	go func() {
+		for {
+			b := make([]byte, 32*1024)
+			n, err := r.Read(b)
+			ir.results <- readResult{b: b[:n], err: err}
+			if nil != err {
+				close(ir.results)
+				return
+			}
+		}
+	}()
+	return ir
+}
+
+/* Read satisfies io.Reader */
+func (ir *idleReader) Read(p []byte) (int, error) {
+	/* Wait for more data if we need it */
+	timer := time.NewTimer(ir.timeout)
+	defer timer.Stop()
+	for 0 == len(ir.pending) && nil == ir.err {
+		select {
+		case res, ok := <-ir.results:
+			if !ok {
+				ir.err = io.EOF
+				break
+			}
+			ir.pending = res.b
+			ir.err = res.err
+		case <-timer.C:
+			return 0, errIdle
+		}
+	}
+
+	/* Send back what we have */
+	if 0 != len(ir.pending) {
+		n := copy(p, ir.pending)
+		ir.pending = ir.pending[n:]
+		return n, nil
+	}
+	return 0, ir.err
+}
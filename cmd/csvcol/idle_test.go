@@ -0,0 +1,88 @@
+/*
+ * idle_test.go
+ * Tests for idle.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+/* runStalled runs csvcol with a standard input which gets a row and then
+stalls until csvcol exits. */
+func runStalled(t *testing.T, args ...string) runResult {
+	t.Helper()
+	pr, pw, err := os.Pipe()
+	if nil != err {
+		t.Fatalf("Making pipe: %v", err)
+	}
+	defer pw.Close()
+	if _, err := pw.WriteString("a,b\n"); nil != err {
+		t.Fatalf("Writing to pipe: %v", err)
+	}
+	cmd := csvcolCommand(t, args...)
+	cmd.Stdin = pr
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	pr.Close()
+	res := runResult{stdout: stdout.String(), stderr: stderr.String()}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		res.code = ee.ExitCode()
+	} else if nil != err {
+		t.Fatalf("Running csvcol: %v", err)
+	}
+	return res
+}
+
+func TestIdleTimeout(t *testing.T) {
+	res := runStalled(t, "-idle-timeout", "100ms", "-cols", "2")
+	if 0 == res.code {
+		t.Errorf("Timeout didn't cause an error")
+	}
+	if "b\n" != res.stdout {
+		t.Errorf("Output before the timeout %q, want %q",
+			res.stdout, "b\n")
+	}
+}
+
+func TestIdleContinue(t *testing.T) {
+	next := writeTestFile(t, t.TempDir(), "next.csv", "c,d\n")
+	res := runStalled(
+		t,
+		"-idle-timeout", "100ms",
+		"-idle-continue",
+		"-cols", "2",
+		"-", next,
+	)
+	if 0 != res.code {
+		t.Errorf("Exit status %d: %s", res.code, res.stderr)
+	}
+	if "b\nd\n" != res.stdout {
+		t.Errorf("Output %q, want %q", res.stdout, "b\nd\n")
+	}
+}
@@ -0,0 +1,399 @@
+/*
+ * job.go
+ * Declarative runs described by job files
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* jobOutput is one of a job's outputs, with its own selection */
+type jobOutput struct {
+	path   string
+	sel    *csvcol.Selector
+	w      recordWriter
+	f      *os.File /* Nil for stdout */
+	window int      /* Rows to hold back, for rows counted from the end */
+	held   [][]string
+}
+
+/* jobFile is a parsed job file */
+type jobFile struct {
+	inputs  []string
+	header  bool
+	outputs []*jobOutput
+}
+
+/* runJobFile reads the job file named name and runs the job.  Every input
+is read once, and each record is passed to each output's selection. */
+func runJobFile(name string) error {
+	j, err := readJobFile(name)
+	if nil != err {
+		return err
+	}
+	for _, o := range j.outputs {
+		if "-" == o.path {
+			o.w = newRecordWriter(stdout, o.sel)
+			continue
+		}
+		if o.f, err = os.Create(o.path); nil != err {
+			return err
+		}
+		defer o.f.Close()
+		o.w = newRecordWriter(o.f, o.sel)
+	}
+
+	/* Feed each input to each output */
+	sentHeader := false
+	for _, in := range j.inputs {
+		verbose("Reading %v", in)
+		if err := j.runInput(in, &sentHeader); nil != err {
+			return fmt.Errorf("%v: %w", in, err)
+		}
+	}
+
+	/* Finish off held rows and tidy up */
+	for _, o := range j.outputs {
+		o.sel.SetTotal(o.sel.Row() + len(o.held))
+		for _, record := range o.held {
+			if err := o.write(record); nil != err {
+				return err
+			}
+		}
+		if err := o.w.Close(); nil != err {
+			return fmt.Errorf("%v: %w", o.path, err)
+		}
+		if nil == o.f {
+			continue
+		}
+		if err := o.f.Close(); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+/* runInput passes the records in the file named in to each of j's outputs */
+func (j *jobFile) runInput(in string, sentHeader *bool) error {
+	var r io.Reader = os.Stdin
+	if "-" != in {
+		f, err := openFile(in)
+		if nil != err {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	cr := newReader(r)
+	needHeader := j.header
+	for {
+		record, err := cr.Read()
+		if io.EOF == err {
+			return nil
+		} else if nil != err {
+			return err
+		}
+		/* Headers are only output once */
+		if needHeader {
+			needHeader = false
+			for _, o := range j.outputs {
+				h, err := o.sel.SelectHeader(record)
+				if nil != err {
+					return fmt.Errorf("%v: %w", o.path, err)
+				}
+				if *sentHeader {
+					continue
+				}
+				if err := o.w.WriteHeader(h); nil != err {
+					return fmt.Errorf("%v: %w", o.path, err)
+				}
+			}
+			*sentHeader = true
+			continue
+		}
+		for _, o := range j.outputs {
+			rec := record
+			/* Maybe hold on to the last few */
+			if 0 != o.window {
+				o.held = append(o.held, rec)
+				if len(o.held) <= o.window {
+					continue
+				}
+				rec, o.held = o.held[0], o.held[1:]
+			}
+			if err := o.write(rec); nil != err {
+				return err
+			}
+		}
+	}
+}
+
+/* write writes record to o, if it's selected */
+func (o *jobOutput) write(record []string) error {
+	orec, ok, err := o.sel.Select(record)
+	if nil != err {
+		return fmt.Errorf("%v: %w", o.path, err)
+	}
+	if !ok {
+		return nil
+	}
+	if o.sel.IsHeaderRow() {
+		err = o.w.WriteHeader(orec)
+	} else {
+		err = o.w.Write(orec)
+	}
+	if nil != err {
+		return fmt.Errorf("%v: %w", o.path, err)
+	}
+	return nil
+}
+
+/* readJobFile reads and parses the job file named name.  Relative paths in
+the file are relative to the directory containing it. */
+func readJobFile(name string) (*jobFile, error) {
+	b, err := os.ReadFile(name)
+	if nil != err {
+		return nil, err
+	}
+	v, err := parseYAML(string(b))
+	if nil != err {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("job must be a mapping")
+	}
+	var (
+		j   jobFile
+		dir = filepath.Dir(name)
+	)
+	for _, k := range sortedKeys(m) {
+		switch k {
+		case "inputs":
+			ins, err := jobStrings(m[k])
+			if nil != err {
+				return nil, fmt.Errorf("inputs: %w", err)
+			}
+			for _, in := range ins {
+				j.inputs = append(j.inputs, jobPath(dir, in))
+			}
+		case "header":
+			if j.header, err = jobBool(m[k]); nil != err {
+				return nil, fmt.Errorf("header: %w", err)
+			}
+		case "outputs":
+			outs, ok := m[k].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("outputs must be a list")
+			}
+			for i, o := range outs {
+				jo, err := parseJobOutput(dir, o)
+				if nil != err {
+					return nil, fmt.Errorf("output %v: %w",
+						i+1, err)
+				}
+				j.outputs = append(j.outputs, jo)
+			}
+		default:
+			return nil, fmt.Errorf("unknown key %q", k)
+		}
+	}
+	if 0 == len(j.inputs) {
+		return nil, fmt.Errorf("no inputs")
+	}
+	if 0 == len(j.outputs) {
+		return nil, fmt.Errorf("no outputs")
+	}
+	return &j, nil
+}
+
+/* parseJobOutput parses one of a job's outputs */
+func parseJobOutput(dir string, v interface{}) (*jobOutput, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("output must be a mapping")
+	}
+	o := &jobOutput{sel: &csvcol.Selector{Comma: gc.comma}}
+	for _, k := range sortedKeys(m) {
+		if err := o.set(dir, k, m[k]); nil != err {
+			return nil, fmt.Errorf("%v: %w", k, err)
+		}
+	}
+	if "" == o.path {
+		return nil, fmt.Errorf("missing path")
+	}
+	if o.sel.Cols.Anchored() {
+		return nil, fmt.Errorf("anchored ranges may only be used " +
+			"for rows")
+	}
+	o.window = o.sel.Rows.Window()
+	return o, nil
+}
+
+/* set sets the output setting named k, which corresponds to the flag of
+(nearly) the same name, to v. */
+func (o *jobOutput) set(dir, k string, v interface{}) error {
+	/* Settings which are a single value */
+	switch k {
+	case "path":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		o.path = s
+		if "-" != s {
+			o.path = jobPath(dir, s)
+		}
+		return nil
+	case "ordered":
+		var err error
+		o.sel.Ordered, err = jobBool(v)
+		return err
+	case "colnames":
+		/* A string is a line of CSV, as for -colnames */
+		if s, ok := v.(string); ok {
+			ns, err := csv.NewReader(strings.NewReader(s)).Read()
+			if nil != err {
+				return err
+			}
+			o.sel.SetColumnNames(ns)
+			return nil
+		}
+		ns, err := jobStrings(v)
+		if nil != err {
+			return err
+		}
+		o.sel.SetColumnNames(ns)
+		return nil
+	case "first_per_group":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		cols, err := csvcol.ParseGroupKey(s)
+		if nil != err {
+			return err
+		}
+		o.sel.SetFirstPerGroup(cols)
+		return nil
+	}
+
+	/* Settings which may be given more than once */
+	var add func(string) error
+	switch k {
+	case "rows":
+		add = func(s string) error {
+			_, err := o.sel.Rows.Add(s)
+			return err
+		}
+	case "not_rows":
+		add = o.sel.Rows.Exclude
+	case "cols":
+		add = func(s string) error {
+			_, err := o.sel.Cols.Add(s)
+			return err
+		}
+	case "not_cols":
+		add = o.sel.Cols.Exclude
+	case "where":
+		add = o.sel.Where.AddAll
+	case "where_any":
+		add = o.sel.Where.AddAny
+	case "in":
+		add = o.sel.Where.AddIn
+	case "not_in":
+		add = o.sel.Where.AddNotIn
+	case "match":
+		add = o.sel.Where.AddMatch
+	case "vmatch":
+		add = o.sel.Where.AddNotMatch
+	default:
+		return fmt.Errorf("unknown setting")
+	}
+	ss, err := jobStrings(v)
+	if nil != err {
+		return err
+	}
+	for _, s := range ss {
+		if err := add(s); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+/* jobStrings returns v, which must be a string or a list of strings, as a
+list of strings */
+func jobStrings(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		ss := make([]string, len(v))
+		for i, s := range v {
+			var ok bool
+			if ss[i], ok = s.(string); !ok {
+				return nil, fmt.Errorf("item %v must be a "+
+					"string", i+1)
+			}
+		}
+		return ss, nil
+	}
+	return nil, fmt.Errorf("must be a string or list of strings")
+}
+
+/* jobBool returns v, which must be true or false */
+func jobBool(v interface{}) (bool, error) {
+	s, ok := v.(string)
+	if !ok {
+		return false, fmt.Errorf("must be true or false")
+	}
+	return strconv.ParseBool(s)
+}
+
+/* jobPath returns p relative to dir, unless p is absolute, a URL, or - */
+func jobPath(dir, p string) string {
+	if "-" == p || !isRelative(p) || isURL(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+/* sortedKeys returns m's keys, sorted, so errors are reported in a
+predictable order */
+func sortedKeys(m map[string]interface{}) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
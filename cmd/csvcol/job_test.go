@@ -0,0 +1,120 @@
+/*
+ * job_test.go
+ * Tests for job.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJob(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "one.csv", "name,n\na,1\nb,2\nc,3\n")
+	writeTestFile(t, dir, "two.csv", "name,n\nd,4\ne,5\n")
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0700); nil != err {
+		t.Fatalf("Making %v: %v", sub, err)
+	}
+	job := writeTestFile(t, dir, "job.yaml", `# Test job
+inputs: [one.csv, two.csv]
+header: true
+outputs:
+  - path: -
+    rows: 2-3
+    cols: 1
+  - path: sub/last.csv
+    rows: -2-
+    colnames: "n,name"
+    ordered: true
+  - path: big.csv
+    where:
+      - c2 > 1
+      - c2 < 5
+    vmatch: "1:^c"
+`)
+	res := runCSVCol(t, "", "-job", job)
+	if 0 != res.code {
+		t.Fatalf("Exit status %d: %s", res.code, res.stderr)
+	}
+	if want := "name\nb\nc\n"; want != res.stdout {
+		t.Errorf("Standard output: got %q, want %q", res.stdout, want)
+	}
+	for _, c := range []struct {
+		name string
+		want string
+	}{
+		{filepath.Join(sub, "last.csv"), "n,name\n4,d\n5,e\n"},
+		{filepath.Join(dir, "big.csv"), "name,n\nb,2\nd,4\n"},
+	} {
+		b, err := os.ReadFile(c.name)
+		if nil != err {
+			t.Errorf("Reading output: %v", err)
+		} else if c.want != string(b) {
+			t.Errorf("%v: got %q, want %q", c.name, b, c.want)
+		}
+	}
+}
+
+func TestJobStdin(t *testing.T) {
+	dir := t.TempDir()
+	job := writeTestFile(t, dir, "job.yaml", "inputs: -\n"+
+		"outputs:\n  - path: -\n    not_rows: 1\n    not_cols: [2]\n")
+	res := runCSVCol(t, "a,b\nc,d\ne,f\n", "-job", job)
+	if 0 != res.code {
+		t.Fatalf("Exit status %d: %s", res.code, res.stderr)
+	}
+	if want := "c\ne\n"; want != res.stdout {
+		t.Errorf("Got %q, want %q", res.stdout, want)
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "in.csv", "a,b\n")
+	for _, c := range []struct {
+		name string
+		job  string
+	}{
+		{"not_mapping", "- a\n"},
+		{"no_inputs", "outputs:\n  - path: -\n"},
+		{"no_outputs", "inputs: in.csv\n"},
+		{"unknown_key", "inputs: in.csv\nfoo: bar\n"},
+		{"no_path", "inputs: in.csv\noutputs:\n  - rows: 1\n"},
+		{"bad_setting", "inputs: in.csv\n" +
+			"outputs:\n  - path: -\n    foo: 1\n"},
+		{"bad_rows", "inputs: in.csv\n" +
+			"outputs:\n  - path: -\n    rows: x\n"},
+		{"anchored_cols", "inputs: in.csv\n" +
+			"outputs:\n  - path: -\n    cols: /a/-\n"},
+		{"bad_bool", "inputs: in.csv\nheader: maybe\n" +
+			"outputs:\n  - path: -\n"},
+		{"missing_input", "inputs: nope.csv\n" +
+			"outputs:\n  - path: -\n"},
+	} {
+		job := writeTestFile(t, dir, c.name+".yaml", c.job)
+		if res := runCSVCol(t, "", "-job", job); 0 == res.code {
+			t.Errorf("%s: succeeded", c.name)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+/*
+ * join.go
+ * Join the input with a lookup file
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* joinStage is a stage which joins rows with the rows of a lookup file with
+the same key, appending some of the lookup's columns.  Whichever of the input
+and the lookup file is smaller is held in memory; if it's the input, rows are
+passed on once the stage is flushed. */
+type joinStage struct {
+	column   func(int) int    /* Input column of an output field */
+	key      int              /* 1-indexed input key column */
+	lkey     int              /* 1-indexed lookup key column */
+	left     bool             /* Keep input rows without a match */
+	right    bool             /* Keep lookup rows without a match */
+	cols     *csvcol.Selector /* Picks the lookup's columns */
+	header   []string         /* Lookup's header's columns */
+	width    int              /* Number of lookup columns */
+	lname    string           /* Printable name of the lookup file */
+	outWidth int              /* Number of output columns */
+	keyOut   []int            /* Output columns holding the input key */
+	shaped   bool             /* outWidth and keyOut are set */
+	lookup   *csv.Reader      /* Lookup file, if the input's held */
+	lfile    io.Closer        /* Lookup file, to close */
+	rows     [][]string       /* Lookup rows' columns */
+	keys     []string         /* Lookup rows' keys */
+	index    map[string][]int /* Row indices, by key */
+	matched  []bool           /* Lookup rows which have matched */
+	held     []stageRow       /* Input rows, if the lookup's streamed */
+	matches  [][][]string     /* Lookup columns for each held row */
+}
+
+/* parseJoinOn parses the key columns of the form A=B, where A is a column of
+the input and B is a column of the lookup file, each col:N, cN, or just N. */
+func parseJoinOn(s string) (int, int, error) {
+	a, b, ok := strings.Cut(s, "=")
+	if !ok {
+		return 0, 0, errors.New("missing =")
+	}
+	var cs [2]int
+	for i, p := range []string{a, b} {
+		c, err := parseDedupKey(p)
+		if nil == err && 1 != len(c) {
+			err = errors.New("need exactly one column")
+		}
+		if nil != err {
+			return 0, 0, fmt.Errorf("column %q: %w", p, err)
+		}
+		cs[i] = c[0]
+	}
+	return cs[0], cs[1], nil
+}
+
+/* inputSize returns the total size of the files in fs, or -1 if any of them
+isn't a regular file. */
+func inputSize(fs []string) int64 {
+	var n int64
+	for _, f := range fs {
+		if "-" == f || isURL(f) {
+			return -1
+		}
+		fi, err := os.Stat(f)
+		if nil != err || !fi.Mode().IsRegular() {
+			return -1
+		}
+		n += fi.Size()
+	}
+	return n
+}
+
+/* newJoinStage returns a joinStage which joins rows with the rows of the
+lookup file named file, as per -join, -on, -join-type, and -join-cols.  If
+hasHeader is true, the lookup's first row is its header.  If size, the size of
+the input, is known (i.e. not negative) and is smaller than the lookup file and
+holdOK is true, the input is held in memory instead of the lookup file. */
+func newJoinStage(
+	file string,
+	hasHeader bool,
+	size int64,
+	holdOK bool,
+	column func(int) int,
+) (*joinStage, error) {
+	j := &joinStage{
+		column: column,
+		lname:  file,
+		cols:   &csvcol.Selector{Ordered: *gc.ordered},
+		index:  make(map[string][]int),
+	}
+
+	/* Work out what to join */
+	var err error
+	if j.key, j.lkey, err = parseJoinOn(*gc.joinOn); nil != err {
+		return nil, fmt.Errorf("invalid -on %q: %w", *gc.joinOn, err)
+	}
+	switch *gc.joinType {
+	case "inner":
+	case "left":
+		j.left = true
+	case "right":
+		j.right = true
+	case "outer", "full":
+		j.left, j.right = true, true
+	default:
+		return nil, fmt.Errorf("unknown -join-type %q", *gc.joinType)
+	}
+	if "" == *gc.joinCols {
+		err = j.cols.Cols.Exclude(strconv.Itoa(j.lkey))
+	} else if _, err = j.cols.Cols.Add(*gc.joinCols); nil == err &&
+		j.cols.Cols.Anchored() {
+		err = errors.New("anchored ranges may only be used for rows")
+	}
+	if nil != err {
+		return nil, fmt.Errorf("invalid -join-cols %q: %w",
+			*gc.joinCols, err)
+	}
+
+	/* Get hold of the lookup file */
+	var fp *os.File
+	if "-" == file {
+		fp, j.lname = os.Stdin, "standard input"
+	} else if fp, err = openFile(file); nil != err {
+		return nil, err
+	}
+	j.lfile = fp
+	r := newReader(fp)
+	if hasHeader {
+		rec, err := r.Read()
+		if nil != err && !errors.Is(err, io.EOF) {
+			fp.Close()
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+		j.header = j.cols.Columns(rec)
+		j.width = len(j.header)
+	}
+
+	/* If the input's smaller, it's what we'll hold */
+	if fi, err := fp.Stat(); nil == err && fi.Mode().IsRegular() &&
+		0 <= size && size < fi.Size() && holdOK {
+		debug("Holding input (%d bytes) to join with %v (%d bytes)",
+			size, j.lname, fi.Size())
+		j.lookup = r
+		return j, nil
+	}
+
+	/* Lookup file's smaller, or we don't know */
+	defer fp.Close()
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if nil != err {
+			return nil, err
+		}
+		k, ok := field(rec, j.lkey)
+		cs := j.lookupCols(rec)
+		if ok {
+			j.index[k] = append(j.index[k], len(j.rows))
+		}
+		j.rows = append(j.rows, cs)
+		j.keys = append(j.keys, k)
+	}
+	j.matched = make([]bool, len(j.rows))
+	debug("Read %d rows from %v to join", len(j.rows), j.lname)
+
+	return j, nil
+}
+
+/* field returns the 1-indexed nth field of rec, and whether rec has n
+fields. */
+func field(rec []string, n int) (string, bool) {
+	if n > len(rec) {
+		return "", false
+	}
+	return rec[n-1], true
+}
+
+/* lookupCols returns the selected columns of the lookup row rec.  The first
+row sets the number of lookup columns, if there's no header. */
+func (j *joinStage) lookupCols(rec []string) []string {
+	cs := j.cols.Columns(rec)
+	if 0 == j.width {
+		j.width = len(cs)
+	}
+	return cs
+}
+
+/* push joins r with the lookup rows with the same key, or holds it until
+flush if we're holding the input. */
+func (j *joinStage) push(r stageRow, next func(stageRow)) {
+	if r.header {
+		r.out = append(r.out, j.header...)
+		next(r)
+		return
+	}
+
+	/* Note where the key goes, for lookup rows without a match */
+	if !j.shaped {
+		j.shaped = true
+		j.outWidth = len(r.out)
+		for i := range r.out {
+			if j.key == j.column(i) {
+				j.keyOut = append(j.keyOut, i)
+			}
+		}
+	}
+
+	/* Hang on to it until we've seen the lookup rows */
+	k, ok := field(r.in, j.key)
+	if nil != j.lookup {
+		if ok {
+			j.index[k] = append(j.index[k], len(j.held))
+		}
+		j.held = append(j.held, r)
+		j.matches = append(j.matches, nil)
+		return
+	}
+
+	/* Join with what we have */
+	var ms []int
+	if ok {
+		ms = j.index[k]
+	}
+	if 0 == len(ms) {
+		if j.left {
+			next(j.joined(r, nil))
+		}
+		return
+	}
+	for _, m := range ms {
+		j.matched[m] = true
+		next(j.joined(r, j.rows[m]))
+	}
+}
+
+/* flush passes on held input rows, once joined, and lookup rows without a
+match, for right and outer joins. */
+func (j *joinStage) flush(next func(stageRow)) {
+	var unmatched []stageRow
+	if nil != j.lookup {
+		for {
+			rec, err := j.lookup.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if nil != err {
+				inform("Error reading %v: %v", j.lname, err)
+				exit(-48)
+			}
+			cs := j.lookupCols(rec)
+			k, ok := field(rec, j.lkey)
+			var hs []int
+			if ok {
+				hs = j.index[k]
+			}
+			if 0 == len(hs) && j.right {
+				unmatched = append(
+					unmatched,
+					j.lookupOnly(k, cs),
+				)
+			}
+			for _, h := range hs {
+				j.matches[h] = append(j.matches[h], cs)
+			}
+		}
+		j.lookup = nil
+		j.lfile.Close()
+		for i, r := range j.held {
+			if 0 == len(j.matches[i]) && j.left {
+				next(j.joined(r, nil))
+			}
+			for _, m := range j.matches[i] {
+				next(j.joined(r, m))
+			}
+		}
+		j.held, j.matches = nil, nil
+	} else if j.right {
+		for i, m := range j.matched {
+			if !m {
+				unmatched = append(
+					unmatched,
+					j.lookupOnly(j.keys[i], j.rows[i]),
+				)
+			}
+			j.matched[i] = false
+		}
+	}
+	for _, r := range unmatched {
+		next(r)
+	}
+}
+
+/* joined returns a copy of r with the lookup columns cs, or empty columns if
+cs is nil, appended. */
+func (j *joinStage) joined(r stageRow, cs []string) stageRow {
+	out := make([]string, len(r.out), len(r.out)+j.width)
+	copy(out, r.out)
+	r.out = j.appendLookup(out, cs)
+	return r
+}
+
+/* lookupOnly returns a row for a lookup row without a match, with the
+columns cs, key k in the output columns from the input key column, and the
+other input columns empty. */
+func (j *joinStage) lookupOnly(k string, cs []string) stageRow {
+	out := make([]string, j.outWidth, j.outWidth+j.width)
+	for _, i := range j.keyOut {
+		out[i] = k
+	}
+	in := make([]string, j.key)
+	in[j.key-1] = k
+	return stageRow{in: in, out: j.appendLookup(out, cs), file: j.lname}
+}
+
+/* appendLookup appends the lookup columns cs to out, padded or cut to the
+number of lookup columns. */
+func (j *joinStage) appendLookup(out, cs []string) []string {
+	if len(cs) > j.width {
+		cs = cs[:j.width]
+	}
+	out = append(out, cs...)
+	for i := len(cs); i < j.width; i++ {
+		out = append(out, "")
+	}
+	return out
+}
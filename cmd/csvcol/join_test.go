@@ -0,0 +1,192 @@
+/*
+ * join_test.go
+ * Tests for join.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseJoinOn(t *testing.T) {
+	for _, c := range []struct {
+		have string
+		in   int
+		lk   int
+		err  bool
+	}{
+		{have: "1=1", in: 1, lk: 1},
+		{have: "2=c1", in: 2, lk: 1},
+		{have: "col:3=4", in: 3, lk: 4},
+		{have: "1", err: true},
+		{have: "1,2=1", err: true},
+		{have: "=1", err: true},
+		{have: "x=1", err: true},
+	} {
+		in, lk, err := parseJoinOn(c.have)
+		if c.err {
+			if nil == err {
+				t.Errorf("%q: expected error", c.have)
+			}
+			continue
+		}
+		if nil != err {
+			t.Errorf("%q: error: %v", c.have, err)
+		} else if in != c.in || lk != c.lk {
+			t.Errorf(
+				"%q: got %d=%d, want %d=%d",
+				c.have,
+				in,
+				lk,
+				c.in,
+				c.lk,
+			)
+		}
+	}
+}
+
+func TestInputSize(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTestFile(t, dir, "a", "abc")
+	b := writeTestFile(t, dir, "b", "defgh")
+	for _, c := range []struct {
+		fs   []string
+		want int64
+	}{
+		{fs: []string{a}, want: 3},
+		{fs: []string{a, b}, want: 8},
+		{fs: []string{a, "-"}, want: -1},
+		{fs: []string{"https://example.com/x.csv"}, want: -1},
+		{fs: []string{filepath.Join(dir, "c")}, want: -1},
+		{fs: []string{dir}, want: -1},
+	} {
+		if got := inputSize(c.fs); got != c.want {
+			t.Errorf("%q: got %d, want %d", c.fs, got, c.want)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	dir := t.TempDir()
+	in := "id,name\n1,ann\n2,bob\n3,\"Smith, J\"\n2,bobby\n"
+	inFile := writeTestFile(t, dir, "in.csv", in)
+	lookup := writeTestFile(t, dir, "lookup.csv", "uid,city,zip\n"+
+		"2,\"Boston, MA\",02101\n"+
+		"4,Austin,73301\n"+
+		"1,Paris,75001\n"+
+		"2,Cambridge,02139\n")
+	matched := "id,name,city,zip\n" +
+		"1,ann,Paris,75001\n" +
+		"2,bob,\"Boston, MA\",02101\n" +
+		"2,bob,Cambridge,02139\n"
+	bobby := "2,bobby,\"Boston, MA\",02101\n" +
+		"2,bobby,Cambridge,02139\n"
+	for _, c := range []struct {
+		name string
+		args []string
+		want string
+	}{{
+		name: "inner",
+		want: matched + bobby,
+	}, {
+		name: "left",
+		args: []string{"-join-type", "left"},
+		want: matched + "3,\"Smith, J\",,\n" + bobby,
+	}, {
+		name: "right",
+		args: []string{"-join-type", "right"},
+		want: matched + bobby + "4,,Austin,73301\n",
+	}, {
+		name: "outer",
+		args: []string{"-join-type", "outer"},
+		want: matched + "3,\"Smith, J\",,\n" + bobby +
+			"4,,Austin,73301\n",
+	}, {
+		name: "join_cols",
+		args: []string{"-on", "c1=col:1", "-join-cols", "3"},
+		want: "id,name,zip\n" +
+			"1,ann,75001\n" +
+			"2,bob,02101\n" +
+			"2,bob,02139\n" +
+			"2,bobby,02101\n" +
+			"2,bobby,02139\n",
+	}, {
+		name: "moved_key",
+		args: []string{
+			"-cols", "2,1",
+			"-ordered",
+			"-join-type", "right",
+			"-where", "c1 = 1",
+		},
+		want: "name,id,city,zip\n" +
+			"ann,1,Paris,75001\n" +
+			",2,\"Boston, MA\",02101\n" +
+			",4,Austin,73301\n" +
+			",2,Cambridge,02139\n",
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			/* The lookup file's held when the input's size
+			isn't known */
+			args := append([]string{
+				"-header",
+				"-join", lookup,
+			}, c.args...)
+			if got := mustRun(t, in, args...); got != c.want {
+				t.Errorf(
+					"Lookup held:\ngot:\n%s\nwant:\n%s",
+					got,
+					c.want,
+				)
+			}
+
+			/* The input's held when it's smaller */
+			args = append(args, "-debug", inFile)
+			res := runCSVCol(t, "", args...)
+			if 0 != res.code {
+				t.Fatalf("Failed: %s", res.stderr)
+			}
+			if !strings.Contains(res.stderr, "Holding input") {
+				t.Errorf("Input not held:\n%s", res.stderr)
+			}
+			if res.stdout != c.want {
+				t.Errorf(
+					"Input held:\ngot:\n%s\nwant:\n%s",
+					res.stdout,
+					c.want,
+				)
+			}
+		})
+	}
+	for _, args := range [][]string{
+		{"-join", lookup, "-on", "1"},
+		{"-join", lookup, "-join-type", "sideways"},
+		{"-join", lookup, "-join-cols", "x"},
+		{"-join", filepath.Join(dir, "missing.csv")},
+	} {
+		res := runCSVCol(t, in, args...)
+		if 0 == res.code {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
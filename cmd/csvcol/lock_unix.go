@@ -0,0 +1,51 @@
+/*
+ * lock_unix.go
+ * Advisory locking on Unix-like systems
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+/* lockFile opens (creating if necessary) the file named n and places an
+exclusive advisory lock on it, waiting until the lock is available.  The lock
+is held until the returned file is closed or the program exits. */
+func lockFile(n string) (*os.File, error) {
+	f, err := os.OpenFile(n, os.O_RDWR|os.O_CREATE, 0644)
+	if nil != err {
+		return nil, err
+	}
+	/* Try without blocking first, to be able to say we're waiting */
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if syscall.EWOULDBLOCK == err {
+		verbose("Waiting for lock on %v", n)
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	}
+	if nil != err {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,110 @@
+/*
+ * lock_unix_test.go
+ * Tests for lock_unix.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/* lockedBuffer is a bytes.Buffer safe for concurrent use */
+type lockedBuffer struct {
+	sync.Mutex
+	b bytes.Buffer
+}
+
+/* Write writes p to the buffer */
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.b.Write(p)
+}
+
+/* String returns what's been written to the buffer */
+func (b *lockedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.b.String()
+}
+
+func TestLockfile(t *testing.T) {
+	dir := t.TempDir()
+	lfn := filepath.Join(dir, "lock")
+	out := filepath.Join(dir, "out.csv")
+
+	/* Hold the lock ourselves */
+	lf, err := lockFile(lfn)
+	if nil != err {
+		t.Fatalf("Locking: %v", err)
+	}
+	defer lf.Close()
+
+	/* csvcol should wait for it */
+	cmd := csvcolCommand(t, "-v", "-lockfile", lfn, "-o", out, "-")
+	cmd.Stdin = strings.NewReader("a,b\n")
+	var stderr lockedBuffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); nil != err {
+		t.Fatalf("Starting csvcol: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for start := time.Now(); !strings.Contains(
+		stderr.String(),
+		"Waiting for lock",
+	); time.Sleep(10 * time.Millisecond) {
+		select {
+		case err := <-done:
+			t.Fatalf("csvcol didn't wait for the lock: %v\n%s",
+				err, stderr.String())
+		default:
+		}
+		if time.Minute < time.Since(start) {
+			t.Fatalf("csvcol never waited for the lock: %s",
+				stderr.String())
+		}
+	}
+	if b, err := os.ReadFile(out); 0 != len(b) {
+		t.Errorf("Output written while waiting for the lock: %q", b)
+	} else if nil != err && !os.IsNotExist(err) {
+		t.Errorf("Reading output: %v", err)
+	}
+
+	/* Once it's released, csvcol should carry on */
+	lf.Close()
+	if err := <-done; nil != err {
+		t.Fatalf("csvcol failed: %v\n%s", err, stderr.String())
+	}
+	if b, err := os.ReadFile(out); nil != err {
+		t.Errorf("Reading output: %v", err)
+	} else if "a,b\n" != string(b) {
+		t.Errorf("Output %q, want %q", b, "a,b\n")
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * mapping.go
+ * Translating values with mapping files
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* valueMapper translates the values of some columns using mappings read
+from two-column CSV files. */
+type valueMapper struct {
+	maps    map[int]map[string]string /* Input column -> old -> new */
+	missing string                    /* Policy for unmapped values */
+	def     string                    /* Replacement, for default=TEXT */
+}
+
+/* newValueMapper returns a valueMapper which handles unmapped values as per
+policy, which is keep, to leave them as-is, empty, to replace them with the
+empty string, error, to fail, or default=TEXT, to replace them with TEXT. */
+func newValueMapper(policy string) (*valueMapper, error) {
+	m := &valueMapper{maps: make(map[int]map[string]string)}
+	switch {
+	case "keep" == policy, "empty" == policy, "error" == policy:
+		m.missing = policy
+	case strings.HasPrefix(policy, "default="):
+		m.missing = "default"
+		m.def = strings.TrimPrefix(policy, "default=")
+	default:
+		return nil, fmt.Errorf("unknown policy %q", policy)
+	}
+	return m, nil
+}
+
+/* add parses a specification of the form COL=FILE, where COL is col:N or cN,
+and reads the mapping from FILE.  The first field of each record in FILE is
+a value and the second is what it's replaced with. */
+func (m *valueMapper) add(spec string) error {
+	c, f, ok := strings.Cut(spec, "=")
+	if !ok {
+		return errors.New("missing =")
+	}
+	cols, err := csvcol.ParseGroupKey(c)
+	if nil != err {
+		return err
+	}
+	if 1 != len(cols) {
+		return errors.New("only one column may be given")
+	}
+	fp, err := openFile(f)
+	if nil != err {
+		return err
+	}
+	defer fp.Close()
+	mp := make(map[string]string)
+	r := csv.NewReader(fp)
+	r.FieldsPerRecord = -1
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if nil != err {
+			return fmt.Errorf("reading %v: %w", f, err)
+		}
+		if 2 > len(rec) {
+			line, _ := r.FieldPos(0)
+			return fmt.Errorf("%v: line %v: need two fields", f, line)
+		}
+		mp[rec[0]] = rec[1]
+	}
+	debug("Read %v mappings for column %v from %v",
+		len(mp), cols[0], f)
+	m.maps[cols[0]] = mp
+	return nil
+}
+
+/* apply translates the fields of orec, selected by sel.  An error is
+returned for an unmapped value if the policy is error. */
+func (m *valueMapper) apply(orec []string, sel *csvcol.Selector) error {
+	for i, f := range orec {
+		c := sel.InputColumn(i)
+		mp, ok := m.maps[c]
+		if !ok {
+			continue
+		}
+		if v, ok := mp[f]; ok {
+			orec[i] = v
+			continue
+		}
+		switch m.missing {
+		case "empty":
+			orec[i] = ""
+		case "default":
+			orec[i] = m.def
+		case "error":
+			return fmt.Errorf("no mapping for %q in column %v",
+				f, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ * mapping_test.go
+ * Tests for mapping.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestMap(t *testing.T) {
+	dir := t.TempDir()
+	codes := writeTestFile(t, dir, "codes.csv", "01,Active\n02,Closed\n")
+	kinds := writeTestFile(t, dir, "kinds.csv", "a,Apple\nb,\"Big, Bad\"\n")
+	in := "status,id,kind\n01,1,a\n02,2,b\n03,3,c\n"
+	runOutputTests(t, []outputTest{{
+		name:  "keep",
+		stdin: in,
+		args:  []string{"-header", "-map", "col:1=" + codes},
+		want: "status,id,kind\n" +
+			"Active,1,a\n" +
+			"Closed,2,b\n" +
+			"03,3,c\n",
+	}, {
+		name:  "empty",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-map", "c1=" + codes,
+			"-map-missing", "empty",
+		},
+		want: "status,id,kind\nActive,1,a\nClosed,2,b\n,3,c\n",
+	}, {
+		name:  "default",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-map", "c1=" + codes,
+			"-map-missing", "default=Unknown",
+		},
+		want: "status,id,kind\n" +
+			"Active,1,a\n" +
+			"Closed,2,b\n" +
+			"Unknown,3,c\n",
+	}, {
+		name:  "reordered_columns",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-ordered",
+			"-cols", "3,1",
+			"-map", "c1=" + codes,
+			"-map", "c3=" + kinds,
+		},
+		want: "kind,status\n" +
+			"Apple,Active\n" +
+			"\"Big, Bad\",Closed\n" +
+			"c,03\n",
+	}, {
+		name:  "error_all_mapped",
+		stdin: "status\n01\n02\n",
+		args: []string{
+			"-header",
+			"-map", "c1=" + codes,
+			"-map-missing", "error",
+		},
+		want: "status\nActive\nClosed\n",
+	}})
+
+	/* Unmapped values with -map-missing error and bad specs should fail */
+	bad := writeTestFile(t, dir, "bad.csv", "01,Active\n02\n")
+	for _, args := range [][]string{
+		{"-map", "c1=" + codes, "-map-missing", "error"},
+		{"-map", "c1=" + codes, "-map-missing", "nope"},
+		{"-map", codes},
+		{"-map", "c1,c2=" + codes},
+		{"-map", "c1=" + bad},
+		{"-map", "c1=" + codes + ".nope"},
+	} {
+		if res := runCSVCol(t, in, args...); -32 != int8(res.code) {
+			t.Errorf(
+				"%q: exit code %d, want -32",
+				args,
+				int8(res.code),
+			)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * mask.go
+ * Mask or hash sensitive columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* maskValue is what masked values are replaced with */
+const maskValue = "***"
+
+/* hashAlgorithms are the hashes which may be given to -hash */
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+/* masker replaces the values of some columns with a mask or a hash */
+type masker map[int]func(string) string /* Input column -> replacer */
+
+/* addMask parses a comma-separated list of columns, each col:N or cN, and
+adds them to m to be masked. */
+func (m masker) addMask(spec string) error {
+	cols, err := csvcol.ParseGroupKey(spec)
+	if nil != err {
+		return err
+	}
+	for _, c := range cols {
+		m[c] = func(string) string { return maskValue }
+	}
+	return nil
+}
+
+/* addHash parses a specification of the form COL:ALG[:SALT], where COL is
+col:N or cN and ALG is one of hashAlgorithms, and adds it to m.  Values are
+replaced with the hex-encoded hash of the salt followed by the value. */
+func (m masker) addHash(spec string) error {
+	c, rest, ok := strings.Cut(spec, ":")
+	if "col" == strings.TrimSpace(c) { /* col:N */
+		var n string
+		n, rest, ok = strings.Cut(rest, ":")
+		c = "col:" + n
+	}
+	if !ok {
+		return fmt.Errorf("missing algorithm")
+	}
+	cols, err := csvcol.ParseGroupKey(c)
+	if nil != err {
+		return err
+	}
+	if 1 != len(cols) {
+		return fmt.Errorf("only one column may be given")
+	}
+	alg, salt, _ := strings.Cut(rest, ":")
+	newHash, ok := hashAlgorithms[strings.ToLower(alg)]
+	if !ok {
+		return fmt.Errorf("unknown algorithm %q", alg)
+	}
+	h := newHash()
+	m[cols[0]] = func(v string) string {
+		h.Reset()
+		h.Write([]byte(salt))
+		h.Write([]byte(v))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+	return nil
+}
+
+/* apply replaces the fields of orec, selected by sel, which are to be masked
+or hashed.  Empty fields are left empty. */
+func (m masker) apply(orec []string, sel *csvcol.Selector) {
+	for i, f := range orec {
+		r, ok := m[sel.InputColumn(i)]
+		if !ok || "" == f {
+			continue
+		}
+		orec[i] = r(f)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * mask_test.go
+ * Tests for mask.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestMask(t *testing.T) {
+	/* Hashes of 42, salty42, and bob@x */
+	const (
+		hash42 = "73475cb40a568e8da8a045ced110137e" +
+			"159f890ac4da883b6b17dc651b3a8049"
+		hashSalty42 = "32448cc0a5572f93694e6d48e1883867" +
+			"051c0c47eb8ef00eda3a7ba2868c2d85"
+		hashBob = "73746cb348f29bfb7046f9ceafa1fa73" +
+			"9cdebe97bca4dcf0818254f68143b92f" +
+			"aadf2e9970f403f8ccd03f311ca89ff3" +
+			"dc1d3ba154a4606a5f0909d07e0cd9f9"
+	)
+	in := "id,email,ssn\n42,bob@x,123\n,,\n"
+	runOutputTests(t, []outputTest{{
+		name:  "mask",
+		stdin: in,
+		args:  []string{"-header", "-mask", "c2,col:3"},
+		want:  "id,email,ssn\n42,***,***\n,,\n",
+	}, {
+		name:  "hash",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-hash", "c1:sha256",
+			"-hash", "col:2:SHA512",
+		},
+		want: "id,email,ssn\n" +
+			hash42 + "," + hashBob + ",123\n" +
+			",,\n",
+	}, {
+		name:  "salt",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "1",
+			"-hash", "c1:sha256:salty",
+		},
+		want: "id\n" + hashSalty42 + "\n\n",
+	}, {
+		name:  "after_selection",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "1",
+			"-mask", "c2",
+			"-where", `c2 == "bob@x"`,
+		},
+		want: "id\n42\n",
+	}})
+	for _, args := range [][]string{
+		{"-mask", "x"},
+		{"-hash", "c1"},
+		{"-hash", "c1:md5"},
+		{"-hash", "c1,c2:sha256"},
+		{"-hash", "col:x:sha256"},
+	} {
+		if res := runCSVCol(t, in, args...); -42 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
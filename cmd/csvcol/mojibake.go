@@ -0,0 +1,153 @@
+/*
+ * mojibake.go
+ * Fixing mojibake and smart quotes
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* cp1252High maps the characters Windows-1252 has for bytes 0x80-0x9f to
+their bytes.  0x81, 0x8d, 0x8f, 0x90, and 0x9d aren't in Windows-1252, and
+end up as the same control characters they'd be in Latin-1. */
+var cp1252High = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86,
+	'‡': 0x87, 'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c,
+	'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95,
+	'–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+/* textFixes replaces smart quotes and unusual spaces with their plain
+equivalents */
+var textFixes = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+	"\u00a0", " ", "\u2007", " ", "\u202f", " ", /* Non-breaking */
+)
+
+/* textFixer fixes UTF-8 which was decoded as Windows-1252 and re-encoded,
+smart quotes, and non-breaking spaces in some or all of the fields of a
+record, and counts how many fields it changed. */
+type textFixer struct {
+	cols  map[int]bool /* 1-indexed, or nil for all */
+	fixed int          /* Fields changed */
+}
+
+/* newTextFixer returns a textFixer which fixes the columns in cols, a list
+of columns as for -group-by, or all columns if cols is empty. */
+func newTextFixer(cols string) (*textFixer, error) {
+	t := &textFixer{}
+	if "" == cols {
+		return t, nil
+	}
+	cs, err := csvcol.ParseGroupKey(cols)
+	if nil != err {
+		return nil, err
+	}
+	t.cols = make(map[int]bool)
+	for _, c := range cs {
+		t.cols[c] = true
+	}
+	return t, nil
+}
+
+/* apply fixes the fields of record in place */
+func (t *textFixer) apply(record []string) {
+	for i, f := range record {
+		if nil != t.cols && !t.cols[i+1] {
+			continue
+		}
+		if n := textFixes.Replace(fixMojibake(f)); n != f {
+			record[i] = n
+			t.fixed++
+		}
+	}
+}
+
+/* fixMojibake replaces sequences of characters in s which are the bytes of
+a UTF-8 character decoded as Windows-1252, such as â€™ for ’, with the
+character.  Text which has been through this more than once is fixed as
+well. */
+func fixMojibake(s string) string {
+	for {
+		f := fixMojibakeOnce(s)
+		if f == s {
+			return f
+		}
+		s = f
+	}
+}
+
+/* fixMojibakeOnce undoes one round of decoding UTF-8 as Windows-1252 */
+func fixMojibakeOnce(s string) string {
+	rs := []rune(s)
+	var sb strings.Builder
+	for i := 0; i < len(rs); i++ {
+		/* Might be the start of a UTF-8 character */
+		lead, ok := cp1252Byte(rs[i])
+		n := 0
+		switch {
+		case !ok:
+		case 0xc2 <= lead && 0xdf >= lead:
+			n = 2
+		case 0xe0 <= lead && 0xef >= lead:
+			n = 3
+		case 0xf0 <= lead && 0xf4 >= lead:
+			n = 4
+		}
+		if 0 == n || len(rs) < i+n {
+			sb.WriteRune(rs[i])
+			continue
+		}
+		/* Make sure the rest are continuation bytes */
+		b := []byte{lead}
+		for _, r := range rs[i+1 : i+n] {
+			c, ok := cp1252Byte(r)
+			if !ok || 0x80 > c || 0xbf < c {
+				break
+			}
+			b = append(b, c)
+		}
+		if r, size := utf8.DecodeRune(b); n != len(b) ||
+			utf8.RuneError == r || n != size {
+			sb.WriteRune(rs[i])
+			continue
+		}
+		sb.Write(b)
+		i += n - 1
+	}
+	return sb.String()
+}
+
+/* cp1252Byte returns the Windows-1252 byte for r, if it has one.  Characters
+in U+0080-U+00FF are taken to be Latin-1. */
+func cp1252Byte(r rune) (byte, bool) {
+	if 0x80 <= r && 0xff >= r {
+		return byte(r), true
+	}
+	b, ok := cp1252High[r]
+	return b, ok
+}
@@ -0,0 +1,141 @@
+/*
+ * mojibake_test.go
+ * Tests for mojibake.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+/* toMojibake returns the bytes of s decoded as Windows-1252 */
+func toMojibake(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		r := rune(b)
+		for k, v := range cp1252High {
+			if v == b {
+				r = k
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func TestFixMojibake(t *testing.T) {
+	for _, s := range []string{
+		"plain",
+		"it’s",
+		"café",
+		"naïve Über",
+		"“quoted” — dash…",
+		"€5 ✓ 😀",
+	} {
+		once := toMojibake(s)
+		if got := fixMojibake(once); got != s {
+			t.Errorf("%q (%q): got %q", s, once, got)
+		}
+		twice := toMojibake(once)
+		if got := fixMojibake(twice); got != s {
+			t.Errorf("%q (%q): got %q", s, twice, got)
+		}
+	}
+	for s, want := range map[string]string{
+		"donâ€™t":  "don’t",
+		"Ã©":       "é",
+		"Ã":        "Ã",
+		"Ã ":       "Ã ",
+		"â€":       "â€",
+		"Â£5":      "£5",
+		"Ãœber":    "Über",
+		"naïve":    "naïve",
+		"Ã¼ and Ã": "ü and Ã",
+	} {
+		if got := fixMojibake(s); got != want {
+			t.Errorf("%q: got %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestTextFixer(t *testing.T) {
+	all, err := newTextFixer("")
+	if nil != err {
+		t.Fatalf("newTextFixer: %v", err)
+	}
+	rec := []string{
+		"donâ€™t",
+		"“hi”",
+		"a b",
+		"fine",
+		"‘x’ Ã©",
+	}
+	all.apply(rec)
+	want := []string{"don't", `"hi"`, "a b", "fine", "'x' é"}
+	if !slices.Equal(rec, want) {
+		t.Errorf("All columns: got %q, want %q", rec, want)
+	}
+	if 4 != all.fixed {
+		t.Errorf("All columns: fixed %d, want 4", all.fixed)
+	}
+
+	some, err := newTextFixer("c2,col:3")
+	if nil != err {
+		t.Fatalf("newTextFixer: %v", err)
+	}
+	rec = []string{"“a”", "“b”", "“c”"}
+	some.apply(rec)
+	want = []string{"“a”", `"b"`, `"c"`}
+	if !slices.Equal(rec, want) {
+		t.Errorf("Some columns: got %q, want %q", rec, want)
+	}
+	if 2 != some.fixed {
+		t.Errorf("Some columns: fixed %d, want 2", some.fixed)
+	}
+
+	if _, err := newTextFixer("x"); nil == err {
+		t.Errorf("Invalid columns didn't fail")
+	}
+}
+
+func TestFixText(t *testing.T) {
+	in := "name,note\nAnn,donâ€™t\nBob,ok\n"
+	res := runCSVCol(t, in, "-fix-text", "-where", "c2 == \"don't\"")
+	if 0 != res.code {
+		t.Fatalf("Failed: %s", res.stderr)
+	}
+	if want := "Ann,don't\n"; want != res.stdout {
+		t.Errorf("Output:\ngot:\n%s\nwant:\n%s", res.stdout, want)
+	}
+	if !strings.Contains(res.stderr, "Fixed text in 1 field(s)") {
+		t.Errorf("Count not reported:\n%s", res.stderr)
+	}
+	for _, args := range [][]string{
+		{"-fix-text-cols", "c1"},
+		{"-fix-text", "-fix-text-cols", "nope"},
+	} {
+		if res := runCSVCol(t, in, args...); -37 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * normalize.go
+ * Unicode normalization of fields
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/magisterquis/csvcol"
+	"golang.org/x/text/unicode/norm"
+)
+
+/* normalizer normalizes the Unicode in some or all of the fields of a
+record, so that visually-identical strings compare equal. */
+type normalizer struct {
+	form  *norm.Form
+	strip bool         /* Remove invisible characters */
+	cols  map[int]bool /* 1-indexed, or nil for all */
+}
+
+/* normForms are the names of the normalization forms */
+var normForms = map[string]norm.Form{
+	"nfc":  norm.NFC,
+	"nfd":  norm.NFD,
+	"nfkc": norm.NFKC,
+	"nfkd": norm.NFKD,
+}
+
+/* newNormalizer returns a normalizer which normalizes to the named form and
+optionally strips invisible characters from the columns in cols, a list of
+columns as for -group-by, or all columns if cols is empty.  If form is empty
+and strip is false, newNormalizer returns nil. */
+func newNormalizer(form string, strip bool, cols string) (*normalizer, error) {
+	if "" == form && !strip {
+		if "" != cols {
+			return nil, fmt.Errorf("columns given but not " +
+				"-normalize or -strip-invisible")
+		}
+		return nil, nil
+	}
+	n := &normalizer{strip: strip}
+	if "" != form {
+		f, ok := normForms[strings.ToLower(form)]
+		if !ok {
+			return nil, fmt.Errorf("unknown form %q", form)
+		}
+		n.form = &f
+	}
+	if "" != cols {
+		cs, err := csvcol.ParseGroupKey(cols)
+		if nil != err {
+			return nil, err
+		}
+		n.cols = make(map[int]bool)
+		for _, c := range cs {
+			n.cols[c] = true
+		}
+	}
+	return n, nil
+}
+
+/* apply normalizes the fields of record in place */
+func (n *normalizer) apply(record []string) {
+	for i, f := range record {
+		if nil != n.cols && !n.cols[i+1] {
+			continue
+		}
+		if n.strip {
+			f = strings.Map(stripInvisible, f)
+		}
+		if nil != n.form {
+			f = n.form.String(f)
+		}
+		record[i] = f
+	}
+}
+
+/* stripInvisible returns -1 for control and format characters, which
+includes zero-width spaces and joiners, byte order marks, and directional
+marks, and r otherwise. */
+func stripInvisible(r rune) rune {
+	if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+		return -1
+	}
+	return r
+}
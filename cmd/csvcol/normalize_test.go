@@ -0,0 +1,136 @@
+/*
+ * normalize_test.go
+ * Tests for normalize.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizer(t *testing.T) {
+	const (
+		composed   = "caf\u00e9"
+		decomposed = "cafe\u0301"
+		zwsp       = "ca\u200bf\u00e9\u200d"
+		ligature   = "\ufb01le"
+	)
+	for _, c := range []struct {
+		name  string
+		form  string
+		strip bool
+		cols  string
+		in    []string
+		want  []string
+	}{{
+		name: "nfc",
+		form: "nfc",
+		in:   []string{decomposed, composed, ligature},
+		want: []string{composed, composed, ligature},
+	}, {
+		name: "nfd",
+		form: "NFD",
+		in:   []string{composed},
+		want: []string{decomposed},
+	}, {
+		name: "nfkc",
+		form: "nfkc",
+		in:   []string{decomposed, ligature},
+		want: []string{composed, "file"},
+	}, {
+		name:  "strip",
+		strip: true,
+		in:    []string{zwsp, "\ufeffa\tb\r\nc\u202e"},
+		want:  []string{composed, "abc"},
+	}, {
+		name:  "strip_and_nfc",
+		form:  "nfc",
+		strip: true,
+		in:    []string{"cafe\u200b\u0301"},
+		want:  []string{composed},
+	}, {
+		name: "columns",
+		form: "nfc",
+		cols: "c2,col:4",
+		in:   []string{decomposed, decomposed, decomposed},
+		want: []string{decomposed, composed, decomposed},
+	}} {
+		n, err := newNormalizer(c.form, c.strip, c.cols)
+		if nil != err {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		got := slices.Clone(c.in)
+		n.apply(got)
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	/* Nothing to do and errors */
+	if n, err := newNormalizer("", false, ""); nil != n || nil != err {
+		t.Errorf("No normalization: got %v, %v", n, err)
+	}
+	for _, c := range [][2]string{
+		{"nfz", ""},
+		{"nfc", "x"},
+		{"", "c1"},
+	} {
+		if _, err := newNormalizer(c[0], false, c[1]); nil == err {
+			t.Errorf(
+				"newNormalizer(%q, %q) didn't fail",
+				c[0],
+				c[1],
+			)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	in := "name,id\ncafe\u0301,1\nca\u200bf\u00e9,2\n"
+	runOutputTests(t, []outputTest{{
+		name:  "where",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-normalize", "nfc",
+			"-strip-invisible",
+			"-where", "c1 == \"caf\u00e9\"",
+		},
+		want: "name,id\ncaf\u00e9,1\ncaf\u00e9,2\n",
+	}, {
+		name:  "unnormalized_column",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-normalize", "nfc",
+			"-strip-invisible",
+			"-normalize-cols", "c2",
+			"-where", "c1 == \"caf\u00e9\"",
+		},
+		want: "name,id\n",
+	}})
+	res := runCSVCol(t, in, "-normalize", "nope")
+	if -35 != int8(res.code) {
+		t.Errorf("-normalize nope exit code %d", int8(res.code))
+	}
+}
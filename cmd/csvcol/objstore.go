@@ -0,0 +1,253 @@
+/*
+ * objstore.go
+ * Reading objects from S3 and GCS
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+/* Requests are made with net/http rather than the SDKs, to avoid the
+dependencies.  Only a signed GET is needed, which is little enough code to
+check against AWS's published test vectors in objstore_test.go. */
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* splitObjectURL splits a URL of the form scheme://bucket/key into the
+bucket and key */
+func splitObjectURL(u, scheme string) (bucket, key string, err error) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(u, scheme), "/")
+	if "" == bucket || "" == key {
+		return "", "", fmt.Errorf("need %sBUCKET/KEY", scheme)
+	}
+	return bucket, key, nil
+}
+
+/* escapePath percent-encodes everything in p but unreserved characters and
+slashes, as AWS expects. */
+func escapePath(p string) string {
+	var sb strings.Builder
+	for _, c := range []byte(p) {
+		switch {
+		case 'A' <= c && 'Z' >= c, 'a' <= c && 'z' >= c,
+			'0' <= c && '9' >= c,
+			0 <= strings.IndexByte("-._~/", c):
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+/* s3Request makes a request for an S3 object, given as s3://BUCKET/KEY.
+Credentials are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+AWS_SESSION_TOKEN; without them, the request is made anonymously.  The region
+is taken from AWS_REGION or AWS_DEFAULT_REGION and defaults to us-east-1.  If
+AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL is set, it's used with path-style
+requests, for S3-compatible services. */
+func s3Request(u string) (*http.Request, error) {
+	bucket, key, err := splitObjectURL(u, "s3://")
+	if nil != err {
+		return nil, err
+	}
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if "" == region {
+		region = "us-east-1"
+	}
+	ou := fmt.Sprintf(
+		"https://%s.s3.%s.amazonaws.com/%s",
+		bucket,
+		region,
+		escapePath(key),
+	)
+	if e := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); "" != e {
+		ou = strings.TrimSuffix(e, "/") + "/" + escapePath(bucket) +
+			"/" + escapePath(key)
+	}
+	req, err := http.NewRequest(http.MethodGet, ou, nil)
+	if nil != err {
+		return nil, err
+	}
+	id, secret := os.Getenv("AWS_ACCESS_KEY_ID"),
+		os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if "" == id || "" == secret {
+		debug("No AWS credentials, requesting %v anonymously", u)
+		return req, nil
+	}
+	signS3Request(req, region, id, secret, os.Getenv("AWS_SESSION_TOKEN"))
+	return req, nil
+}
+
+/* signS3Request signs req with AWS Signature Version 4.  The payload isn't
+signed, as a GET has none. */
+func signS3Request(req *http.Request, region, id, secret, token string) {
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	signV4(
+		req,
+		time.Now(),
+		region,
+		"s3",
+		unsignedPayload,
+		id,
+		secret,
+		token,
+	)
+}
+
+/* unsignedPayload is used in place of the payload's hash to not sign it */
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+/* signV4 signs req for the given region and service with AWS Signature
+Version 4, as of now.  Payload is the hex-encoded SHA256 hash of the payload,
+or unsignedPayload.  All of req's headers are signed. */
+func signV4(
+	req *http.Request,
+	now time.Time,
+	region string,
+	service string,
+	payload string,
+	id string,
+	secret string,
+	token string,
+) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if "" != token {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	/* Canonical headers, sorted, host included */
+	hs := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		hs[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(hs))
+	for k := range hs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var ch strings.Builder
+	for _, k := range names {
+		fmt.Fprintf(&ch, "%s:%s\n", k, hs[k])
+	}
+	signed := strings.Join(names, ";")
+
+	/* What gets signed */
+	creq := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		ch.String(),
+		signed,
+		payload,
+	}, "\n")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	sum := sha256.Sum256([]byte(creq))
+	sts := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" +
+		hex.EncodeToString(sum[:])
+
+	/* Key derived from the secret */
+	k := []byte("AWS4" + secret)
+	for _, s := range []string{
+		date,
+		region,
+		service,
+		"aws4_request",
+		sts,
+	} {
+		m := hmac.New(sha256.New, k)
+		m.Write([]byte(s))
+		k = m.Sum(nil)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, "+
+			"Signature=%x",
+		id,
+		scope,
+		signed,
+		k,
+	))
+}
+
+/* gcsRequest makes a request for a GCS object, given as gs://BUCKET/OBJECT.
+An access token is taken from GOOGLE_OAUTH_ACCESS_TOKEN or, failing that, from
+gcloud auth print-access-token if gcloud is in the PATH; without one, the
+request is made anonymously.  If STORAGE_EMULATOR_HOST is set, it's used
+instead of storage.googleapis.com. */
+func gcsRequest(u string) (*http.Request, error) {
+	bucket, object, err := splitObjectURL(u, "gs://")
+	if nil != err {
+		return nil, err
+	}
+	host := "https://storage.googleapis.com"
+	if e := os.Getenv("STORAGE_EMULATOR_HOST"); "" != e {
+		host = strings.TrimSuffix(e, "/")
+		if !strings.Contains(host, "://") {
+			host = "http://" + host
+		}
+	}
+	req, err := http.NewRequest(
+		http.MethodGet,
+		host+"/"+escapePath(bucket)+"/"+escapePath(object),
+		nil,
+	)
+	if nil != err {
+		return nil, err
+	}
+	tok := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if _, err := exec.LookPath("gcloud"); "" == tok && nil == err &&
+		!sandboxed {
+		o, err := exec.Command(
+			"gcloud", "auth", "print-access-token",
+		).Output()
+		if nil != err {
+			verbose("Unable to get GCS access token: %v", err)
+		}
+		tok = strings.TrimSpace(string(o))
+	}
+	if "" == tok {
+		debug("No GCS access token, requesting %v anonymously", u)
+		return req, nil
+	}
+	req.Header.Set("Authorization", "Bearer "+tok)
+	return req, nil
+}
+
+/* firstEnv returns the value of the first of the environment variables
+named ns which is set and not empty */
+func firstEnv(ns ...string) string {
+	for _, n := range ns {
+		if v := os.Getenv(n); "" != v {
+			return v
+		}
+	}
+	return ""
+}
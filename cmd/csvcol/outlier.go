@@ -0,0 +1,182 @@
+/*
+ * outlier.go
+ * Flagging outlying values
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* outlierStage is a stage which holds all of its rows and then, knowing the
+distribution of the values in a column, passes them on with an added column
+saying whether each is an outlier or passes on only the outliers or only the
+others.  The header is passed on immediately, with outlierColumn added if
+flagging. */
+type outlierStage struct {
+	col       int     /* 1-indexed input column */
+	zscore    float64 /* Z-score threshold, or 0 to use iqr */
+	iqr       float64 /* Multiple of the interquartile range */
+	mode      string  /* flag, drop, or only */
+	rows      []stageRow
+	vals      []float64 /* Values of rows' columns */
+	isNumeric []bool
+}
+
+/* outlierColumn is the name of the column added when flagging outliers */
+const outlierColumn = "outlier"
+
+/* parseOutlierStage parses an outlier specification of the form
+COL,METHOD=N[,MODE], where COL is col:N or cN, METHOD is zscore or iqr, and
+MODE is flag (the default), drop, or only. */
+func parseOutlierStage(s string) (*outlierStage, error) {
+	o := &outlierStage{mode: "flag"}
+	parts := strings.Split(s, ",")
+	if 2 > len(parts) || 3 < len(parts) {
+		return nil, fmt.Errorf("expected COL,METHOD=N[,MODE]")
+	}
+	cols, err := csvcol.ParseGroupKey(parts[0])
+	if nil != err {
+		return nil, err
+	}
+	o.col = cols[0]
+	method, n, ok := strings.Cut(strings.TrimSpace(parts[1]), "=")
+	if !ok {
+		return nil, fmt.Errorf("missing = after %q", method)
+	}
+	t, err := strconv.ParseFloat(n, 64)
+	if nil != err || !(0 < t) {
+		return nil, fmt.Errorf("invalid threshold %q", n)
+	}
+	switch method {
+	case "zscore":
+		o.zscore = t
+	case "iqr":
+		o.iqr = t
+	default:
+		return nil, fmt.Errorf("unknown method %q", method)
+	}
+	if 3 == len(parts) {
+		o.mode = strings.TrimSpace(parts[2])
+	}
+	switch o.mode {
+	case "flag", "drop", "only":
+	default:
+		return nil, fmt.Errorf("unknown mode %q", o.mode)
+	}
+	return o, nil
+}
+
+/* flagging returns true if o adds a column */
+func (o *outlierStage) flagging() bool { return "flag" == o.mode }
+
+/* push holds on to r until we've seen all the rows */
+func (o *outlierStage) push(r stageRow, next func(stageRow)) {
+	if r.header {
+		if o.flagging() {
+			r.out = append(r.out[:len(r.out):len(r.out)],
+				outlierColumn)
+		}
+		next(r)
+		return
+	}
+	var f string
+	if o.col <= len(r.in) {
+		f = strings.TrimSpace(r.in[o.col-1])
+	}
+	v, err := strconv.ParseFloat(f, 64)
+	ok := nil == err && !math.IsNaN(v) && !math.IsInf(v, 0)
+	o.rows = append(o.rows, r)
+	o.vals = append(o.vals, v)
+	o.isNumeric = append(o.isNumeric, ok)
+}
+
+/* flush works out which held rows are outliers and passes them on as per
+o.mode.  Values which aren't numbers are never outliers. */
+func (o *outlierStage) flush(next func(stageRow)) {
+	lo, hi := o.limits()
+	debug("Outliers are outside [%v, %v]", lo, hi)
+	for i, r := range o.rows {
+		out := o.isNumeric[i] && (o.vals[i] < lo || o.vals[i] > hi)
+		switch o.mode {
+		case "flag":
+			r.out = append(r.out[:len(r.out):len(r.out)],
+				strconv.FormatBool(out))
+		case "drop":
+			if out {
+				continue
+			}
+		case "only":
+			if !out {
+				continue
+			}
+		}
+		next(r)
+	}
+	o.rows, o.vals, o.isNumeric = nil, nil, nil
+}
+
+/* limits returns the smallest and largest values which aren't outliers */
+func (o *outlierStage) limits() (lo, hi float64) {
+	var vs []float64
+	for i, v := range o.vals {
+		if o.isNumeric[i] {
+			vs = append(vs, v)
+		}
+	}
+	if 0 == len(vs) {
+		return math.Inf(-1), math.Inf(1)
+	}
+
+	/* Z-scores need the mean and standard deviation */
+	if 0 != o.zscore {
+		var mean, m2 float64
+		for i, v := range vs {
+			d := v - mean
+			mean += d / float64(i+1)
+			m2 += d * (v - mean)
+		}
+		sd := math.Sqrt(m2 / float64(len(vs)))
+		return mean - o.zscore*sd, mean + o.zscore*sd
+	}
+
+	/* IQR needs the quartiles */
+	sort.Float64s(vs)
+	q1, q3 := quantile(vs, 0.25), quantile(vs, 0.75)
+	return q1 - o.iqr*(q3-q1), q3 + o.iqr*(q3-q1)
+}
+
+/* quantile returns the qth quantile of the sorted values vs, interpolating
+between values as needed. */
+func quantile(vs []float64, q float64) float64 {
+	p := q * float64(len(vs)-1)
+	i := int(p)
+	if i+1 >= len(vs) {
+		return vs[len(vs)-1]
+	}
+	return vs[i] + (p-float64(i))*(vs[i+1]-vs[i])
+}
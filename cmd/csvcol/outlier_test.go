@@ -0,0 +1,125 @@
+/*
+ * outlier_test.go
+ * Tests for outlier.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseOutlierStage(t *testing.T) {
+	for s, want := range map[string]outlierStage{
+		"col:5,zscore=4":    {col: 5, zscore: 4, mode: "flag"},
+		"c2, iqr=1.5":       {col: 2, iqr: 1.5, mode: "flag"},
+		"c1,iqr=3,drop":     {col: 1, iqr: 3, mode: "drop"},
+		"c3,zscore=2, only": {col: 3, zscore: 2, mode: "only"},
+	} {
+		got, err := parseOutlierStage(s)
+		if nil != err {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if want.col != got.col || want.zscore != got.zscore ||
+			want.iqr != got.iqr || want.mode != got.mode {
+			t.Errorf("%q: got %+v, want %+v", s, *got, want)
+		}
+	}
+	for _, s := range []string{
+		"c1",
+		"c1,zscore=4,flag,x",
+		"x,zscore=4",
+		"c1,zscore",
+		"c1,zscore=0",
+		"c1,zscore=-1",
+		"c1,zscore=x",
+		"c1,mad=3",
+		"c1,iqr=1.5,keep",
+	} {
+		if _, err := parseOutlierStage(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	vs := []float64{1, 2, 3, 4, 5}
+	for q, want := range map[float64]float64{
+		0:     1,
+		0.25:  2,
+		0.5:   3,
+		0.75:  4,
+		1:     5,
+		0.125: 1.5,
+	} {
+		if got := quantile(vs, q); 1e-12 < math.Abs(got-want) {
+			t.Errorf("%v: got %v, want %v", q, got, want)
+		}
+	}
+	if got := quantile([]float64{7}, 0.75); 7 != got {
+		t.Errorf("Single value: got %v", got)
+	}
+}
+
+func TestOutliers(t *testing.T) {
+	in := "a,1\nb,1\nc,2\nd,100\ne,x\nf,-50\n"
+	runOutputTests(t, []outputTest{{
+		name:  "flag",
+		stdin: in,
+		args:  []string{"-flag-outliers", "c2,iqr=1.5"},
+		want: "a,1,false\nb,1,false\nc,2,false\nd,100,true\n" +
+			"e,x,false\nf,-50,true\n",
+	}, {
+		name:  "drop",
+		stdin: in,
+		args:  []string{"-flag-outliers", "c2,iqr=1.5,drop"},
+		want:  "a,1\nb,1\nc,2\ne,x\n",
+	}, {
+		name:  "only",
+		stdin: in,
+		args: []string{
+			"-flag-outliers", "c2,iqr=1.5,only",
+			"-cols", "1",
+		},
+		want: "d\nf\n",
+	}, {
+		name:  "zscore",
+		stdin: "a,1\nb,2\nc,3\nd,4\ne,5\nf,6\ng,7\nh,8\ni,9\nj,100\n",
+		args:  []string{"-flag-outliers", "c2,zscore=2,only"},
+		want:  "j,100\n",
+	}, {
+		name:  "zscore_too_high",
+		stdin: "a,1\nb,2\nc,3\nd,4\ne,5\nf,6\ng,7\nh,8\ni,9\nj,100\n",
+		args:  []string{"-flag-outliers", "c2,zscore=4,only"},
+		want:  "",
+	}, {
+		name:  "no_numbers",
+		stdin: "a,x\nb\n",
+		args:  []string{"-flag-outliers", "c2,zscore=1"},
+		want:  "a,x,false\nb,false\n",
+	}, {
+		name:  "header",
+		stdin: "name,v\n" + in,
+		args:  []string{"-header", "-flag-outliers", "c2,iqr=1.5,only"},
+		want:  "name,v\nd,100\nf,-50\n",
+	}})
+}
@@ -0,0 +1,718 @@
+/*
+ * output.go
+ * Output file handling
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* recordWriter writes output records.  WriteHeader writes the header, if
+there is one, before any records.  WriteLine writes a line of text as-is, e.g.
+to separate groups of records.  Close flushes everything, including anything
+which can't be written until all records have been seen, and returns any
+error; the underlying writer is left open. */
+type recordWriter interface {
+	WriteHeader(header []string) error
+	Write(record []string) error
+	WriteLine(line string) error
+	Flush()
+	Error() error
+	Close() error
+}
+
+/* newRecordWriter returns a recordWriter which writes to w in the format
+requested on the command line.  The selection is used to name columns. */
+func newRecordWriter(w io.Writer, sel *csvcol.Selector) recordWriter {
+	rw := newFormatWriter(w, sel)
+	if *gc.transpose {
+		return &transposeWriter{w: rw, max: gc.maxTranspose}
+	}
+	return rw
+}
+
+/* newFormatWriter returns a recordWriter which writes to w in the format
+requested on the command line, for newRecordWriter. */
+func newFormatWriter(w io.Writer, sel *csvcol.Selector) recordWriter {
+	/* The first record will be a header if we're looking up names */
+	header := *gc.header || "" != *gc.colnames || 0 != len(gc.colre)
+	switch {
+	case "" != *gc.sqlite:
+		return newSQLiteWriter(*gc.sqlite, *gc.sqliteTable, sel, header)
+	case nil != gc.tmpl:
+		return newTemplateWriter(w, sel, gc.tmpl, gc.tmplNewline)
+	case *gc.json:
+		return newJSONWriter(
+			w,
+			sel,
+			"json-array" == *gc.format,
+			*gc.jsonNested,
+		)
+	case "table" == *gc.format, "markdown" == *gc.format:
+		return newTableWriter(w, sel, "markdown" == *gc.format, header)
+	case "text-email" == *gc.format:
+		return newEmailWriter(
+			w,
+			sel,
+			header,
+			*gc.emailRows,
+			*gc.emailWidth,
+		)
+	case "xlsx" == *gc.format:
+		return newXLSXWriter(w, *gc.xlsxHeader && header)
+	case "sql" == *gc.format, "pgcopy" == *gc.format:
+		return newSQLWriter(
+			w,
+			*gc.sqliteTable,
+			sel,
+			"pgcopy" == *gc.format,
+			header,
+		)
+	}
+	return &csvWriter{Writer: newWriter(w), w: w}
+}
+
+/* csvWriter is a recordWriter which writes CSV */
+type csvWriter struct {
+	*csv.Writer
+	w io.Writer /* Underlying writer, for WriteLine */
+}
+
+/* WriteHeader writes the header like any other record */
+func (c *csvWriter) WriteHeader(header []string) error {
+	return c.Write(header)
+}
+
+/* WriteLine flushes buffered records and writes line to the underlying
+writer, followed by a newline. */
+func (c *csvWriter) WriteLine(line string) error {
+	c.Flush()
+	if err := c.Error(); nil != err {
+		return err
+	}
+	_, err := io.WriteString(c.w, line+"\n")
+	return err
+}
+
+/* Close flushes buffered output */
+func (c *csvWriter) Close() error {
+	c.Flush()
+	return c.Error()
+}
+
+/* jsonWriter writes records as JSON objects, one per line, optionally as
+elements of a single JSON array. */
+type jsonWriter struct {
+	w      *bufio.Writer
+	sel    *csvcol.Selector
+	array  bool     /* Write a JSON array */
+	nested bool     /* Dotted keys make nested objects */
+	n      int      /* Objects written */
+	names  []string /* Keys, from the header */
+	err    error
+}
+
+/* newJSONWriter returns a jsonWriter which writes to w and gets the names of
+columns from sel.  If array is true, the objects are written as a single JSON
+array.  If nested is true, keys with dots in them, like user.name, are split
+into nested objects. */
+func newJSONWriter(
+	w io.Writer,
+	sel *csvcol.Selector,
+	array bool,
+	nested bool,
+) *jsonWriter {
+	return &jsonWriter{
+		w:      bufio.NewWriter(w),
+		sel:    sel,
+		array:  array,
+		nested: nested,
+	}
+}
+
+/* WriteHeader notes the names in header, to be used as keys.  The header
+itself is not written. */
+func (j *jsonWriter) WriteHeader(header []string) error {
+	j.names = append([]string{}, header...)
+	return j.err
+}
+
+/* Write writes the record as a JSON object.  Keys are taken from the header,
+if there is one, or are of the form cN, for column N, if not. */
+func (j *jsonWriter) Write(record []string) error {
+	if nil != j.err {
+		return j.err
+	}
+	switch {
+	case !j.array:
+	case 0 == j.n:
+		j.w.WriteString("[\n")
+	default:
+		j.w.WriteString(",\n")
+	}
+	j.n++
+	names := make([]string, len(record))
+	for i := range record {
+		names[i] = headerName(j.names, j.sel, i, len(record))
+	}
+	root := &jsonNode{}
+	for i, f := range record {
+		root.add(names[i], f, j.nested && splittable(names[i], names))
+	}
+	j.writeNode(root)
+	if !j.array {
+		j.w.WriteByte('\n')
+	}
+	return j.err
+}
+
+/* splittable returns true if the key k may be split into nested objects, which
+it may not be if one of names is the start of it, as a is of a.b. */
+func splittable(k string, names []string) bool {
+	for _, n := range names {
+		if strings.HasPrefix(k, n+".") {
+			return false
+		}
+	}
+	return true
+}
+
+/* jsonNode is a JSON object or a string in an object.  Keys may be
+repeated. */
+type jsonNode struct {
+	keys  []string
+	kids  []*jsonNode
+	value string
+	leaf  bool /* Node is a string, not an object */
+}
+
+/* add adds the key k with value v to n.  If nested is true and k has dots in
+it, nested objects are made as needed.  If that's not possible, because a key
+would have to be both a string and an object, k is added as-is. */
+func (n *jsonNode) add(k, v string, nested bool) {
+	path := []string{k}
+	if nested {
+		path = strings.Split(k, ".")
+		if slices.Contains(path, "") {
+			path = []string{k}
+		}
+	}
+	if !n.addPath(path, v) {
+		n.keys = append(n.keys, k)
+		n.kids = append(n.kids, &jsonNode{value: v, leaf: true})
+	}
+}
+
+/* addPath adds v to n under the keys in path, and returns false if a key
+which should be an object is already a string, or the last key is already
+there. */
+func (n *jsonNode) addPath(path []string, v string) bool {
+	i := slices.Index(n.keys, path[0])
+	if 1 == len(path) {
+		if -1 != i {
+			return false
+		}
+		n.keys = append(n.keys, path[0])
+		n.kids = append(n.kids, &jsonNode{value: v, leaf: true})
+		return true
+	}
+	if -1 == i {
+		n.keys = append(n.keys, path[0])
+		n.kids = append(n.kids, &jsonNode{})
+		i = len(n.kids) - 1
+	} else if n.kids[i].leaf {
+		return false
+	}
+	return n.kids[i].addPath(path[1:], v)
+}
+
+/* writeNode writes n as JSON */
+func (j *jsonWriter) writeNode(n *jsonNode) {
+	if n.leaf {
+		j.writeString(n.value)
+		return
+	}
+	j.w.WriteByte('{')
+	for i, k := range n.keys {
+		if 0 != i {
+			j.w.WriteByte(',')
+		}
+		j.writeString(k)
+		j.w.WriteByte(':')
+		j.writeNode(n.kids[i])
+	}
+	j.w.WriteByte('}')
+}
+
+/* writeString writes s as a JSON string */
+func (j *jsonWriter) writeString(s string) {
+	b, err := json.Marshal(s)
+	if nil != err && nil == j.err {
+		j.err = err
+	}
+	j.w.Write(b)
+}
+
+/* WriteLine writes line followed by a newline.  Lines can't go in a JSON
+array, so are skipped. */
+func (j *jsonWriter) WriteLine(line string) error {
+	if nil != j.err {
+		return j.err
+	}
+	if j.array {
+		debug("Not writing line in JSON array: %q", line)
+		return nil
+	}
+	_, j.err = j.w.WriteString(line + "\n")
+	return j.err
+}
+
+/* Flush flushes buffered output */
+func (j *jsonWriter) Flush() {
+	if err := j.w.Flush(); nil != err && nil == j.err {
+		j.err = err
+	}
+}
+
+/* Error returns the first error encountered while writing or flushing */
+func (j *jsonWriter) Error() error { return j.err }
+
+/* Close ends the array, if we're writing one, and flushes buffered
+output */
+func (j *jsonWriter) Close() error {
+	switch {
+	case nil != j.err || !j.array:
+	case 0 == j.n:
+		j.w.WriteString("[]\n")
+	default:
+		j.w.WriteString("\n]\n")
+	}
+	j.Flush()
+	return j.err
+}
+
+/* Names of the columns added by -with-linenum and -with-filename */
+const (
+	linenumColumn  = "linenum"
+	filenameColumn = "filename"
+)
+
+/* addOrigin adds fields containing the row number row and file name f to
+record, as requested with -with-linenum and -with-filename.  The row number
+goes at the start.  The file name goes before it or, with -filename-last, at
+the end. */
+func addOrigin(record []string, row, f string) []string {
+	pre, post := originColumns(row, f)
+	if 0 == len(pre) && 0 == len(post) {
+		return record
+	}
+	r := make([]string, 0, len(pre)+len(record)+len(post))
+	r = append(r, pre...)
+	r = append(r, record...)
+	return append(r, post...)
+}
+
+/* originColumns returns the fields added to the start and end of each output
+record by addOrigin. */
+func originColumns(row, f string) (pre, post []string) {
+	if *gc.withFilename && !*gc.filenameLast {
+		pre = append(pre, f)
+	}
+	if *gc.withLinenum {
+		pre = append(pre, row)
+	}
+	if *gc.withFilename && *gc.filenameLast {
+		post = append(post, f)
+	}
+	return pre, post
+}
+
+/* columnName returns the name of the ith of n fields of an output record,
+which may have fields added by -with-linenum, -with-filename, and
+-flag-outliers. */
+func columnName(sel *csvcol.Selector, i, n int) string {
+	pre, post := originColumns(linenumColumn, filenameColumn)
+	if gc.flagOutliers {
+		post = append([]string{outlierColumn}, post...)
+	}
+	for i := len(gc.computed) - 1; 0 <= i; i-- {
+		post = append([]string{gc.computed[i].Name}, post...)
+	}
+	switch {
+	case i < len(pre):
+		return pre[i]
+	case i >= n-len(post):
+		return post[i-(n-len(post))]
+	}
+	return sel.ColumnName(i - len(pre))
+}
+
+/* headerName returns the name of the ith of n fields of an output record,
+from header if it has that many names, or from columnName if not. */
+func headerName(header []string, sel *csvcol.Selector, i, n int) string {
+	if i < len(header) {
+		return header[i]
+	}
+	return columnName(sel, i, n)
+}
+
+/* addComputed appends the -add-col columns computed from record to orec, or
+their names if header is true. */
+func addComputed(orec, record []string, header bool) []string {
+	for _, c := range gc.computed {
+		if header {
+			orec = append(orec, c.Name)
+		} else {
+			orec = append(orec, c.Value(record))
+		}
+	}
+	return orec
+}
+
+/* expandOutputTemplate works out the name of the output file for the input
+file in from the template t.  The following are replaced in t:
+
+{dir}  The directory containing in
+{base} The final element of in
+{name} The final element of in, without its extension
+{ext}  The extension of in, including the leading .
+
+The standard input is treated as a file named stdin in the current directory.
+*/
+func expandOutputTemplate(t, in string) string {
+	if "-" == in {
+		in = "stdin"
+	}
+	base := filepath.Base(in)
+	ext := filepath.Ext(base)
+	return strings.NewReplacer(
+		"{dir}", filepath.Dir(in),
+		"{base}", base,
+		"{name}", strings.TrimSuffix(base, ext),
+		"{ext}", ext,
+	).Replace(t)
+}
+
+/* isRelative returns true if the path p is relative to the current
+directory.  Unlike filepath.IsAbs, on Windows isRelative returns false for
+paths which start with a drive letter or a backslash, e.g. C:x.csv or
+\data\x.csv. */
+func isRelative(p string) bool {
+	return !filepath.IsAbs(p) && "" == filepath.VolumeName(p) &&
+		("" == p || !os.IsPathSeparator(p[0]))
+}
+
+/* perFileOutput is an output file made with -output-per-file.  If the output
+file is the same as the input file, output is written to a temporary file
+which replaces the input file when closed. */
+type perFileOutput struct {
+	*os.File
+	name string      /* Final name, if File is a temporary file */
+	orig os.FileInfo /* Original file's metadata */
+}
+
+/* openPerFileOutput creates the output file for the input file named in,
+which has already been opened as inf, as per -output-per-file, or in the
+bundle, with -bundle. */
+func openPerFileOutput(in string, inf *os.File) (io.WriteCloser, error) {
+	if nil != outBundle {
+		return outBundle.create(in)
+	}
+	name := expandOutputTemplate(*gc.outputPerFile, in)
+	/* If we're not about to clobber the input, life's easy */
+	ii, err := inf.Stat()
+	if nil != err {
+		ii = nil
+	}
+	oi, err := os.Stat(name)
+	if nil == ii || nil != err || !os.SameFile(ii, oi) {
+		verbose("Writing output for %v to %v", in, name)
+		f, err := os.Create(name)
+		if nil != err {
+			return nil, err
+		}
+		return &perFileOutput{File: f}, nil
+	}
+
+	/* Output is the input, write to a temporary file next to it */
+	f, err := os.CreateTemp(
+		filepath.Dir(name),
+		"."+filepath.Base(name)+".csvcol.*",
+	)
+	if nil != err {
+		return nil, err
+	}
+	verbose("Writing output for %v to %v, to replace %v", in, f.Name(),
+		name)
+	addTempOutput(f.Name())
+	return &perFileOutput{File: f, name: name, orig: ii}, nil
+}
+
+/* Close closes the output file.  If the output is a temporary file, the
+original file's permissions, owner (if possible), and, with -preserve-mtime,
+modification time are copied to it and it replaces the original file. */
+func (o *perFileOutput) Close() error {
+	if err := o.File.Close(); nil != err {
+		return err
+	}
+	if "" == o.name {
+		return nil
+	}
+	tmp := o.File.Name()
+	defer doneTempOutput(tmp)
+	err := o.copyMetadata(tmp)
+	if nil == err {
+		err = os.Rename(tmp, o.name)
+	}
+	if nil != err {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+/* copyMetadata copies the original file's metadata to the file named n */
+func (o *perFileOutput) copyMetadata(n string) error {
+	if err := os.Chmod(n, o.orig.Mode().Perm()); nil != err {
+		return fmt.Errorf("setting permissions: %v", err)
+	}
+	if err := chownLike(n, o.orig); nil != err {
+		return fmt.Errorf("setting owner: %v", err)
+	}
+	if *gc.preserveMtime {
+		mt := o.orig.ModTime()
+		if err := os.Chtimes(n, mt, mt); nil != err {
+			return fmt.Errorf("setting modification time: %v",
+				err)
+		}
+	}
+	return nil
+}
+
+/* output is where output goes when it's not going to a file per input
+file.  If a name was given, output is written to a temporary file which
+replaces the named file when closed. */
+type output struct {
+	io.Writer
+	f    *os.File     /* Temporary file, if we have one */
+	gz   *gzip.Writer /* Compressor, if we're compressing */
+	zip  *zip.Writer  /* Archive, if we're writing to a member */
+	name string       /* File to replace with f */
+}
+
+/* tempOutputs are the temporary output files which haven't yet replaced
+the files they're for, to be removed if we exit early. */
+var (
+	tempOutputs  = make(map[string]bool)
+	tempOutputsL sync.Mutex
+)
+
+/* addTempOutput notes that the file named n is a temporary output file */
+func addTempOutput(n string) {
+	tempOutputsL.Lock()
+	defer tempOutputsL.Unlock()
+	tempOutputs[n] = true
+}
+
+/* doneTempOutput notes that the file named n is no longer a temporary output
+file, having been renamed or removed. */
+func doneTempOutput(n string) {
+	tempOutputsL.Lock()
+	defer tempOutputsL.Unlock()
+	delete(tempOutputs, n)
+}
+
+/* removeTempOutputs removes the temporary output files */
+func removeTempOutputs() {
+	tempOutputsL.Lock()
+	defer tempOutputsL.Unlock()
+	for n := range tempOutputs {
+		debug("Removing %v", n)
+		os.Remove(n)
+		delete(tempOutputs, n)
+	}
+}
+
+/* splitZipOutput splits an output name of the form ARCHIVE::MEMBER into the
+name of a zip archive and the name of the member in the archive.  If there's
+no ::, member is empty. */
+func splitZipOutput(name string) (archive, member string) {
+	archive, member, _ = strings.Cut(name, "::")
+	return archive, member
+}
+
+/* openOutput returns an output which writes to the file named name, or to
+the standard output if name is empty.  If name is of the form ARCHIVE::MEMBER,
+output is written to the member of the zip archive, which is created if it
+doesn't exist.  Output is gzipped if compress is true or name ends in .gz. */
+func openOutput(name string, compress bool) (*output, error) {
+	archive, member := splitZipOutput(name)
+	if strings.Contains(name, "::") && ("" == archive || "" == member) {
+		return nil, fmt.Errorf("need both an archive and a member")
+	}
+	o := &output{Writer: stdout, name: archive}
+	if "" != archive {
+		/* Use the existing file's permissions, if there is one */
+		perm := os.FileMode(0644)
+		if fi, err := os.Stat(archive); nil == err {
+			perm = fi.Mode().Perm()
+		}
+		f, err := os.CreateTemp(
+			filepath.Dir(archive),
+			"."+filepath.Base(archive)+".csvcol.*",
+		)
+		if nil != err {
+			return nil, err
+		}
+		if err := f.Chmod(perm); nil != err {
+			f.Close()
+			os.Remove(f.Name())
+			return nil, err
+		}
+		verbose("Writing output to %v, to replace %v",
+			f.Name(), archive)
+		addTempOutput(f.Name())
+		o.f = f
+		o.Writer = f
+	}
+	if "" != member {
+		if err := o.openMember(member); nil != err {
+			o.f.Close()
+			os.Remove(o.f.Name())
+			doneTempOutput(o.f.Name())
+			return nil, err
+		}
+	}
+	if compress || strings.HasSuffix(name, ".gz") {
+		debug("Compressing output")
+		o.gz = gzip.NewWriter(o.Writer)
+		o.Writer = o.gz
+	}
+	return o, nil
+}
+
+/* openMember starts a zip archive in o.f with the members of the existing
+archive o.name, if there is one, except for a member named member, and then
+adds member, to which o will write. */
+func (o *output) openMember(member string) error {
+	o.zip = zip.NewWriter(o.f)
+
+	/* Copy the existing members */
+	zr, err := zip.OpenReader(o.name)
+	if nil == err {
+		defer zr.Close()
+		for _, zf := range zr.File {
+			if member == zf.Name {
+				debug("Replacing %v in %v", member, o.name)
+				continue
+			}
+			if err := o.zip.Copy(zf); nil != err {
+				return fmt.Errorf("copying %v from %v: %w",
+					zf.Name, o.name, err)
+			}
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("reading %v: %w", o.name, err)
+	}
+
+	/* Add our member, without a time if we're being deterministic */
+	h := &zip.FileHeader{Name: member, Method: zip.Deflate}
+	if !*gc.deterministic {
+		h.Modified = time.Now()
+	}
+	w, err := o.zip.CreateHeader(h)
+	if nil != err {
+		return fmt.Errorf("adding %v to %v: %w", member, o.name, err)
+	}
+	debug("Writing output to %v in %v", member, o.name)
+	o.Writer = w
+	return nil
+}
+
+/* Close finishes compression, if we're compressing, finishes the zip
+archive, if we're writing a member of one, and replaces the output file with
+the temporary file, if we have one.  The standard output is left open. */
+func (o *output) Close() error {
+	var err error
+	if nil != o.gz {
+		err = o.gz.Close()
+	}
+	if nil != o.zip {
+		if zerr := o.zip.Close(); nil == err {
+			err = zerr
+		}
+	}
+	if nil == o.f {
+		return err
+	}
+	if cerr := o.f.Close(); nil == err {
+		err = cerr
+	}
+	if nil == err {
+		err = os.Rename(o.f.Name(), o.name)
+	}
+	if nil != err {
+		os.Remove(o.f.Name())
+	}
+	doneTempOutput(o.f.Name())
+	return err
+}
+
+/* watermark returns a comment line noting that n rows have been output, for
+-watermark */
+func watermark(n int) string {
+	c := *gc.commentChar
+	if "" == c {
+		c = "#"
+	}
+	/* Add commas every three digits */
+	d := strconv.Itoa(n)
+	var b strings.Builder
+	for i, r := range d {
+		if 0 != i && 0 == (len(d)-i)%3 {
+			b.WriteRune(',')
+		}
+		b.WriteRune(r)
+	}
+	w := "rows"
+	if 1 == n {
+		w = "row"
+	}
+	return fmt.Sprintf("%s %s %s", c, b.String(), w)
+}
@@ -0,0 +1,496 @@
+/*
+ * output_test.go
+ * Tests for output.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestJSONLines(t *testing.T) {
+	in := "name,age\nal,3\n\"q\"\"t\",\n"
+	runOutputTests(t, []outputTest{{
+		name:  "no_header",
+		stdin: in,
+		args:  []string{"-json"},
+		want: `{"c1":"name","c2":"age"}` + "\n" +
+			`{"c1":"al","c2":"3"}` + "\n" +
+			`{"c1":"q\"t","c2":""}` + "\n",
+	}, {
+		name:  "header",
+		stdin: in,
+		args:  []string{"-json", "-header"},
+		want: `{"name":"al","age":"3"}` + "\n" +
+			`{"name":"q\"t","age":""}` + "\n",
+	}, {
+		name:  "colnames",
+		stdin: in,
+		args:  []string{"-json", "-colnames", "age"},
+		want:  `{"age":"3"}` + "\n" + `{"age":""}` + "\n",
+	}, {
+		name:  "cols",
+		stdin: in,
+		args:  []string{"-json", "-cols", "2", "-rows", "2"},
+		want:  `{"c2":"3"}` + "\n",
+	}, {
+		name:  "short_row",
+		stdin: "a,b,c\nd\n",
+		args:  []string{"-json", "-header"},
+		want:  `{"a":"d"}` + "\n",
+	}})
+}
+
+func TestJSONArray(t *testing.T) {
+	in := "name,age\nal,3\nbo,\n"
+	runOutputTests(t, []outputTest{{
+		name:  "header",
+		stdin: in,
+		args:  []string{"-format", "json-array", "-header"},
+		want: "[\n" +
+			`{"name":"al","age":"3"},` + "\n" +
+			`{"name":"bo","age":""}` + "\n" +
+			"]\n",
+	}, {
+		name:  "one",
+		stdin: in,
+		args: []string{
+			"-format", "json-array",
+			"-header",
+			"-rows", "1",
+		},
+		want: "[\n" + `{"name":"al","age":"3"}` + "\n]\n",
+	}, {
+		name:  "empty",
+		stdin: in,
+		args: []string{
+			"-format", "json-array",
+			"-header",
+			"-rows", "9",
+		},
+		want: "[]\n",
+	}, {
+		name:  "watermark",
+		stdin: in,
+		args: []string{
+			"-format", "json-array",
+			"-colnames", "age",
+			"-watermark", "1",
+		},
+		want: "[\n" + `{"age":"3"},` + "\n" + `{"age":""}` + "\n]\n",
+	}})
+
+	/* Should be actual JSON */
+	var v []map[string]string
+	out := mustRun(t, in, "-format", "json-array", "-header")
+	if err := json.Unmarshal([]byte(out), &v); nil != err {
+		t.Errorf("Invalid JSON: %v\n%s", err, out)
+	} else if 2 != len(v) || "bo" != v[1]["name"] {
+		t.Errorf("Wrong JSON: %v", v)
+	}
+}
+
+func TestJSONNested(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "nested",
+		stdin: "id,user.name,user.id,user.addr.city,x\n1,al,7,ny,\n",
+		args:  []string{"-json", "-json-nested", "-header"},
+		want: `{"id":"1","user":{"name":"al","id":"7",` +
+			`"addr":{"city":"ny"}},"x":""}` + "\n",
+	}, {
+		name:  "not_nested",
+		stdin: "user.name,user.id\nal,7\n",
+		args:  []string{"-json", "-header"},
+		want:  `{"user.name":"al","user.id":"7"}` + "\n",
+	}, {
+		name:  "array",
+		stdin: "a.b,a.c\n1,2\n",
+		args: []string{
+			"-format", "json-array",
+			"-json-nested",
+			"-header",
+		},
+		want: "[\n" + `{"a":{"b":"1","c":"2"}}` + "\n]\n",
+	}, {
+		name:  "conflict_after",
+		stdin: "a,a.b,a.c.d\n1,2,3\n",
+		args:  []string{"-json", "-json-nested", "-header"},
+		want:  `{"a":"1","a.b":"2","a.c.d":"3"}` + "\n",
+	}, {
+		name:  "conflict_before",
+		stdin: "a.b,a.c.d,a\n1,2,3\n",
+		args:  []string{"-json", "-json-nested", "-header"},
+		want:  `{"a.b":"1","a.c.d":"2","a":"3"}` + "\n",
+	}, {
+		name:  "deeper_conflict",
+		stdin: "a.b.c,a.b\n1,2\n",
+		args:  []string{"-json", "-json-nested", "-header"},
+		want:  `{"a.b.c":"1","a":{"b":"2"}}` + "\n",
+	}, {
+		name:  "empty_parts",
+		stdin: "a..b,.c,d.\n1,2,3\n",
+		args:  []string{"-json", "-json-nested", "-header"},
+		want:  `{"a..b":"1",".c":"2","d.":"3"}` + "\n",
+	}, {
+		name:  "no_header",
+		stdin: "1,2\n",
+		args:  []string{"-json", "-json-nested"},
+		want:  `{"c1":"1","c2":"2"}` + "\n",
+	}})
+}
+
+func TestWatermark(t *testing.T) {
+	in := "a\nb\nc\nd\ne\n"
+	runOutputTests(t, []outputTest{{
+		name:  "every_two",
+		stdin: in,
+		args:  []string{"-watermark", "2"},
+		want:  "a\nb\n# 2 rows\nc\nd\n# 4 rows\ne\n",
+	}, {
+		name:  "every_one",
+		stdin: "a\nb\n",
+		args:  []string{"-watermark", "1"},
+		want:  "a\n# 1 row\nb\n# 2 rows\n",
+	}, {
+		name:  "commentchar",
+		stdin: in,
+		args:  []string{"-watermark", "3", "-commentchar", ";"},
+		want:  "a\nb\nc\n; 3 rows\nd\ne\n",
+	}, {
+		name:  "no_commentchar",
+		stdin: in,
+		args:  []string{"-watermark", "5", "-commentchar", ""},
+		want:  "a\nb\nc\nd\ne\n# 5 rows\n",
+	}, {
+		name:  "counts_output_rows",
+		stdin: in,
+		args:  []string{"-watermark", "2", "-rows", "2-"},
+		want:  "b\nc\n# 2 rows\nd\ne\n# 4 rows\n",
+	}, {
+		name:  "readable",
+		stdin: "a\nb\nc\n",
+		args:  []string{"-watermark", "1", "-commentchar", "#"},
+		want:  "a\n# 1 row\nb\n# 2 rows\nc\n# 3 rows\n",
+	}})
+
+	/* Output with watermarks should read back as the original rows. */
+	out := mustRun(t, in, "-watermark", "2")
+	if got := mustRun(t, out, "-commentchar", "#"); in != got {
+		t.Errorf("Reread: got %q, want %q", got, in)
+	}
+
+	/* Can't transpose with watermarks. */
+	if res := runCSVCol(
+		t,
+		in,
+		"-watermark", "2", "-transpose",
+	); 0 == res.code {
+		t.Errorf("-transpose with -watermark succeeded")
+	}
+}
+
+func TestWatermarkCommas(t *testing.T) {
+	old := gc.commentChar
+	defer func() { gc.commentChar = old }()
+	cc := ""
+	gc.commentChar = &cc
+	for n, want := range map[int]string{
+		1:       "# 1 row",
+		999:     "# 999 rows",
+		1000:    "# 1,000 rows",
+		12345:   "# 12,345 rows",
+		100000:  "# 100,000 rows",
+		1000000: "# 1,000,000 rows",
+	} {
+		if got := watermark(n); want != got {
+			t.Errorf("%d: got %q, want %q", n, got, want)
+		}
+	}
+}
+
+/* gunzip returns the gunzipped b */
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if nil != err {
+		t.Fatalf("Starting gunzip: %v", err)
+	}
+	u, err := io.ReadAll(zr)
+	if nil != err {
+		t.Fatalf("Gunzipping: %v", err)
+	}
+	return string(u)
+}
+
+/* checkDir checks that the only files in dir are those in want */
+func checkDir(t *testing.T, dir string, want ...string) {
+	t.Helper()
+	des, err := os.ReadDir(dir)
+	if nil != err {
+		t.Fatalf("Reading %v: %v", dir, err)
+	}
+	var got []string
+	for _, de := range des {
+		got = append(got, de.Name())
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Files in %v: got %q, want %q", dir, got, want)
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	in := "a,b\nc,d\n"
+
+	/* Plain file, replacing one which exists */
+	out := writeTestFile(t, dir, "out.csv", "old")
+	if err := os.Chmod(out, 0600); nil != err {
+		t.Fatalf("Chmod: %v", err)
+	}
+	if got := mustRun(t, in, "-cols", "2", "-o", out); "" != got {
+		t.Errorf("Unexpected standard output: %q", got)
+	}
+	if b, err := os.ReadFile(out); nil != err {
+		t.Errorf("Reading output: %v", err)
+	} else if want := "b\nd\n"; want != string(b) {
+		t.Errorf("Output: got %q, want %q", b, want)
+	}
+	if fi, err := os.Stat(out); nil != err {
+		t.Errorf("Stat: %v", err)
+	} else if 0600 != fi.Mode().Perm() {
+		t.Errorf("Permissions changed to %v", fi.Mode().Perm())
+	}
+
+	/* Gzipped, by name */
+	gz := filepath.Join(dir, "out.csv.gz")
+	mustRun(t, in, "-o", gz)
+	if b, err := os.ReadFile(gz); nil != err {
+		t.Errorf("Reading gzipped output: %v", err)
+	} else if got := gunzip(t, b); in != got {
+		t.Errorf("Gzipped output: got %q, want %q", got, in)
+	}
+	checkDir(t, dir, "out.csv", "out.csv.gz")
+
+	/* Gzipped, to stdout */
+	if got := gunzip(t, []byte(mustRun(t, in, "-compress"))); in != got {
+		t.Errorf("-compress: got %q, want %q", got, in)
+	}
+}
+
+func TestOutputFileFailure(t *testing.T) {
+	dir := t.TempDir()
+	out := writeTestFile(t, dir, "out.csv", "old")
+	for _, c := range []struct {
+		name  string
+		stdin string
+		args  []string
+	}{{
+		name:  "bad_input",
+		stdin: "a,b\nc\"d,e\n",
+		args:  []string{"-strict"},
+	}, {
+		name: "missing_input",
+		args: []string{filepath.Join(dir, "missing.csv")},
+	}, {
+		name:  "gzipped",
+		stdin: "a,b\nc\"d,e\n",
+		args:  []string{"-strict", "-compress"},
+	}} {
+		args := append([]string{"-o", out}, c.args...)
+		if res := runCSVCol(t, c.stdin, args...); 0 == res.code {
+			t.Errorf("%s: succeeded", c.name)
+		}
+		if b, err := os.ReadFile(out); nil != err {
+			t.Errorf("%s: reading output: %v", c.name, err)
+		} else if "old" != string(b) {
+			t.Errorf("%s: output file changed: %q", c.name, b)
+		}
+		checkDir(t, dir, "out.csv")
+	}
+
+	/* Directory doesn't exist */
+	res := runCSVCol(t, "a\n", "-o", filepath.Join(dir, "no", "out.csv"))
+	if -26 != int8(res.code) {
+		t.Errorf("Missing directory: exit status %d", int8(res.code))
+	}
+}
+
+func TestIsRelative(t *testing.T) {
+	cases := map[string]bool{
+		"x.csv":      true,
+		"data/x.csv": true,
+		"../x.csv":   true,
+		"/x.csv":     false,
+	}
+	/* Windows has more ways to not be relative */
+	if "windows" == runtime.GOOS {
+		for k, v := range map[string]bool{
+			`data\x.csv`:    true,
+			`C:\data\x.csv`: false,
+			`C:x.csv`:       false,
+			`\data\x.csv`:   false,
+			`\\srv\s\x.csv`: false,
+		} {
+			cases[k] = v
+		}
+	}
+	for p, want := range cases {
+		if got := isRelative(p); got != want {
+			t.Errorf(
+				"isRelative(%q): got %t, want %t",
+				p,
+				got,
+				want,
+			)
+		}
+	}
+}
+
+func TestSplitZipOutput(t *testing.T) {
+	for _, c := range []struct {
+		have    string
+		archive string
+		member  string
+	}{
+		{have: "out.csv", archive: "out.csv"},
+		{
+			have:    "out.zip::result.csv",
+			archive: "out.zip",
+			member:  "result.csv",
+		},
+		{
+			have:    "out.zip::dir/r.csv",
+			archive: "out.zip",
+			member:  "dir/r.csv",
+		},
+		{have: "::r.csv", member: "r.csv"},
+		{have: "out.zip::", archive: "out.zip"},
+	} {
+		a, m := splitZipOutput(c.have)
+		if a != c.archive || m != c.member {
+			t.Errorf(
+				"%q: got %q, %q, want %q, %q",
+				c.have,
+				a,
+				m,
+				c.archive,
+				c.member,
+			)
+		}
+	}
+}
+
+/* readZip returns the members of the zip archive named n, which must all be
+compressed */
+func readZip(t *testing.T, n string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(n)
+	if nil != err {
+		t.Fatalf("Opening %v: %v", n, err)
+	}
+	defer zr.Close()
+	ms := make(map[string]string)
+	for _, zf := range zr.File {
+		if zip.Deflate != zf.Method {
+			t.Errorf("%v not compressed", zf.Name)
+		}
+		f, err := zf.Open()
+		if nil != err {
+			t.Fatalf("Opening %v in %v: %v", zf.Name, n, err)
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if nil != err {
+			t.Fatalf("Reading %v in %v: %v", zf.Name, n, err)
+		}
+		ms[zf.Name] = string(b)
+	}
+	return ms
+}
+
+func TestZipOutput(t *testing.T) {
+	dir := t.TempDir()
+	in := "a,b\nc,d\n"
+	archive := filepath.Join(dir, "out.zip")
+
+	/* New archive */
+	mustRun(t, in, "-cols", "2", "-o", archive+"::first.csv")
+	want := map[string]string{"first.csv": "b\nd\n"}
+	if got := readZip(t, archive); !maps.Equal(got, want) {
+		t.Errorf("New archive: got %q, want %q", got, want)
+	}
+
+	/* Another member, and a replaced member */
+	mustRun(t, in, "-o", archive+"::second.csv")
+	mustRun(t, in, "-cols", "1", "-o", archive+"::first.csv")
+	want = map[string]string{
+		"first.csv":  "a\nc\n",
+		"second.csv": in,
+	}
+	if got := readZip(t, archive); !maps.Equal(got, want) {
+		t.Errorf("Added to archive: got %q, want %q", got, want)
+	}
+	checkDir(t, dir, "out.zip")
+
+	/* With -deterministic, the same every time */
+	var outs [2][]byte
+	for i := range outs {
+		n := filepath.Join(dir, fmt.Sprintf("d%d.zip", i))
+		mustRun(t, in, "-deterministic", "-o", n+"::r.csv")
+		var err error
+		if outs[i], err = os.ReadFile(n); nil != err {
+			t.Fatalf("Reading %v: %v", n, err)
+		}
+	}
+	if !bytes.Equal(outs[0], outs[1]) {
+		t.Errorf("Deterministic archives differ")
+	}
+
+	/* Things which don't work shouldn't leave anything behind */
+	notZip := writeTestFile(t, dir, "not.zip", "not a zip")
+	for _, o := range []string{
+		"::r.csv",
+		archive + "::",
+		notZip + "::r.csv",
+	} {
+		res := runCSVCol(t, in, "-o", o)
+		if -26 != int8(res.code) {
+			t.Errorf("%q: exit code %d", o, int8(res.code))
+		}
+	}
+	if b, err := os.ReadFile(notZip); nil != err {
+		t.Errorf("Reading %v: %v", notZip, err)
+	} else if "not a zip" != string(b) {
+		t.Errorf("%v changed to %q", notZip, b)
+	}
+	checkDir(t, dir, "d0.zip", "d1.zip", "not.zip", "out.zip")
+}
@@ -0,0 +1,113 @@
+/*
+ * pad.go
+ * Padding and truncating fields to a fixed length
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* padSpec describes how to pad a column */
+type padSpec struct {
+	width    int  /* In characters */
+	left     bool /* Pad on the left, e.g. for numbers */
+	char     string
+	truncate bool /* Cut longer values to width */
+}
+
+/* padder pads the values of some columns to a fixed width */
+type padder map[int]padSpec /* Input column -> padding */
+
+/* add parses a specification of the form
+COL,width=N[,side=left|right][,char=C][,truncate], where COL is col:N or cN,
+and adds it to p.  By default, values are padded on the right with spaces.
+With truncate, values longer than N characters are cut to their first N
+characters. */
+func (p padder) add(spec string) error {
+	parts := strings.Split(spec, ",")
+	cols, err := csvcol.ParseGroupKey(parts[0])
+	if nil != err {
+		return err
+	}
+	if 1 != len(cols) {
+		return fmt.Errorf("only one column may be given")
+	}
+	ps := padSpec{char: " "}
+	for _, o := range parts[1:] {
+		k, v, _ := strings.Cut(o, "=")
+		switch strings.TrimSpace(k) {
+		case "width":
+			if ps.width, err = strconv.Atoi(v); nil != err ||
+				0 >= ps.width {
+				return fmt.Errorf("invalid width %q", v)
+			}
+		case "side":
+			switch v {
+			case "left":
+				ps.left = true
+			case "right":
+				ps.left = false
+			default:
+				return fmt.Errorf("side must be left or right")
+			}
+		case "char":
+			if 1 != utf8.RuneCountInString(v) {
+				return fmt.Errorf("char must be one character")
+			}
+			ps.char = v
+		case "truncate":
+			ps.truncate = true
+		default:
+			return fmt.Errorf("unknown option %q", o)
+		}
+	}
+	if 0 == ps.width {
+		return fmt.Errorf("missing width=")
+	}
+	p[cols[0]] = ps
+	return nil
+}
+
+/* apply pads or truncates the fields of orec, selected by sel, which are to
+be padded. */
+func (p padder) apply(orec []string, sel *csvcol.Selector) {
+	for i, f := range orec {
+		ps, ok := p[sel.InputColumn(i)]
+		if !ok {
+			continue
+		}
+		n := utf8.RuneCountInString(f)
+		switch {
+		case n < ps.width && ps.left:
+			orec[i] = strings.Repeat(ps.char, ps.width-n) + f
+		case n < ps.width:
+			orec[i] = f + strings.Repeat(ps.char, ps.width-n)
+		case n > ps.width && ps.truncate:
+			orec[i] = string([]rune(f)[:ps.width])
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * pad_test.go
+ * Tests for pad.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestPadderAdd(t *testing.T) {
+	for _, s := range []string{
+		"c1",
+		"c1,width=0",
+		"c1,width=x",
+		"c1,width=3,side=up",
+		"c1,width=3,char=ab",
+		"c1,width=3,char=",
+		"c1,width=3,nope",
+		"c1,c2,width=3",
+		"col:1-2,width=3",
+		"x,width=3",
+	} {
+		if err := make(padder).add(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	in := "id,code,name\n7,ab,Zoë\n1234,abcdef,Alexander\n"
+	runOutputTests(t, []outputTest{{
+		name:  "right",
+		stdin: in,
+		args:  []string{"-header", "-pad", "c3,width=5"},
+		want: "id,code,name\n" +
+			"7,ab,Zoë  \n" +
+			"1234,abcdef,Alexander\n",
+	}, {
+		name:  "left_zeros",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-pad", "c1,width=3,side=left,char=0",
+		},
+		want: "id,code,name\n007,ab,Zoë\n1234,abcdef,Alexander\n",
+	}, {
+		name:  "truncate",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-pad", "col:2,width=4,char=_,truncate",
+			"-pad", "c3,width=3,side=left,truncate",
+		},
+		want: "id,code,name\n7,ab__,Zoë\n1234,abcd,Ale\n",
+	}, {
+		name:  "unselected_column",
+		stdin: in,
+		args:  []string{"-cols", "1,2", "-pad", "c3,width=20"},
+		want:  "id,code\n7,ab\n1234,abcdef\n",
+	}})
+	res := runCSVCol(t, in, "-pad", "c1,width=-1")
+	if -34 != int8(res.code) {
+		t.Errorf("Bad -pad exit code %d, want -34", int8(res.code))
+	}
+}
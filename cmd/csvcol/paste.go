@@ -0,0 +1,127 @@
+/*
+ * paste.go
+ * Read several files side-by-side
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* recordReader reads CSV records.  It's the part of a *csv.Reader used to
+read input. */
+type recordReader interface {
+	Read() ([]string, error)
+	InputOffset() int64
+	FieldPos(field int) (line, column int)
+}
+
+/* pasteReader reads a record from each of several CSV readers at once and
+joins them into a single record, for -paste.  InputOffset and FieldPos refer
+to the first reader. */
+type pasteReader struct {
+	*csv.Reader
+	rest   []*csv.Reader
+	files  []*os.File /* Files to close, for all but the first reader */
+	widths []int      /* Number of fields in each reader's last record */
+	done   []bool     /* Readers which have hit EOF */
+	pad    bool       /* Read until the longest reader's done */
+}
+
+/* newPasteReader returns a pasteReader which reads records from r followed
+by records from the files named in rest.  If pad is true, the pasteReader
+reads until all of the files are done, using empty fields for files which are
+done; otherwise, it stops when any file is done.  If a file can't be opened,
+the program exits. */
+func newPasteReader(r *csv.Reader, rest []string, pad bool) *pasteReader {
+	p := &pasteReader{
+		Reader: r,
+		widths: make([]int, len(rest)+1),
+		done:   make([]bool, len(rest)+1),
+		pad:    pad,
+	}
+	for _, f := range rest {
+		fp, _ := openInput(f)
+		cr, _ := inputReader(fp)
+		p.rest = append(p.rest, cr)
+		p.files = append(p.files, fp)
+	}
+	return p
+}
+
+/* Read reads a record from each reader and returns them joined.  Once the
+readers are done, as per p.pad, Read returns io.EOF. */
+func (p *pasteReader) Read() ([]string, error) {
+	var out []string
+	nDone := 0
+	for i := range p.done {
+		/* Don't bother with the rest if we're stopping */
+		if !p.pad && 0 != nDone {
+			break
+		}
+		/* Get this reader's next record */
+		var (
+			rec []string
+			err error
+		)
+		if !p.done[i] {
+			rec, err = p.reader(i).Read()
+		}
+		switch {
+		case p.done[i]:
+		case errors.Is(err, io.EOF):
+			p.done[i] = true
+			if 0 != i && os.Stdin != p.files[i-1] {
+				p.files[i-1].Close()
+			}
+		case nil != err:
+			if 0 != i {
+				err = fmt.Errorf("file %d: %w", i+1, err)
+			}
+			return nil, err
+		default:
+			p.widths[i] = len(rec)
+			out = append(out, rec...)
+			continue
+		}
+		/* Reader's done, pad if we're padding */
+		nDone++
+		for j := 0; j < p.widths[i]; j++ {
+			out = append(out, "")
+		}
+	}
+	if len(p.done) == nDone || (!p.pad && 0 != nDone) {
+		return nil, io.EOF
+	}
+	return out, nil
+}
+
+/* reader returns the ith reader */
+func (p *pasteReader) reader(i int) *csv.Reader {
+	if 0 == i {
+		return p.Reader
+	}
+	return p.rest[i-1]
+}
@@ -0,0 +1,78 @@
+/*
+ * paste_test.go
+ * Tests for paste.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestPaste(t *testing.T) {
+	dir := t.TempDir()
+	ab := writeTestFile(t, dir, "ab.csv", "a,b\n1,2\n3,4\n")
+	c := writeTestFile(t, dir, "c.csv", "c\nx\ny\nz\n")
+	de := writeTestFile(t, dir, "de.csv", "d,e\n\"q,r\",s\n")
+	runOutputTests(t, []outputTest{{
+		name: "stop",
+		args: []string{"-paste", ab, c, de},
+		want: "a,b,c,d,e\n1,2,x,\"q,r\",s\n",
+	}, {
+		name: "pad",
+		args: []string{"-paste", "-paste-end", "pad", ab, c, de},
+		want: "a,b,c,d,e\n" +
+			"1,2,x,\"q,r\",s\n" +
+			"3,4,y,,\n" +
+			",,z,,\n",
+	}, {
+		name: "selected",
+		args: []string{
+			"-header",
+			"-paste",
+			"-paste-end", "pad",
+			"-cols", "3,2",
+			"-ordered",
+			ab,
+			c,
+		},
+		want: "c,b\nx,2\ny,4\nz,\n",
+	}, {
+		name:  "stdin",
+		stdin: "s\nt\n",
+		args:  []string{"-paste", c, "-"},
+		want:  "c,s\nx,t\n",
+	}, {
+		name: "one_file",
+		args: []string{"-paste", ab},
+		want: "a,b\n1,2\n3,4\n",
+	}, {
+		name: "not_pasted",
+		args: []string{ab, c},
+		want: "a,b\n1,2\n3,4\nc\nx\ny\nz\n",
+	}})
+	for _, args := range [][]string{
+		{"-paste", "-paste-end", "wrap", ab, c},
+		{"-paste", "-in-place", ab, c},
+	} {
+		res := runCSVCol(t, "", args...)
+		if -49 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
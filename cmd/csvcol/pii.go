@@ -0,0 +1,168 @@
+/*
+ * pii.go
+ * Heuristic detection of personal information
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* piiDetector detects one kind of personal information in a value */
+type piiDetector struct {
+	kind  string
+	re    *regexp.Regexp
+	check func(string) bool /* Further check, may be nil */
+}
+
+/* piiDetectors are the kinds of PII we look for.  A value is counted as the
+first kind it matches. */
+var piiDetectors = []piiDetector{{
+	kind: "email",
+	re:   regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[A-Za-z]{2,}$`),
+}, {
+	kind:  "credit_card",
+	re:    regexp.MustCompile(`^\d(?:[ -]?\d){12,18}$`),
+	check: csvcol.ValidLuhn,
+}, {
+	kind:  "us_ssn",
+	re:    regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`),
+	check: validSSN,
+}, {
+	kind: "uk_nino",
+	re: regexp.MustCompile(
+		`^(?i)[A-CEGHJ-PR-TW-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]$`,
+	),
+}, {
+	kind: "phone",
+	re: regexp.MustCompile(
+		`^\+?(?:\(\d{1,4}\)|\d{1,4})(?:[ .-]?\(?\d{2,4}\)?){2,4}$`,
+	),
+	check: func(s string) bool {
+		if notPhoneRE.MatchString(s) {
+			return false
+		}
+		n := 0
+		for _, r := range s {
+			if '0' <= r && '9' >= r {
+				n++
+			}
+		}
+		return 7 <= n && 15 >= n
+	},
+}}
+
+/* notPhoneRE matches dates and things shaped like US Social Security numbers,
+which would otherwise look like phone numbers */
+var notPhoneRE = regexp.MustCompile(
+	`^(?:\d{4}[-.]\d{1,2}[-.]\d{1,2}|\d{1,2}[-.]\d{1,2}[-.]\d{4}|` +
+		`\d{3}-\d{2}-\d{4})$`,
+)
+
+/* piiThreshold is the fraction of a column's non-empty values which must be
+of one kind for the column to be reported */
+const piiThreshold = 0.5
+
+/* validSSN returns true if s, of the form AAA-GG-SSSS, is a possible US
+Social Security number.  Area 000, 666, and 9xx, group 00, and serial 0000
+are never issued. */
+func validSSN(s string) bool {
+	a, g, sn := s[:3], s[4:6], s[7:]
+	return "000" != a && "666" != a && '9' != a[0] &&
+		"00" != g && "0000" != sn
+}
+
+/* piiScan counts values in each column which look like PII.  Column returns
+the input column number of the ith field of the last record passed to add. */
+type piiScan struct {
+	column func(i int) int
+	cols   []int /* Input column numbers */
+	names  []string
+	values []int            /* Non-empty values per column */
+	counts []map[string]int /* Kind -> matching values, per column */
+}
+
+/* add checks the fields of orec for PII.  Name returns the name of the ith
+field of orec.  Record is ignored. */
+func (p *piiScan) add(record, orec []string, name func(i int) string) {
+	for i, f := range orec {
+		if len(p.names) == i {
+			p.cols = append(p.cols, p.column(i))
+			p.names = append(p.names, name(i))
+			p.values = append(p.values, 0)
+			p.counts = append(p.counts, make(map[string]int))
+		}
+		f = strings.TrimSpace(f)
+		if "" == f {
+			continue
+		}
+		p.values[i]++
+		for _, d := range piiDetectors {
+			if !d.re.MatchString(f) {
+				continue
+			}
+			if nil == d.check || d.check(f) {
+				p.counts[i][d.kind]++
+				break
+			}
+		}
+	}
+}
+
+/* each calls f with a header and then with a record for each column in
+which at least piiThreshold of the non-empty values look like the same kind
+of PII.  Each record has the column's input column number and name, the kind
+of PII, the number of matching and non-empty values, and the fraction of
+values which match. */
+func (p *piiScan) each(f func([]string) error) error {
+	if err := f([]string{
+		"column", "name", "kind", "matches", "values", "fraction",
+	}); nil != err {
+		return err
+	}
+	for i, n := range p.names {
+		for _, d := range piiDetectors {
+			m := p.counts[i][d.kind]
+			if 0 == m {
+				continue
+			}
+			frac := float64(m) / float64(p.values[i])
+			if piiThreshold > frac {
+				continue
+			}
+			if err := f([]string{
+				strconv.Itoa(p.cols[i]),
+				n,
+				d.kind,
+				strconv.Itoa(m),
+				strconv.Itoa(p.values[i]),
+				strconv.FormatFloat(frac, 'f', 2, 64),
+			}); nil != err {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+/*
+ * pii_test.go
+ * Tests for pii.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+/* piiKind returns the kind of PII v looks like, or "" if none */
+func piiKind(v string) string {
+	for _, d := range piiDetectors {
+		if d.re.MatchString(v) && (nil == d.check || d.check(v)) {
+			return d.kind
+		}
+	}
+	return ""
+}
+
+func TestPIIDetectors(t *testing.T) {
+	for v, want := range map[string]string{
+		"alice@example.com":   "email",
+		"a.b+c@mail.co.uk":    "email",
+		"alice@localhost":     "",
+		"@example.com":        "",
+		"4111111111111111":    "credit_card",
+		"4111 1111 1111 1111": "credit_card",
+		"4111-1111-1111-1111": "credit_card",
+		"4111111111111112":    "",
+		"123-45-6789":         "us_ssn",
+		"000-45-6789":         "",
+		"666-45-6789":         "",
+		"900-45-6789":         "",
+		"123-00-6789":         "",
+		"123-45-0000":         "",
+		"AB 12 34 56 C":       "uk_nino",
+		"ab123456c":           "uk_nino",
+		"DA123456C":           "",
+		"AB123456E":           "",
+		"+44 20 7946 0958":    "phone",
+		"(555) 123-4567":      "phone",
+		"555.123.4567":        "phone",
+		"12-34":               "",
+		"hello":               "",
+		"2026-01-02":          "",
+		"02.01.2026":          "",
+		"2026-1-2":            "",
+	} {
+		if got := piiKind(v); want != got {
+			t.Errorf("%q: got %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestPIIScan(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name: "header",
+		stdin: "id,email,card,note\n" +
+			"1,a@example.com,4111111111111111,hi\n" +
+			"2,b@example.com,5500 0000 0000 0004,\n" +
+			"3,,1234,a@example.com\n" +
+			"4,none,123-45-6789,there\n",
+		args: []string{"-header", "-pii-scan"},
+		want: "column,name,kind,matches,values,fraction\n" +
+			"2,email,email,2,3,0.67\n" +
+			"3,card,credit_card,2,4,0.50\n",
+	}, {
+		name:  "selected_columns",
+		stdin: "a@example.com,123-45-6789\n",
+		args:  []string{"-pii-scan", "-cols", "2"},
+		want: "column,name,kind,matches,values,fraction\n" +
+			"2,c2,us_ssn,1,1,1.00\n",
+	}, {
+		name:  "nothing",
+		stdin: "a,b\n",
+		args:  []string{"-pii-scan"},
+		want:  "column,name,kind,matches,values,fraction\n",
+	}})
+}
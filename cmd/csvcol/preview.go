@@ -0,0 +1,148 @@
+/*
+ * preview.go
+ * Quick preview of the selected rows
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* preview prints the first record of the input as a header followed by the
+first n selected rows, with the columns aligned. */
+func preview(files []string, s csvcol.Selector, n int) error {
+	var rows [][]string
+	first := true
+	for _, f := range files {
+		fp, fname := openInput(f)
+		verbose("Previewing %v", fname)
+		r := newReader(fp)
+		for n > len(rows)-1 || first {
+			record, err := r.Read()
+			if nil != err {
+				break
+			}
+			/* The first record is the header.  We'll always print
+			it, but only once. */
+			if first {
+				first = false
+				if _, _, err := s.Select(record); nil != err {
+					return err
+				}
+				rows = append(rows, s.Columns(record))
+				continue
+			}
+			if orec, ok, _ := s.Select(record); ok {
+				rows = append(rows, orec)
+			}
+		}
+		if os.Stdin != fp {
+			fp.Close()
+		}
+		if n <= len(rows)-1 {
+			break
+		}
+	}
+	return writeAligned(stdout, rows, true)
+}
+
+/* listCols prints the number and value of each field of the first record
+of the first of the files which has one. */
+func listCols(files []string) error {
+	var record []string
+	for _, f := range files {
+		fp, fname := openInput(f)
+		verbose("Listing columns from %v", fname)
+		var err error
+		record, err = newReader(fp).Read()
+		if os.Stdin != fp {
+			fp.Close()
+		}
+		if nil == err {
+			break
+		}
+		if !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading %v: %w", fname, err)
+		}
+	}
+	w := bufio.NewWriter(stdout)
+	for i, f := range record {
+		fmt.Fprintf(w, "%d\t%s\n", i+1, f)
+	}
+	return w.Flush()
+}
+
+/* writeAligned writes rows to w with columns padded to line up.  If header
+is true, the first row is underlined. */
+func writeAligned(w io.Writer, rows [][]string, header bool) error {
+	/* Work out how wide each column is */
+	var widths []int
+	for _, row := range rows {
+		for i, f := range row {
+			if len(widths) <= i {
+				widths = append(widths, 0)
+			}
+			if l := utf8.RuneCountInString(f); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	for n, row := range rows {
+		writeAlignedRow(bw, row, widths)
+		/* Underline the header */
+		if 0 == n && header {
+			u := make([]string, len(widths))
+			for i, w := range widths {
+				u[i] = strings.Repeat("-", w)
+			}
+			writeAlignedRow(bw, u, widths)
+		}
+	}
+	return bw.Flush()
+}
+
+/* writeAlignedRow writes a single row for writeAligned */
+func writeAlignedRow(w *bufio.Writer, row []string, widths []int) {
+	line := make([]string, len(row))
+	for i, f := range row {
+		/* Control characters would spoil the alignment */
+		f = strings.Map(func(r rune) rune {
+			if r < ' ' {
+				return ' '
+			}
+			return r
+		}, f)
+		line[i] = f + strings.Repeat(" ",
+			widths[i]-utf8.RuneCountInString(f))
+	}
+	w.WriteString(strings.TrimRight(strings.Join(line, "  "), " "))
+	w.WriteString("\n")
+}
@@ -0,0 +1,66 @@
+/*
+ * preview_test.go
+ * Tests for preview.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	in := "id,name,age\n1,alice,30\n2,bob,4\n3,carol,55\n"
+	runOutputTests(t, []outputTest{{
+		name:  "rows",
+		stdin: in,
+		args:  []string{"-preview", "1", "-rows", "3-"},
+		want:  "id  name  age\n--  ----  ---\n2   bob   4\n",
+	}, {
+		name:  "cols",
+		stdin: in,
+		args:  []string{"-preview", "2", "-notcols", "1"},
+		want:  "name   age\n-----  ---\nalice  30\nbob    4\n",
+	}, {
+		name:  "colnames_where",
+		stdin: in,
+		args: []string{
+			"-preview", "5",
+			"-colnames", "name,age",
+			"-where", "c3 > 10",
+		},
+		want: "name   age\n-----  ---\nalice  30\ncarol  55\n",
+	}})
+
+	/* Other output settings are ignored */
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	got := mustRun(t, in, "-preview", "1", "-format", "json", "-o", out)
+	want := "id  name   age\n--  -----  ---\n1   alice  30\n"
+	if want != got {
+		t.Errorf("Output with -format json and -o:\n%s\nwant:\n%s",
+			got, want)
+	}
+	if _, err := os.Stat(out); nil == err {
+		t.Errorf("Output file written")
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * pseudonym.go
+ * Keyed pseudonymization of columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* pseudonymizer replaces the values of some columns with the hex-encoded
+HMAC-SHA256 of the value, so that the same value always gets the same
+pseudonym but the value can't be recovered without the key. */
+type pseudonymizer map[int]hash.Hash /* Input column -> HMAC */
+
+/* add parses a specification of the form COL,key=KEY, where COL is col:N or
+cN and KEY is the HMAC key or @FILE to read the key from a file, and adds it
+to p.  A single trailing newline is removed from a key read from a file. */
+func (p pseudonymizer) add(spec string) error {
+	c, k, ok := strings.Cut(spec, ",")
+	if !ok {
+		return fmt.Errorf("missing key")
+	}
+	cols, err := csvcol.ParseGroupKey(c)
+	if nil != err {
+		return err
+	}
+	if 1 != len(cols) {
+		return fmt.Errorf("only one column may be given")
+	}
+	k, ok = strings.CutPrefix(strings.TrimSpace(k), "key=")
+	if !ok {
+		return fmt.Errorf("missing key=")
+	}
+	key := []byte(k)
+	if f, ok := strings.CutPrefix(k, "@"); ok {
+		if key, err = os.ReadFile(f); nil != err {
+			return fmt.Errorf("reading key: %w", err)
+		}
+		key = bytes.TrimSuffix(key, []byte("\n"))
+		key = bytes.TrimSuffix(key, []byte("\r"))
+	}
+	if 0 == len(key) {
+		return fmt.Errorf("empty key")
+	}
+	p[cols[0]] = hmac.New(sha256.New, key)
+	return nil
+}
+
+/* apply replaces the fields of orec, selected by sel, which are to be
+pseudonymized.  Empty fields are left empty. */
+func (p pseudonymizer) apply(orec []string, sel *csvcol.Selector) {
+	for i, f := range orec {
+		h, ok := p[sel.InputColumn(i)]
+		if !ok || "" == f {
+			continue
+		}
+		h.Reset()
+		h.Write([]byte(f))
+		orec[i] = hex.EncodeToString(h.Sum(nil))
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * pseudonym_test.go
+ * Tests for pseudonym.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+)
+
+/* testPseudonym returns the pseudonym for v with the given key */
+func testPseudonym(key, v string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(v))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func TestPseudonymizerAdd(t *testing.T) {
+	dir := t.TempDir()
+	for _, s := range []string{
+		"c1",
+		"x,key=k",
+		"c1,c2,key=k",
+		"c1,k",
+		"c1,key=",
+		"c1,key=@" + filepath.Join(dir, "missing"),
+		"c1,key=@" + writeTestFile(t, dir, "empty", "\n"),
+	} {
+		if err := make(pseudonymizer).add(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
+
+func TestPseudonymize(t *testing.T) {
+	dir := t.TempDir()
+	kf := writeTestFile(t, dir, "key", "s3cret\r\n")
+	a, b := testPseudonym("s3cret", "alice"), testPseudonym("s3cret", "bob")
+	runOutputTests(t, []outputTest{{
+		name:  "literal_key",
+		stdin: "1,alice\n2,bob\n3,alice\n4,\n",
+		args:  []string{"-pseudonymize", "col:2,key=s3cret"},
+		want:  "1," + a + "\n2," + b + "\n3," + a + "\n4,\n",
+	}, {
+		name:  "key_file",
+		stdin: "1,alice\n",
+		args:  []string{"-pseudonymize", "c2, key=@" + kf},
+		want:  "1," + a + "\n",
+	}, {
+		name:  "header",
+		stdin: "id,name\n1,bob\n",
+		args:  []string{"-header", "-pseudonymize", "c2,key=s3cret"},
+		want:  "id,name\n1," + b + "\n",
+	}, {
+		name:  "two_columns",
+		stdin: "alice,bob\n",
+		args: []string{
+			"-pseudonymize", "c1,key=s3cret",
+			"-pseudonymize", "c2,key=other",
+		},
+		want: a + "," + testPseudonym("other", "bob") + "\n",
+	}, {
+		name:  "reordered",
+		stdin: "1,alice\n",
+		args: []string{
+			"-ordered",
+			"-cols", "2,1",
+			"-pseudonymize", "c2,key=s3cret",
+		},
+		want: a + ",1\n",
+	}})
+}
@@ -0,0 +1,165 @@
+/*
+ * sample.go
+ * Random sampling of rows
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"container/heap"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/* newRand returns a random number generator seeded with -seed, if it was
+given, or randomly if not.  Different streams give different numbers from
+the same seed. */
+func newRand(stream uint64) *rand.Rand {
+	if 0 == *gc.seed {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return rand.New(rand.NewPCG(*gc.seed, stream))
+}
+
+/* newShuffleStage returns a sortStage which passes on its rows in a random
+order, for -shuffle. */
+func newShuffleStage() *sortStage {
+	return &sortStage{
+		keys:   []sortKey{{random: true}},
+		maxMem: gc.maxMemBytes,
+		seed:   newRand(3).Uint64(),
+	}
+}
+
+/* fractionStage is a stage which passes on each of its rows with probability
+p.  The header is always passed on. */
+type fractionStage struct {
+	p    float64
+	rand *rand.Rand
+}
+
+/* newFractionStage returns a fractionStage which passes on a fraction p of
+its rows. */
+func newFractionStage(p float64) *fractionStage {
+	return &fractionStage{p: p, rand: newRand(1)}
+}
+
+/* push passes on r if it's lucky */
+func (s *fractionStage) push(r stageRow, next func(stageRow)) {
+	if r.header || s.rand.Float64() < s.p {
+		next(r)
+	}
+}
+
+/* flush does nothing, as rows aren't held */
+func (s *fractionStage) flush(func(stageRow)) {}
+
+/* sampleStage is a stage which passes on a random sample of n of its rows,
+in the order in which they arrived.  If col isn't 0, each row's chance of
+being chosen is proportional to the number in its weight column; rows with a
+weight which isn't a positive number are never chosen.  The header is passed
+on immediately. */
+type sampleStage struct {
+	n    int
+	col  int /* 1-indexed input column, or 0 for no weights */
+	rand *rand.Rand
+	res  sampleHeap /* Reservoir */
+	seq  int        /* Rows seen, to put the sample back in order */
+}
+
+/* newSampleStage returns a sampleStage which samples n rows weighted by the
+given 1-indexed input column, or unweighted if col is 0. */
+func newSampleStage(n, col int) *sampleStage {
+	return &sampleStage{
+		n:    n,
+		col:  col,
+		rand: newRand(2),
+	}
+}
+
+/* sampled is a row in the reservoir */
+type sampled struct {
+	key float64 /* Random key, larger keys are kept */
+	seq int
+	row stageRow
+}
+
+/* sampleHeap is a min-heap of sampled rows, by key */
+type sampleHeap []sampled
+
+func (h sampleHeap) Len() int           { return len(h) }
+func (h sampleHeap) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h sampleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *sampleHeap) Push(x any)        { *h = append(*h, x.(sampled)) }
+func (h *sampleHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+/* push adds r to the reservoir if it's lucky.  Each row gets a key of
+u^(1/w), where u is uniformly random in (0,1) and w is the row's weight, and
+the rows with the n largest keys are kept (Efraimidis and Spirakis' A-Res). */
+func (s *sampleStage) push(r stageRow, next func(stageRow)) {
+	if r.header {
+		next(r)
+		return
+	}
+	s.seq++
+	/* Work out how much this one counts */
+	w := 1.0
+	if 0 != s.col {
+		var f string
+		if s.col <= len(r.in) {
+			f = strings.TrimSpace(r.in[s.col-1])
+		}
+		var err error
+		w, err = strconv.ParseFloat(f, 64)
+		if nil != err || !(0 < w) || math.IsInf(w, 1) {
+			debug("Not sampling row with weight %q", f)
+			return
+		}
+	}
+	/* Use logs to keep small weights from rounding to 0 */
+	key := math.Log(1-s.rand.Float64()) / w
+	if len(s.res) < s.n {
+		heap.Push(&s.res, sampled{key: key, seq: s.seq, row: r})
+		return
+	}
+	if key > s.res[0].key {
+		s.res[0] = sampled{key: key, seq: s.seq, row: r}
+		heap.Fix(&s.res, 0)
+	}
+}
+
+/* flush passes on the sampled rows, in the order they arrived */
+func (s *sampleStage) flush(next func(stageRow)) {
+	sort.Slice(s.res, func(i, j int) bool {
+		return s.res[i].seq < s.res[j].seq
+	})
+	for _, sr := range s.res {
+		next(sr.row)
+	}
+	s.res = nil
+}
@@ -0,0 +1,237 @@
+/*
+ * sample_test.go
+ * Tests for sample.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestShuffle(t *testing.T) {
+	/* Enough rows that shuffling them in order is unlikely */
+	var sb strings.Builder
+	sb.WriteString("name,n\n")
+	var rows []string
+	for i := 1; i <= 100; i++ {
+		r := fmt.Sprintf("r%d,%d", i, i)
+		rows = append(rows, r)
+		sb.WriteString(r + "\n")
+	}
+	in := sb.String()
+
+	for _, h := range [][]string{
+		{"-header"},
+		{"-colnames", "name,n"},
+	} {
+		t.Run(h[0], func(t *testing.T) {
+			args := append([]string{"-shuffle", "-seed", "2"}, h...)
+			got := mustRun(t, in, args...)
+			lines := strings.Split(
+				strings.TrimSuffix(got, "\n"),
+				"\n",
+			)
+			if 0 == len(lines) || "name,n" != lines[0] {
+				t.Fatalf("Header not first:\n%s", got)
+			}
+			/* Should be the same rows, in a different order */
+			lines = lines[1:]
+			if slices.Equal(rows, lines) {
+				t.Errorf("Rows not shuffled")
+			}
+			sorted := slices.Clone(lines)
+			slices.Sort(sorted)
+			want := slices.Clone(rows)
+			slices.Sort(want)
+			if !slices.Equal(want, sorted) {
+				t.Errorf("Not a permutation of the input:\n%s",
+					got)
+			}
+
+			/* Same seed, same order, even spilling to disk */
+			if again := mustRun(t, in, args...); again != got {
+				t.Errorf("Different order with the same seed")
+			}
+			spilled := mustRun(
+				t,
+				in,
+				append(args, "-max-mem", "256")...,
+			)
+			if spilled != got {
+				t.Errorf("Different order when spilled")
+			}
+		})
+	}
+}
+
+func TestSampleWeight(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "unweighable",
+		stdin: "a,1\nb,0\nc,-1\nd,x\ne,5\nf\ng, 2 \nh,+Inf\n",
+		args:  []string{"-sample-n", "10", "-sample-weight", "c2"},
+		want:  "a,1\ne,5\ng,\" 2 \"\n",
+	}, {
+		name:  "header",
+		stdin: "name,w\na,1\nb,0\n",
+		args: []string{
+			"-header",
+			"-sample-n", "5",
+			"-sample-weight", "col:2",
+		},
+		want: "name,w\na,1\n",
+	}})
+
+	for _, args := range [][]string{
+		{"-sample-weight", "c2"},
+		{"-sample-n", "1", "-sample-weight", "x"},
+	} {
+		if res := runCSVCol(t, "a,1\n", args...); 0 == res.code {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
+
+func TestSampleWeightProportional(t *testing.T) {
+	s := &sampleStage{n: 1, col: 2, rand: rand.New(rand.NewPCG(1, 2))}
+	const tries = 10000
+	got := make(map[string]int)
+	for i := 0; i < tries; i++ {
+		for _, r := range [][]string{{"a", "1"}, {"b", "3"}} {
+			s.push(stageRow{in: r}, nil)
+		}
+		s.flush(func(r stageRow) { got[r.in[0]]++ })
+	}
+	/* b should be picked about three times in four */
+	if f := float64(got["b"]) / tries; 0.72 > f || 0.78 < f {
+		t.Errorf("Heavier row picked %v of the time: %v", f, got)
+	}
+	if tries != got["a"]+got["b"] {
+		t.Errorf("Not one row per sample: %v", got)
+	}
+}
+
+func TestFractionStage(t *testing.T) {
+	s := &fractionStage{p: 0.25, rand: rand.New(rand.NewPCG(1, 2))}
+	const tries = 10000
+	var header bool
+	s.push(stageRow{header: true}, func(stageRow) { header = true })
+	if !header {
+		t.Errorf("Header not passed on")
+	}
+	n := 0
+	for i := 0; i < tries; i++ {
+		s.push(stageRow{}, func(stageRow) { n++ })
+	}
+	if f := float64(n) / tries; 0.23 > f || 0.27 < f {
+		t.Errorf("Passed on %v of the rows", f)
+	}
+}
+
+func TestSample(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("name,n\n")
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&sb, "r%d,%d\n", i, i)
+	}
+	in := sb.String()
+
+	/* sampleRows runs csvcol and returns the numbers of the output rows,
+	after checking the header's there */
+	sampleRows := func(t *testing.T, args ...string) []int {
+		t.Helper()
+		lines := strings.Split(
+			strings.TrimSuffix(mustRun(t, in, args...), "\n"),
+			"\n",
+		)
+		if "name,n" != lines[0] {
+			t.Fatalf("Header missing: %q", lines[0])
+		}
+		var ns []int
+		for _, l := range lines[1:] {
+			var n int
+			if _, err := fmt.Sscanf(
+				l[strings.Index(l, ",")+1:],
+				"%d",
+				&n,
+			); nil != err {
+				t.Fatalf("Bad line %q: %v", l, err)
+			}
+			ns = append(ns, n)
+		}
+		return ns
+	}
+
+	/* Exact counts, in order */
+	ns := sampleRows(t, "-header", "-sample-n", "10", "-seed", "5")
+	if 10 != len(ns) || !slices.IsSorted(ns) {
+		t.Errorf("Sample not 10 rows in order: %d", ns)
+	}
+	again := sampleRows(t, "-header", "-sample-n", "10", "-seed", "5")
+	if !slices.Equal(ns, again) {
+		t.Errorf("Same seed, different sample: %d, %d", ns, again)
+	}
+	other := sampleRows(t, "-header", "-sample-n", "10", "-seed", "6")
+	if slices.Equal(ns, other) {
+		t.Errorf("Different seed, same sample: %d", ns)
+	}
+	ns = sampleRows(t, "-header", "-sample-n", "5000")
+	if 1000 != len(ns) {
+		t.Errorf("Sampled %d of 1000 rows", len(ns))
+	}
+
+	/* Fractions, roughly */
+	ns = sampleRows(t, "-header", "-sample", "0.1", "-seed", "5")
+	if 60 > len(ns) || 140 < len(ns) || !slices.IsSorted(ns) {
+		t.Errorf("Sampled %d rows: %d", len(ns), ns)
+	}
+	ns = sampleRows(t, "-header", "-sample", "1")
+	if 1000 != len(ns) {
+		t.Errorf("Sampled %d of 1000 rows with -sample 1", len(ns))
+	}
+
+	/* Sampling's after filtering */
+	ns = sampleRows(
+		t,
+		"-header",
+		"-where", "c2 <= 20",
+		"-sample", "0.5",
+		"-sample-n", "3",
+		"-seed", "7",
+	)
+	if 3 != len(ns) || 20 < slices.Max(ns) {
+		t.Errorf("Sample not 3 filtered rows: %d", ns)
+	}
+
+	for _, args := range [][]string{
+		{"-sample", "1.5"},
+		{"-sample", "-0.5"},
+		{"-sample-n", "-1"},
+	} {
+		res := runCSVCol(t, in, args...)
+		if -28 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ * sandbox.go
+ * Restrict csvcol to the files it needs
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* sandboxed is true once -sandbox has restricted us, after which other
+programs may not be run. */
+var sandboxed bool
+
+/* netFiles are files which might be read to resolve names and check
+certificates when reading from a URL. */
+var netFiles = []string{
+	"/etc/hosts",
+	"/etc/resolv.conf",
+	"/etc/nsswitch.conf",
+	"/etc/host.conf",
+	"/etc/gai.conf",
+	"/etc/ssl",
+	"/etc/pki",
+	"/etc/ca-certificates",
+	"/usr/share/ca-certificates",
+	"/usr/local/share/certs",
+}
+
+/* sandboxPolicy describes what we may touch once sandboxed. */
+type sandboxPolicy struct {
+	read  []string /* Files and directories which may be read */
+	write []string /* Directories in which files may be made and replaced */
+	net   bool     /* Network access is needed, for URLs */
+	fattr bool     /* File permissions, owners, and times may be changed */
+	flock bool     /* Files may be locked */
+}
+
+/* addRead allows reading n, if it exists.  Files which don't exist are left
+for opening to fail normally. */
+func (p *sandboxPolicy) addRead(n string) {
+	if _, err := os.Stat(n); nil == err {
+		p.read = append(p.read, n)
+	}
+}
+
+/* addWrite allows creating, writing, and removing files in the directory
+containing the file n, if it exists. */
+func (p *sandboxPolicy) addWrite(n string) {
+	d := filepath.Dir(n)
+	if fi, err := os.Stat(d); nil == err && fi.IsDir() {
+		p.write = append(p.write, d)
+	}
+}
+
+/* checkSandbox makes sure nothing we've been asked to do needs another
+program, which can't be run in the sandbox.  Files, named in files, which are
+compressed with zstd but don't end in .zst will cause an error when read.  If
+another program is needed, the program exits. */
+func checkSandbox(files []string) {
+	if "" != *gc.sqlite {
+		inform("-sandbox may not be used with -sqlite, as sqlite3 " +
+			"can't be run in the sandbox.")
+		exit(-47)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f, ".zst") {
+			inform("-sandbox may not be used to read %v, as zstd "+
+				"can't be run in the sandbox.", f)
+			exit(-47)
+		}
+	}
+}
+
+/* startSandbox restricts us, as per -sandbox, to reading the input files
+named in files and writing the outputs named on the command line.  Platforms
+on which we can't restrict ourselves are left as-is.  If restricting ourselves
+fails, the program exits. */
+func startSandbox(files []string) {
+	if !*gc.sandbox {
+		return
+	}
+
+	/* Work out what we need */
+	var p sandboxPolicy
+	if nil != outBundle {
+		p.addWrite(outBundle.name)
+	}
+	for _, f := range files {
+		if "" != *gc.outputPerFile && nil == outBundle {
+			p.addWrite(expandOutputTemplate(*gc.outputPerFile, f))
+		}
+		switch {
+		case "-" == f:
+		case isURL(f):
+			p.net = true
+		default:
+			p.addRead(f)
+		}
+	}
+	if p.net {
+		for _, f := range netFiles {
+			p.addRead(f)
+		}
+	}
+	if "" != *gc.output {
+		a, _ := splitZipOutput(*gc.output)
+		p.addWrite(a)
+	}
+	if 0 != len(gc.tokenize) {
+		p.addWrite(*gc.tokenMap)
+	}
+	if 0 != gc.maxMemBytes {
+		p.addWrite(filepath.Join(os.TempDir(), "x"))
+	}
+	p.fattr = *gc.inPlace
+	p.flock = "" != *gc.lockfile
+
+	/* Lock ourselves down */
+	ok, err := restrict(p)
+	if nil != err {
+		inform("Unable to sandbox ourselves: %v", err)
+		inform("Use -sandbox=false to run without a sandbox.")
+		exit(-47)
+	}
+	if ok {
+		sandboxed = true
+		debug("Sandboxed: read %q, write %q, network %t",
+			p.read, p.write, p.net)
+	}
+}
@@ -0,0 +1,170 @@
+/*
+ * sandbox_linux.go
+ * Restrict csvcol with Landlock
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+/* Landlock filesystem rights, by ABI version.  Rights which the running
+kernel's ABI doesn't have aren't handled and so aren't restricted. */
+const (
+	landlockRead = unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_DIR
+	landlockWrite = landlockRead |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG
+	landlockFileRights = unix.LANDLOCK_ACCESS_FS_EXECUTE |
+		unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_READ_FILE |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE |
+		unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+	landlockV1 = 1<<13 - 1 /* EXECUTE through MAKE_SYM */
+	landlockV2 = landlockV1 | unix.LANDLOCK_ACCESS_FS_REFER
+	landlockV3 = landlockV2 | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+	landlockV5 = landlockV3 | unix.LANDLOCK_ACCESS_FS_IOCTL_DEV
+)
+
+/* restrict uses Landlock to limit us to the files and directories in p.
+Network access isn't restricted.  If the kernel doesn't support Landlock or
+we can't restrict every thread (i.e. we were built with cgo), restrict returns
+false and a nil error. */
+func restrict(p sandboxPolicy) (bool, error) {
+	/* Work out which rights the kernel knows about */
+	abi, _, errno := syscall.Syscall(
+		unix.SYS_LANDLOCK_CREATE_RULESET,
+		0,
+		0,
+		unix.LANDLOCK_CREATE_RULESET_VERSION,
+	)
+	if 0 != errno {
+		debug("Landlock unavailable: %v", errno)
+		return false, nil
+	}
+	var handled uint64
+	switch {
+	case 5 <= abi:
+		handled = landlockV5
+	case 3 <= abi:
+		handled = landlockV3
+	case 2 <= abi:
+		handled = landlockV2
+	default:
+		handled = landlockV1
+	}
+	debug("Landlock ABI version %d", abi)
+
+	/* Make a ruleset allowing only what we need */
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, errno := syscall.Syscall(
+		unix.SYS_LANDLOCK_CREATE_RULESET,
+		uintptr(unsafe.Pointer(&attr)),
+		unsafe.Sizeof(attr),
+		0,
+	)
+	if 0 != errno {
+		return false, fmt.Errorf("creating ruleset: %w", errno)
+	}
+	defer unix.Close(int(fd))
+	rd := landlockRead & handled
+	for _, f := range p.read {
+		if err := landlockAllow(int(fd), f, rd); nil != err {
+			return false, err
+		}
+	}
+	/* Replacing files needs REFER and TRUNCATE, if we've got them */
+	wr := (landlockWrite | unix.LANDLOCK_ACCESS_FS_REFER |
+		unix.LANDLOCK_ACCESS_FS_TRUNCATE) & handled
+	for _, d := range p.write {
+		if err := landlockAllow(int(fd), d, wr); nil != err {
+			return false, err
+		}
+	}
+
+	/* Apply it to every thread */
+	if _, _, errno := syscall.AllThreadsSyscall(
+		syscall.SYS_PRCTL,
+		unix.PR_SET_NO_NEW_PRIVS,
+		1,
+		0,
+	); 0 != errno {
+		if errors.Is(errno, syscall.ENOTSUP) {
+			debug("Unable to sandbox a program built with cgo")
+			return false, nil
+		}
+		return false, fmt.Errorf("setting no_new_privs: %w", errno)
+	}
+	if _, _, errno := syscall.AllThreadsSyscall(
+		unix.SYS_LANDLOCK_RESTRICT_SELF,
+		fd,
+		0,
+		0,
+	); 0 != errno {
+		return false, fmt.Errorf("restricting ourselves: %w", errno)
+	}
+
+	return true, nil
+}
+
+/* landlockAllow adds a rule to the ruleset rfd allowing the access in rights
+beneath the path n.  Rights which only make sense for directories are
+dropped if n isn't a directory.  Paths on pseudo-filesystems, e.g. pipes from
+/dev/fd, aren't restricted by Landlock and are skipped. */
+func landlockAllow(rfd int, n string, rights uint64) error {
+	pfd, err := unix.Open(n, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if nil != err {
+		return fmt.Errorf("opening %s: %w", n, err)
+	}
+	defer unix.Close(pfd)
+	var st unix.Stat_t
+	if err := unix.Fstat(pfd, &st); nil != err {
+		return fmt.Errorf("checking %s: %w", n, err)
+	}
+	if unix.S_IFDIR != st.Mode&unix.S_IFMT {
+		rights &= landlockFileRights
+	}
+	attr := unix.LandlockPathBeneathAttr{
+		Allowed_access: rights,
+		Parent_fd:      int32(pfd),
+	}
+	if _, _, errno := syscall.Syscall6(
+		unix.SYS_LANDLOCK_ADD_RULE,
+		uintptr(rfd),
+		unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&attr)),
+		0,
+		0,
+		0,
+	); unix.EBADFD == errno {
+		debug("Not adding Landlock rule for %s: %v", n, errno)
+	} else if 0 != errno {
+		return fmt.Errorf("allowing access to %s: %w", n, errno)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * sandbox_openbsd.go
+ * Restrict csvcol with pledge(2) and unveil(2)
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+/* restrict unveils the files and directories in p and pledges not to do
+anything else.  It always returns true or an error. */
+func restrict(p sandboxPolicy) (bool, error) {
+	/* Only see what we need */
+	for _, f := range p.read {
+		if err := unix.Unveil(f, "r"); nil != err {
+			return false, fmt.Errorf("unveil %s: %w", f, err)
+		}
+	}
+	for _, d := range p.write {
+		if err := unix.Unveil(d, "rwc"); nil != err {
+			return false, fmt.Errorf("unveil %s: %w", d, err)
+		}
+	}
+	if err := unix.UnveilBlock(); nil != err {
+		return false, fmt.Errorf("unveil: %w", err)
+	}
+
+	/* Only do what we need */
+	ps := []string{"stdio", "rpath"}
+	if 0 != len(p.write) {
+		ps = append(ps, "wpath", "cpath")
+	}
+	if p.fattr {
+		ps = append(ps, "fattr", "chown")
+	}
+	if p.flock {
+		ps = append(ps, "flock")
+	}
+	if p.net {
+		ps = append(ps, "inet", "dns")
+	}
+	if err := unix.PledgePromises(strings.Join(ps, " ")); nil != err {
+		return false, fmt.Errorf("pledge: %w", err)
+	}
+
+	return true, nil
+}
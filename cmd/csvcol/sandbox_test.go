@@ -0,0 +1,79 @@
+/*
+ * sandbox_test.go
+ * Tests for sandbox.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSandboxOffByDefault(t *testing.T) {
+	if got, want := mustRun(t, "a,b\n", "-cols", "2"), "b\n"; want != got {
+		t.Errorf("Output incorrect:\ngot: %q\nwant: %q", got, want)
+	}
+}
+
+func TestSandboxRejectsHelpers(t *testing.T) {
+	dir := t.TempDir()
+	zst := writeTestFile(t, dir, "x.zst", "a,b\n")
+	for _, c := range []struct {
+		name string
+		args []string
+		want string
+	}{{
+		name: "sqlite",
+		args: []string{"-sandbox", "-sqlite", dir + "/x.db"},
+		want: "-sqlite",
+	}, {
+		name: "zst",
+		args: []string{"-sandbox", zst},
+		want: "x.zst",
+	}} {
+		t.Run(c.name, func(t *testing.T) {
+			res := runCSVCol(t, "a,b\n", c.args...)
+			if 0 == res.code {
+				t.Errorf("Exited happily")
+			}
+			if !strings.Contains(res.stderr, c.want) {
+				t.Errorf("Error %q doesn't mention %q",
+					res.stderr, c.want)
+			}
+			if "" != res.stdout {
+				t.Errorf("Unexpected output: %q", res.stdout)
+			}
+		})
+	}
+}
+
+func TestUnreadableInputFails(t *testing.T) {
+	dir := t.TempDir()
+	ok := writeTestFile(t, dir, "ok.csv", "a,b\n")
+	/* Directories can be opened but not read */
+	res := runCSVCol(t, "", dir, ok)
+	if 0 == res.code {
+		t.Errorf("Exited happily after failing to read %v", dir)
+	}
+	if got, want := res.stdout, "a,b\n"; want != got {
+		t.Errorf("Output incorrect:\ngot: %q\nwant: %q", got, want)
+	}
+}
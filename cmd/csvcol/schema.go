@@ -0,0 +1,213 @@
+/*
+ * schema.go
+ * Compare two files' schemas
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* schemaEmptyDrift is how much the fraction of empty values in a column must
+change to be reported */
+const schemaEmptyDrift = 0.1
+
+/* schemaColumn is what we've inferred about a column */
+type schemaColumn struct {
+	pos    int /* 1-indexed */
+	typ    string
+	values int
+	empty  int
+}
+
+/* schema is what we've inferred about a file's columns, from its header
+and a sample of its rows */
+type schema struct {
+	names []string
+	cols  map[string]*schemaColumn /* First column with each name */
+}
+
+/* valueType returns the type of a non-empty value: integer, real, boolean,
+timestamp, or text. */
+func valueType(v string) string {
+	v = strings.TrimSpace(v)
+	if _, err := strconv.ParseInt(v, 10, 64); nil == err {
+		return "integer"
+	}
+	if _, err := strconv.ParseFloat(v, 64); nil == err {
+		return "real"
+	}
+	switch strings.ToLower(v) {
+	case "true", "false":
+		return "boolean"
+	}
+	for _, l := range timeLayouts {
+		if _, err := time.Parse(l, v); nil == err {
+			return "timestamp"
+		}
+	}
+	return "text"
+}
+
+/* mergeTypes returns the type of a column with values of types a and b.  An
+empty type is a column with no values so far. */
+func mergeTypes(a, b string) string {
+	switch {
+	case "" == a || a == b:
+		return b
+	case "" == b:
+		return a
+	case ("integer" == a && "real" == b) || ("real" == a && "integer" == b):
+		return "real"
+	}
+	return "text"
+}
+
+/* readSchema infers the schema of the named file from its header and up to
+sample rows, or all of the rows if sample is 0. */
+func readSchema(f string, sample int) (*schema, error) {
+	fp, fname := openInput(f)
+	if os.Stdin != fp {
+		defer fp.Close()
+	}
+	verbose("Reading schema of %v", fname)
+	r := newReader(fp)
+	names, err := r.Read()
+	if nil != err {
+		return nil, fmt.Errorf("reading header of %v: %w", fname, err)
+	}
+	s := &schema{names: names, cols: make(map[string]*schemaColumn)}
+	byPos := make([]*schemaColumn, len(names))
+	for i, n := range names {
+		byPos[i] = &schemaColumn{pos: i + 1}
+		if _, ok := s.cols[n]; !ok {
+			s.cols[n] = byPos[i]
+		}
+	}
+	for n := 0; 0 == sample || n < sample; n++ {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if nil != err {
+			return nil, fmt.Errorf("reading %v: %w", fname, err)
+		}
+		for i, c := range byPos {
+			c.values++
+			if i >= len(rec) || "" == strings.TrimSpace(rec[i]) {
+				c.empty++
+				continue
+			}
+			c.typ = mergeTypes(c.typ, valueType(rec[i]))
+		}
+	}
+	for _, c := range byPos {
+		if "" == c.typ {
+			c.typ = "empty"
+		}
+	}
+	return s, nil
+}
+
+/* emptyFraction returns the fraction of c's values which are empty */
+func (c *schemaColumn) emptyFraction() float64 {
+	if 0 == c.values {
+		return 0
+	}
+	return float64(c.empty) / float64(c.values)
+}
+
+/* compareSchema compares the schemas of two files, inferred from their
+headers and up to sample rows, and writes CSV describing how the second
+differs from the first.  It returns the number of differences found. */
+func compareSchema(files []string, sample int) (int, error) {
+	if 2 != len(files) {
+		return 0, fmt.Errorf("need two files, got %d", len(files))
+	}
+	old, err := readSchema(files[0], sample)
+	if nil != err {
+		return 0, err
+	}
+	cur, err := readSchema(files[1], sample)
+	if nil != err {
+		return 0, err
+	}
+
+	w := newWriter(stdout)
+	w.Write([]string{"column", "change", "old", "new"})
+	n := 0
+	drift := func(col, change, o, c string) {
+		w.Write([]string{col, change, o, c})
+		n++
+	}
+	ff := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 2, 64)
+	}
+	for _, name := range old.names {
+		o := old.cols[name]
+		if o.pos != indexOf(old.names, name)+1 {
+			continue /* Duplicate name */
+		}
+		c, ok := cur.cols[name]
+		if !ok {
+			drift(name, "removed", strconv.Itoa(o.pos), "")
+			continue
+		}
+		if o.pos != c.pos {
+			drift(name, "moved",
+				strconv.Itoa(o.pos), strconv.Itoa(c.pos))
+		}
+		if o.typ != c.typ {
+			drift(name, "retyped", o.typ, c.typ)
+		}
+		oe, ce := o.emptyFraction(), c.emptyFraction()
+		if schemaEmptyDrift <= math.Abs(oe-ce) {
+			drift(name, "empty", ff(oe), ff(ce))
+		}
+	}
+	for _, name := range cur.names {
+		c := cur.cols[name]
+		if c.pos != indexOf(cur.names, name)+1 {
+			continue
+		}
+		if _, ok := old.cols[name]; !ok {
+			drift(name, "added", "", strconv.Itoa(c.pos))
+		}
+	}
+	w.Flush()
+	return n, w.Error()
+}
+
+/* indexOf returns the index of the first s in ss, or -1 if it's not there */
+func indexOf(ss []string, s string) int {
+	for i, v := range ss {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
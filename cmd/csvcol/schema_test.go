@@ -0,0 +1,149 @@
+/*
+ * schema_test.go
+ * Tests for schema.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValueType(t *testing.T) {
+	for have, want := range map[string]string{
+		"12":                   "integer",
+		" -7 ":                 "integer",
+		"1.5":                  "real",
+		"1e3":                  "real",
+		"true":                 "boolean",
+		"FALSE":                "boolean",
+		"2026-10-17":           "timestamp",
+		"2026-10-17T01:02:03Z": "timestamp",
+		"hello":                "text",
+		"1,5":                  "text",
+	} {
+		if got := valueType(have); got != want {
+			t.Errorf(
+				"valueType(%q): got %s, want %s",
+				have,
+				got,
+				want,
+			)
+		}
+	}
+}
+
+func TestMergeTypes(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want string
+	}{
+		{a: "", b: "integer", want: "integer"},
+		{a: "real", b: "", want: "real"},
+		{a: "integer", b: "integer", want: "integer"},
+		{a: "integer", b: "real", want: "real"},
+		{a: "real", b: "integer", want: "real"},
+		{a: "integer", b: "boolean", want: "text"},
+		{a: "timestamp", b: "real", want: "text"},
+	} {
+		if got := mergeTypes(c.a, c.b); got != c.want {
+			t.Errorf(
+				"mergeTypes(%q, %q): got %s, want %s",
+				c.a,
+				c.b,
+				got,
+				c.want,
+			)
+		}
+	}
+}
+
+func TestCompareSchema(t *testing.T) {
+	dir := t.TempDir()
+	old := writeTestFile(t, dir, "old.csv", "id,name,score,when,flag\n"+
+		"1,a,1,2026-01-01,true\n"+
+		"2,b,2,2026-01-02,false\n"+
+		"3,c,3,2026-01-03,true\n"+
+		"4,d,,2026-01-04,true\n")
+	cur := writeTestFile(t, dir, "new.csv", "id,score,name,when,extra\n"+
+		"1,1.5,a,2026-02-01,x\n"+
+		"2,2,b,,y\n"+
+		"3,3,c,,z\n"+
+		"4,4,d,,w\n")
+
+	/* Every sort of drift */
+	res := runCSVCol(t, "", "-compare-schema", old, cur)
+	if -44 != int8(res.code) {
+		t.Errorf("Exit code %d: %s", int8(res.code), res.stderr)
+	}
+	want := "column,change,old,new\n" +
+		"name,moved,2,3\n" +
+		"score,moved,3,2\n" +
+		"score,retyped,integer,real\n" +
+		"score,empty,0.25,0.00\n" +
+		"when,empty,0.00,0.75\n" +
+		"flag,removed,5,\n" +
+		"extra,added,,5\n"
+	if res.stdout != want {
+		t.Errorf("Got:\n%s\nwant:\n%s", res.stdout, want)
+	}
+
+	/* Only sampling the first row misses the empty values */
+	res = runCSVCol(
+		t,
+		"",
+		"-compare-schema",
+		"-schema-sample", "1",
+		old,
+		cur,
+	)
+	want = "column,change,old,new\n" +
+		"name,moved,2,3\n" +
+		"score,moved,3,2\n" +
+		"score,retyped,integer,real\n" +
+		"flag,removed,5,\n" +
+		"extra,added,,5\n"
+	if res.stdout != want {
+		t.Errorf("Sampled:\nGot:\n%s\nwant:\n%s", res.stdout, want)
+	}
+
+	/* No drift, no complaints */
+	got := mustRun(t, "", "-compare-schema", old, old)
+	if "column,change,old,new\n" != got {
+		t.Errorf("Same file had differences:\n%s", got)
+	}
+
+	/* Need exactly two files */
+	res = runCSVCol(t, "", "-compare-schema", old)
+	if -8 != int8(res.code) {
+		t.Errorf("One file exit code %d", int8(res.code))
+	}
+	res = runCSVCol(
+		t,
+		"",
+		"-compare-schema",
+		old,
+		filepath.Join(dir, "missing.csv"),
+	)
+	if 0 == res.code {
+		t.Errorf("Missing file succeeded")
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * section.go
+ * Pick one table out of a file with several
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+/* sectionReader passes through only the lines of one section of its
+underlying reader.  Sections are separated either by blank lines or by lines
+matching a regular expression, but not by lines in quoted fields. */
+type sectionReader struct {
+	r       *bufio.Reader
+	want    int            /* Section to pass through */
+	marker  *regexp.Regexp /* Section marker, or nil for blank lines */
+	section int            /* Current section */
+	blank   bool           /* Previous line was blank */
+	quoted  bool           /* In a quoted field which spans lines */
+	pending string         /* Unread part of current line */
+	err     error
+
+	/* Where the section started, for -trace */
+	lines       int   /* Lines read so far */
+	offset      int64 /* Bytes read so far */
+	started     bool  /* Wanted section found */
+	startLine   int   /* Lines before the section */
+	startOffset int64 /* Bytes before the section */
+}
+
+/* newSectionReader returns a sectionReader which reads section want from r.
+If marker is nil, sections are separated by one or more blank lines and the
+first section is section 1.  Otherwise, each line matching marker starts a new
+section, the first of which is section 1, and lines before the first marker
+are section 0.  Marker lines are not passed through. */
+func newSectionReader(
+	r io.Reader,
+	want int,
+	marker *regexp.Regexp,
+) *sectionReader {
+	sr := &sectionReader{
+		r:      bufio.NewReader(r),
+		want:   want,
+		marker: marker,
+	}
+	if nil == marker {
+		sr.section = 1
+		sr.blank = true /* Leading blank lines don't count */
+	}
+	return sr
+}
+
+/* Read satisfies io.Reader */
+func (sr *sectionReader) Read(p []byte) (int, error) {
+	if !sr.fill() {
+		return 0, sr.err
+	}
+	n := copy(p, sr.pending)
+	sr.pending = sr.pending[n:]
+	return n, nil
+}
+
+/* fill reads lines until it has one to pass through, which it puts in
+sr.pending.  It returns false if there's no more to pass through. */
+func (sr *sectionReader) fill() bool {
+	for "" == sr.pending {
+		if nil != sr.err {
+			return false
+		}
+		/* Past the section we want, no need to read any more */
+		if sr.section > sr.want {
+			sr.err = io.EOF
+			continue
+		}
+		var l string
+		l, sr.err = sr.r.ReadString('\n')
+		if "" == l {
+			continue
+		}
+		line, offset := sr.lines, sr.offset
+		sr.lines++
+		sr.offset += int64(len(l))
+		if !sr.quoted && sr.newSection(l) {
+			continue
+		}
+		/* An odd number of quotes starts or ends a multi-line field */
+		if 1 == strings.Count(l, `"`)%2 {
+			sr.quoted = !sr.quoted
+		}
+		if sr.section == sr.want {
+			if !sr.started {
+				sr.started = true
+				sr.startLine, sr.startOffset = line, offset
+			}
+			sr.pending = l
+		}
+	}
+	return true
+}
+
+/* start returns the number of lines and bytes before the first line of the
+section, reading up to it if need be. */
+func (sr *sectionReader) start() (lines int, offset int64) {
+	sr.fill()
+	if !sr.started {
+		return sr.lines, sr.offset
+	}
+	return sr.startLine, sr.startOffset
+}
+
+/* newSection checks whether l separates sections and updates sr.section if
+so.  It returns true if l should not be passed through. */
+func (sr *sectionReader) newSection(l string) bool {
+	t := strings.TrimRight(l, "\r\n")
+	if nil != sr.marker {
+		if sr.marker.MatchString(t) {
+			sr.section++
+			return true
+		}
+		return false
+	}
+	if "" == strings.TrimSpace(t) {
+		if !sr.blank {
+			sr.section++
+		}
+		sr.blank = true
+		return true
+	}
+	/* Back in a section */
+	sr.blank = false
+	return false
+}
+
+/* sectionPos is a recordReader which reports line numbers and byte offsets
+relative to the start of the file rather than the start of the section, for
+-trace. */
+type sectionPos struct {
+	recordReader
+	sr *sectionReader
+}
+
+/* InputOffset returns the byte offset in the file of the end of the most
+recently read record. */
+func (s sectionPos) InputOffset() int64 {
+	_, offset := s.sr.start()
+	return offset + s.recordReader.InputOffset()
+}
+
+/* FieldPos returns the line and column in the file of the start of the given
+field of the most recently read record. */
+func (s sectionPos) FieldPos(field int) (line, column int) {
+	lines, _ := s.sr.start()
+	line, column = s.recordReader.FieldPos(field)
+	return lines + line, column
+}
@@ -0,0 +1,86 @@
+/*
+ * section_test.go
+ * Tests for section.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestSection(t *testing.T) {
+	blank := "a,b\nc,d\n\n\ne,f\ng,h\n  \ni,j\n"
+	marked := "pre\n[one]\na,b\n[two]\nc,d\ne,f\n"
+	runOutputTests(t, []outputTest{{
+		name:  "first",
+		stdin: blank,
+		args:  []string{"-section", "1"},
+		want:  "a,b\nc,d\n",
+	}, {
+		name:  "blank_lines",
+		stdin: blank,
+		args:  []string{"-section", "2"},
+		want:  "e,f\ng,h\n",
+	}, {
+		name:  "whitespace_line",
+		stdin: blank,
+		args:  []string{"-section", "3"},
+		want:  "i,j\n",
+	}, {
+		name:  "leading_blank_lines",
+		stdin: "\n\na,b\n\nc,d\n",
+		args:  []string{"-section", "2"},
+		want:  "c,d\n",
+	}, {
+		name:  "crlf",
+		stdin: "a\r\n\r\nb\r\n",
+		args:  []string{"-section", "2"},
+		want:  "b\n",
+	}, {
+		name:  "past_the_end",
+		stdin: blank,
+		args:  []string{"-section", "4"},
+		want:  "",
+	}, {
+		name:  "marker",
+		stdin: marked,
+		args:  []string{"-section", "2", "-section-marker", `^\[`},
+		want:  "c,d\ne,f\n",
+	}, {
+		name:  "marker_default",
+		stdin: marked,
+		args:  []string{"-section-marker", `^\[`},
+		want:  "a,b\n",
+	}, {
+		name:  "quoted_blank_line",
+		stdin: "a,b\n\n\"x\n\ny\",z\n\nq,r\n",
+		args:  []string{"-section", "2"},
+		want:  "\"x\n\ny\",z\n",
+	}, {
+		name:  "quoted_marker",
+		stdin: "[a]\n\"x\n[b]\ny\"\n[c]\nq\n",
+		args:  []string{"-section", "2", "-section-marker", `^\[`},
+		want:  "q\n",
+	}, {
+		name:  "header",
+		stdin: "h1,h2\n1,2\n\nk1,k2\n3,4\n",
+		args:  []string{"-section", "2", "-header", "-json"},
+		want:  `{"k1":"3","k2":"4"}` + "\n",
+	}})
+}
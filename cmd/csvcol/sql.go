@@ -0,0 +1,183 @@
+/*
+ * sql.go
+ * SQL INSERT and PostgreSQL COPY output
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* sqlWriter is a recordWriter which writes records as SQL INSERT statements
+or as PostgreSQL COPY text format.  Empty fields become NULL. */
+type sqlWriter struct {
+	w      *bufio.Writer
+	table  string
+	sel    *csvcol.Selector
+	pgcopy bool   /* COPY, not INSERT */
+	header bool   /* First record is a header */
+	names  string /* Quoted, comma-separated column names */
+	err    error
+}
+
+/* pgcopyEscaper escapes fields for COPY's text format */
+var pgcopyEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+/* newSQLWriter returns an sqlWriter which writes statements for the table
+named table to w.  If pgcopy is true, a COPY statement and data are written
+instead of INSERTs.  If header is true, the first record is taken to be the
+column names; if not, column names come from sel. */
+func newSQLWriter(
+	w io.Writer,
+	table string,
+	sel *csvcol.Selector,
+	pgcopy bool,
+	header bool,
+) *sqlWriter {
+	return &sqlWriter{
+		w:      bufio.NewWriter(w),
+		table:  sqlName(table),
+		sel:    sel,
+		pgcopy: pgcopy,
+		header: header,
+	}
+}
+
+/* WriteHeader writes header like any other record; if s was made with
+header true, it names the columns */
+func (s *sqlWriter) WriteHeader(header []string) error {
+	return s.Write(header)
+}
+
+/* Write writes record as an INSERT statement or a line of COPY data */
+func (s *sqlWriter) Write(record []string) error {
+	if nil != s.err {
+		return s.err
+	}
+	/* First record tells us the columns */
+	if "" == s.names {
+		names := make([]string, len(record))
+		for i, f := range record {
+			if !s.header {
+				f = columnName(s.sel, i, len(record))
+			}
+			names[i] = sqlName(f)
+		}
+		s.names = strings.Join(names, ", ")
+		if s.pgcopy {
+			fmt.Fprintf(s.w, "COPY %s (%s) FROM stdin;\n", s.table,
+				s.names)
+		}
+		if s.header {
+			return s.err
+		}
+	}
+
+	/* COPY data is tab-separated */
+	if s.pgcopy {
+		for i, f := range record {
+			if 0 != i {
+				s.w.WriteByte('\t')
+			}
+			if "" == f {
+				s.w.WriteString(`\N`)
+			} else {
+				pgcopyEscaper.WriteString(s.w, f)
+			}
+		}
+		_, s.err = s.w.WriteString("\n")
+		return s.err
+	}
+
+	fmt.Fprintf(s.w, "INSERT INTO %s (%s) VALUES (", s.table, s.names)
+	for i, f := range record {
+		if 0 != i {
+			s.w.WriteString(", ")
+		}
+		switch {
+		case "" == f:
+			s.w.WriteString("NULL")
+		case isPlainNumber(f):
+			s.w.WriteString(f)
+		default:
+			s.w.WriteString(sqlString(f))
+		}
+	}
+	_, s.err = s.w.WriteString(");\n")
+	return s.err
+}
+
+/* WriteLine writes line as an SQL comment.  Lines can't go in COPY data, so
+are skipped. */
+func (s *sqlWriter) WriteLine(line string) error {
+	if nil != s.err {
+		return s.err
+	}
+	if s.pgcopy {
+		debug("Not writing line in COPY data: %q", line)
+		return nil
+	}
+	for _, l := range strings.Split(line, "\n") {
+		if _, s.err = fmt.Fprintf(s.w, "-- %s\n", l); nil != s.err {
+			break
+		}
+	}
+	return s.err
+}
+
+/* Flush writes any buffered output */
+func (s *sqlWriter) Flush() {
+	if nil == s.err {
+		s.err = s.w.Flush()
+	}
+}
+
+/* Error returns the first error encountered, if any */
+func (s *sqlWriter) Error() error { return s.err }
+
+/* Close ends the COPY data, if there is any, and flushes the output */
+func (s *sqlWriter) Close() error {
+	if s.pgcopy && "" != s.names && nil == s.err {
+		_, s.err = s.w.WriteString("\\.\n")
+	}
+	s.Flush()
+	return s.err
+}
+
+/* sqlName quotes n for use as an identifier */
+func sqlName(n string) string {
+	return `"` + strings.ReplaceAll(n, `"`, `""`) + `"`
+}
+
+/* sqlString quotes s for use as a string literal */
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,78 @@
+/*
+ * sql_test.go
+ * Tests for sql.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestSQLOutput(t *testing.T) {
+	in := "id,name,note\n1,O'Brien,\n007,\"Tab\there\",\"a\\b\nc\"\n"
+	runOutputTests(t, []outputTest{{
+		name:  "insert",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-format", "sql",
+			"-table", "people",
+		},
+		want: `INSERT INTO "people" ("id", "name", "note") ` +
+			`VALUES (1, 'O''Brien', NULL);` + "\n" +
+			`INSERT INTO "people" ("id", "name", "note") ` +
+			"VALUES ('007', 'Tab\there', 'a\\b\nc');\n",
+	}, {
+		name:  "insert_no_header",
+		stdin: "x\"y,2.5\n",
+		args:  []string{"-format", "sql", "-table", `my "t"`},
+		want: `INSERT INTO "my ""t""" ("c1", "c2") ` +
+			`VALUES ('x"y', 2.5);` + "\n",
+	}, {
+		name:  "insert_selected_columns",
+		stdin: in,
+		args:  []string{"-header", "-format", "sql", "-cols", "2"},
+		want: `INSERT INTO "data" ("name") VALUES ('O''Brien');` +
+			"\n" + `INSERT INTO "data" ("name") ` +
+			"VALUES ('Tab\there');\n",
+	}, {
+		name:  "pgcopy",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-format", "pgcopy",
+			"-table", "people",
+		},
+		want: `COPY "people" ("id", "name", "note") FROM stdin;` +
+			"\n" +
+			"1\tO'Brien\t\\N\n" +
+			"007\tTab\\there\ta\\\\b\\nc\n" +
+			"\\.\n",
+	}, {
+		name:  "pgcopy_header_only",
+		stdin: "a,b\n",
+		args:  []string{"-header", "-format", "pgcopy"},
+		want:  "COPY \"data\" (\"a\", \"b\") FROM stdin;\n\\.\n",
+	}, {
+		name:  "pgcopy_nothing",
+		stdin: "",
+		args:  []string{"-format", "pgcopy"},
+		want:  "",
+	}})
+}
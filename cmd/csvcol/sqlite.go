@@ -0,0 +1,239 @@
+/*
+ * sqlite.go
+ * Loading output into SQLite
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+/* Rows are loaded with the sqlite3 program rather than a library, to avoid
+the dependencies. */
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* sqliteStaging is the temporary table into which rows are loaded before
+we know the columns' types */
+const sqliteStaging = "temp.csvcol_staging"
+
+/* sqliteWriter is a recordWriter which loads records into a SQLite table
+using the sqlite3 program.  Records are loaded into a temporary table as they
+come, and copied into the real table, created with each column's type
+inferred from its values, when the writer is closed. */
+type sqliteWriter struct {
+	file   string
+	table  string
+	sel    *csvcol.Selector
+	header bool     /* First record is a header */
+	names  []string /* Column names */
+	types  []string /* Inferred types, INTEGER, REAL, or TEXT */
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	sql    *bufio.Writer
+	stderr strings.Builder
+	err    error
+}
+
+/* checkSQLite returns an error if the sqlite3 program isn't in the PATH, so
+that -sqlite fails before any input is read. */
+func checkSQLite() error {
+	_, err := exec.LookPath("sqlite3")
+	return err
+}
+
+/* newSQLiteWriter returns a sqliteWriter which writes to the table named
+table in the database in file.  If header is true, the first record is taken
+to be the table's column names; if not, column names come from sel. */
+func newSQLiteWriter(
+	file string,
+	table string,
+	sel *csvcol.Selector,
+	header bool,
+) *sqliteWriter {
+	return &sqliteWriter{file: file, table: table, sel: sel, header: header}
+}
+
+/* start starts sqlite3 and makes the staging table with the columns in
+names. */
+func (s *sqliteWriter) start(names []string) error {
+	s.names = names
+	s.types = make([]string, len(names))
+	s.cmd = exec.Command("sqlite3", "-batch", "-bail", s.file)
+	s.cmd.Stderr = &s.stderr
+	var err error
+	if s.stdin, err = s.cmd.StdinPipe(); nil != err {
+		return err
+	}
+	if err := s.cmd.Start(); nil != err {
+		return fmt.Errorf("starting sqlite3: %w", err)
+	}
+	s.sql = bufio.NewWriter(s.stdin)
+	fmt.Fprintf(s.sql, "BEGIN;\nCREATE TABLE %s (", sqliteStaging)
+	for i, n := range names {
+		if 0 != i {
+			s.sql.WriteString(", ")
+		}
+		s.sql.WriteString(sqlName(n))
+	}
+	_, err = s.sql.WriteString(");\n")
+	return err
+}
+
+/* WriteHeader writes header like any other record; if s was made with
+header true, it names the columns */
+func (s *sqliteWriter) WriteHeader(header []string) error {
+	return s.Write(header)
+}
+
+/* Write loads record into the staging table.  Records with fewer fields than
+the table has columns are padded with empty fields. */
+func (s *sqliteWriter) Write(record []string) error {
+	if nil != s.err {
+		return s.err
+	}
+	/* First record tells us the columns */
+	if nil == s.names {
+		names := record
+		if !s.header {
+			names = make([]string, len(record))
+			for i := range names {
+				names[i] = columnName(s.sel, i, len(record))
+			}
+		}
+		if s.err = s.start(names); nil != s.err || s.header {
+			return s.err
+		}
+	}
+	if len(record) > len(s.names) {
+		s.err = fmt.Errorf("record has %v fields, but table has %v "+
+			"columns", len(record), len(s.names))
+		return s.err
+	}
+	fmt.Fprintf(s.sql, "INSERT INTO %s VALUES (", sqliteStaging)
+	for i := range s.names {
+		var f string
+		if i < len(record) {
+			f = record[i]
+		}
+		s.types[i] = inferSQLiteType(s.types[i], f)
+		if 0 != i {
+			s.sql.WriteString(", ")
+		}
+		s.sql.WriteString(sqlString(f))
+	}
+	_, s.err = s.sql.WriteString(");\n")
+	return s.err
+}
+
+/* WriteLine does nothing, as lines don't belong in a table */
+func (s *sqliteWriter) WriteLine(line string) error {
+	debug("Not loading line into SQLite: %q", line)
+	return s.err
+}
+
+/* Flush sends buffered records to sqlite3 */
+func (s *sqliteWriter) Flush() {
+	if nil != s.sql && nil == s.err {
+		s.err = s.sql.Flush()
+	}
+}
+
+/* Error returns the first error encountered, if any */
+func (s *sqliteWriter) Error() error { return s.err }
+
+/* Close creates the real table, copies the records from the staging table
+into it, and waits for sqlite3 to finish.  Empty fields in INTEGER and REAL
+columns are loaded as NULL.  If the table already exists, records are added to
+it. */
+func (s *sqliteWriter) Close() error {
+	if nil == s.sql { /* Never got any records */
+		return s.err
+	}
+	if nil == s.err {
+		fmt.Fprintf(s.sql, "CREATE TABLE IF NOT EXISTS %s (",
+			sqlName(s.table))
+		for i, n := range s.names {
+			if 0 != i {
+				s.sql.WriteString(", ")
+			}
+			fmt.Fprintf(s.sql, "%s %s", sqlName(n),
+				sqliteType(s.types[i]))
+		}
+		fmt.Fprintf(s.sql, ");\nINSERT INTO %s SELECT ",
+			sqlName(s.table))
+		for i, n := range s.names {
+			if 0 != i {
+				s.sql.WriteString(", ")
+			}
+			if "TEXT" == sqliteType(s.types[i]) {
+				s.sql.WriteString(sqlName(n))
+			} else {
+				fmt.Fprintf(s.sql, "NULLIF(%s, '')",
+					sqlName(n))
+			}
+		}
+		fmt.Fprintf(s.sql, " FROM %s;\nCOMMIT;\n", sqliteStaging)
+		s.err = s.sql.Flush()
+	}
+	s.stdin.Close()
+	if err := s.cmd.Wait(); nil != err && nil == s.err {
+		s.err = fmt.Errorf("sqlite3: %w (%v)", err,
+			strings.TrimSpace(s.stderr.String()))
+	}
+	return s.err
+}
+
+/* inferSQLiteType returns the type of a column which was t before seeing the
+field f.  An empty t means nothing's been seen yet but empty fields.  Numbers
+with leading zeros and the like are taken to be text, so as to keep them as
+they are. */
+func inferSQLiteType(t, f string) string {
+	switch {
+	case "" == f, "TEXT" == t:
+		return t
+	case "REAL" != t && isPlainInteger(f):
+		return "INTEGER"
+	case isPlainNumber(f):
+		return "REAL"
+	}
+	return "TEXT"
+}
+
+/* isPlainInteger returns true if f is an integer written as simply as it can
+be */
+func isPlainInteger(f string) bool {
+	n, err := strconv.ParseInt(f, 10, 64)
+	return nil == err && f == strconv.FormatInt(n, 10)
+}
+
+/* sqliteType returns the type to use for a column inferred to be t */
+func sqliteType(t string) string {
+	if "" == t {
+		return "TEXT"
+	}
+	return t
+}
@@ -0,0 +1,89 @@
+/*
+ * sqlite_test.go
+ * Tests for sqlite.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteMissing(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(dir, "out.db")
+	t.Setenv("PATH", dir)
+	/* Input which can't be read should never be reached */
+	res := runCSVColIn(
+		t,
+		dir,
+		"",
+		"-sqlite", db,
+		filepath.Join(dir, "nonexistent.csv"),
+	)
+	if 0 == res.code {
+		t.Fatalf("No error without sqlite3")
+	}
+	if !strings.Contains(res.stderr, "needs the sqlite3 program") {
+		t.Errorf("Unexpected error: %s", res.stderr)
+	}
+	if strings.Contains(res.stderr, "nonexistent.csv") {
+		t.Errorf("Input read before checking for sqlite3: %s",
+			res.stderr)
+	}
+	if _, err := os.Stat(db); nil == err {
+		t.Errorf("Database created without sqlite3")
+	}
+}
+
+func TestSQLiteLoad(t *testing.T) {
+	if err := checkSQLite(); nil != err {
+		t.Skipf("No sqlite3: %v", err)
+	}
+	dir := t.TempDir()
+	db := filepath.Join(dir, "out.db")
+	mustRun(
+		t,
+		"name,age,score\nalice,31,1.5\nbob,,2\n",
+		"-header",
+		"-sqlite", db,
+		"-table", "people",
+		"-",
+	)
+	out, err := exec.Command(
+		"sqlite3",
+		db,
+		"SELECT name, typeof(age), typeof(score) FROM people "+
+			"ORDER BY name; "+
+			"SELECT type FROM pragma_table_info('people') "+
+			"ORDER BY cid;",
+	).CombinedOutput()
+	if nil != err {
+		t.Fatalf("Querying database: %v\n%s", err, out)
+	}
+	want := "alice|integer|real\nbob|null|real\nTEXT\nINTEGER\nREAL\n"
+	if got := string(out); want != got {
+		t.Errorf("Database incorrect:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,320 @@
+/*
+ * stage.go
+ * In-process pipelines of selection stages
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* stageRow is a row passing through a pipeline.  Stages work on out; the
+rest come along for the ride, for -group-sep, -with-linenum, -with-filename,
+and -trace.  The header, if there is one, is the first row through the
+pipeline and has header set.  Stages pass it on straight away, changing its
+columns as they'd change any other row's. */
+type stageRow struct {
+	in     []string /* Input record */
+	out    []string /* Record so far */
+	row    int      /* Input row number */
+	file   string   /* Printable name of the input file */
+	header bool     /* Row is the header */
+	tr     *traceRecord
+}
+
+/* stage is a single stage of a pipeline.  Push is called for each row and
+calls next for each row the stage passes on.  Flush is called once there are
+no more rows, for stages which hold rows back. */
+type stage interface {
+	push(r stageRow, next func(stageRow))
+	flush(next func(stageRow))
+}
+
+/* pipeline passes rows through a list of stages, and then to out */
+type pipeline struct {
+	stages []stage
+	out    func(stageRow)
+}
+
+/* push passes r to the first stage */
+func (p *pipeline) push(r stageRow) {
+	p.pushAt(0, r)
+}
+
+/* pushAt passes r to the ith stage, or to p.out if there's no ith stage */
+func (p *pipeline) pushAt(i int, r stageRow) {
+	if len(p.stages) == i {
+		p.out(r)
+		return
+	}
+	p.stages[i].push(r, func(r stageRow) { p.pushAt(i+1, r) })
+}
+
+/* flush flushes each stage in turn, so that rows held by one stage are seen
+by the next before it's flushed. */
+func (p *pipeline) flush() {
+	for i, s := range p.stages {
+		s.flush(func(r stageRow) { p.pushAt(i+1, r) })
+	}
+}
+
+/* parseStage parses a stage of the form kind=spec.  Kind is one of rows,
+notrows, cols, notcols, where, match, vmatch, sort, or rsort. */
+func parseStage(s string) (stage, error) {
+	kind, spec, ok := strings.Cut(s, "=")
+	if !ok {
+		return nil, fmt.Errorf("missing =")
+	}
+	kind = strings.TrimSpace(kind)
+	switch kind {
+	case "sort", "rsort":
+		keys, err := parseSortKeys(spec)
+		if nil != err {
+			return nil, err
+		}
+		return &sortStage{
+			keys:    keys,
+			reverse: "rsort" == kind,
+			maxMem:  gc.maxMemBytes,
+		}, nil
+	}
+
+	/* Everything else is a selection */
+	ss := &selectStage{sel: &csvcol.Selector{
+		Ordered: *gc.ordered,
+		Comma:   gc.comma,
+	}}
+	var err error
+	switch kind {
+	case "rows":
+		_, err = ss.sel.Rows.Add(spec)
+	case "notrows":
+		err = ss.sel.Rows.Exclude(spec)
+	case "cols":
+		if _, err = ss.sel.Cols.Add(spec); nil == err &&
+			ss.sel.Cols.Anchored() {
+			err = fmt.Errorf("anchored ranges may only be used " +
+				"for rows")
+		}
+	case "notcols":
+		err = ss.sel.Cols.Exclude(spec)
+	case "where":
+		err = ss.sel.Where.AddAll(spec)
+	case "match":
+		err = ss.sel.Where.AddMatch(spec)
+	case "vmatch":
+		err = ss.sel.Where.AddNotMatch(spec)
+	default:
+		return nil, fmt.Errorf("unknown stage %q", kind)
+	}
+	if nil != err {
+		return nil, err
+	}
+	ss.window = ss.sel.Rows.Window()
+	return ss, nil
+}
+
+/* selectStage selects rows and columns, like the rest of csvcol.  Row numbers
+count the rows which reach the stage, not counting the header. */
+type selectStage struct {
+	sel    *csvcol.Selector
+	window int /* Rows to hold back, for rows counted from the end */
+	held   []stageRow
+}
+
+/* push passes on the selected part of r, if any */
+func (s *selectStage) push(r stageRow, next func(stageRow)) {
+	/* The header only loses columns */
+	if r.header {
+		r.out = s.sel.Columns(r.out)
+		next(r)
+		return
+	}
+	/* Maybe hold on to the last few */
+	if 0 != s.window {
+		s.held = append(s.held, r)
+		if len(s.held) <= s.window {
+			return
+		}
+		r, s.held = s.held[0], s.held[1:]
+	}
+	s.selectRow(r, next)
+}
+
+/* selectRow passes on the selected part of r, if any */
+func (s *selectStage) selectRow(r stageRow, next func(stageRow)) {
+	/* There's no column names, so no error */
+	orec, ok, _ := s.sel.Select(r.out)
+	if !ok {
+		return
+	}
+	r.out = orec
+	next(r)
+}
+
+/* flush passes on any held rows which are selected */
+func (s *selectStage) flush(next func(stageRow)) {
+	s.sel.SetTotal(s.sel.Row() + len(s.held))
+	for _, r := range s.held {
+		s.selectRow(r, next)
+	}
+	s.held = nil
+}
+
+/* sortKey is one of the keys by which a sortStage sorts */
+type sortKey struct {
+	col    int  /* 1-indexed, or 0 for the order in which rows arrived */
+	desc   bool /* Sort in descending order */
+	random bool /* Sort in a random order, for -shuffle */
+}
+
+/* parseSortKeys parses a comma-separated list of sort keys.  Each is a column
+of the form col:N or cN, or order for the order in which rows arrived, and may
+be preceded by a - to sort in descending order. */
+func parseSortKeys(spec string) ([]sortKey, error) {
+	var keys []sortKey
+	for _, p := range strings.Split(spec, ",") {
+		var k sortKey
+		p = strings.TrimSpace(p)
+		p, k.desc = strings.CutPrefix(p, "-")
+		if "order" != p {
+			cols, err := csvcol.ParseGroupKey(p)
+			if nil != err {
+				return nil, err
+			}
+			k.col = cols[0]
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+/* sortStage holds all of its rows and passes them on sorted by the given
+keys.  Columns are compared numerically if both are numbers.  The sort is
+stable: rows with equal keys are kept in the order in which they arrived.  If
+maxMem is not 0 and the held rows would take more than about maxMem bytes,
+they're sorted and spilled to a temporary file, and the files are merged at
+the end. */
+type sortStage struct {
+	keys    []sortKey
+	reverse bool
+	rows    []stageRow
+	order   []int /* Rows' original positions, for the order key */
+	maxMem  int64
+	seed    uint64 /* For random keys */
+	mem     int64  /* Approximate size of rows */
+	n       int    /* Rows pushed */
+	runs    []*sortRun
+}
+
+/* push holds on to r, unless it's the header, which is passed on */
+func (s *sortStage) push(r stageRow, next func(stageRow)) {
+	if r.header {
+		next(r)
+		return
+	}
+	s.rows = append(s.rows, r)
+	s.order = append(s.order, s.n)
+	s.n++
+	if 0 == s.maxMem {
+		return
+	}
+	if s.mem += rowSize(r); s.mem > s.maxMem {
+		s.spill()
+	}
+}
+
+/* flush sorts the held rows and passes them on */
+func (s *sortStage) flush(next func(stageRow)) {
+	sort.Stable(sortRows{s, s.order})
+	if 0 != len(s.runs) {
+		s.merge(next)
+	} else {
+		for _, r := range s.rows {
+			next(r)
+		}
+	}
+	s.rows, s.order = nil, nil
+}
+
+/* sortRows sorts a sortStage's rows and their original positions together */
+type sortRows struct {
+	s     *sortStage
+	order []int
+}
+
+/* Len returns the number of rows */
+func (r sortRows) Len() int { return len(r.order) }
+
+/* Swap swaps two rows */
+func (r sortRows) Swap(i, j int) {
+	r.s.rows[i], r.s.rows[j] = r.s.rows[j], r.s.rows[i]
+	r.order[i], r.order[j] = r.order[j], r.order[i]
+}
+
+/* Less compares two rows by each key in turn */
+func (r sortRows) Less(i, j int) bool {
+	return 0 > r.s.compare(
+		r.s.rows[i].out, r.s.rows[j].out,
+		r.order[i], r.order[j],
+	)
+}
+
+/* compare compares records a and b, which arrived at positions oa and ob, by
+each key in turn.  It returns a negative number if a sorts first, a positive
+number if b sorts first, or 0 if their keys are equal. */
+func (s *sortStage) compare(a, b []string, oa, ob int) int {
+	for _, k := range s.keys {
+		var cmp int
+		if k.random {
+			ra := mix64(s.seed ^ uint64(oa))
+			rb := mix64(s.seed ^ uint64(ob))
+			switch {
+			case ra < rb:
+				cmp = -1
+			case ra > rb:
+				cmp = 1
+			}
+		} else if 0 == k.col {
+			cmp = oa - ob
+		} else {
+			var af, bf string
+			if k.col <= len(a) {
+				af = a[k.col-1]
+			}
+			if k.col <= len(b) {
+				bf = b[k.col-1]
+			}
+			cmp = csvcol.CompareValues(af, bf)
+		}
+		if k.desc != s.reverse {
+			cmp = -cmp
+		}
+		if 0 != cmp {
+			return cmp
+		}
+	}
+	return 0
+}
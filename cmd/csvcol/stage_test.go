@@ -0,0 +1,190 @@
+/*
+ * stage_test.go
+ * Tests for stage.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+/* stageTestInput is the input for stage tests, with a header */
+const stageTestInput = "name,city\nzed,b\namy,a\nbob,c\n"
+
+func TestStageHeader(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want string
+	}{{
+		args: []string{"-stage", "rows=2"},
+		want: "name,city\namy,a\n",
+	}, {
+		args: []string{"-stage", "notrows=1"},
+		want: "name,city\namy,a\nbob,c\n",
+	}, {
+		args: []string{"-stage", "cols=2"},
+		want: "city\nb\na\nc\n",
+	}, {
+		args: []string{"-stage", "notcols=1"},
+		want: "city\nb\na\nc\n",
+	}, {
+		args: []string{"-stage", "where=c2=a"},
+		want: "name,city\namy,a\n",
+	}, {
+		args: []string{"-stage", "match=1:^b"},
+		want: "name,city\nbob,c\n",
+	}, {
+		args: []string{"-stage", "vmatch=1:^b"},
+		want: "name,city\nzed,b\namy,a\n",
+	}, {
+		args: []string{"-stage", "sort=c1"},
+		want: "name,city\namy,a\nbob,c\nzed,b\n",
+	}, {
+		args: []string{"-stage", "rsort=c2"},
+		want: "name,city\nbob,c\nzed,b\namy,a\n",
+	}, {
+		args: []string{"-stage", "sort=c1", "-max-mem", "1"},
+		want: "name,city\namy,a\nbob,c\nzed,b\n",
+	}, {
+		args: []string{"-stage", "sort=c1", "-stage", "cols=2"},
+		want: "city\na\nc\nb\n",
+	}, {
+		args: []string{"-dedup-key", "1", "-stage", "cols=2"},
+		want: "city\nb\na\nc\n",
+	}, {
+		args: []string{"-dedup-window", "2"},
+		want: "name,city\nzed,b\namy,a\nbob,c\n",
+	}, {
+		args: []string{"-sample-n", "3", "-seed", "1"},
+		want: "name,city\nzed,b\namy,a\nbob,c\n",
+	}, {
+		args: []string{"-sample", "1", "-seed", "1"},
+		want: "name,city\nzed,b\namy,a\nbob,c\n",
+	}, {
+		args: []string{"-stage", "sort=c1", "-json"},
+		want: `{"name":"amy","city":"a"}` + "\n" +
+			`{"name":"bob","city":"c"}` + "\n" +
+			`{"name":"zed","city":"b"}` + "\n",
+	}, {
+		args: []string{"-stage", "cols=2", "-json"},
+		want: `{"city":"b"}` + "\n" + `{"city":"a"}` + "\n" +
+			`{"city":"c"}` + "\n",
+	}, {
+		args: []string{
+			"-stage", "sort=c1",
+			"-template", `{{field "name"}}`,
+		},
+		want: "amy\nbob\nzed\n",
+	}} {
+		/* The header may be found with -header or -colnames */
+		for _, h := range [][]string{
+			{"-header"},
+			{"-colnames", "name,city"},
+		} {
+			args := append(append([]string{}, h...), c.args...)
+			t.Run(strings.Join(args, " "), func(t *testing.T) {
+				got := mustRun(t, stageTestInput, args...)
+				if c.want != got {
+					t.Errorf(
+						"Output incorrect:\n"+
+							"got:\n%s\nwant:\n%s",
+						got,
+						c.want,
+					)
+				}
+			})
+		}
+	}
+}
+
+func TestStageHeaderJoin(t *testing.T) {
+	dir := t.TempDir()
+	lk := writeTestFile(t, dir, "lookup.csv", "n,pop\nzed,10\namy,20\n")
+	got := mustRun(
+		t,
+		stageTestInput,
+		"-header", "-join", lk, "-stage", "sort=c3",
+	)
+	if want := "name,city,pop\nzed,b,10\namy,a,20\n"; want != got {
+		t.Errorf("Output incorrect:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStageHeaderOutliers(t *testing.T) {
+	got := mustRun(
+		t,
+		"n,v\na,1\nb,1\nc,1\nd,100\n",
+		"-header", "-flag-outliers", "c2,iqr=1.5", "-stage", "sort=-c2",
+	)
+	want := "n,v,outlier\nd,100,true\na,1,false\nb,1,false\nc,1,false\n"
+	if want != got {
+		t.Errorf("Output incorrect:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	in := "k,v\nb,1\na,2\nb,0\na,2\nc,10\n"
+	rows := []string{"", "b,1", "a,2", "b,0", "a,2", "c,10"}
+	var tests []outputTest
+	for _, c := range []struct {
+		spec string
+		want string /* Line numbers, in order */
+	}{
+		{"sort=c1", "24135"},
+		{"sort=c1,-c2", "24135"},
+		{"sort=c1,c2", "24315"},
+		{"rsort=c1,c2", "51324"},
+		{"rsort=c1,-c2", "53124"},
+		{"sort=c2", "31245"},
+		{"sort=-order", "54321"},
+		{"sort=c1,-order", "42315"},
+		{"rsort=c1,order", "53142"},
+		{"sort=c3,c1", "24135"},
+	} {
+		want := "linenum,k,v\n"
+		for _, n := range c.want {
+			want += string(n) + "," + rows[n-'0'] + "\n"
+		}
+		for _, mem := range []string{"", "1"} {
+			args := []string{
+				"-header",
+				"-with-linenum",
+				"-stage", c.spec,
+			}
+			if "" != mem {
+				args = append(args, "-max-mem", mem)
+			}
+			tests = append(tests, outputTest{
+				name:  c.spec + "/" + mem,
+				stdin: in,
+				args:  args,
+				want:  want,
+			})
+		}
+	}
+	runOutputTests(t, tests)
+	for _, s := range []string{"sort=", "sort=c1,", "sort=x", "sort=--c1"} {
+		if res := runCSVCol(t, in, "-stage", s); -23 != int8(res.code) {
+			t.Errorf("%q: exit code %d", s, int8(res.code))
+		}
+	}
+}
@@ -0,0 +1,264 @@
+/*
+ * stats.go
+ * Summary statistics of selected columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* summary summarizes rows instead of outputting them.  Add is called for
+each selected row with the input record and the selected fields and a
+function which returns the name of the ith selected field.  Each is called
+at the end of the input with a function to write each record of the
+summary. */
+type summary interface {
+	add(record, orec []string, name func(i int) string)
+	each(func([]string) error) error
+}
+
+/* statsHeader is the header of the CSV of statistics */
+var statsHeader = []string{
+	"group", "column", "count", "empty", "numeric",
+	"min", "max", "sum", "mean",
+}
+
+/* colStats holds summary statistics for one column */
+type colStats struct {
+	count   int     /* Values seen */
+	empty   int     /* Empty or whitespace-only values */
+	numeric int     /* Values which are numbers */
+	min     string  /* Smallest non-empty value, per csvcol.CompareValues */
+	max     string  /* Largest non-empty value */
+	sum     float64 /* Sum of numeric values */
+	mean    float64 /* Running mean of numeric values, for m2 */
+	m2      float64 /* Sum of squared differences from the mean */
+}
+
+/* add adds v to the statistics */
+func (c *colStats) add(v string) {
+	c.count++
+	t := strings.TrimSpace(v)
+	if "" == t {
+		c.empty++
+		return
+	}
+	if f, err := strconv.ParseFloat(t, 64); nil == err {
+		c.numeric++
+		c.sum += f
+		/* Welford's algorithm, for the standard deviation */
+		d := f - c.mean
+		c.mean += d / float64(c.numeric)
+		c.m2 += d * (f - c.mean)
+	}
+	if c.count-c.empty == 1 || 0 > csvcol.CompareValues(v, c.min) {
+		c.min = v
+	}
+	if c.count-c.empty == 1 || 0 < csvcol.CompareValues(v, c.max) {
+		c.max = v
+	}
+}
+
+/* record returns the statistics as a CSV record, less the group and column
+name.  The sum and mean are empty if there were no numeric values. */
+func (c *colStats) record() []string {
+	r := []string{
+		strconv.Itoa(c.count),
+		strconv.Itoa(c.empty),
+		strconv.Itoa(c.numeric),
+		c.min,
+		c.max,
+		"",
+		"",
+	}
+	if 0 != c.numeric {
+		r[5] = strconv.FormatFloat(c.sum, 'f', -1, 64)
+		r[6] = strconv.FormatFloat(
+			c.sum/float64(c.numeric),
+			'f', -1, 64,
+		)
+	}
+	return r
+}
+
+/* stddev returns the sample standard deviation of the numeric values and
+true, or false if there weren't at least two numeric values */
+func (c *colStats) stddev() (float64, bool) {
+	if 2 > c.numeric {
+		return 0, false
+	}
+	return math.Sqrt(c.m2 / float64(c.numeric-1)), true
+}
+
+/* columnSummary holds statistics for each selected column, for -stats.
+Column returns the input column number of the ith field of the last record
+passed to add. */
+type columnSummary struct {
+	column func(i int) int
+	cols   []int /* Input column numbers */
+	names  []string
+	stats  []*colStats
+	hlls   []*hyperLogLog /* Distinct values */
+}
+
+/* add adds the fields of orec to the statistics.  Name returns the name of
+the ith field of orec.  Record is ignored. */
+func (c *columnSummary) add(record, orec []string, name func(i int) string) {
+	for i, f := range orec {
+		if len(c.names) == i {
+			c.cols = append(c.cols, c.column(i))
+			c.names = append(c.names, name(i))
+			c.stats = append(c.stats, &colStats{})
+			c.hlls = append(c.hlls, new(hyperLogLog))
+		}
+		c.stats[i].add(f)
+		if "" != strings.TrimSpace(f) {
+			c.hlls[i].add(f)
+		}
+	}
+}
+
+/* each calls f with a header and then with a record for each column with
+the column's input column number and name, the number of values, empty
+values, numeric values, and estimated distinct non-empty values, the minimum
+and maximum, and the mean and sample standard deviation of the numeric
+values. */
+func (c *columnSummary) each(f func([]string) error) error {
+	if err := f([]string{
+		"column", "name", "count", "empty", "numeric", "distinct",
+		"min", "max", "mean", "stddev",
+	}); nil != err {
+		return err
+	}
+	for i, n := range c.names {
+		s := c.stats[i]
+		d := c.hlls[i].estimate()
+		if nonEmpty := uint64(s.count - s.empty); nonEmpty < d {
+			d = nonEmpty
+		}
+		r := s.record() /* count empty numeric min max sum mean */
+		sd := ""
+		if v, ok := s.stddev(); ok {
+			sd = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		if err := f([]string{
+			strconv.Itoa(c.cols[i]),
+			n,
+			r[0],
+			r[1],
+			r[2],
+			strconv.FormatUint(d, 10),
+			r[3],
+			r[4],
+			r[6],
+			sd,
+		}); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+/* groupStats holds per-column statistics for each value of a key */
+type groupStats struct {
+	key     func([]string) (string, bool) /* Gets a record's key */
+	names   []string                      /* Names of the output columns */
+	groups  map[string][]*colStats        /* Key -> per-column stats */
+	order   []string                      /* Keys, in the order seen */
+	sorted  bool                          /* Output groups sorted by key */
+	skipped int                           /* Records without a key */
+}
+
+/* newGroupStats returns a groupStats which groups by the key returned by
+key.  Records for which key returns false are skipped. */
+func newGroupStats(key func([]string) (string, bool)) *groupStats {
+	return &groupStats{key: key, groups: make(map[string][]*colStats)}
+}
+
+/* newColumnStats returns a groupStats which groups by the values of the
+given columns. */
+func newColumnStats(cols []int) *groupStats {
+	return newGroupStats(func(record []string) (string, bool) {
+		return csvcol.GroupKey(cols, record), true
+	})
+}
+
+/* newBucketStats returns a groupStats which groups by time bucket.  Groups
+are output in chronological order. */
+func newBucketStats(b timeBucket) *groupStats {
+	g := newGroupStats(b.key)
+	g.sorted = true
+	return g
+}
+
+/* add adds the fields of orec, selected from record, to the statistics for
+record's group.  Name returns the name of the ith field of orec. */
+func (g *groupStats) add(record, orec []string, name func(i int) string) {
+	k, ok := g.key(record)
+	if !ok {
+		g.skipped++
+		return
+	}
+	cs, ok := g.groups[k]
+	if !ok {
+		g.order = append(g.order, k)
+	}
+	for i, v := range orec {
+		if len(g.names) == i {
+			g.names = append(g.names, name(i))
+		}
+		if len(cs) == i {
+			cs = append(cs, &colStats{})
+		}
+		cs[i].add(v)
+	}
+	g.groups[k] = cs
+}
+
+/* each calls f with statsHeader and then with a record of statistics for
+each column of each group, in the order the groups were seen or sorted by
+key.  Multi-column keys are output with their values separated by spaces. */
+func (g *groupStats) each(f func([]string) error) error {
+	if err := f(statsHeader); nil != err {
+		return err
+	}
+	if g.sorted {
+		sort.Strings(g.order)
+	}
+	for _, k := range g.order {
+		gname := strings.ReplaceAll(k, "\x00", " ")
+		for i, c := range g.groups[k] {
+			if err := f(append(
+				[]string{gname, g.names[i]},
+				c.record()...,
+			)); nil != err {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+/*
+ * stats_test.go
+ * Tests for stats.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+/* statsTestInput is the input for statistics tests, with a header */
+const statsTestInput = "region,item,price\n" +
+	"east,a,10\nwest,b,3\neast,c,x\nwest,d,\neast,e,5\n"
+
+func TestStatsBy(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "no_header",
+		stdin: statsTestInput,
+		args:  []string{"-stats-by", "c1", "-cols", "3"},
+		want: "group,column,count,empty,numeric,min,max,sum,mean\n" +
+			"region,c3,1,0,0,price,price,,\n" +
+			"east,c3,3,0,2,5,x,15,7.5\n" +
+			"west,c3,2,1,1,3,3,3,3\n",
+	}, {
+		name:  "header",
+		stdin: statsTestInput,
+		args:  []string{"-header", "-stats-by", "col:1", "-cols", "2-"},
+		want: "group,column,count,empty,numeric,min,max,sum,mean\n" +
+			"east,item,3,0,0,a,e,,\n" +
+			"east,price,3,0,2,5,x,15,7.5\n" +
+			"west,item,2,0,0,b,d,,\n" +
+			"west,price,2,1,1,3,3,3,3\n",
+	}, {
+		name:  "where",
+		stdin: statsTestInput,
+		args: []string{
+			"-header",
+			"-stats-by", "c1",
+			"-cols", "3",
+			"-where", "c3 > 4",
+		},
+		want: "group,column,count,empty,numeric,min,max,sum,mean\n" +
+			"east,price,3,0,2,5,x,15,7.5\n",
+	}, {
+		name:  "multiple_columns",
+		stdin: "a,x,1\na,y,2\na,x,3\nb,x,4\n",
+		args:  []string{"-stats-by", "c1,c2", "-cols", "3"},
+		want: "group,column,count,empty,numeric,min,max,sum,mean\n" +
+			"a x,c3,2,0,2,1,3,4,2\n" +
+			"a y,c3,1,0,1,2,2,2,2\n" +
+			"b x,c3,1,0,1,4,4,4,4\n",
+	}, {
+		name:  "missing_group_column",
+		stdin: "a,1\n2\na,3\n",
+		args:  []string{"-stats-by", "c2", "-cols", "1"},
+		want: "group,column,count,empty,numeric,min,max,sum,mean\n" +
+			"1,c1,1,0,0,a,a,,\n" +
+			",c1,1,0,1,2,2,2,2\n" +
+			"3,c1,1,0,0,a,a,,\n",
+	}, {
+		name:  "empty",
+		stdin: "",
+		args:  []string{"-stats-by", "c1"},
+		want:  "group,column,count,empty,numeric,min,max,sum,mean\n",
+	}})
+
+	res := runCSVCol(t, statsTestInput, "-stats-by", "x")
+	if 0 == res.code {
+		t.Errorf("Invalid key succeeded")
+	}
+}
+
+func TestColStats(t *testing.T) {
+	var c colStats
+	for _, v := range []string{"10", "3", "x", " ", "5", "-1.5e1"} {
+		c.add(v)
+	}
+	got := fmt.Sprint(c.record())
+	want := "[6 1 4 -1.5e1 x 3 0.75]"
+	if got != want {
+		t.Errorf("Got %s, want %s", got, want)
+	}
+	sd, ok := c.stddev()
+	if want := 10.9048919; !ok || 1e-6 < sd-want || -1e-6 > sd-want {
+		t.Errorf("Standard deviation %v (%v), want %v", sd, ok, want)
+	}
+
+	/* Need two numbers for a standard deviation */
+	c = colStats{}
+	c.add("1")
+	c.add("a")
+	if _, ok := c.stddev(); ok {
+		t.Errorf("Got standard deviation of one number")
+	}
+}
+
+func TestStats(t *testing.T) {
+	runOutputTests(t, []outputTest{{
+		name:  "header",
+		stdin: statsTestInput,
+		args:  []string{"-header", "-stats"},
+		want: "column,name,count,empty,numeric,distinct,min,max," +
+			"mean,stddev\n" +
+			"1,region,5,0,0,2,east,west,,\n" +
+			"2,item,5,0,0,5,a,e,,\n" +
+			"3,price,5,1,3,4,3,x,6,3.605551275463989\n",
+	}, {
+		name:  "selected",
+		stdin: statsTestInput,
+		args: []string{
+			"-header",
+			"-stats",
+			"-cols", "3",
+			"-where", "c1 = east",
+		},
+		want: "column,name,count,empty,numeric,distinct,min,max," +
+			"mean,stddev\n" +
+			"3,price,3,0,2,3,5,x,7.5,3.5355339059327378\n",
+	}, {
+		name:  "no_header",
+		stdin: "a\n1\n",
+		args:  []string{"-stats"},
+		want: "column,name,count,empty,numeric,distinct,min,max," +
+			"mean,stddev\n" +
+			"1,c1,2,0,1,2,1,a,1,\n",
+	}})
+	res := runCSVCol(t, statsTestInput, "-stats", "-stats-by", "c1")
+	if -25 != int8(res.code) {
+		t.Errorf("-stats -stats-by exit code %d", int8(res.code))
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * stream.go
+ * Reading from pipes, FIFOs, and the like
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "os"
+
+/* isStream returns true if fp is the standard input or isn't a regular file,
+e.g. a FIFO or one of the /dev/fd files used for process substitution.  Such
+files can only be read once and may block until something writes to them. */
+func isStream(fp *os.File) bool {
+	if os.Stdin == fp {
+		return true
+	}
+	fi, err := fp.Stat()
+	if nil != err {
+		return true
+	}
+	return !fi.Mode().IsRegular()
+}
+
+/* haveDevFD returns true if the OS provides /dev/fd, which allows more than
+one stream to be given as a filename, e.g. with a shell's <(...). */
+func haveDevFD() bool {
+	fi, err := os.Stat("/dev/fd")
+	return nil == err && fi.IsDir()
+}
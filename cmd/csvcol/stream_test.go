@@ -0,0 +1,164 @@
+/*
+ * stream_test.go
+ * Tests for reading FIFOs and process substitution
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+//go:build unix
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+/* Input for stream tests, and what selecting from it should give */
+const (
+	streamTestCSV  = "a,b,c\nd,e,f\ng,h,i\n"
+	streamTestRows = "1\n3\n"
+	streamTestCols = "3\n1\n"
+	streamTestWant = "c,a\ni,g\n"
+)
+
+/* feedFIFO opens the FIFO named name for writing once, writes contents to
+it, and closes it.  If the FIFO's opened for reading more than once, the
+second open will block until csvcol is killed. */
+func feedFIFO(t *testing.T, name, contents string) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		f, err := os.OpenFile(name, os.O_WRONLY, 0)
+		if nil != err {
+			ch <- err
+			return
+		}
+		_, err = f.WriteString(contents)
+		if cerr := f.Close(); nil == err {
+			err = cerr
+		}
+		ch <- err
+	}()
+	return ch
+}
+
+func TestFIFOInputs(t *testing.T) {
+	dir := t.TempDir()
+	/* One FIFO for each input */
+	fifos := make(map[string]string)
+	for _, n := range []string{"csv", "rows", "cols"} {
+		p := filepath.Join(dir, n)
+		if err := syscall.Mkfifo(p, 0600); nil != err {
+			t.Skipf("Unable to make FIFO: %v", err)
+		}
+		fifos[n] = p
+	}
+	var feeds []<-chan error
+	for n, c := range map[string]string{
+		"csv":  streamTestCSV,
+		"rows": streamTestRows,
+		"cols": streamTestCols,
+	} {
+		feeds = append(feeds, feedFIFO(t, fifos[n], c))
+	}
+
+	cmd := csvcolCommand(
+		t,
+		"-csvfile", fifos["csv"],
+		"-rowfile", fifos["rows"],
+		"-colfile", fifos["cols"],
+		"-ordered",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); nil != err {
+		t.Fatalf("Error: %v\n%s", err, stderr.String())
+	}
+	for _, f := range feeds {
+		if err := <-f; nil != err {
+			t.Errorf("Error feeding FIFO: %v", err)
+		}
+	}
+	if got := stdout.String(); streamTestWant != got {
+		t.Errorf("Output incorrect:\ngot: %q\nwant: %q",
+			got, streamTestWant)
+	}
+	if strings.Contains(stderr.String(), "seek") {
+		t.Errorf("Tried to seek:\n%s", stderr.String())
+	}
+}
+
+func TestProcessSubstitution(t *testing.T) {
+	if !haveDevFD() {
+		t.Skipf("No /dev/fd")
+	}
+	/* Like <(...), pipes passed as /dev/fd/N, plus the standard input */
+	var (
+		args  []string
+		extra []*os.File
+	)
+	for i, a := range []struct {
+		flag     string
+		contents string
+	}{
+		{"-rowfile", streamTestRows},
+		{"-colfile", streamTestCols},
+	} {
+		r, w, err := os.Pipe()
+		if nil != err {
+			t.Fatalf("Making pipe: %v", err)
+		}
+		defer r.Close()
+		go func() {
+			w.WriteString(a.contents)
+			w.Close()
+		}()
+		extra = append(extra, r)
+		args = append(args, a.flag, fmt.Sprintf("/dev/fd/%d", 3+i))
+	}
+
+	cmd := csvcolCommand(t, append(args, "-ordered", "-")...)
+	cmd.ExtraFiles = extra
+	cmd.Stdin = strings.NewReader(streamTestCSV)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); nil != err {
+		t.Fatalf("Error: %v\n%s", err, stderr.String())
+	}
+	if got := stdout.String(); streamTestWant != got {
+		t.Errorf("Output incorrect:\ngot: %q\nwant: %q",
+			got, streamTestWant)
+	}
+}
+
+func TestStdinOnlyOnce(t *testing.T) {
+	res := runCSVCol(t, streamTestCSV, "-rowfile", "-", "-")
+	if 0 == res.code {
+		t.Errorf("Reading the standard input twice didn't fail")
+	}
+	if !strings.Contains(res.stderr, "only be read once") {
+		t.Errorf("Unexpected error: %s", res.stderr)
+	}
+}
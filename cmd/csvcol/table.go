@@ -0,0 +1,329 @@
+/*
+ * table.go
+ * Aligned table and Markdown output
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* Truncation markers, for -format text-email */
+const (
+	emailFieldMax = 24    /* Longest field */
+	emailMore     = "..." /* Marks truncated fields and columns */
+)
+
+/* tableWriter is a recordWriter which writes records as an aligned table or
+as a Markdown table.  As the columns' widths aren't known until all of the
+records have been seen, records are held until the writer is closed. */
+type tableWriter struct {
+	w        io.Writer
+	sel      *csvcol.Selector
+	markdown bool
+	header   bool       /* First record is a header */
+	rows     [][]string /* Held records */
+	lines    []string   /* Lines to write before each held record */
+	err      error
+
+	/* For -format text-email */
+	maxRows  int /* Most records held, if not 0 */
+	maxWidth int /* Widest line */
+	dropped  int /* Records not held */
+}
+
+/* newTableWriter returns a tableWriter which writes to w.  If markdown is
+true, a Markdown table is written.  If header is true, the first record is
+taken to be a header; if not, a Markdown table gets a header with columns'
+names from sel. */
+func newTableWriter(
+	w io.Writer,
+	sel *csvcol.Selector,
+	markdown bool,
+	header bool,
+) *tableWriter {
+	return &tableWriter{
+		w:        w,
+		sel:      sel,
+		markdown: markdown,
+		header:   header,
+		lines:    []string{""},
+	}
+}
+
+/* newEmailWriter returns a tableWriter which writes a plain-text table to
+w, small enough to paste into an email.  At most maxRows records are written
+after the header, if there is one, and lines are at most maxWidth characters
+long.  Long fields are truncated and columns which don't fit are left out; a
+column of ...'s marks the missing columns, and a line at the end notes how
+many rows and columns weren't written. */
+func newEmailWriter(
+	w io.Writer,
+	sel *csvcol.Selector,
+	header bool,
+	maxRows int,
+	maxWidth int,
+) *tableWriter {
+	t := newTableWriter(w, sel, false, header)
+	t.maxRows = maxRows
+	t.maxWidth = maxWidth
+	return t
+}
+
+/* Write holds a copy of record until Close is called */
+func (t *tableWriter) Write(record []string) error {
+	/* Emails only get the first few */
+	if 0 != t.maxRows {
+		n := t.maxRows
+		if t.header {
+			n++
+		}
+		if len(t.rows) >= n {
+			t.dropped++
+			return t.err
+		}
+	}
+	t.rows = append(t.rows, append([]string{}, record...))
+	t.lines = append(t.lines, "")
+	return t.err
+}
+
+/* WriteHeader holds a copy of header, which is taken to be the header if
+the tableWriter was made with header true */
+func (t *tableWriter) WriteHeader(header []string) error {
+	return t.Write(header)
+}
+
+/* WriteLine holds line until Close is called.  It'll be written before the
+next record. */
+func (t *tableWriter) WriteLine(line string) error {
+	if 0 != t.dropped {
+		return t.err
+	}
+	t.lines[len(t.lines)-1] += line + "\n"
+	return t.err
+}
+
+/* Flush does nothing, as nothing can be written until Close is called */
+func (t *tableWriter) Flush() {}
+
+/* Error returns the error from Close, if any */
+func (t *tableWriter) Error() error { return t.err }
+
+/* Close writes the held records */
+func (t *tableWriter) Close() error {
+	if nil != t.err {
+		return t.err
+	}
+	rows := t.rows
+	lines := t.lines
+	dropped := t.dropped
+	t.rows = nil
+	t.lines = []string{""}
+	t.dropped = 0
+
+	/* Markdown tables need a header */
+	if t.markdown && !t.header && 0 != len(rows) {
+		h := make([]string, len(rows[0]))
+		for i := range h {
+			h[i] = columnName(t.sel, i, len(h))
+		}
+		rows = append([][]string{h}, rows...)
+		lines = append([]string{""}, lines...)
+	}
+
+	/* Clean up fields and work out how wide each column is */
+	fieldMax := min(emailFieldMax, t.maxWidth)
+	var widths []int
+	for _, row := range rows {
+		for i, f := range row {
+			f = tableField(f, t.markdown)
+			if 0 != t.maxRows {
+				f = truncateField(f, fieldMax)
+			}
+			row[i] = f
+			if len(widths) <= i {
+				widths = append(widths, 0)
+			}
+			if l := utf8.RuneCountInString(f); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+
+	/* Emails only get the columns which fit */
+	droppedCols := 0
+	if 0 != t.maxRows {
+		rows, widths, droppedCols = t.fitColumns(rows, widths)
+	}
+
+	/* Write it all out */
+	bw := bufio.NewWriter(t.w)
+	for n, row := range rows {
+		bw.WriteString(lines[n])
+		t.writeRow(bw, row, widths)
+		/* Underline the header */
+		if 0 == n && (t.header || t.markdown) {
+			u := make([]string, len(widths))
+			for i, w := range widths {
+				u[i] = strings.Repeat("-", w)
+			}
+			t.writeRow(bw, u, widths)
+		}
+	}
+	bw.WriteString(lines[len(lines)-1])
+	/* Note what didn't fit in an email */
+	var more []string
+	if 0 != dropped {
+		more = append(more, fmt.Sprintf("%d more row(s)", dropped))
+	}
+	if 0 != droppedCols {
+		more = append(more, fmt.Sprintf(
+			"%d more column(s)",
+			droppedCols,
+		))
+	}
+	if 0 != len(more) {
+		bw.WriteString(emailMore + " " + strings.Join(more, ", ") +
+			"\n")
+	}
+	t.err = bw.Flush()
+	return t.err
+}
+
+/* fitColumns removes the columns from rows which don't fit in t.maxWidth and
+adds a column of emailMore's in their place, if there's room.  At least one
+column is kept, truncated if need be.  The updated rows and widths are
+returned, as well as the number of columns removed. */
+func (t *tableWriter) fitColumns(
+	rows [][]string,
+	widths []int,
+) ([][]string, []int, int) {
+	/* Work out how many fit */
+	total := 0
+	for i, w := range widths {
+		if 0 != i {
+			total += 2 /* Space between columns */
+		}
+		total += w
+	}
+	if total <= t.maxWidth {
+		return rows, widths, 0
+	}
+	mw := len(emailMore) + 2 /* Room for the marker column */
+	n, total := 1, widths[0]
+	for ; n < len(widths); n++ {
+		if total+2+widths[n]+mw > t.maxWidth {
+			break
+		}
+		total += 2 + widths[n]
+	}
+	dropped := len(widths) - n
+
+	/* The first column may need to shrink to make room for the marker, or
+	to fit at all if the marker won't. */
+	marker := 0 != dropped && t.maxWidth > mw
+	fw := t.maxWidth
+	if marker {
+		fw -= mw
+	}
+	if widths[0] > fw {
+		for _, row := range rows {
+			if 0 != len(row) {
+				row[0] = truncateField(row[0], fw)
+			}
+		}
+		widths[0] = fw
+	}
+
+	/* Replace the rest with the marker */
+	for i, row := range rows {
+		if len(row) > n {
+			row = row[:n]
+		}
+		if !marker {
+			rows[i] = row
+			continue
+		}
+		for len(row) < n {
+			row = append(row, "")
+		}
+		rows[i] = append(row, emailMore)
+	}
+	widths = widths[:n]
+	if marker {
+		widths = append(widths, len(emailMore))
+	}
+	return rows, widths, dropped
+}
+
+/* writeRow writes a single row, padded to the given widths */
+func (t *tableWriter) writeRow(w *bufio.Writer, row []string, widths []int) {
+	line := make([]string, len(widths))
+	for i, width := range widths {
+		f := ""
+		if i < len(row) {
+			f = row[i]
+		}
+		line[i] = f + strings.Repeat(" ",
+			width-utf8.RuneCountInString(f))
+	}
+	if t.markdown {
+		w.WriteString("| " + strings.Join(line, " | ") + " |\n")
+		return
+	}
+	w.WriteString(strings.TrimRight(strings.Join(line, "  "), " "))
+	w.WriteString("\n")
+}
+
+/* truncateField truncates f to at most n characters, ending it with
+emailMore if it's truncated and n leaves room for it. */
+func truncateField(f string, n int) string {
+	if utf8.RuneCountInString(f) <= n {
+		return f
+	}
+	rs := []rune(f)
+	if n < len(emailMore) {
+		return string(rs[:n])
+	}
+	return string(rs[:n-len(emailMore)]) + emailMore
+}
+
+/* tableField replaces control characters in f, which would spoil the
+alignment, with spaces.  If markdown is true, |'s are escaped as well. */
+func tableField(f string, markdown bool) string {
+	f = strings.Map(func(r rune) rune {
+		if r < ' ' {
+			return ' '
+		}
+		return r
+	}, f)
+	if markdown {
+		f = strings.ReplaceAll(f, "|", `\|`)
+	}
+	return f
+}
@@ -0,0 +1,213 @@
+/*
+ * table_test.go
+ * Tests for table.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestTable(t *testing.T) {
+	in := "name,n\nalice,1\nbob,22\n"
+	runOutputTests(t, []outputTest{{
+		name:  "table",
+		stdin: in,
+		args:  []string{"-format", "table"},
+		want:  "name   n\nalice  1\nbob    22\n",
+	}, {
+		name:  "table_header",
+		stdin: in,
+		args:  []string{"-format", "table", "-header"},
+		want:  "name   n\n-----  --\nalice  1\nbob    22\n",
+	}, {
+		name:  "table_colnames",
+		stdin: in,
+		args:  []string{"-format", "table", "-colnames", "n"},
+		want:  "n\n--\n1\n22\n",
+	}, {
+		name:  "table_ragged",
+		stdin: "a,b,c\nd\n",
+		args:  []string{"-format", "table"},
+		want:  "a  b  c\nd\n",
+	}, {
+		name:  "table_control_characters",
+		stdin: "\"a\tb\",\"c\nd\"\nx,y\n",
+		args:  []string{"-format", "table"},
+		want:  "a b  c d\nx    y\n",
+	}, {
+		name:  "table_wide_characters",
+		stdin: "é,x\nab,y\n",
+		args:  []string{"-format", "table"},
+		want:  "é   x\nab  y\n",
+	}, {
+		name:  "table_lines",
+		stdin: "a\nb\nc\n",
+		args:  []string{"-format", "table", "-watermark", "2"},
+		want:  "a\nb\n# 2 rows\nc\n",
+	}, {
+		name:  "markdown",
+		stdin: in,
+		args:  []string{"-format", "markdown"},
+		want: "| c1    | c2 |\n" +
+			"| ----- | -- |\n" +
+			"| name  | n  |\n" +
+			"| alice | 1  |\n" +
+			"| bob   | 22 |\n",
+	}, {
+		name:  "markdown_header",
+		stdin: in,
+		args:  []string{"-format", "markdown", "-header", "-rows", "2"},
+		want: "| name | n  |\n" +
+			"| ---- | -- |\n" +
+			"| bob  | 22 |\n",
+	}, {
+		name:  "markdown_escaped",
+		stdin: "a|b,\"x\ny\"\n",
+		args:  []string{"-format", "markdown"},
+		want: "| c1   | c2  |\n" +
+			"| ---- | --- |\n" +
+			"| a\\|b | x y |\n",
+	}, {
+		name:  "markdown_empty",
+		stdin: "",
+		args:  []string{"-format", "markdown"},
+		want:  "",
+	}})
+}
+
+func TestTruncateField(t *testing.T) {
+	for _, c := range []struct {
+		have string
+		n    int
+		want string
+	}{
+		{have: "short", n: 10, want: "short"},
+		{have: "exactly10!", n: 10, want: "exactly10!"},
+		{have: "a bit too long", n: 10, want: "a bit t..."},
+		{have: "ééééééééééé", n: 10, want: "ééééééé..."},
+		{have: "too long", n: 3, want: "..."},
+		{have: "too long", n: 2, want: "to"},
+	} {
+		if got := truncateField(c.have, c.n); got != c.want {
+			t.Errorf(
+				"truncateField(%q, %d): got %q, want %q",
+				c.have,
+				c.n,
+				got,
+				c.want,
+			)
+		}
+	}
+}
+
+func TestTextEmail(t *testing.T) {
+	in := "name,city,note\n" +
+		"alice,Paris,short\n" +
+		"bob,\"Boston, MA\"," +
+		"this note is far too long to fit in an email\n" +
+		"carl,Rome,x\n"
+	runOutputTests(t, []outputTest{{
+		name:  "long_field",
+		stdin: in,
+		args:  []string{"-format", "text-email", "-header"},
+		want: "name   city        note\n" +
+			"-----  ----------  ------------------------\n" +
+			"alice  Paris       short\n" +
+			"bob    Boston, MA  " +
+			"this note is far too ...\n" +
+			"carl   Rome        x\n",
+	}, {
+		name:  "too_many_rows",
+		stdin: in,
+		args: []string{
+			"-format", "text-email",
+			"-header",
+			"-email-rows", "1",
+			"-email-width", "20",
+		},
+		want: "name   city   note\n" +
+			"-----  -----  -----\n" +
+			"alice  Paris  short\n" +
+			"... 2 more row(s)\n",
+	}, {
+		name:  "too_many_columns",
+		stdin: in,
+		args: []string{
+			"-format", "text-email",
+			"-header",
+			"-email-width", "30",
+		},
+		want: "name   city        ...\n" +
+			"-----  ----------  ---\n" +
+			"alice  Paris       ...\n" +
+			"bob    Boston, MA  ...\n" +
+			"carl   Rome        ...\n" +
+			"... 1 more column(s)\n",
+	}, {
+		name:  "first_column_truncated",
+		stdin: in,
+		args: []string{
+			"-format", "text-email",
+			"-email-rows", "2",
+			"-email-width", "9",
+		},
+		want: "name  ...\n" +
+			"a...  ...\n" +
+			"... 2 more row(s), 2 more column(s)\n",
+	}, {
+		name:  "no_room_for_marker",
+		stdin: in,
+		args: []string{
+			"-format", "text-email",
+			"-email-rows", "2",
+			"-email-width", "5",
+		},
+		want: "name\n" +
+			"alice\n" +
+			"... 2 more row(s), 2 more column(s)\n",
+	}, {
+		name:  "single_column",
+		stdin: "aaaaaaaaaaaaaaaaaaaa\nb\n",
+		args: []string{
+			"-format", "text-email",
+			"-email-width", "10",
+		},
+		want: "aaaaaaa...\n" +
+			"b\n",
+	}, {
+		name:  "narrow_fields",
+		stdin: "abcdefghijkl,x\n",
+		args: []string{
+			"-format", "text-email",
+			"-email-width", "10",
+		},
+		want: "ab...  ...\n" +
+			"... 1 more column(s)\n",
+	}})
+	for _, args := range [][]string{
+		{"-format", "text-email", "-email-rows", "0"},
+		{"-format", "text-email", "-email-width", "-1"},
+	} {
+		res := runCSVCol(t, in, args...)
+		if -27 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * template.go
+ * Output through text/template
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* templateWriter is a recordWriter which writes each record through a
+text/template.  The template's dot is the record, as a []string, and the
+template function field returns a field by column name. */
+type templateWriter struct {
+	w       *bufio.Writer
+	sel     *csvcol.Selector
+	tmpl    *template.Template
+	newline bool     /* Add a newline after each record */
+	record  []string /* Record being written, for field */
+	names   []string /* Column names, from the header */
+	err     error
+}
+
+/* parseTemplate parses the template given with -template or in the file
+named with -template-file. */
+func parseTemplate(text, file string) (*template.Template, bool, error) {
+	name := "template"
+	if "" != file {
+		if "" != text {
+			return nil, false, fmt.Errorf("only one of -template " +
+				"and -template-file may be given")
+		}
+		b, err := os.ReadFile(file)
+		if nil != err {
+			return nil, false, err
+		}
+		text, name = string(b), file
+	}
+	t := template.New(name).Option("missingkey=error")
+	t.Funcs(template.FuncMap{"field": func(string) (string, error) {
+		return "", nil /* Replaced for each writer */
+	}})
+	t, err := t.Parse(text)
+	return t, !strings.HasSuffix(text, "\n"), err
+}
+
+/* newTemplateWriter returns a templateWriter which writes records through
+t to w and gets the names of columns from sel.  If newline is true, a newline
+is written after each record. */
+func newTemplateWriter(
+	w io.Writer,
+	sel *csvcol.Selector,
+	t *template.Template,
+	newline bool,
+) *templateWriter {
+	tw := &templateWriter{w: bufio.NewWriter(w), sel: sel, newline: newline}
+	tw.tmpl = template.Must(t.Clone()).Funcs(template.FuncMap{
+		"field": tw.field,
+	})
+	return tw
+}
+
+/* field returns the field of the record being written in the column named
+n, from the header, or cN */
+func (t *templateWriter) field(n string) (string, error) {
+	for i, f := range t.record {
+		if n == headerName(t.names, t.sel, i, len(t.record)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("no column named %q", n)
+}
+
+/* WriteHeader notes the column names in header, for field.  The header
+itself isn't written. */
+func (t *templateWriter) WriteHeader(header []string) error {
+	t.names = append([]string{}, header...)
+	return t.err
+}
+
+/* Write writes record through the template. */
+func (t *templateWriter) Write(record []string) error {
+	if nil != t.err {
+		return t.err
+	}
+	t.record = record
+	if t.err = t.tmpl.Execute(t.w, record); nil != t.err {
+		return t.err
+	}
+	if t.newline {
+		t.w.WriteByte('\n')
+	}
+	return t.err
+}
+
+/* WriteLine writes line followed by a newline */
+func (t *templateWriter) WriteLine(line string) error {
+	if nil != t.err {
+		return t.err
+	}
+	_, t.err = t.w.WriteString(line + "\n")
+	return t.err
+}
+
+/* Flush flushes buffered output */
+func (t *templateWriter) Flush() {
+	if err := t.w.Flush(); nil != err && nil == t.err {
+		t.err = err
+	}
+}
+
+/* Error returns the first error encountered while writing or flushing */
+func (t *templateWriter) Error() error { return t.err }
+
+/* Close flushes buffered output */
+func (t *templateWriter) Close() error {
+	t.Flush()
+	return t.err
+}
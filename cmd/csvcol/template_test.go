@@ -0,0 +1,107 @@
+/*
+ * template_test.go
+ * Tests for template.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestTemplate(t *testing.T) {
+	dir := t.TempDir()
+	tf := writeTestFile(
+		t,
+		dir,
+		"t.tmpl",
+		"{{range $i, $f := .}}{{if $i}};{{end}}{{$f}}{{end}}\n",
+	)
+	in := "name,age,city\nal,3,ny\nbo,40,la\n"
+	runOutputTests(t, []outputTest{{
+		name:  "index",
+		stdin: in,
+		args:  []string{"-template", "{{index . 0}} -> {{index . 2}}"},
+		want:  "name -> city\nal -> ny\nbo -> la\n",
+	}, {
+		name:  "field",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-template", `{{field "city"}}: {{field "name"}}`,
+		},
+		want: "ny: al\nla: bo\n",
+	}, {
+		name:  "field_cN",
+		stdin: in,
+		args: []string{
+			"-cols", "2,3",
+			"-rows", "2-",
+			"-template", `{{field "c3"}}={{field "c2"}}`,
+		},
+		want: "ny=3\nla=40\n",
+	}, {
+		name:  "selected_columns",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "3",
+			"-template", "<{{index . 0}}>",
+		},
+		want: "<ny>\n<la>\n",
+	}, {
+		name:  "trailing_newline",
+		stdin: in,
+		args:  []string{"-header", "-template", "{{len .}}\n\n"},
+		want:  "3\n\n3\n\n",
+	}, {
+		name:  "file",
+		stdin: in,
+		args:  []string{"-header", "-template-file", tf},
+		want:  "al;3;ny\nbo;40;la\n",
+	}, {
+		name:  "no_rows",
+		stdin: in,
+		args:  []string{"-header", "-rows", "9", "-template", "x"},
+		want:  "",
+	}})
+
+	/* Bad templates and flags shouldn't work */
+	for _, args := range [][]string{
+		{"-template", "{{"},
+		{"-template", "x", "-template-file", tf},
+		{"-template-file", tf + ".nope"},
+		{"-template", "x", "-json"},
+		{"-template", "x", "-format", "table"},
+	} {
+		if res := runCSVCol(t, in, args...); -39 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+
+	/* Nor should missing things */
+	for _, tm := range []string{
+		`{{field "nope"}}`,
+		"{{index . 5}}",
+	} {
+		res := runCSVCol(t, in, "-header", "-template", tm)
+		if 0 == res.code {
+			t.Errorf("%q succeeded: %s", tm, res.stdout)
+		}
+	}
+}
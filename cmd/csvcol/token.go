@@ -0,0 +1,251 @@
+/*
+ * token.go
+ * Reversible tokenization of columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* tokenMagic starts a token map file */
+const tokenMagic = "csvcol-tokens-1\n"
+
+/* Token map file encryption parameters */
+const (
+	tokenSaltLen = 16
+	tokenIter    = 600000
+)
+
+/* tokenizer replaces values with random tokens, or tokens with the values
+they replaced.  The mapping between tokens and values is kept in a file
+encrypted with AES-256-GCM, with a key derived from a passphrase with
+PBKDF2-SHA256. */
+type tokenizer struct {
+	cols    map[int]bool      /* Input columns to (de)tokenize */
+	toValue map[string]string /* Token -> value */
+	toToken map[string]string /* Value -> token */
+	file    string            /* Token map file */
+	pass    string            /* Passphrase */
+	changed bool              /* Tokens have been added */
+}
+
+/* newTokenizer returns a tokenizer which (de)tokenizes the given columns,
+each of which is col:N or cN, and which uses the token map file named file,
+which will be read if it exists.  The passphrase is key or, if key starts
+with an @, read from the file named after the @. */
+func newTokenizer(cols []string, file, key string) (*tokenizer, error) {
+	t := &tokenizer{
+		cols:    make(map[int]bool),
+		toValue: make(map[string]string),
+		toToken: make(map[string]string),
+		file:    file,
+		pass:    key,
+	}
+	for _, c := range cols {
+		cs, err := csvcol.ParseGroupKey(c)
+		if nil != err {
+			return nil, err
+		}
+		for _, c := range cs {
+			t.cols[c] = true
+		}
+	}
+	if "" == file {
+		return nil, errors.New("no token map file given")
+	}
+	if f, ok := strings.CutPrefix(key, "@"); ok {
+		b, err := os.ReadFile(f)
+		if nil != err {
+			return nil, fmt.Errorf("reading key: %w", err)
+		}
+		t.pass = strings.TrimRight(string(b), "\r\n")
+	}
+	if "" == t.pass {
+		return nil, errors.New("empty key")
+	}
+	if err := t.load(); nil != err && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading %v: %w", file, err)
+	}
+	return t, nil
+}
+
+/* tokenize replaces the fields of orec, selected by sel, which are to be
+tokenized with tokens, making new tokens as needed.  Empty fields are left
+empty. */
+func (t *tokenizer) tokenize(orec []string, sel *csvcol.Selector) error {
+	for i, f := range orec {
+		if "" == f || !t.cols[sel.InputColumn(i)] {
+			continue
+		}
+		tok, ok := t.toToken[f]
+		if !ok {
+			b := make([]byte, 12)
+			if _, err := rand.Read(b); nil != err {
+				return err
+			}
+			tok = "tok_" + hex.EncodeToString(b)
+			t.toToken[f] = tok
+			t.toValue[tok] = f
+			t.changed = true
+		}
+		orec[i] = tok
+	}
+	return nil
+}
+
+/* detokenize replaces tokens in the fields of orec, selected by sel, which
+are to be detokenized with the values they replaced.  Fields which aren't
+known tokens are left as-is. */
+func (t *tokenizer) detokenize(orec []string, sel *csvcol.Selector) {
+	for i, f := range orec {
+		if !t.cols[sel.InputColumn(i)] {
+			continue
+		}
+		if v, ok := t.toValue[f]; ok {
+			orec[i] = v
+		}
+	}
+}
+
+/* load reads the token map file */
+func (t *tokenizer) load() error {
+	b, err := os.ReadFile(t.file)
+	if nil != err {
+		return err
+	}
+	rest, ok := bytes.CutPrefix(b, []byte(tokenMagic))
+	if !ok || tokenSaltLen > len(rest) {
+		return errors.New("not a token map file")
+	}
+	salt, rest := rest[:tokenSaltLen], rest[tokenSaltLen:]
+	aead, err := t.aead(salt)
+	if nil != err {
+		return err
+	}
+	if aead.NonceSize() > len(rest) {
+		return errors.New("truncated")
+	}
+	nonce, rest := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	pt, err := aead.Open(nil, nonce, rest, []byte(tokenMagic))
+	if nil != err {
+		return errors.New("wrong key or corrupted file")
+	}
+	r := csv.NewReader(bytes.NewReader(pt))
+	r.FieldsPerRecord = 2
+	for {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if nil != err {
+			return err
+		}
+		t.toValue[rec[0]] = rec[1]
+		t.toToken[rec[1]] = rec[0]
+	}
+	debug("Loaded %v tokens from %v", len(t.toValue), t.file)
+	return nil
+}
+
+/* save writes the token map file, if there are new tokens.  It's written to
+a temporary file first, which replaces the token map file. */
+func (t *tokenizer) save() error {
+	if !t.changed {
+		return nil
+	}
+	/* Serialize the map */
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for tok, v := range t.toValue {
+		w.Write([]string{tok, v})
+	}
+	w.Flush()
+	if err := w.Error(); nil != err {
+		return err
+	}
+
+	/* Encrypt it */
+	salt := make([]byte, tokenSaltLen)
+	if _, err := rand.Read(salt); nil != err {
+		return err
+	}
+	aead, err := t.aead(salt)
+	if nil != err {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); nil != err {
+		return err
+	}
+	out := append([]byte(tokenMagic), salt...)
+	out = append(out, nonce...)
+	out = aead.Seal(out, nonce, buf.Bytes(), []byte(tokenMagic))
+
+	/* Write it out */
+	f, err := os.CreateTemp(
+		filepath.Dir(t.file),
+		"."+filepath.Base(t.file)+".csvcol.*",
+	)
+	if nil != err {
+		return err
+	}
+	_, err = f.Write(out)
+	if cerr := f.Close(); nil == err {
+		err = cerr
+	}
+	if nil == err {
+		err = os.Rename(f.Name(), t.file)
+	}
+	if nil != err {
+		os.Remove(f.Name())
+		return err
+	}
+	verbose("Wrote %v tokens to %v", len(t.toValue), t.file)
+	return nil
+}
+
+/* aead returns the cipher used to encrypt the token map file */
+func (t *tokenizer) aead(salt []byte) (cipher.AEAD, error) {
+	k, err := pbkdf2.Key(sha256.New, t.pass, salt, tokenIter, 32)
+	if nil != err {
+		return nil, err
+	}
+	b, err := aes.NewCipher(k)
+	if nil != err {
+		return nil, err
+	}
+	return cipher.NewGCM(b)
+}
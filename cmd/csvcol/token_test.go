@@ -0,0 +1,119 @@
+/*
+ * token_test.go
+ * Tests for token.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	dir := t.TempDir()
+	tm := filepath.Join(dir, "tokens.enc")
+	kf := writeTestFile(t, dir, "key", "passphrase\n")
+	in := "id,name\n1,alice\n2,bob\n3,alice\n4,\n"
+	args := []string{"-header", "-token-map", tm, "-token-key", "@" + kf}
+
+	/* Tokenize, and make sure we get tokens */
+	tokd := mustRun(t, in, append(args, "-tokenize", "c2")...)
+	lines := strings.Split(tokd, "\n")
+	if 6 != len(lines) {
+		t.Fatalf("Wrong number of lines:\n%s", tokd)
+	}
+	tokRE := regexp.MustCompile(`^[0-9],tok_[0-9a-f]{24}$`)
+	for _, l := range []string{lines[1], lines[2], lines[3]} {
+		if !tokRE.MatchString(l) {
+			t.Errorf("Not tokenized: %q", l)
+		}
+	}
+	if lines[1][2:] != lines[3][2:] {
+		t.Errorf("Same value, different tokens: %q %q",
+			lines[1], lines[3])
+	}
+	if lines[1][2:] == lines[2][2:] {
+		t.Errorf("Different values, same token: %q", lines[1])
+	}
+	if "id,name" != lines[0] || "4," != lines[4] {
+		t.Errorf("Header or empty value changed:\n%s", tokd)
+	}
+
+	/* The map shouldn't have the values in the clear */
+	b, err := os.ReadFile(tm)
+	if nil != err {
+		t.Fatalf("Reading token map: %v", err)
+	}
+	if bytes.Contains(b, []byte("alice")) {
+		t.Errorf("Token map not encrypted")
+	}
+
+	/* Detokenize */
+	got := mustRun(t, tokd+"5,tok_unknown\n",
+		append(args, "-detokenize", "col:2")...)
+	if want := in + "5,tok_unknown\n"; want != got {
+		t.Errorf("Detokenized:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	/* Tokens are reused in later runs */
+	again := mustRun(t, "id,name\n5,carol\n6,bob\n",
+		append(args, "-tokenize", "c2")...)
+	if !strings.HasSuffix(again, "6,"+lines[2][2:]+"\n") {
+		t.Errorf("Token not reused:\n%s", again)
+	}
+	got = mustRun(t, again, append(args, "-detokenize", "c2")...)
+	if want := "id,name\n5,carol\n6,bob\n"; want != got {
+		t.Errorf("Detokenized:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	dir := t.TempDir()
+	tm := filepath.Join(dir, "tokens.enc")
+	mustRun(
+		t,
+		"a\n",
+		"-tokenize", "c1", "-token-map", tm, "-token-key", "k",
+	)
+	bad := writeTestFile(t, dir, "bad.enc", "not tokens")
+	for _, args := range [][]string{
+		{"-tokenize", "c1", "-token-map", tm, "-token-key", "wrong"},
+		{"-detokenize", "c1", "-token-map", tm, "-token-key", "wrong"},
+		{"-tokenize", "c1", "-token-map", bad, "-token-key", "k"},
+		{"-tokenize", "c1", "-token-key", "k"},
+		{"-tokenize", "c1", "-token-map", tm},
+		{"-tokenize", "x", "-token-map", tm, "-token-key", "k"},
+		{
+			"-tokenize", "c1",
+			"-detokenize", "c1",
+			"-token-map", tm,
+			"-token-key", "k",
+		},
+	} {
+		if res := runCSVCol(t, "a\n", args...); 0 == res.code {
+			t.Errorf("%q succeeded", args)
+		}
+	}
+}
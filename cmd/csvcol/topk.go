@@ -0,0 +1,119 @@
+/*
+ * topk.go
+ * Approximate most frequent values
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"sort"
+	"strconv"
+)
+
+/* topK finds the approximate k most frequent values in each column using
+the Misra-Gries algorithm with a fixed number of counters per column, so
+memory use doesn't grow with the number of distinct values.  A reported
+count may be less than the true count by at most the column's error, which is
+the number of times all of its counters were decremented.  Any value which
+makes up more than 1/(counters+1) of a column is sure to be found. */
+type topK struct {
+	k        int
+	counters int
+	column   func(i int) int
+	cols     []int /* Input column numbers */
+	names    []string
+	counts   []map[string]int /* Value -> count, per column */
+	errs     []int            /* Decrements, per column */
+}
+
+/* newTopK returns a topK which reports k values per column.  If counters is
+less than k, 10*k counters are used. */
+func newTopK(k, counters int, column func(i int) int) *topK {
+	if counters < k {
+		counters = 10 * k
+	}
+	return &topK{k: k, counters: counters, column: column}
+}
+
+/* add counts the fields of orec.  Name returns the name of the ith field of
+orec.  Record is ignored. */
+func (t *topK) add(record, orec []string, name func(i int) string) {
+	for i, f := range orec {
+		if len(t.names) == i {
+			t.cols = append(t.cols, t.column(i))
+			t.names = append(t.names, name(i))
+			t.counts = append(t.counts, make(map[string]int))
+			t.errs = append(t.errs, 0)
+		}
+		c := t.counts[i]
+		/* Already counting, or room to start counting */
+		if _, ok := c[f]; ok || len(c) < t.counters {
+			c[f]++
+			continue
+		}
+		/* No room, decrement everything */
+		for v := range c {
+			if c[v]--; 0 == c[v] {
+				delete(c, v)
+			}
+		}
+		t.errs[i]++
+	}
+}
+
+/* each calls f with a header and then with a record for each of the k values
+with the highest counts in each column, most frequent first.  Each record has
+the column's input column number and name, the value, its count, and the
+most by which the count may be too low. */
+func (t *topK) each(f func([]string) error) error {
+	if err := f([]string{
+		"column", "name", "value", "count", "error",
+	}); nil != err {
+		return err
+	}
+	for i, n := range t.names {
+		vs := make([]string, 0, len(t.counts[i]))
+		for v := range t.counts[i] {
+			vs = append(vs, v)
+		}
+		sort.Slice(vs, func(a, b int) bool {
+			ca, cb := t.counts[i][vs[a]], t.counts[i][vs[b]]
+			if ca != cb {
+				return ca > cb
+			}
+			return vs[a] < vs[b]
+		})
+		if len(vs) > t.k {
+			vs = vs[:t.k]
+		}
+		for _, v := range vs {
+			if err := f([]string{
+				strconv.Itoa(t.cols[i]),
+				n,
+				v,
+				strconv.Itoa(t.counts[i][v]),
+				strconv.Itoa(t.errs[i]),
+			}); nil != err {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+ * topk_test.go
+ * Tests for topk.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+/* topKRows returns what tk reports, as a slice of records */
+func topKRows(t *testing.T, tk *topK) [][]string {
+	var rs [][]string
+	if err := tk.each(func(r []string) error {
+		rs = append(rs, r)
+		return nil
+	}); nil != err {
+		t.Fatalf("each: %v", err)
+	}
+	return rs
+}
+
+func TestTopKExact(t *testing.T) {
+	tk := newTopK(2, 0, func(i int) int { return i + 3 })
+	for _, r := range []string{"a,x", "b,x", "a,y", "c,y", "a,x", "b,z"} {
+		tk.add(nil, strings.Split(r, ","), func(i int) string {
+			return fmt.Sprintf("n%d", i)
+		})
+	}
+	got := fmt.Sprint(topKRows(t, tk))
+	want := "[[column name value count error] " +
+		"[3 n0 a 3 0] [3 n0 b 2 0] " +
+		"[4 n1 x 3 0] [4 n1 y 2 0]]"
+	if got != want {
+		t.Errorf("Got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTopKHeavyHitters(t *testing.T) {
+	/* One value is a third of the stream, the rest are all different */
+	const (
+		n        = 3000
+		counters = 5
+	)
+	tk := newTopK(1, counters, func(i int) int { return 1 })
+	for i := 0; i < n; i++ {
+		v := strconv.Itoa(i)
+		if 0 == i%3 {
+			v = "heavy"
+		}
+		tk.add(nil, []string{v}, func(int) string { return "c1" })
+	}
+	if counters < len(tk.counts[0]) {
+		t.Errorf(
+			"%d values counted, max %d",
+			len(tk.counts[0]),
+			counters,
+		)
+	}
+	rs := topKRows(t, tk)
+	if 2 != len(rs) || "heavy" != rs[1][2] {
+		t.Fatalf("Heavy hitter not found: %q", rs)
+	}
+	count, _ := strconv.Atoi(rs[1][3])
+	errs, _ := strconv.Atoi(rs[1][4])
+	if count > n/3 || count+errs < n/3 {
+		t.Errorf("Count %d, error %d, true count %d", count, errs, n/3)
+	}
+	if errs > n/(counters+1) {
+		t.Errorf("Error %d more than %d", errs, n/(counters+1))
+	}
+}
+
+func TestTopK(t *testing.T) {
+	in := "name,color\nann,red\nbob,blue\ncat,red\ndan,red\neve,blue\n"
+	runOutputTests(t, []outputTest{{
+		name:  "header",
+		stdin: in,
+		args:  []string{"-header", "-cols", "2", "-top-k", "1"},
+		want:  "column,name,value,count,error\n2,color,red,3,0\n",
+	}, {
+		name:  "no_header",
+		stdin: in,
+		args:  []string{"-top-k", "2", "-cols", "2", "-rows", "1-3"},
+		want: "column,name,value,count,error\n" +
+			"2,c2,blue,1,0\n" +
+			"2,c2,color,1,0\n",
+	}, {
+		name:  "few_counters",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "2",
+			"-top-k", "1",
+			"-top-k-counters", "1",
+		},
+		want: "column,name,value,count,error\n2,color,red,1,2\n",
+	}})
+}
@@ -0,0 +1,126 @@
+/*
+ * transform.go
+ * Transforming column values
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/magisterquis/csvcol"
+)
+
+/* transformer applies transforms to the values of some columns */
+type transformer map[int][]func(string) string /* Input column -> transforms */
+
+/* simpleTransforms are the transforms which take no arguments */
+var simpleTransforms = map[string]func(string) string{
+	"trim":  strings.TrimSpace,
+	"ltrim": func(s string) string { return strings.TrimLeft(s, " \t") },
+	"rtrim": func(s string) string { return strings.TrimRight(s, " \t") },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"squeeze": func(s string) string {
+		return strings.Join(strings.Fields(s), " ")
+	},
+}
+
+/* add parses a specification of the form COL:OP[:ARGS], where COL is col:N
+or cN, and adds it to t.  OP is one of trim, ltrim, rtrim, upper, lower,
+squeeze, or replace.  Replace's ARGS are REGEX:REPLACEMENT; a : in REGEX may
+be escaped with a backslash.  Transforms on the same column are applied in the
+order they're added. */
+func (t transformer) add(spec string) error {
+	/* Work out which column */
+	c, rest, ok := strings.Cut(spec, ":")
+	if "col" == c {
+		var n string
+		n, rest, ok = strings.Cut(rest, ":")
+		c += ":" + n
+	}
+	if !ok {
+		return fmt.Errorf("missing :OP")
+	}
+	cols, err := csvcol.ParseGroupKey(c)
+	if nil != err {
+		return err
+	}
+	if 1 != len(cols) {
+		return fmt.Errorf("only one column may be given")
+	}
+
+	/* Work out what to do */
+	op, args, hasArgs := strings.Cut(rest, ":")
+	var f func(string) string
+	switch op {
+	case "replace":
+		if f, err = parseReplaceTransform(args); nil != err {
+			return err
+		}
+	default:
+		if f, ok = simpleTransforms[op]; !ok {
+			return fmt.Errorf("unknown transform %q", op)
+		}
+		if hasArgs {
+			return fmt.Errorf("%s takes no arguments", op)
+		}
+	}
+	t[cols[0]] = append(t[cols[0]], f)
+	return nil
+}
+
+/* parseReplaceTransform parses replace's REGEX:REPLACEMENT.  REPLACEMENT may
+refer to submatches as for regexp.Regexp.ReplaceAllString. */
+func parseReplaceTransform(args string) (func(string) string, error) {
+	/* Find the first : which isn't escaped */
+	i := 0
+	for ; i < len(args); i++ {
+		if '\\' == args[i] {
+			i++
+		} else if ':' == args[i] {
+			break
+		}
+	}
+	if len(args) <= i {
+		return nil, fmt.Errorf("replace needs REGEX:REPLACEMENT")
+	}
+	re, err := regexp.Compile(args[:i])
+	if nil != err {
+		return nil, err
+	}
+	repl := args[i+1:]
+	return func(s string) string {
+		return re.ReplaceAllString(s, repl)
+	}, nil
+}
+
+/* apply transforms the fields of orec, selected by sel, which are to be
+transformed. */
+func (t transformer) apply(orec []string, sel *csvcol.Selector) {
+	for i, f := range orec {
+		for _, tf := range t[sel.InputColumn(i)] {
+			f = tf(f)
+		}
+		orec[i] = f
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * transform_test.go
+ * Tests for transform.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import "testing"
+
+func TestSimpleTransforms(t *testing.T) {
+	const in = " \tHello   Wörld \t"
+	for op, want := range map[string]string{
+		"trim":    "Hello   Wörld",
+		"ltrim":   "Hello   Wörld \t",
+		"rtrim":   " \tHello   Wörld",
+		"upper":   " \tHELLO   WÖRLD \t",
+		"lower":   " \thello   wörld \t",
+		"squeeze": "Hello Wörld",
+	} {
+		if got := simpleTransforms[op](in); got != want {
+			t.Errorf("%s: got %q, want %q", op, got, want)
+		}
+	}
+}
+
+func TestTransformerAdd(t *testing.T) {
+	for _, s := range []string{
+		"c1",
+		"col:1",
+		"c1:",
+		"c1:nope",
+		"c1:trim:x",
+		"c1:replace",
+		"c1:replace:a",
+		"c1:replace:(:x",
+		"x:trim",
+		"c1,c2:trim",
+	} {
+		if err := make(transformer).add(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	in := "name,phone,time\n" +
+		"  ann  SMITH ,(555) 123-4567,12:30\n" +
+		"Bob,555.987,9:05\n"
+	runOutputTests(t, []outputTest{{
+		name:  "in_order",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-transform", "c1:squeeze",
+			"-transform", "col:1:lower",
+			"-transform", `c2:replace:[^0-9]+:`,
+		},
+		want: "name,phone,time\n" +
+			"ann smith,5551234567,12:30\n" +
+			"bob,555987,9:05\n",
+	}, {
+		name:  "escaped_colon",
+		stdin: in,
+		args: []string{
+			"-header",
+			"-cols", "3",
+			"-transform", `c3:replace:^(\d+)\:(\d+)$:${2}m past $1`,
+		},
+		want: "time\n30m past 12\n05m past 9\n",
+	}, {
+		name:  "before_map",
+		stdin: "k\n a \nb\n",
+		args: []string{
+			"-transform", "c1:trim",
+			"-transform", "c1:upper",
+			"-map", "c1=" + writeTestFile(
+				t,
+				t.TempDir(),
+				"m.csv",
+				"A,apple\n",
+			),
+		},
+		want: "K\napple\nB\n",
+	}})
+	res := runCSVCol(t, in, "-transform", "c1:nope")
+	if -40 != int8(res.code) {
+		t.Errorf("Bad -transform exit code %d", int8(res.code))
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * transpose.go
+ * Swap output rows and columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+/* transposeWriter is a recordWriter which holds on to records until it's
+closed and then writes them to w with rows and columns swapped, for
+-transpose.  Short records are padded with empty fields. */
+type transposeWriter struct {
+	w       recordWriter
+	records [][]string
+	width   int   /* Number of fields in the widest record */
+	size    int64 /* Roughly how much memory records takes */
+	max     int64 /* Maximum size, or 0 for no limit */
+	err     error
+}
+
+/* Write holds on to a copy of record.  If there's more than w.max bytes of
+records, Write returns an error. */
+func (w *transposeWriter) Write(record []string) error {
+	if nil != w.err {
+		return w.err
+	}
+	w.size += 64
+	for _, f := range record {
+		w.size += int64(16 + len(f))
+	}
+	if 0 != w.max && w.size > w.max {
+		w.err = fmt.Errorf(
+			"more than %d bytes of output to transpose, "+
+				"see -transpose-max",
+			w.max,
+		)
+		return w.err
+	}
+	w.records = append(w.records, append([]string{}, record...))
+	if len(record) > w.width {
+		w.width = len(record)
+	}
+	return nil
+}
+
+/* WriteHeader holds on to header like any other record, so it becomes the
+first column */
+func (w *transposeWriter) WriteHeader(header []string) error {
+	return w.Write(header)
+}
+
+/* WriteLine returns an error, as lines can't be transposed */
+func (w *transposeWriter) WriteLine(string) error {
+	return errors.New("lines can't be transposed")
+}
+
+/* Flush does nothing, as nothing can be written until everything's been
+written. */
+func (w *transposeWriter) Flush() {}
+
+/* Error returns the first error encountered by Write or by w.w */
+func (w *transposeWriter) Error() error {
+	if nil != w.err {
+		return w.err
+	}
+	return w.w.Error()
+}
+
+/* Close writes the transposed records to w.w and closes it */
+func (w *transposeWriter) Close() error {
+	if nil != w.err {
+		w.w.Close()
+		return w.err
+	}
+	for i := 0; i < w.width; i++ {
+		out := make([]string, len(w.records))
+		for j, r := range w.records {
+			if i < len(r) {
+				out[j] = r[i]
+			}
+		}
+		if err := w.w.Write(out); nil != err {
+			w.w.Close()
+			return err
+		}
+	}
+	w.records = nil
+	return w.w.Close()
+}
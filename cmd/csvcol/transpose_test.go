@@ -0,0 +1,120 @@
+/*
+ * transpose_test.go
+ * Tests for transpose.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestTransposeWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := &transposeWriter{w: &csvWriter{Writer: csv.NewWriter(&b), w: &b}}
+	if err := w.WriteHeader([]string{"k", "v"}); nil != err {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	for _, r := range [][]string{{"a", "1", "x"}, {"b"}, {"c", "3"}} {
+		if err := w.Write(r); nil != err {
+			t.Fatalf("Write %q: %v", r, err)
+		}
+	}
+	if 0 != b.Len() {
+		t.Errorf("Wrote before Close: %q", b.String())
+	}
+	if err := w.WriteLine("--"); nil == err {
+		t.Errorf("WriteLine succeeded")
+	}
+	if err := w.Close(); nil != err {
+		t.Fatalf("Close: %v", err)
+	}
+	if want := "k,a,b,c\nv,1,,3\n,x,,\n"; b.String() != want {
+		t.Errorf("Got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	/* Too much to hold */
+	b.Reset()
+	w = &transposeWriter{
+		w:   &csvWriter{Writer: csv.NewWriter(&b), w: &b},
+		max: 250,
+	}
+	for i := 0; i < 2; i++ {
+		if err := w.Write([]string{"a", "b", "c"}); nil != err {
+			t.Fatalf("Write %d: %v", i, err)
+		}
+	}
+	if err := w.Write([]string{"a", "b", "c"}); nil == err {
+		t.Fatalf("Too much output accepted")
+	}
+	if err := w.Close(); nil == err ||
+		!strings.Contains(err.Error(), "-transpose-max") {
+		t.Errorf("Close after too much output: %v", err)
+	}
+	if 0 != b.Len() {
+		t.Errorf("Wrote too much output: %q", b.String())
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	in := "key,value\nname,\"Smith, J\"\nport,80\nhost\n"
+	runOutputTests(t, []outputTest{{
+		name:  "all",
+		stdin: in,
+		args:  []string{"-transpose"},
+		want:  "key,name,port,host\nvalue,\"Smith, J\",80,\n",
+	}, {
+		name:  "selected",
+		stdin: in,
+		args:  []string{"-header", "-transpose", "-rows", "2-3"},
+		want:  "key,port,host\nvalue,80,\n",
+	}, {
+		name:  "table",
+		stdin: in,
+		args: []string{
+			"-transpose",
+			"-format", "table",
+			"-cols", "2",
+			"-rows", "1-2",
+		},
+		want: "value  Smith, J\n",
+	}})
+	for _, args := range [][]string{
+		{"-transpose", "-transpose-max", "huge"},
+		{"-transpose", "-watermark", "1"},
+	} {
+		res := runCSVCol(t, in, args...)
+		if -50 != int8(res.code) {
+			t.Errorf("%q: exit code %d", args, int8(res.code))
+		}
+	}
+	res := runCSVCol(t, in, "-transpose", "-transpose-max", "100")
+	if 0 == res.code ||
+		!strings.Contains(res.stderr, "see -transpose-max") {
+		t.Errorf(
+			"Too much output: exit code %d: %s",
+			int8(res.code),
+			res.stderr,
+		)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * untrusted.go
+ * Limits for untrusted input
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/* Limits used with -untrusted unless others are given */
+const (
+	untrustedMaxRows      = 1000000
+	untrustedMaxFieldSize = 1 << 20
+	untrustedMaxOutput    = "100M"
+	untrustedMaxTime      = time.Minute
+)
+
+/* recordSizeFactor is how many times -max-field-size a record may be, as
+records are read whole before their fields can be checked */
+const recordSizeFactor = 64
+
+/* untrustedForbidden are the flags which write files or serve requests,
+which may not be used with -untrusted */
+var untrustedForbidden = []string{
+	"o", "output-per-file", "in-place", "bundle", "sqlite", "trace",
+	"tokenize", "lockfile", "max-mem", "daemon", "grpc", "job",
+}
+
+/* errLimit is returned when input or output exceeds a limit */
+var errLimit = errors.New("limit exceeded")
+
+/* limits holds the parsed limits */
+var limits struct {
+	maxOutput int64
+	rows      int /* Rows read so far */
+}
+
+/* setupLimits checks -untrusted's restrictions and sets the default limits
+it needs, then parses the limits and starts the clock for -max-time. */
+func setupLimits() error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if *gc.untrusted {
+		var bad []string
+		for _, n := range untrustedForbidden {
+			if set[n] {
+				bad = append(bad, "-"+n)
+			}
+		}
+		if 0 != len(bad) {
+			return fmt.Errorf("%s may not be used with -untrusted",
+				strings.Join(bad, ", "))
+		}
+		if !set["max-rows"] {
+			*gc.maxRows = untrustedMaxRows
+		}
+		if !set["max-field-size"] {
+			*gc.maxFieldSize = untrustedMaxFieldSize
+		}
+		if !set["max-output"] {
+			*gc.maxOutput = untrustedMaxOutput
+		}
+		if !set["max-time"] {
+			*gc.maxTime = untrustedMaxTime
+		}
+	}
+	if "" != *gc.maxOutput {
+		var err error
+		limits.maxOutput, err = parseSize(*gc.maxOutput)
+		if nil != err {
+			return fmt.Errorf("invalid -max-output: %w", err)
+		}
+	}
+	if 0 < *gc.maxTime {
+		time.AfterFunc(*gc.maxTime, func() {
+			inform("Giving up after %v", *gc.maxTime)
+			exit(-46)
+		})
+	}
+	return nil
+}
+
+/* checkLimits counts record as a row and returns an error if there have
+been too many rows or record has a field which is too large */
+func checkLimits(record []string) error {
+	limits.rows++
+	if 0 < *gc.maxRows && limits.rows > *gc.maxRows {
+		return fmt.Errorf("%w: more than %d rows",
+			errLimit, *gc.maxRows)
+	}
+	if 0 >= *gc.maxFieldSize {
+		return nil
+	}
+	for i, f := range record {
+		if len(f) > *gc.maxFieldSize {
+			return fmt.Errorf("%w: field %d longer than %d bytes",
+				errLimit, i+1, *gc.maxFieldSize)
+		}
+	}
+	return nil
+}
+
+/* recordLimiter is an io.Reader which returns an error if the CSV reader
+reading from it has read more than max bytes since the end of the last
+record it returned */
+type recordLimiter struct {
+	r   io.Reader
+	cr  *csv.Reader
+	n   int64 /* Bytes read */
+	max int64
+}
+
+/* Read reads from l.r, unless the current record is too long */
+func (l *recordLimiter) Read(p []byte) (int, error) {
+	if l.n-l.cr.InputOffset() > l.max {
+		return 0, fmt.Errorf("%w: record longer than %d bytes",
+			errLimit, l.max)
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	return n, err
+}
+
+/* limitedWriter is an io.Writer which refuses to write more than max
+bytes */
+type limitedWriter struct {
+	w   io.Writer
+	n   int64
+	max int64
+}
+
+/* Write writes p to l.w if it fits */
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.n+int64(len(p)) > l.max {
+		return 0, fmt.Errorf("%w: more than %d bytes of output",
+			errLimit, l.max)
+	}
+	n, err := l.w.Write(p)
+	l.n += int64(n)
+	return n, err
+}
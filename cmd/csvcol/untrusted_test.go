@@ -0,0 +1,184 @@
+/*
+ * untrusted_test.go
+ * Tests for untrusted.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestCheckLimits(t *testing.T) {
+	defer func(r, f *int, n int) {
+		gc.maxRows, gc.maxFieldSize, limits.rows = r, f, n
+	}(gc.maxRows, gc.maxFieldSize, limits.rows)
+	gc.maxRows, gc.maxFieldSize = new(int), new(int)
+	*gc.maxRows, *gc.maxFieldSize = 2, 3
+	limits.rows = 0
+
+	for _, c := range []struct {
+		rec []string
+		ok  bool
+	}{
+		{rec: []string{"abc", ""}, ok: true},
+		{rec: []string{"a", "abcd"}},
+		{rec: []string{"a"}},
+	} {
+		err := checkLimits(c.rec)
+		if c.ok && nil != err {
+			t.Errorf("%q: error: %v", c.rec, err)
+		} else if !c.ok && !errors.Is(err, errLimit) {
+			t.Errorf("%q: got %v, want %v", c.rec, err, errLimit)
+		}
+	}
+
+	/* No limits, no errors */
+	*gc.maxRows, *gc.maxFieldSize = 0, 0
+	if err := checkLimits([]string{strings.Repeat("x", 100)}); nil != err {
+		t.Errorf("Error without limits: %v", err)
+	}
+}
+
+func TestLimitedWriter(t *testing.T) {
+	var b bytes.Buffer
+	lw := &limitedWriter{w: &b, max: 5}
+	if _, err := io.WriteString(lw, "abc"); nil != err {
+		t.Fatalf("First write: %v", err)
+	}
+	if _, err := io.WriteString(lw, "def"); !errors.Is(err, errLimit) {
+		t.Errorf("Second write: got %v, want %v", err, errLimit)
+	}
+	if _, err := io.WriteString(lw, "de"); nil != err {
+		t.Errorf("Third write: %v", err)
+	}
+	if "abcde" != b.String() {
+		t.Errorf("Wrote %q", b.String())
+	}
+}
+
+func TestRecordLimiter(t *testing.T) {
+	/* The CSV reader reads in big chunks, so the record has to be
+	longer than a chunk */
+	in := "a,b\n" + strings.Repeat("x", 10000) + "\nc,d\n"
+	lr := &recordLimiter{r: strings.NewReader(in), max: 10}
+	cr := csv.NewReader(lr)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	lr.cr = cr
+	if _, err := cr.Read(); nil != err {
+		t.Fatalf("First record: %v", err)
+	}
+	if _, err := cr.Read(); !errors.Is(err, errLimit) {
+		t.Errorf("Long record: got %v, want %v", err, errLimit)
+	}
+}
+
+func TestUntrusted(t *testing.T) {
+	in := "a,b\n1,2\n3,4\n5,6\n"
+	for _, c := range []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string /* In standard error */
+	}{{
+		name: "max_rows",
+		args: []string{"-max-rows", "3"},
+		want: "more than 3 rows",
+	}, {
+		name:  "max_field_size",
+		stdin: "a,b\nc,de\n",
+		args:  []string{"-max-field-size", "1"},
+		want:  "field 2 longer than 1 bytes",
+	}, {
+		name:  "max_field_size_record",
+		stdin: strings.Repeat("x", 10000) + "\n",
+		args:  []string{"-max-field-size", "1"},
+		want:  "record longer than 64 bytes",
+	}, {
+		name: "forbidden",
+		args: []string{"-untrusted", "-o", "out.csv"},
+		want: "-o may not be used with -untrusted",
+	}, {
+		name: "forbidden_several",
+		args: []string{"-untrusted", "-max-mem", "1M", "-trace", "t"},
+		want: "-trace, -max-mem may not be used",
+	}, {
+		name: "untrusted_max_rows",
+		args: []string{"-untrusted", "-max-rows", "1"},
+		want: "more than 1 rows",
+	}, {
+		name:  "untrusted_default_field_size",
+		stdin: strings.Repeat("x", untrustedMaxFieldSize+1) + "\n",
+		args:  []string{"-untrusted"},
+		want:  "longer than",
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			stdin := c.stdin
+			if "" == stdin {
+				stdin = in
+			}
+			res := runCSVCol(t, stdin, c.args...)
+			if -46 != int8(res.code) {
+				t.Errorf("Exit code %d", int8(res.code))
+			}
+			if !strings.Contains(res.stderr, c.want) {
+				t.Errorf(
+					"Standard error missing %q:\n%s",
+					c.want,
+					res.stderr,
+				)
+			}
+		})
+	}
+
+	/* Stalled input shouldn't stall forever */
+	res := runStalled(t, "-max-time", "100ms")
+	if -46 != int8(res.code) ||
+		!strings.Contains(res.stderr, "Giving up after 100ms") {
+		t.Errorf("-max-time %d: %s", int8(res.code), res.stderr)
+	}
+
+	/* Output's limited too, though just how it stops depends on when the
+	output's written */
+	res = runCSVCol(
+		t,
+		strings.Repeat("abcdefghij\n", 1000),
+		"-max-output", "1K",
+	)
+	if 0 == res.code || !strings.Contains(res.stderr, "1024 bytes") {
+		t.Errorf("-max-output %d: %s", int8(res.code), res.stderr)
+	}
+	if 1024 < len(res.stdout) {
+		t.Errorf("Wrote %d bytes", len(res.stdout))
+	}
+
+	/* Under the limits, -untrusted doesn't change anything */
+	got := mustRun(t, in, "-untrusted", "-cols", "2")
+	if "b\n2\n4\n6\n" != got {
+		t.Errorf("Untrusted output %q", got)
+	}
+}
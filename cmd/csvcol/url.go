@@ -0,0 +1,125 @@
+/*
+ * url.go
+ * Reading input from URLs
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+/* urlSchemes are the URL schemes which may be given in place of a file */
+var urlSchemes = []string{"http://", "https://", "s3://", "gs://"}
+
+/* isURL returns true if s is a URL we can read, i.e. one starting with one of
+urlSchemes. */
+func isURL(s string) bool {
+	for _, p := range urlSchemes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+/* openFile opens the file named n, which may be an HTTP or HTTPS URL or an
+S3 or GCS object. */
+func openFile(n string) (*os.File, error) {
+	var (
+		req *http.Request
+		err error
+	)
+	switch {
+	case strings.HasPrefix(n, "s3://"):
+		req, err = s3Request(n)
+	case strings.HasPrefix(n, "gs://"):
+		req, err = gcsRequest(n)
+	case isURL(n):
+		req, err = http.NewRequest(http.MethodGet, n, nil)
+	default:
+		return os.Open(n)
+	}
+	if nil != err {
+		return nil, err
+	}
+	return openRequest(req, n)
+}
+
+/* openRequest makes the request req for the file named u and returns a pipe
+from which the response body may be read.  Redirects are followed.  -timeout
+limits the time taken to connect and receive the response's headers, but not
+the time taken to read the body.  An error reading the body is reported and
+the pipe closed, as if the body had ended. */
+func openRequest(req *http.Request, u string) (*os.File, error) {
+	c := &http.Client{Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout: *gc.timeout,
+		}).DialContext,
+		TLSHandshakeTimeout:   *gc.timeout,
+		ResponseHeaderTimeout: *gc.timeout,
+	}}
+	verbose("Requesting %v", u)
+	res, err := c.Do(req)
+	if nil != err {
+		return nil, err
+	}
+	if 2 != res.StatusCode/100 {
+		res.Body.Close()
+		return nil, fmt.Errorf("HTTP error: %v", res.Status)
+	}
+	debug("Got %v from %v", res.Status, res.Request.URL)
+
+	/* Give the caller something file-like */
+	pr, pw, err := os.Pipe()
+	if nil != err {
+		res.Body.Close()
+		return nil, err
+	}
+	go func() {
+		defer res.Body.Close()
+		defer pw.Close()
+		if _, err := io.Copy(pw, res.Body); nil != err {
+			inform("Error reading %v: %v", u, err)
+		}
+	}()
+	return pr, nil
+}
+
+/* resolveURL returns ref, which may be relative, resolved relative to the
+URL base. */
+func resolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if nil != err {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if nil != err {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
@@ -0,0 +1,88 @@
+/*
+ * url_test.go
+ * Tests for url.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestURLInput(t *testing.T) {
+	/* serve returns a handler which serves body */
+	serve := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, body)
+		}
+	}
+	done := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.Handle("/data.csv", serve("a,b,c\n1,2,3\n4,5,6\n"))
+	mux.Handle("/cols", serve("3 # Last\n1\n"))
+	mux.Handle("/moved.csv", http.RedirectHandler(
+		"/data.csv",
+		http.StatusFound,
+	))
+	mux.HandleFunc("/slow.csv", func(http.ResponseWriter, *http.Request) {
+		<-done
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	defer close(done) /* Before s.Close, which waits for handlers */
+
+	runOutputTests(t, []outputTest{{
+		name: "argument",
+		args: []string{"-cols", "2", s.URL + "/data.csv"},
+		want: "b\n2\n5\n",
+	}, {
+		name: "csvfile",
+		args: []string{"-cols", "1", "-csvfile", s.URL + "/data.csv"},
+		want: "a\n1\n4\n",
+	}, {
+		name: "redirect",
+		args: []string{"-cols", "3", s.URL + "/moved.csv"},
+		want: "c\n3\n6\n",
+	}, {
+		name: "colfile",
+		args: []string{
+			"-colfile", s.URL + "/cols",
+			s.URL + "/data.csv",
+		},
+		want: "a,c\n1,3\n4,6\n",
+	}})
+
+	/* Errors and timeouts should be fatal */
+	for _, args := range [][]string{
+		{s.URL + "/missing.csv"},
+		{"-timeout", "100ms", s.URL + "/slow.csv"},
+	} {
+		if res := runCSVCol(t, "", args...); -5 != int8(res.code) {
+			t.Errorf(
+				"%q: exit code %d, want -5",
+				args,
+				int8(res.code),
+			)
+		}
+	}
+}
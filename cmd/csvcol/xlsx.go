@@ -0,0 +1,490 @@
+/*
+ * xlsx.go
+ * Reading XLSX workbooks
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+/* Workbooks are read with archive/zip and encoding/xml rather than a library,
+to avoid the dependencies. */
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* zipMagic is the start of a zip file, which an XLSX workbook is */
+var zipMagic = []byte("PK\x03\x04")
+
+/* xlsxMaxColumns is the number of columns a sheet may have, up to XFD */
+const xlsxMaxColumns = 16384
+
+/* Limits on the size of a workbook, and of each file in it once
+decompressed, to keep zip bombs at bay.  They're variables for testing. */
+var (
+	xlsxMaxSize   int64 = 256 << 20
+	xlsxMaxMember int64 = 1 << 30
+)
+
+/* xlsxDateFormats are the built-in number formats which are dates */
+var xlsxDateFormats = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true,
+	21: true, 22: true, 45: true, 46: true, 47: true,
+}
+
+/* xlsxWorkbook holds the parts of a workbook needed to read a sheet */
+type xlsxWorkbook struct {
+	z        *zip.Reader
+	sheets   []xlsxSheet
+	strings  []string
+	dates    []bool /* Per style, whether numbers are dates */
+	date1904 bool
+}
+
+/* xlsxSheet is a sheet's name and where it is in the zip file */
+type xlsxSheet struct {
+	name string
+	file string
+}
+
+/* fromXLSX returns a reader which reads CSV converted from the sheet of the
+XLSX workbook read from r named with -sheet, if r starts with the magic bytes
+of a zip file and workbooks are to be recognized.  Otherwise, a reader which
+reads r is returned.  The workbook is read into memory, as reading a zip file
+needs random access.  Errors are returned by the first read. */
+func fromXLSX(r io.Reader) io.Reader {
+	if !gc.xlsx {
+		return r
+	}
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(zipMagic)); !bytes.Equal(magic, zipMagic) {
+		return br
+	}
+	debug("Reading XLSX workbook")
+	b, err := io.ReadAll(io.LimitReader(br, xlsxMaxSize+1))
+	if nil != err {
+		return errReader{fmt.Errorf("xlsx: %w", err)}
+	}
+	if int64(len(b)) > xlsxMaxSize {
+		return errReader{fmt.Errorf(
+			"xlsx: workbook larger than %d bytes",
+			xlsxMaxSize,
+		)}
+	}
+	wb, err := openXLSX(b)
+	if nil != err {
+		return errReader{fmt.Errorf("xlsx: %w", err)}
+	}
+	sheet, err := wb.sheet(*gc.sheet)
+	if nil != err {
+		return errReader{fmt.Errorf("xlsx: %w", err)}
+	}
+	verbose("Reading sheet %q", sheet.name)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(wb.writeCSV(pw, sheet))
+	}()
+	return pr
+}
+
+/* openXLSX parses the workbook, shared strings, and styles from b */
+func openXLSX(b []byte) (*xlsxWorkbook, error) {
+	z, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if nil != err {
+		return nil, err
+	}
+	wb := &xlsxWorkbook{z: z}
+	if err := wb.readSheets(); nil != err {
+		return nil, fmt.Errorf("workbook: %w", err)
+	}
+	if err := wb.readStrings(); nil != err {
+		return nil, fmt.Errorf("shared strings: %w", err)
+	}
+	if err := wb.readStyles(); nil != err {
+		return nil, fmt.Errorf("styles: %w", err)
+	}
+	return wb, nil
+}
+
+/* open opens the file n in the workbook.  Reading more than xlsxMaxMember
+bytes from it returns an error. */
+func (wb *xlsxWorkbook) open(n string) (io.ReadCloser, error) {
+	f, err := wb.z.Open(n)
+	if nil != err {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if nil != err {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() > xlsxMaxMember {
+		f.Close()
+		return nil, fmt.Errorf(
+			"%s: larger than %d bytes",
+			n,
+			xlsxMaxMember,
+		)
+	}
+	return &xlsxMember{f: f, n: n, left: xlsxMaxMember}, nil
+}
+
+/* xlsxMember is a file in a workbook which refuses to read more than it's
+allowed, in case its size in the zip file is wrong. */
+type xlsxMember struct {
+	f    fs.File
+	n    string
+	left int64
+}
+
+/* Read satisfies io.Reader */
+func (m *xlsxMember) Read(p []byte) (int, error) {
+	if 0 >= m.left {
+		return 0, fmt.Errorf("%s: larger than %d bytes",
+			m.n, xlsxMaxMember)
+	}
+	if int64(len(p)) > m.left {
+		p = p[:m.left]
+	}
+	n, err := m.f.Read(p)
+	m.left -= int64(n)
+	return n, err
+}
+
+/* Close satisfies io.Closer */
+func (m *xlsxMember) Close() error { return m.f.Close() }
+
+/* decodeXML decodes the file n in the workbook into v.  If the file doesn't
+exist and optional is true, v is left alone. */
+func (wb *xlsxWorkbook) decodeXML(n string, v any, optional bool) error {
+	f, err := wb.open(n)
+	if optional && errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if nil != err {
+		return err
+	}
+	defer f.Close()
+	return xml.NewDecoder(f).Decode(v)
+}
+
+/* readSheets reads the sheets' names and files */
+func (wb *xlsxWorkbook) readSheets() error {
+	var w struct {
+		Pr struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"id,attr"` /* r:id */
+		} `xml:"sheets>sheet"`
+	}
+	if err := wb.decodeXML("xl/workbook.xml", &w, false); nil != err {
+		return err
+	}
+	wb.date1904 = "1" == w.Pr.Date1904 || "true" == w.Pr.Date1904
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decodeXML(
+		"xl/_rels/workbook.xml.rels",
+		&rels,
+		false,
+	); nil != err {
+		return err
+	}
+	targets := make(map[string]string)
+	for _, r := range rels.Rels {
+		if t, ok := strings.CutPrefix(r.Target, "/"); ok {
+			targets[r.ID] = t
+		} else {
+			targets[r.ID] = path.Join("xl", r.Target)
+		}
+	}
+	for _, s := range w.Sheets {
+		t, ok := targets[s.ID]
+		if !ok {
+			return fmt.Errorf("no file for sheet %q", s.Name)
+		}
+		wb.sheets = append(wb.sheets, xlsxSheet{name: s.Name, file: t})
+	}
+	if 0 == len(wb.sheets) {
+		return fmt.Errorf("no sheets")
+	}
+	return nil
+}
+
+/* readStrings reads the shared strings.  Phonetic runs are ignored. */
+func (wb *xlsxWorkbook) readStrings() error {
+	var sst struct {
+		SI []struct {
+			T string `xml:"t"`
+			R []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := wb.decodeXML("xl/sharedStrings.xml", &sst, true); nil != err {
+		return err
+	}
+	wb.strings = make([]string, len(sst.SI))
+	for i, si := range sst.SI {
+		s := si.T
+		for _, r := range si.R {
+			s += r.T
+		}
+		wb.strings[i] = s
+	}
+	return nil
+}
+
+/* readStyles works out which styles format numbers as dates */
+func (wb *xlsxWorkbook) readStyles() error {
+	var ss struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		XFs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := wb.decodeXML("xl/styles.xml", &ss, true); nil != err {
+		return err
+	}
+	dates := make(map[int]bool)
+	for k, v := range xlsxDateFormats {
+		dates[k] = v
+	}
+	for _, f := range ss.NumFmts {
+		dates[f.ID] = isDateFormat(f.Code)
+	}
+	wb.dates = make([]bool, len(ss.XFs))
+	for i, xf := range ss.XFs {
+		wb.dates[i] = dates[xf.NumFmtID]
+	}
+	return nil
+}
+
+/* isDateFormat returns true if the number format code c has a date or time
+in it, ignoring quoted strings, escaped characters, and bracketed colors and
+conditions. */
+func isDateFormat(c string) bool {
+	for i := 0; i < len(c); i++ {
+		switch c[i] {
+		case '"':
+			if j := strings.IndexByte(c[i+1:], '"'); -1 != j {
+				i += j + 1
+			}
+		case '[':
+			if j := strings.IndexByte(c[i+1:], ']'); -1 != j {
+				i += j + 1
+			}
+		case '\\', '_', '*':
+			i++
+		case 'd', 'D', 'm', 'M', 'y', 'Y', 'h', 'H', 's', 'S':
+			return true
+		}
+	}
+	return false
+}
+
+/* sheet returns the sheet named s, or with the 1-indexed number s.  If s is
+empty, the first sheet is returned. */
+func (wb *xlsxWorkbook) sheet(s string) (xlsxSheet, error) {
+	if "" == s {
+		return wb.sheets[0], nil
+	}
+	for _, sh := range wb.sheets {
+		if s == sh.name {
+			return sh, nil
+		}
+	}
+	if n, err := strconv.Atoi(s); nil == err {
+		if 1 > n || len(wb.sheets) < n {
+			return xlsxSheet{}, fmt.Errorf(
+				"sheet %v requested, but only %v sheets",
+				n,
+				len(wb.sheets),
+			)
+		}
+		return wb.sheets[n-1], nil
+	}
+	return xlsxSheet{}, fmt.Errorf("no sheet named %q", s)
+}
+
+/* xlsxCell is a cell in a sheet */
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Style  int    `xml:"s,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		T string `xml:"t"`
+		R []struct {
+			T string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+/* writeCSV writes the rows of the sheet to w as CSV.  Rows are read one at a
+time.  Empty rows are skipped, as blank lines are in CSV. */
+func (wb *xlsxWorkbook) writeCSV(w io.Writer, sheet xlsxSheet) error {
+	f, err := wb.open(sheet.file)
+	if nil != err {
+		return err
+	}
+	defer f.Close()
+	cw := csv.NewWriter(w)
+	cw.Comma = gc.comma
+	dec := xml.NewDecoder(f)
+	var record []string
+	for {
+		t, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if nil != err {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				record = record[:0]
+			case "c":
+				var c xlsxCell
+				err := dec.DecodeElement(&c, &t)
+				if nil == err {
+					record, err = wb.addCell(record, c)
+				}
+				if nil != err {
+					return err
+				}
+			}
+		case xml.EndElement:
+			if "row" != t.Name.Local || 0 == len(record) {
+				continue
+			}
+			if err := cw.Write(record); nil != err {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+/* addCell adds the value of c to record, padding with empty fields if cells
+were skipped. */
+func (wb *xlsxWorkbook) addCell(record []string, c xlsxCell) ([]string, error) {
+	if "" != c.Ref {
+		col, err := xlsxColumn(c.Ref)
+		if nil != err {
+			return nil, err
+		}
+		for len(record) < col-1 {
+			record = append(record, "")
+		}
+	}
+	var v string
+	switch c.Type {
+	case "s":
+		n, err := strconv.Atoi(c.Value)
+		if nil != err || 0 > n || len(wb.strings) <= n {
+			return nil, fmt.Errorf("cell %v: bad shared string %q",
+				c.Ref, c.Value)
+		}
+		v = wb.strings[n]
+	case "inlineStr":
+		v = c.Inline.T
+		for _, r := range c.Inline.R {
+			v += r.T
+		}
+	case "b":
+		v = "FALSE"
+		if "1" == c.Value {
+			v = "TRUE"
+		}
+	case "", "n":
+		v = c.Value
+		if 0 <= c.Style && c.Style < len(wb.dates) &&
+			wb.dates[c.Style] {
+			v = wb.date(v)
+		}
+	default: /* str, e, d */
+		v = c.Value
+	}
+	return append(record, v), nil
+}
+
+/* date converts the serial date s to a date, and a time if it has one.  If s
+isn't a number, it's returned unchanged. */
+func (wb *xlsxWorkbook) date(s string) string {
+	f, err := strconv.ParseFloat(s, 64)
+	if nil != err {
+		return s
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if wb.date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days, frac := math.Modf(f)
+	t := epoch.AddDate(0, 0, int(days)).Add(
+		time.Duration(math.Round(frac*86400)) * time.Second,
+	)
+	if 0 == frac {
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.DateTime)
+}
+
+/* xlsxColumn returns the 1-indexed column of a cell reference like AB12.
+Columns past xlsxMaxColumns are an error. */
+func xlsxColumn(ref string) (int, error) {
+	col := 0
+	for _, c := range ref {
+		if 'A' > c || 'Z' < c {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		if xlsxMaxColumns < col {
+			return 0, fmt.Errorf("cell reference %q past column "+
+				"%d", ref, xlsxMaxColumns)
+		}
+	}
+	if 0 == col {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col, nil
+}
@@ -0,0 +1,456 @@
+/*
+ * xlsx_test.go
+ * Tests for xlsx.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/* testWorkbook is the XML of a workbook with two sheets */
+var testWorkbook = map[string]string{
+	"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+ xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets>
+<sheet name="People" sheetId="1" r:id="rId1"/>
+<sheet name="Dates" sheetId="2" r:id="rId2"/>
+</sheets>
+</workbook>`,
+	"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships
+ xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`,
+	"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>name</t></si>
+<si><t>age</t></si>
+<si><r><t>Ann, </t></r><r><t>Smith</t></r></si>
+</sst>`,
+	"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd hh:mm"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/></cellXfs>
+</styleSheet>`,
+	"xl/worksheets/sheet1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c>
+<c r="C1" t="inlineStr"><is><t>ok</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>42</v></c>
+<c r="C2" t="b"><v>1</v></c></row>
+<row r="3"></row>
+<row r="4"><c r="A4" t="str"><v>Bob</v></c>
+<c r="C4" t="b"><v>0</v></c></row>
+</sheetData>
+</worksheet>`,
+	"xl/worksheets/sheet2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetData>
+<row><c s="1"><v>45658</v></c><c s="2"><v>45658.5</v></c>
+<c><v>45658</v></c></row>
+</sheetData>
+</worksheet>`,
+}
+
+/* writeTestWorkbook writes the files in testWorkbook to a zip file named n
+in dir and returns its path. */
+func writeTestWorkbook(t *testing.T, dir, n string) string {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for n, s := range testWorkbook {
+		w, err := zw.Create(n)
+		if nil != err {
+			t.Fatalf("Adding %s: %v", n, err)
+		}
+		if _, err := w.Write([]byte(s)); nil != err {
+			t.Fatalf("Writing %s: %v", n, err)
+		}
+	}
+	if err := zw.Close(); nil != err {
+		t.Fatalf("Closing zip: %v", err)
+	}
+	p := filepath.Join(dir, n)
+	if err := os.WriteFile(p, b.Bytes(), 0600); nil != err {
+		t.Fatalf("Writing %s: %v", p, err)
+	}
+	return p
+}
+
+func TestXLSXInput(t *testing.T) {
+	f := writeTestWorkbook(t, t.TempDir(), "book.xlsx")
+	people := "name,age,ok\n\"Ann, Smith\",42,TRUE\nBob,,FALSE\n"
+	dates := "2025-01-01,2025-01-01 12:00:00,45658\n"
+	runOutputTests(t, []outputTest{{
+		name: "first_sheet",
+		args: []string{f},
+		want: people,
+	}, {
+		name: "sheet_by_name",
+		args: []string{"-sheet", "Dates", f},
+		want: dates,
+	}, {
+		name: "sheet_by_number",
+		args: []string{"-sheet", "2", f},
+		want: dates,
+	}, {
+		name: "filters",
+		args: []string{
+			"-header",
+			"-where", "c3 == TRUE",
+			"-cols", "1",
+			f,
+		},
+		want: "name\n\"Ann, Smith\"\n",
+	}, {
+		name:  "stdin",
+		stdin: "a,b\n",
+		args:  []string{"-", f},
+		want:  "a,b\n" + people,
+	}})
+	for _, s := range []string{"Nope", "0", "3"} {
+		if res := runCSVCol(t, "", "-sheet", s, f); 0 == res.code {
+			t.Errorf("-sheet %q succeeded", s)
+		}
+	}
+}
+
+func TestXLSXSniffing(t *testing.T) {
+	f := writeTestWorkbook(t, t.TempDir(), "book.xlsx")
+	people := "name,age,ok\n\"Ann, Smith\",42,TRUE\nBob,,FALSE\n"
+
+	/* -untrusted needs -sheet */
+	if res := runCSVCol(t, "", "-untrusted", f); people == res.stdout {
+		t.Errorf("Workbook read with -untrusted")
+	}
+	got := mustRun(t, "", "-untrusted", "-sheet", "1", f)
+	if people != got {
+		t.Errorf("-untrusted -sheet: got %q, want %q", got, people)
+	}
+
+	/* As does -daemon */
+	job := daemonJob{Files: []string{f}}
+	res := dialDaemon(t, startDaemon(t)).run(job)
+	if people == res.Output {
+		t.Errorf("Workbook read by daemon")
+	}
+	res = dialDaemon(t, startDaemon(t, "-sheet", "People")).run(job)
+	if people != res.Output {
+		t.Errorf("Daemon with -sheet: got %+v, want %q", res, people)
+	}
+}
+
+func TestXLSXLimits(t *testing.T) {
+	b, err := os.ReadFile(writeTestWorkbook(t, t.TempDir(), "book.xlsx"))
+	if nil != err {
+		t.Fatalf("Reading workbook: %v", err)
+	}
+	defer func(x bool, s, m int64) {
+		gc.xlsx, xlsxMaxSize, xlsxMaxMember = x, s, m
+	}(gc.xlsx, xlsxMaxSize, xlsxMaxMember)
+	gc.xlsx = true
+	defer func(d *bool) { gc.debug = d }(gc.debug)
+	gc.debug = new(bool)
+
+	/* Workbooks which are too big */
+	xlsxMaxSize = int64(len(b)) - 1
+	if _, err := io.ReadAll(fromXLSX(bytes.NewReader(b))); nil == err {
+		t.Errorf("Oversized workbook read")
+	}
+	xlsxMaxSize = int64(len(b))
+
+	/* Files in the workbook which are too big */
+	xlsxMaxMember = 10
+	if _, err := openXLSX(b); nil == err {
+		t.Errorf("Workbook with oversized files opened")
+	}
+	xlsxMaxMember = 1 << 20
+	wb, err := openXLSX(b)
+	if nil != err {
+		t.Fatalf("Opening workbook: %v", err)
+	}
+
+	/* Even if they lie about their size */
+	f, err := wb.open("xl/workbook.xml")
+	if nil != err {
+		t.Fatalf("Opening workbook.xml: %v", err)
+	}
+	defer f.Close()
+	f.(*xlsxMember).left = 10
+	if got, err := io.ReadAll(f); nil == err {
+		t.Errorf("Read %d bytes past the limit", len(got))
+	} else if 10 != len(got) {
+		t.Errorf("Read %d bytes, not 10", len(got))
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for ref, want := range map[string]int{
+		"A1":    1,
+		"Z9":    26,
+		"AA10":  27,
+		"AB12":  28,
+		"XFD1":  16384,
+		"1":     0,
+		"a1":    0,
+		"":      0,
+		"$A$1":  0,
+		"ZZ100": 702,
+		"XFE1":  0,
+		"ZZZ1":  0,
+	} {
+		got, err := xlsxColumn(ref)
+		switch {
+		case 0 == want && nil == err:
+			t.Errorf("%q: didn't fail, got %d", ref, got)
+		case 0 != want && nil != err:
+			t.Errorf("%q: %v", ref, err)
+		case got != want:
+			t.Errorf("%q: got %d, want %d", ref, got, want)
+		}
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	for c, want := range map[string]bool{
+		"yyyy-mm-dd":          true,
+		"h:mm AM/PM":          true,
+		"[$-409]d-mmm":        true,
+		"0.00":                false,
+		"#,##0;[Red]-#,##0":   false,
+		`0.0" days"`:          false,
+		`\d0`:                 false,
+		"General":             false,
+		"_(* #,##0_);_(* (#)": false,
+		"@":                   false,
+	} {
+		if got := isDateFormat(c); got != want {
+			t.Errorf("%q: got %t, want %t", c, got, want)
+		}
+	}
+}
+
+func TestXLSXDate(t *testing.T) {
+	var wb xlsxWorkbook
+	for s, want := range map[string]string{
+		"45658":     "2025-01-01",
+		"45658.75":  "2025-01-01 18:00:00",
+		"1":         "1899-12-31",
+		"not a day": "not a day",
+	} {
+		if got := wb.date(s); got != want {
+			t.Errorf("%q: got %q, want %q", s, got, want)
+		}
+	}
+	wb.date1904 = true
+	if got := wb.date("0"); "1904-01-01" != got {
+		t.Errorf("1904: got %q", got)
+	}
+}
+
+func TestXLSXRef(t *testing.T) {
+	for n, want := range map[int]string{
+		1:     "A",
+		26:    "Z",
+		27:    "AA",
+		28:    "AB",
+		702:   "ZZ",
+		703:   "AAA",
+		16384: "XFD",
+	} {
+		if got := xlsxRef(n); got != want {
+			t.Errorf("%d: got %q, want %q", n, got, want)
+		}
+		if got, err := xlsxColumn(want + "1"); nil != err || got != n {
+			t.Errorf("%q: round trip gave %d, %v", want, got, err)
+		}
+	}
+}
+
+func TestIsPlainNumber(t *testing.T) {
+	for f, want := range map[string]bool{
+		"0":      true,
+		"42":     true,
+		"-3.5":   true,
+		"0.25":   true,
+		"007":    false,
+		"1e3":    false,
+		"1.50":   false,
+		"+1":     false,
+		" 1":     false,
+		"NaN":    false,
+		"Inf":    false,
+		"":       false,
+		"1,000":  false,
+		"0x10":   false,
+		"12345a": false,
+	} {
+		if got := isPlainNumber(f); got != want {
+			t.Errorf("%q: got %t, want %t", f, got, want)
+		}
+	}
+}
+
+/* readTestWorkbook returns the first sheet of the workbook in b as CSV, as
+well as the sheet's XML. */
+func readTestWorkbook(t *testing.T, b []byte) (string, string) {
+	defer func(c rune) { gc.comma = c }(gc.comma)
+	gc.comma = ','
+	wb, err := openXLSX(b)
+	if nil != err {
+		t.Fatalf("Opening workbook: %v", err)
+	}
+	sheet, err := wb.sheet("")
+	if nil != err {
+		t.Fatalf("Getting sheet: %v", err)
+	}
+	var sb bytes.Buffer
+	if err := wb.writeCSV(&sb, sheet); nil != err {
+		t.Fatalf("Converting sheet: %v", err)
+	}
+	f, err := wb.z.Open(sheet.file)
+	if nil != err {
+		t.Fatalf("Opening sheet: %v", err)
+	}
+	defer f.Close()
+	x, err := io.ReadAll(f)
+	if nil != err {
+		t.Fatalf("Reading sheet: %v", err)
+	}
+	return sb.String(), string(x)
+}
+
+func TestXLSXWriter(t *testing.T) {
+	const (
+		bold   = `s="1"`
+		frozen = `state="frozen"`
+	)
+	for _, c := range []struct {
+		name    string
+		header  bool
+		line    string
+		records [][]string
+		want    string
+		has     []string /* In the sheet's XML */
+		lacks   []string /* Not in the sheet's XML */
+	}{{
+		name:  "empty",
+		want:  "",
+		lacks: []string{bold, frozen},
+	}, {
+		name: "plain",
+		records: [][]string{
+			{"id", "zip", "note"},
+			{"1", "02134", "<a & b>"},
+			{"-2.5", "1e3", "  spaced  "},
+		},
+		want: "id,zip,note\n" +
+			"1,02134,<a & b>\n" +
+			"-2.5,1e3,\"  spaced  \"\n",
+		has: []string{
+			`<c r="A2"><v>1</v></c>`,
+			`<c r="A3"><v>-2.5</v></c>`,
+			`<c r="B2" t="inlineStr">`,
+			`<c r="B3" t="inlineStr">`,
+			"&lt;a &amp; b&gt;",
+		},
+		lacks: []string{bold, frozen},
+	}, {
+		name:   "header",
+		header: true,
+		records: [][]string{
+			{"n", "1"},
+			{"2", "x"},
+		},
+		want: "n,1\n2,x\n",
+		has: []string{
+			`<c r="B1" t="inlineStr" s="1">`,
+			`<c r="A2"><v>2</v></c>`,
+			frozen,
+		},
+	}, {
+		name:    "line_before_header",
+		header:  true,
+		line:    "# comment",
+		records: [][]string{{"n"}, {"2"}},
+		want:    "# comment\nn\n2\n",
+		lacks:   []string{bold, frozen},
+	}} {
+		var b bytes.Buffer
+		x := newXLSXWriter(&b, c.header)
+		if "" != c.line {
+			if err := x.WriteLine(c.line); nil != err {
+				t.Fatalf("%s: WriteLine: %v", c.name, err)
+			}
+		}
+		for _, r := range c.records {
+			if err := x.Write(r); nil != err {
+				t.Fatalf("%s: Write: %v", c.name, err)
+			}
+		}
+		if err := x.Close(); nil != err {
+			t.Fatalf("%s: Close: %v", c.name, err)
+		}
+		got, sheet := readTestWorkbook(t, b.Bytes())
+		if got != c.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", c.name, got, c.want)
+		}
+		for _, h := range c.has {
+			if !strings.Contains(sheet, h) {
+				t.Errorf("%s: no %s:\n%s", c.name, h, sheet)
+			}
+		}
+		for _, l := range c.lacks {
+			if strings.Contains(sheet, l) {
+				t.Errorf("%s: has %s:\n%s", c.name, l, sheet)
+			}
+		}
+	}
+}
+
+func TestXLSXOutput(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out.xlsx")
+	in := "name,n\nann,1\nbob,022\n"
+	mustRun(t, in, "-header", "-xlsx-header", "-format", "xlsx", "-o", out)
+	if got := mustRun(t, "", out); in != got {
+		t.Errorf("Round trip:\ngot:\n%s\nwant:\n%s", got, in)
+	}
+	b, err := os.ReadFile(out)
+	if nil != err {
+		t.Fatalf("Reading %s: %v", out, err)
+	}
+	if _, sheet := readTestWorkbook(t, b); !strings.Contains(
+		sheet,
+		`<c r="A1" t="inlineStr" s="1">`,
+	) {
+		t.Errorf("Header not bold:\n%s", sheet)
+	}
+}
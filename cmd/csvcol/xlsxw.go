@@ -0,0 +1,261 @@
+/*
+ * xlsxw.go
+ * Writing XLSX workbooks
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* xlsxParts are the parts of a workbook other than the sheet, which are the
+same for every workbook.  {ox} and {ct} are replaced with xlsxReplacer, to
+keep things short. */
+var xlsxParts = []struct{ name, body string }{{
+	"[Content_Types].xml", `
+<Types xmlns="{ox}package/2006/content-types">
+	<Default Extension="rels"
+		ContentType="{ct}package.relationships+xml"/>
+	<Default Extension="xml" ContentType="application/xml"/>
+	<Override PartName="/xl/workbook.xml"
+		ContentType="{ct}officedocument.spreadsheetml.sheet.main+xml"/>
+	<Override PartName="/xl/worksheets/sheet1.xml"
+		ContentType="{ct}officedocument.spreadsheetml.worksheet+xml"/>
+	<Override PartName="/xl/styles.xml"
+		ContentType="{ct}officedocument.spreadsheetml.styles+xml"/>
+</Types>`,
+}, {
+	"_rels/.rels", `
+<Relationships xmlns="{ox}package/2006/relationships">
+	<Relationship Id="rId1"
+		Type="{ox}officeDocument/2006/relationships/officeDocument"
+		Target="xl/workbook.xml"/>
+</Relationships>`,
+}, {
+	"xl/workbook.xml", `
+<workbook xmlns="{ox}spreadsheetml/2006/main"
+	xmlns:r="{ox}officeDocument/2006/relationships">
+	<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`,
+}, {
+	"xl/_rels/workbook.xml.rels", `
+<Relationships xmlns="{ox}package/2006/relationships">
+	<Relationship Id="rId1"
+		Type="{ox}officeDocument/2006/relationships/worksheet"
+		Target="worksheets/sheet1.xml"/>
+	<Relationship Id="rId2"
+		Type="{ox}officeDocument/2006/relationships/styles"
+		Target="styles.xml"/>
+</Relationships>`,
+}, {
+	/* Style 1 is bold, for the header */
+	"xl/styles.xml", `
+<styleSheet xmlns="{ox}spreadsheetml/2006/main">
+	<fonts count="2">
+		<font><sz val="11"/><name val="Calibri"/></font>
+		<font><b/><sz val="11"/><name val="Calibri"/></font>
+	</fonts>
+	<fills count="2">
+		<fill><patternFill patternType="none"/></fill>
+		<fill><patternFill patternType="gray125"/></fill>
+	</fills>
+	<borders count="1">
+		<border><left/><right/><top/><bottom/><diagonal/></border>
+	</borders>
+	<cellStyleXfs count="1">
+		<xf numFmtId="0" fontId="0" fillId="0" borderId="0"/>
+	</cellStyleXfs>
+	<cellXfs count="2">
+		<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+		<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0"
+			applyFont="1"/>
+	</cellXfs>
+</styleSheet>`,
+}}
+
+/* xlsxReplacer expands the abbreviations in xlsxParts */
+var xlsxReplacer = strings.NewReplacer(
+	"{ox}", "http://schemas.openxmlformats.org/",
+	"{ct}", "application/vnd.openxmlformats-",
+)
+
+/* xlsxWriter is a recordWriter which writes records to a single-sheet XLSX
+workbook.  Rows are written to the sheet as they come, but the workbook isn't
+complete until the writer is closed. */
+type xlsxWriter struct {
+	z      *zip.Writer
+	sheet  *bufio.Writer /* Sheet being written, nil before first row */
+	header bool          /* Style the first record as a header */
+	row    int           /* Last row written */
+	err    error
+}
+
+/* newXLSXWriter returns an xlsxWriter which writes to w.  If header is true,
+the first record is bolded and frozen, unless a line's been written before
+it. */
+func newXLSXWriter(w io.Writer, header bool) *xlsxWriter {
+	return &xlsxWriter{z: zip.NewWriter(w), header: header}
+}
+
+/* start starts the sheet, if it's not already started.  If freeze is true,
+the first row is frozen. */
+func (x *xlsxWriter) start(freeze bool) error {
+	if nil != x.sheet || nil != x.err {
+		return x.err
+	}
+	f, err := x.z.Create("xl/worksheets/sheet1.xml")
+	if nil != err {
+		x.err = err
+		return err
+	}
+	x.sheet = bufio.NewWriter(f)
+	x.sheet.WriteString(xml.Header + xlsxReplacer.Replace(
+		`<worksheet xmlns="{ox}spreadsheetml/2006/main">`,
+	))
+	if freeze {
+		x.sheet.WriteString(`<sheetViews>` +
+			`<sheetView workbookViewId="0">` +
+			`<pane ySplit="1" topLeftCell="A2" ` +
+			`activePane="bottomLeft" state="frozen"/>` +
+			`</sheetView></sheetViews>`)
+	}
+	x.sheet.WriteString(`<sheetData>`)
+	return nil
+}
+
+/* Write writes record as the next row.  Fields which are numbers in their
+simplest form are written as numbers; everything else is written as text, so
+as to keep leading zeros and the like. */
+func (x *xlsxWriter) Write(record []string) error {
+	return x.writeRow(record, x.header && 0 == x.row)
+}
+
+/* WriteHeader writes header as the first row, in bold if x was made with
+header true */
+func (x *xlsxWriter) WriteHeader(header []string) error {
+	return x.Write(header)
+}
+
+/* WriteLine writes line in the first cell of the next row */
+func (x *xlsxWriter) WriteLine(line string) error {
+	return x.writeRow([]string{line}, false)
+}
+
+/* writeRow writes record as the next row, in bold if header is true */
+func (x *xlsxWriter) writeRow(record []string, header bool) error {
+	if err := x.start(header); nil != err {
+		return err
+	}
+	x.row++
+	style := ""
+	if header {
+		style = ` s="1"`
+	}
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.row)
+	for i, f := range record {
+		ref := xlsxRef(i+1) + strconv.Itoa(x.row)
+		if !header && isPlainNumber(f) {
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, f)
+			continue
+		}
+		fmt.Fprintf(x.sheet, `<c r="%s" t="inlineStr"%s><is>`+
+			`<t xml:space="preserve">`, ref, style)
+		xml.EscapeText(x.sheet, []byte(f))
+		x.sheet.WriteString(`</t></is></c>`)
+	}
+	if _, err := x.sheet.WriteString(`</row>`); nil != err {
+		x.err = err
+	}
+	return x.err
+}
+
+/* isPlainNumber returns true if f is a finite number written as simply as it
+can be, i.e. without leading zeros, exponents, and so on. */
+func isPlainNumber(f string) bool {
+	n, err := strconv.ParseFloat(f, 64)
+	return nil == err && !math.IsInf(n, 0) && !math.IsNaN(n) &&
+		f == strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+/* Flush flushes the sheet written so far.  The workbook won't be usable until
+the writer is closed. */
+func (x *xlsxWriter) Flush() {
+	if nil == x.sheet || nil != x.err {
+		return
+	}
+	if err := x.sheet.Flush(); nil != err {
+		x.err = err
+		return
+	}
+	x.err = x.z.Flush()
+}
+
+/* Error returns the first error encountered, if any */
+func (x *xlsxWriter) Error() error { return x.err }
+
+/* Close finishes the sheet and writes the rest of the workbook. */
+func (x *xlsxWriter) Close() error {
+	if err := x.start(false); nil != err {
+		return err
+	}
+	x.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := x.sheet.Flush(); nil != err {
+		x.err = err
+		return err
+	}
+	for _, p := range xlsxParts {
+		f, err := x.z.Create(p.name)
+		if nil == err {
+			_, err = io.WriteString(
+				f,
+				xml.Header+xlsxReplacer.Replace(p.body[1:]),
+			)
+		}
+		if nil != err {
+			x.err = err
+			return err
+		}
+	}
+	if err := x.z.Close(); nil != err {
+		x.err = err
+	}
+	return x.err
+}
+
+/* xlsxRef returns the letters for the 1-indexed column n, e.g. AB for 28 */
+func xlsxRef(n int) string {
+	var b []byte
+	for ; 0 < n; n = (n - 1) / 26 {
+		b = append(b, byte('A'+(n-1)%26))
+	}
+	var sb strings.Builder
+	for i := len(b) - 1; 0 <= i; i-- {
+		sb.WriteByte(b[i])
+	}
+	return sb.String()
+}
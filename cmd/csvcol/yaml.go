@@ -0,0 +1,306 @@
+/*
+ * yaml.go
+ * Just enough YAML for job files
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+/* This is a small subset of YAML: block mappings and sequences, plain and
+quoted scalars, flow sequences of scalars ([a, b]), and comments.  Scalars
+are all strings.  Anchors, tags, multi-line scalars, and flow mappings aren't
+supported. */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* yamlLine is a non-blank line of YAML, with comments removed */
+type yamlLine struct {
+	n      int    /* Line number, for errors */
+	indent int    /* Leading spaces */
+	text   string /* Everything after the leading spaces */
+}
+
+/* yamlParser parses lines of YAML */
+type yamlParser struct {
+	lines []yamlLine
+	i     int /* Next line */
+}
+
+/* parseYAML parses a YAML document.  The returned value is a string, a
+[]interface{}, or a map[string]interface{}, or nil if the document is empty. */
+func parseYAML(doc string) (interface{}, error) {
+	var p yamlParser
+	for i, l := range strings.Split(doc, "\n") {
+		l = strings.TrimRight(stripYAMLComment(l), " \t\r")
+		t := strings.TrimLeft(l, " ")
+		if "" == t || "---" == t {
+			continue
+		}
+		if strings.HasPrefix(t, "\t") {
+			return nil, fmt.Errorf("line %v: tabs may not be "+
+				"used for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{
+			n:      i + 1,
+			indent: len(l) - len(t),
+			text:   t,
+		})
+	}
+	if 0 == len(p.lines) {
+		return nil, nil
+	}
+	v, err := p.block(p.lines[0].indent)
+	if nil != err {
+		return nil, err
+	}
+	if p.i < len(p.lines) {
+		return nil, fmt.Errorf("line %v: unexpected indentation",
+			p.lines[p.i].n)
+	}
+	return v, nil
+}
+
+/* stripYAMLComment removes a comment from l.  Comments start with a # at the
+start of the line or after whitespace, outside of quotes. */
+func stripYAMLComment(l string) string {
+	var q byte /* Quote we're in, if any */
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch {
+		case 0 != q && '\\' == c && '"' == q:
+			i++
+		case 0 != q && c == q:
+			q = 0
+		case 0 != q:
+		case '"' == c || '\'' == c:
+			q = c
+		case '#' == c && (0 == i || ' ' == l[i-1] || '\t' == l[i-1]):
+			return l[:i]
+		}
+	}
+	return l
+}
+
+/* block parses the mapping or sequence starting at the next line, which has
+the given indent. */
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isYAMLSeqItem(p.lines[p.i].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+/* isYAMLSeqItem returns true if t starts a sequence item */
+func isYAMLSeqItem(t string) bool {
+	return "-" == t || strings.HasPrefix(t, "- ")
+}
+
+/* sequence parses a block sequence whose items start with - at indent */
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	var s []interface{}
+	for p.i < len(p.lines) {
+		l := &p.lines[p.i]
+		if l.indent < indent ||
+			(l.indent == indent && !isYAMLSeqItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %v: unexpected "+
+				"indentation", l.n)
+		}
+		/* Item on the following lines */
+		if "-" == l.text {
+			p.i++
+			v, err := p.nested(indent, false)
+			if nil != err {
+				return nil, err
+			}
+			s = append(s, v)
+			continue
+		}
+		/* Item on this line, possibly continued on the next */
+		t := strings.TrimLeft(l.text[1:], " ")
+		l.indent += len(l.text) - len(t)
+		l.text = t
+		if !isYAMLSeqItem(t) && !isYAMLKey(t) {
+			p.i++
+			v, err := parseYAMLScalar(t)
+			if nil != err {
+				return nil, fmt.Errorf("line %v: %v", l.n, err)
+			}
+			s = append(s, v)
+			continue
+		}
+		v, err := p.block(l.indent)
+		if nil != err {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
+
+/* isYAMLKey returns true if t starts with a key: */
+func isYAMLKey(t string) bool {
+	_, _, ok := splitYAMLKey(t)
+	return ok
+}
+
+/* splitYAMLKey splits t into a key and the rest of the line, if t is of the
+form key: or key: value */
+func splitYAMLKey(t string) (key, rest string, ok bool) {
+	if strings.HasPrefix(t, `"`) || strings.HasPrefix(t, "'") ||
+		strings.HasPrefix(t, "[") {
+		return "", "", false
+	}
+	i := strings.Index(t, ":")
+	for -1 != i && i+1 < len(t) && ' ' != t[i+1] {
+		j := strings.Index(t[i+1:], ":")
+		if -1 == j {
+			return "", "", false
+		}
+		i += j + 1
+	}
+	if -1 == i {
+		return "", "", false
+	}
+	return strings.TrimSpace(t[:i]), strings.TrimSpace(t[i+1:]), true
+}
+
+/* mapping parses a block mapping whose keys are at indent */
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for p.i < len(p.lines) {
+		l := p.lines[p.i]
+		if l.indent < indent {
+			break
+		}
+		k, rest, ok := splitYAMLKey(l.text)
+		if l.indent > indent || !ok {
+			return nil, fmt.Errorf("line %v: expected key: value",
+				l.n)
+		}
+		if _, ok := m[k]; ok {
+			return nil, fmt.Errorf("line %v: duplicate key %q",
+				l.n, k)
+		}
+		p.i++
+		/* Value on this line */
+		if "" != rest {
+			v, err := parseYAMLScalar(rest)
+			if nil != err {
+				return nil, fmt.Errorf("line %v: %v", l.n, err)
+			}
+			m[k] = v
+			continue
+		}
+		/* Value on the next lines */
+		v, err := p.nested(indent, true)
+		if nil != err {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+/* nested parses the block after a key: or - with nothing after it, which
+must be more indented than indent.  If sameSeq is true, as it is after a
+mapping's key, a sequence may be at the same indent.  If there's no such
+block, nested returns nil. */
+func (p *yamlParser) nested(indent int, sameSeq bool) (interface{}, error) {
+	if p.i >= len(p.lines) {
+		return nil, nil
+	}
+	l := p.lines[p.i]
+	switch {
+	case l.indent > indent:
+		return p.block(l.indent)
+	case l.indent == indent && sameSeq && isYAMLSeqItem(l.text):
+		return p.sequence(indent)
+	}
+	return nil, nil
+}
+
+/* parseYAMLScalar parses a quoted or plain scalar or a flow sequence of
+scalars */
+func parseYAMLScalar(s string) (interface{}, error) {
+	/* Flow sequence */
+	if strings.HasPrefix(s, "[") {
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated [")
+		}
+		var l []interface{}
+		for _, p := range splitYAMLFlow(s[1 : len(s)-1]) {
+			v, err := parseYAMLScalar(strings.TrimSpace(p))
+			if nil != err {
+				return nil, err
+			}
+			l = append(l, v)
+		}
+		return l, nil
+	}
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if nil != err {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if 2 > len(s) || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("invalid quoted string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, nil
+}
+
+/* splitYAMLFlow splits s on commas which aren't in quotes.  An empty s
+yields no pieces. */
+func splitYAMLFlow(s string) []string {
+	if "" == strings.TrimSpace(s) {
+		return nil
+	}
+	var (
+		ps    []string
+		start int
+		q     byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 0 != q && '\\' == c && '"' == q:
+			i++
+		case 0 != q && c == q:
+			q = 0
+		case 0 != q:
+		case '"' == c || '\'' == c:
+			q = c
+		case ',' == c:
+			ps = append(ps, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(ps, s[start:])
+}
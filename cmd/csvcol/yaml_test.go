@@ -0,0 +1,106 @@
+/*
+ * yaml_test.go
+ * Tests for yaml.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		doc  string
+		want interface{}
+	}{{
+		name: "empty",
+		doc:  "# Nothing\n---\n",
+		want: nil,
+	}, {
+		name: "mapping",
+		doc:  "a: b\nc: 'd # e'  # Comment\n",
+		want: map[string]interface{}{"a": "b", "c": "d # e"},
+	}, {
+		name: "sequence",
+		doc:  "- a\n- \"b\\tc\"\n- 'it''s'\n",
+		want: []interface{}{"a", "b\tc", "it's"},
+	}, {
+		name: "flow",
+		doc:  `a: [x, "y, z", '']`,
+		want: map[string]interface{}{
+			"a": []interface{}{"x", "y, z", ""},
+		},
+	}, {
+		name: "nested",
+		doc: "inputs:\n  - one.csv\n  - two.csv\n" +
+			"outputs:\n" +
+			"  - path: -\n    rows: 1-3\n" +
+			"  - path: x\n    where:\n      - c1 == a\n",
+		want: map[string]interface{}{
+			"inputs": []interface{}{"one.csv", "two.csv"},
+			"outputs": []interface{}{
+				map[string]interface{}{
+					"path": "-",
+					"rows": "1-3",
+				},
+				map[string]interface{}{
+					"path":  "x",
+					"where": []interface{}{"c1 == a"},
+				},
+			},
+		},
+	}, {
+		name: "same_indent_sequence",
+		doc:  "a:\n- x\n- y\nb: z\n",
+		want: map[string]interface{}{
+			"a": []interface{}{"x", "y"},
+			"b": "z",
+		},
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseYAML(c.doc)
+			if nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %#v\nwant %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, doc := range []string{
+		"a:\n\t- b\n",
+		"a: b\n  c: d\n",
+		"a: [b, c\n",
+		`a: "b`,
+		"a: 'b\n",
+		"- a\nb: c\n",
+	} {
+		if _, err := parseYAML(doc); nil == err {
+			t.Errorf("%q didn't fail", doc)
+		}
+	}
+}
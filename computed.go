@@ -0,0 +1,215 @@
+/*
+ * computed.go
+ * Columns computed from other columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* Computed is a column whose value is computed from the other columns of a
+record, as given to csvcol's -add-col. */
+type Computed struct {
+	Name  string
+	cases []computedCase
+	def   valueExpr
+}
+
+/* computedCase is a single condition and the value to use if it's true */
+type computedCase struct {
+	cond  expr
+	value valueExpr
+}
+
+/* computedValue is either a fixed value or the value of a column */
+type computedValue struct {
+	col   int /* 1-indexed, or 0 for value */
+	value string
+}
+
+/* eval returns the value of v for record.  Missing fields are empty. */
+func (v computedValue) eval(record []string) string {
+	switch {
+	case 0 == v.col:
+		return v.value
+	case v.col <= len(record):
+		return record[v.col-1]
+	}
+	return ""
+}
+
+/* ParseComputed parses a computed column of the form NAME=VALUE or
+NAME=case(COND:VALUE, COND:VALUE, ..., DEFAULT).  Each COND is a condition as
+for Where.AddAll and each VALUE is a double-quoted string, a column, a bare
+word, or an expression combining them with the arithmetic operators +, -, *,
+/, and %, the concatenation operator &, and parentheses.  Arithmetic on
+anything but numbers, including empty fields, or division by zero gives an
+empty value.  The value of the column is the VALUE for the first COND which
+is true, or DEFAULT if none are.  If no DEFAULT is given, the column is empty
+if no COND is true. */
+func ParseComputed(spec string) (*Computed, error) {
+	name, def, ok := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !ok || "" == name {
+		return nil, fmt.Errorf("missing NAME=")
+	}
+	c := &Computed{Name: name}
+	def = strings.TrimSpace(def)
+
+	/* Not a case, just a value */
+	if !strings.HasPrefix(def, "case(") {
+		v, err := parseComputedValue(def)
+		if nil != err {
+			return nil, err
+		}
+		c.def = v
+		return c, nil
+	}
+	if !strings.HasSuffix(def, ")") {
+		return nil, fmt.Errorf("missing ) after case(")
+	}
+	args, err := splitTopLevel(def[len("case(") : len(def)-1])
+	if nil != err {
+		return nil, err
+	}
+	for i, a := range args {
+		cc, ok := parseComputedCase(a)
+		switch {
+		case ok:
+			c.cases = append(c.cases, cc)
+		case len(args)-1 != i:
+			return nil, fmt.Errorf("expected COND:VALUE, not %q",
+				strings.TrimSpace(a))
+		default: /* The default value */
+			if c.def, err = parseComputedValue(a); nil != err {
+				return nil, err
+			}
+		}
+	}
+	if 0 == len(c.cases) {
+		return nil, fmt.Errorf("case() needs at least one COND:VALUE")
+	}
+	return c, nil
+}
+
+/* parseComputedCase parses a COND:VALUE.  As conditions may contain colons,
+each colon is tried in turn until one is found which separates a valid
+condition from a valid value. */
+func parseComputedCase(s string) (computedCase, bool) {
+	for i := range s {
+		if ':' != s[i] || inQuotes(s[:i]) {
+			continue
+		}
+		v, err := parseComputedValue(s[i+1:])
+		if nil != err {
+			continue
+		}
+		e, err := parseExpr(s[:i])
+		if nil != err {
+			continue
+		}
+		return computedCase{cond: e, value: v}, true
+	}
+	return computedCase{}, false
+}
+
+/* parseComputedValue parses a double-quoted string, column, or bare word, or
+an expression made of them */
+func parseComputedValue(s string) (valueExpr, error) {
+	ts, err := tokenizeValue(s)
+	if nil != err {
+		return nil, err
+	}
+	if 0 == len(ts) {
+		return nil, fmt.Errorf("empty value")
+	}
+	p := &valueParser{ts: ts}
+	v, err := p.concat()
+	if nil != err {
+		return nil, err
+	}
+	if 0 != len(p.ts) {
+		return nil, fmt.Errorf("unexpected %q", p.ts[0].s)
+	}
+	return v, nil
+}
+
+/* inQuotes returns true if s ends inside a double-quoted string */
+func inQuotes(s string) bool {
+	q := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case q && '\\' == s[i]:
+			i++
+		case '"' == s[i]:
+			q = !q
+		}
+	}
+	return q
+}
+
+/* splitTopLevel splits s on the commas which aren't in double quotes or
+parentheses. */
+func splitTopLevel(s string) ([]string, error) {
+	var (
+		parts []string
+		depth int
+		start int
+		q     bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case q && '\\' == s[i]:
+			i++
+		case '"' == s[i]:
+			q = !q
+		case q:
+		case '(' == s[i]:
+			depth++
+		case ')' == s[i]:
+			if depth--; 0 > depth {
+				return nil, fmt.Errorf("unbalanced )")
+			}
+		case ',' == s[i] && 0 == depth:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if 0 != depth || q {
+		return nil, fmt.Errorf("unbalanced ( or \"")
+	}
+	return append(parts, s[start:]), nil
+}
+
+/* Value returns the value of the column for record */
+func (c *Computed) Value(record []string) string {
+	for _, cc := range c.cases {
+		if cc.cond.matches(record) {
+			return cc.value.eval(record)
+		}
+	}
+	if nil == c.def {
+		return ""
+	}
+	return c.def.eval(record)
+}
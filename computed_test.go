@@ -0,0 +1,130 @@
+/*
+ * computed_test.go
+ * Tests for computed.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"strings"
+	"testing"
+)
+
+/* computedTest is a computed column and what it should give for records */
+type computedTest struct {
+	spec string
+	name string
+	want map[string]string /* Comma-separated record -> value */
+}
+
+/* runComputedTests checks that each of the specs in tests is parsed and
+gives the wanted values. */
+func runComputedTests(t *testing.T, tests []computedTest) {
+	for _, c := range tests {
+		cc, err := ParseComputed(c.spec)
+		if nil != err {
+			t.Errorf("ParseComputed(%q): %v", c.spec, err)
+			continue
+		}
+		if c.name != cc.Name {
+			t.Errorf(
+				"%q: name %q, want %q",
+				c.spec,
+				cc.Name,
+				c.name,
+			)
+		}
+		for rec, want := range c.want {
+			got := cc.Value(strings.Split(rec, ","))
+			if got != want {
+				t.Errorf(
+					"%q: record %q: got %q, want %q",
+					c.spec,
+					rec,
+					got,
+					want,
+				)
+			}
+		}
+	}
+}
+
+func TestComputedCase(t *testing.T) {
+	runComputedTests(t, []computedTest{{
+		spec: `tier=case(c5>1000:"gold", c5>100:"silver", bronze)`,
+		name: "tier",
+		want: map[string]string{
+			"a,b,c,d,5000": "gold",
+			"a,b,c,d,1001": "gold",
+			"a,b,c,d,1000": "silver",
+			"a,b,c,d,101":  "silver",
+			"a,b,c,d,100":  "bronze",
+			"a,b,c,d,99":   "bronze",
+			"a":            "bronze",
+		},
+	}, {
+		spec: `flag = case(c1 == "a:b": yes)`,
+		name: "flag",
+		want: map[string]string{
+			"a:b": "yes",
+			"a":   "",
+		},
+	}, {
+		spec: `pick=case(c1 == x && c2 != "": c2, c3)`,
+		name: "pick",
+		want: map[string]string{
+			"x,y,z": "y",
+			"x,,z":  "z",
+			"w,y,z": "z",
+		},
+	}, {
+		spec: `q=case(c1 == "a,b": "has, comma", "(none)")`,
+		name: "q",
+		want: map[string]string{
+			"a": "(none)",
+		},
+	}, {
+		spec: `const="fixed"`,
+		name: "const",
+		want: map[string]string{"a,b": "fixed", "": "fixed"},
+	}, {
+		spec: "copy=c2",
+		name: "copy",
+		want: map[string]string{"a,b": "b", "a": ""},
+	}})
+}
+
+func TestComputedErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"nope",
+		`=case(c1>1:"a")`,
+		`x=case(c1>1:"a"`,
+		`x=case("a")`,
+		`x=case()`,
+		`x=case(c1>1:"a", nope, "b")`,
+		`x=case(c1>1:"a))`,
+		`x=case(c1>1:(a)`,
+	} {
+		if _, err := ParseComputed(s); nil == err {
+			t.Errorf("ParseComputed(%q) didn't fail", s)
+		}
+	}
+}
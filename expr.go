@@ -0,0 +1,451 @@
+/*
+ * expr.go
+ * Boolean expressions for -where
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/* expr is a boolean expression evaluated against a record, such as a
+condition or conditions joined with && and || */
+type expr interface {
+	matches(record []string) bool
+}
+
+/* andExpr is true if all of its expressions are true */
+type andExpr []expr
+
+/* matches returns true if record satisfies all of a's expressions */
+func (a andExpr) matches(record []string) bool {
+	for _, e := range a {
+		if !e.matches(record) {
+			return false
+		}
+	}
+	return true
+}
+
+/* orExpr is true if any of its expressions are true */
+type orExpr []expr
+
+/* matches returns true if record satisfies any of o's expressions */
+func (o orExpr) matches(record []string) bool {
+	for _, e := range o {
+		if e.matches(record) {
+			return true
+		}
+	}
+	return false
+}
+
+/* notExpr is true if its expression is false */
+type notExpr struct{ e expr }
+
+/* matches returns true if record doesn't satisfy n's expression */
+func (n notExpr) matches(record []string) bool {
+	return !n.e.matches(record)
+}
+
+/* exprToken is a single token of an expression */
+type exprToken struct {
+	s      string /* Unquoted, for strings */
+	quoted bool   /* Token was a double-quoted string */
+}
+
+/* exprPuncts are the operators and punctuation which may appear in an
+expression, longest first */
+var exprPuncts = []string{
+	"&&", "||", "==", "!=", "<=", ">=", "=", "<", ">", "!", "(", ")", ",",
+}
+
+/* tokenizeExpr splits s into tokens.  Tokens are double-quoted strings,
+operators and punctuation, and runs of anything else not containing
+whitespace. */
+func tokenizeExpr(s string) ([]exprToken, error) {
+	var ts []exprToken
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if "" == s {
+			return ts, nil
+		}
+		/* Quoted string */
+		if '"' == s[0] {
+			q, err := strconv.QuotedPrefix(s)
+			if nil != err {
+				return nil, fmt.Errorf("invalid quoted string "+
+					"at %q", s)
+			}
+			v, err := strconv.Unquote(q)
+			if nil != err {
+				return nil, fmt.Errorf("invalid quoted "+
+					"string %s: %v", q, err)
+			}
+			ts = append(ts, exprToken{s: v, quoted: true})
+			s = s[len(q):]
+			continue
+		}
+		/* Operators and such */
+		p := ""
+		for _, o := range exprPuncts {
+			if strings.HasPrefix(s, o) {
+				p = o
+				break
+			}
+		}
+		if "" != p {
+			ts = append(ts, exprToken{s: p})
+			s = s[len(p):]
+			continue
+		}
+		/* Anything else */
+		n := strings.IndexAny(s, " \t\"&|=!<>(),")
+		switch n {
+		case -1:
+			n = len(s)
+		case 0: /* A lone & or | */
+			return nil, fmt.Errorf("unexpected %q", s[:1])
+		}
+		ts = append(ts, exprToken{s: s[:n]})
+		s = s[n:]
+	}
+}
+
+/* exprParser parses a list of tokens into an expr.  Its grammar is
+
+	or      = and { "||" and }
+	and     = unary { "&&" unary }
+	unary   = "!" unary | "(" or ")" | valid | test
+	valid   = ( "luhn" | "iban" | "uuid" | "email" ) "(" column ")"
+	test    = operand ( OP operand | "is" [ "not" ] "empty" |
+		  [ "not" ] "in" "(" value { "," value } ")" )
+	operand = column | "len" "(" column ")" | value
+
+One side of an OP must be a column or len(column). */
+type exprParser struct {
+	ts   []exprToken
+	spec string
+}
+
+/* parseExpr parses s into an expr */
+func parseExpr(s string) (expr, error) {
+	ts, err := tokenizeExpr(s)
+	if nil != err {
+		return nil, err
+	}
+	if 0 == len(ts) {
+		return nil, fmt.Errorf("empty expression")
+	}
+	p := &exprParser{ts: ts, spec: s}
+	e, err := p.or()
+	if nil != err {
+		return nil, err
+	}
+	if 0 != len(p.ts) {
+		return nil, fmt.Errorf("unexpected %q", p.ts[0].s)
+	}
+	return e, nil
+}
+
+/* peek returns true if the next token is the unquoted string s */
+func (p *exprParser) peek(s string) bool {
+	return 0 != len(p.ts) && !p.ts[0].quoted && s == p.ts[0].s
+}
+
+/* next removes and returns the next token */
+func (p *exprParser) next() (exprToken, error) {
+	if 0 == len(p.ts) {
+		return exprToken{}, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.ts[0]
+	p.ts = p.ts[1:]
+	return t, nil
+}
+
+/* expect removes the next token, which must be the unquoted string s */
+func (p *exprParser) expect(s string) error {
+	if !p.peek(s) {
+		if 0 == len(p.ts) {
+			return fmt.Errorf("expected %q at end of expression", s)
+		}
+		return fmt.Errorf("expected %q, not %q", s, p.ts[0].s)
+	}
+	p.ts = p.ts[1:]
+	return nil
+}
+
+/* or parses expressions joined with || */
+func (p *exprParser) or() (expr, error) {
+	var o orExpr
+	for {
+		e, err := p.and()
+		if nil != err {
+			return nil, err
+		}
+		o = append(o, e)
+		if !p.peek("||") {
+			break
+		}
+		p.ts = p.ts[1:]
+	}
+	if 1 == len(o) {
+		return o[0], nil
+	}
+	return o, nil
+}
+
+/* and parses expressions joined with && */
+func (p *exprParser) and() (expr, error) {
+	var a andExpr
+	for {
+		e, err := p.unary()
+		if nil != err {
+			return nil, err
+		}
+		a = append(a, e)
+		if !p.peek("&&") {
+			break
+		}
+		p.ts = p.ts[1:]
+	}
+	if 1 == len(a) {
+		return a[0], nil
+	}
+	return a, nil
+}
+
+/* unary parses a negated expression, an expression in parenthesis, a
+validity check, or a single test */
+func (p *exprParser) unary() (expr, error) {
+	switch {
+	case p.peek("!"):
+		p.ts = p.ts[1:]
+		e, err := p.unary()
+		if nil != err {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	case p.peek("("):
+		p.ts = p.ts[1:]
+		e, err := p.or()
+		if nil != err {
+			return nil, err
+		}
+		if err := p.expect(")"); nil != err {
+			return nil, err
+		}
+		return e, nil
+	}
+	if 1 < len(p.ts) && !p.ts[0].quoted && !p.ts[1].quoted &&
+		"(" == p.ts[1].s {
+		if v, ok := validators[p.ts[0].s]; ok {
+			return p.valid(v)
+		}
+	}
+	return p.test()
+}
+
+/* valid parses a validity check of the form NAME(column), which is true if
+the column's value passes the check v. */
+func (p *exprParser) valid(v func(string) bool) (expr, error) {
+	name := p.ts[0].s
+	p.ts = p.ts[2:]
+	t, err := p.next()
+	if nil != err {
+		return nil, err
+	}
+	c := condition{spec: p.spec, op: "valid", valid: v}
+	if c.col, err = exprColumn(t); nil != err {
+		return nil, fmt.Errorf("%s(%s): %v", name, t.s, err)
+	}
+	if err := p.expect(")"); nil != err {
+		return nil, err
+	}
+	return c, nil
+}
+
+/* operand parses a column, len(column), or value.  If the operand is a value,
+col will be 0. */
+func (p *exprParser) operand() (col int, isLen bool, value string, err error) {
+	t, err := p.next()
+	if nil != err {
+		return 0, false, "", err
+	}
+	if t.quoted {
+		return 0, false, t.s, nil
+	}
+	for _, o := range exprPuncts {
+		if o == t.s {
+			return 0, false, "", fmt.Errorf("unexpected %q", t.s)
+		}
+	}
+	/* Length of a field */
+	if "len" == t.s && p.peek("(") {
+		p.ts = p.ts[1:]
+		c, err := p.next()
+		if nil != err {
+			return 0, false, "", err
+		}
+		if col, err = exprColumn(c); nil != err {
+			return 0, false, "", fmt.Errorf("len(%s): %v", c.s, err)
+		}
+		if err := p.expect(")"); nil != err {
+			return 0, false, "", err
+		}
+		return col, true, "", nil
+	}
+	/* A column or a bare value */
+	if col, err := exprColumn(t); nil == err {
+		return col, false, "", nil
+	}
+	return 0, false, t.s, nil
+}
+
+/* exprColumn returns the column number in t, which must be nothing but a
+column */
+func exprColumn(t exprToken) (int, error) {
+	if t.quoted {
+		return 0, fmt.Errorf("%q is not a column", t.s)
+	}
+	c, rest, err := parseColumn(t.s)
+	if nil != err {
+		return 0, err
+	}
+	if "" != rest {
+		return 0, fmt.Errorf("%q is not a column", t.s)
+	}
+	return c, nil
+}
+
+/* flipped maps comparison operators to their equivalents with the operands
+swapped */
+var flipped = map[string]string{
+	"==": "==",
+	"!=": "!=",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+/* test parses a single comparison, emptiness check, or set membership
+test */
+func (p *exprParser) test() (expr, error) {
+	c := condition{spec: p.spec}
+	var (
+		value string
+		err   error
+	)
+	c.col, c.len, value, err = p.operand()
+	if nil != err {
+		return nil, err
+	}
+	op, err := p.next()
+	if nil != err {
+		return nil, err
+	}
+	if op.quoted {
+		return nil, fmt.Errorf("expected an operator, not %q", op.s)
+	}
+	/* Only comparisons may have a value on the left */
+	if _, ok := flipped[op.s]; !ok && "=" != op.s && 0 == c.col {
+		return nil, fmt.Errorf("%q is not a column", value)
+	}
+	switch op.s {
+	case "is":
+		c.op = "is empty"
+		if p.peek("not") {
+			p.ts = p.ts[1:]
+			c.op = "is not empty"
+		}
+		if err := p.expect("empty"); nil != err {
+			return nil, err
+		}
+		return c, nil
+	case "not":
+		if err := p.expect("in"); nil != err {
+			return nil, err
+		}
+		c.op = "not in"
+		return c, p.set(&c)
+	case "in":
+		c.op = "in"
+		return c, p.set(&c)
+	case "=":
+		op.s = "=="
+	}
+	if _, ok := flipped[op.s]; !ok {
+		return nil, fmt.Errorf("unknown operator %q", op.s)
+	}
+	c.op = op.s
+
+	/* Other side of the comparison */
+	ocol, olen, ovalue, err := p.operand()
+	if nil != err {
+		return nil, err
+	}
+	switch {
+	case olen && 0 != c.col:
+		return nil, fmt.Errorf("len() may only be compared to a " +
+			"column or value on its right")
+	case olen || (0 == c.col && 0 != ocol):
+		/* value OP column, written backwards */
+		c.col, c.len, c.value = ocol, olen, value
+		c.op = flipped[c.op]
+	case 0 == c.col:
+		return nil, fmt.Errorf("%q and %q are not columns", value,
+			ovalue)
+	case 0 != ocol:
+		c.other = ocol
+	default:
+		c.value = ovalue
+	}
+	return c, nil
+}
+
+/* set parses the parenthesized, comma-separated list of values after in or
+not in into c.set */
+func (p *exprParser) set(c *condition) error {
+	if err := p.expect("("); nil != err {
+		return err
+	}
+	c.set = make(map[string]bool)
+	for {
+		t, err := p.next()
+		if nil != err {
+			return err
+		}
+		if !t.quoted && (")" == t.s || "," == t.s) {
+			return fmt.Errorf("expected a value, not %q", t.s)
+		}
+		c.set[t.s] = true
+		if p.peek(")") {
+			p.ts = p.ts[1:]
+			return nil
+		}
+		if err := p.expect(","); nil != err {
+			return err
+		}
+	}
+}
@@ -0,0 +1,102 @@
+/*
+ * expr_test.go
+ * Tests for expr.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import "testing"
+
+func TestParseExpr(t *testing.T) {
+	for _, c := range []struct {
+		expr        string
+		match, miss []string
+	}{{
+		expr:  `col3 == "ERROR"`,
+		match: []string{"a,b,ERROR"},
+		miss:  []string{"a,b,error", "a,b,ERROR "},
+	}, {
+		expr:  `col7 > 100 && col2 != ""`,
+		match: []string{"1,x,3,4,5,6,101"},
+		miss:  []string{"1,,3,4,5,6,101", "1,x,3,4,5,6,99"},
+	}, {
+		expr:  "c1 == a || c1 == b && c2 == x",
+		match: []string{"a,y", "b,x"},
+		miss:  []string{"b,y", "c,x"},
+	}, {
+		expr:  "(c1 == a || c1 == b) && c2 == x",
+		match: []string{"a,x", "b,x"},
+		miss:  []string{"a,y", "c,x"},
+	}, {
+		expr:  "!(c1 == a) && !c2 is empty",
+		match: []string{"b,x"},
+		miss:  []string{"a,x", "b,"},
+	}, {
+		expr:  "c1 in (red, \"a b\", blue)",
+		match: []string{"red", "a b"},
+		miss:  []string{"green", "a"},
+	}, {
+		expr:  "c1 not in (red)",
+		match: []string{"blue"},
+		miss:  []string{"red"},
+	}, {
+		expr:  "100 < c1",
+		match: []string{"101"},
+		miss:  []string{"100", "99"},
+	}, {
+		expr:  "c1 = 9.0",
+		match: []string{"9", " 9.00"},
+		miss:  []string{"9.1"},
+	}, {
+		expr:  `c1 == "quoted \"string\""`,
+		match: []string{`quoted "string"`},
+		miss:  []string{"quoted"},
+	}} {
+		c := c
+		t.Run(c.expr, func(t *testing.T) {
+			var w Where
+			if err := w.AddAll(c.expr); nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			checkMatches(t, w, c.match, c.miss)
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"c1 ==",
+		"c1 == a &&",
+		"(c1 == a",
+		"c1 == a)",
+		"c1 & c2",
+		"c1 ~ a",
+		`c1 == "unterminated`,
+		"a == b",
+		"c1 is full",
+		"c1 in red",
+		"c1 == a c2 == b",
+	} {
+		if _, err := parseExpr(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * golden.go
+ * Helpers for golden tests
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+/* SelectBytes is like Process, but reads CSV from in and returns the CSV it
+would have written.  The CSV is read and written using s.Comma, or a comma
+if s.Comma is 0.  This is handy for golden tests, along with CheckGolden. */
+func (s *Selector) SelectBytes(in []byte) ([]byte, error) {
+	var out bytes.Buffer
+	comma := s.Comma
+	if 0 == comma {
+		comma = ','
+	}
+	r := csv.NewReader(bytes.NewReader(in))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	w := csv.NewWriter(&out)
+	w.Comma = comma
+	err := s.Process(r, w)
+	return out.Bytes(), err
+}
+
+/* CompareCSV compares two CSV documents record by record, so differences in
+quoting and line endings don't matter.  It returns nil if they have the same
+records, or an error describing the first difference.  A comma of 0 means
+a comma. */
+func CompareCSV(got, want []byte, comma rune) error {
+	if 0 == comma {
+		comma = ','
+	}
+	gr := csv.NewReader(bytes.NewReader(got))
+	wr := csv.NewReader(bytes.NewReader(want))
+	for _, r := range []*csv.Reader{gr, wr} {
+		r.Comma = comma
+		r.FieldsPerRecord = -1
+	}
+	for n := 1; ; n++ {
+		g, gerr := gr.Read()
+		w, werr := wr.Read()
+		switch {
+		case errors.Is(gerr, io.EOF) && errors.Is(werr, io.EOF):
+			return nil
+		case nil != gerr && !errors.Is(gerr, io.EOF):
+			return fmt.Errorf("record %d: reading got: %w", n, gerr)
+		case nil != werr && !errors.Is(werr, io.EOF):
+			return fmt.Errorf("record %d: reading want: %w",
+				n, werr)
+		case errors.Is(gerr, io.EOF):
+			return fmt.Errorf("record %d: missing, want %q", n, w)
+		case errors.Is(werr, io.EOF):
+			return fmt.Errorf("record %d: unexpected %q", n, g)
+		case len(g) != len(w):
+			return fmt.Errorf("record %d: got %d fields %q, "+
+				"want %d fields %q", n, len(g), g, len(w), w)
+		}
+		for i := range g {
+			if g[i] != w[i] {
+				return fmt.Errorf("record %d, field %d: "+
+					"got %q, want %q", n, i+1, g[i], w[i])
+			}
+		}
+	}
+}
+
+/* CheckGolden compares got with the CSV in the golden file, as with
+CompareCSV.  If update is true, got is written to the file instead, which
+should then be checked by hand.  A typical test calls CheckGolden with update
+set by a command-line flag. */
+func CheckGolden(file string, got []byte, comma rune, update bool) error {
+	if update {
+		return os.WriteFile(file, got, 0644)
+	}
+	want, err := os.ReadFile(file)
+	if nil != err {
+		return err
+	}
+	if err := CompareCSV(got, want, comma); nil != err {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+ * golden_test.go
+ * Tests for golden.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/* update makes TestGolden rewrite its golden files */
+var update = flag.Bool("update", false, "Update golden files")
+
+/* goldenInput is the CSV from which the golden tests select */
+const goldenInput = "name,city,age\n" +
+	"alice,\"Oslo, NO\",31\n" +
+	"bob,Lima,27\n" +
+	"carol,\"Line\nBreak\",45\n"
+
+func TestGolden(t *testing.T) {
+	s, err := NewSelector("2-", "3,1")
+	if nil != err {
+		t.Fatalf("NewSelector: %v", err)
+	}
+	s.Ordered = true
+	got, err := s.SelectBytes([]byte(goldenInput))
+	if nil != err {
+		t.Fatalf("SelectBytes: %v", err)
+	}
+	if err := CheckGolden(
+		filepath.Join("testdata", "select.golden"),
+		got,
+		s.Comma,
+		*update,
+	); nil != err {
+		t.Errorf("CheckGolden: %v", err)
+	}
+}
+
+func TestCheckGoldenUpdate(t *testing.T) {
+	s, err := NewSelector("1,3", "2")
+	if nil != err {
+		t.Fatalf("NewSelector: %v", err)
+	}
+	got, err := s.SelectBytes([]byte(goldenInput))
+	if nil != err {
+		t.Fatalf("SelectBytes: %v", err)
+	}
+
+	/* Updating writes the golden file */
+	fn := filepath.Join(t.TempDir(), "update.golden")
+	if err := CheckGolden(fn, got, ',', true); nil != err {
+		t.Fatalf("Updating: %v", err)
+	}
+	b, err := os.ReadFile(fn)
+	if nil != err {
+		t.Fatalf("Reading updated file: %v", err)
+	}
+	if want := "city\nLima\n"; want != string(b) {
+		t.Errorf("Updated file incorrect:\ngot: %q\nwant: %q", b, want)
+	}
+
+	/* After which it matches, even requoted */
+	if err := CheckGolden(fn, []byte("\"city\"\r\nLima\r\n"), ',',
+		false); nil != err {
+		t.Errorf("Requoted output didn't match: %v", err)
+	}
+	/* And differences are caught */
+	if err := CheckGolden(fn, []byte("city\nLagos\n"), ',',
+		false); nil == err {
+		t.Errorf("Different output matched")
+	}
+	/* As is a missing file */
+	if err := CheckGolden(fn+".missing", got, ',', false); nil == err {
+		t.Errorf("Missing golden file didn't cause an error")
+	}
+}
+
+func TestSelectBytesZero(t *testing.T) {
+	var s Selector
+	got, err := s.SelectBytes([]byte(goldenInput))
+	if nil != err {
+		t.Fatalf("SelectBytes: %v", err)
+	}
+	if err := CompareCSV(
+		got,
+		[]byte(goldenInput),
+		s.Comma,
+	); nil != err {
+		t.Errorf("CompareCSV: %v", err)
+	}
+}
+
+func TestCompareCSV(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		got, want string
+		ok        bool
+	}{
+		{"same", "a,b\nc,d\n", "a,b\nc,d\n", true},
+		{"quoting", "\"a\",b\r\n", "a,\"b\"\n", true},
+		{"field", "a,b\n", "a,c\n", false},
+		{"fields", "a,b\n", "a,b,c\n", false},
+		{"missing", "a\n", "a\nb\n", false},
+		{"extra", "a\nb\n", "a\n", false},
+		{"bad_got", "\"a\n", "a\n", false},
+		{"bad_want", "a\n", "a\"\n", false},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			err := CompareCSV([]byte(c.got), []byte(c.want), ',')
+			if c.ok && nil != err {
+				t.Errorf("Unexpected error: %v", err)
+			} else if !c.ok && nil == err {
+				t.Errorf("Difference not found")
+			}
+		})
+	}
+}
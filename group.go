@@ -0,0 +1,64 @@
+/*
+ * group.go
+ * Grouping rows by the values of key columns
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* ParseGroupKey parses a comma-separated list of columns, each of the form
+col:N or cN, which together make up a key used to group rows.  The returned
+column numbers are 1-indexed. */
+func ParseGroupKey(s string) ([]int, error) {
+	var cols []int
+	for _, p := range strings.Split(s, ",") {
+		c, rest, err := parseColumn(p)
+		if nil != err {
+			return nil, fmt.Errorf("%q: %v", p, err)
+		}
+		if "" != rest {
+			return nil, fmt.Errorf("%q: unexpected %q", p, rest)
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+/* GroupKey returns the values of the given columns of record, joined by a
+character unlikely to appear in real data.  Missing fields are empty. */
+func GroupKey(cols []int, record []string) string {
+	if 1 == len(cols) {
+		if cols[0] <= len(record) {
+			return record[cols[0]-1]
+		}
+		return ""
+	}
+	vs := make([]string, len(cols))
+	for i, c := range cols {
+		if c <= len(record) {
+			vs[i] = record[c-1]
+		}
+	}
+	return strings.Join(vs, "\x00")
+}
@@ -0,0 +1,126 @@
+/*
+ * process.go
+ * Process untrusted CSV in memory
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+)
+
+/* Default limits for ProcessBytes */
+const (
+	DefaultMaxFields = 10000
+	DefaultMaxOutput = 64 << 20
+)
+
+/* ErrLimit is returned by ProcessBytes, wrapped, when input or output
+exceeds a limit. */
+var ErrLimit = errors.New("limit exceeded")
+
+/* Options configures ProcessBytes. */
+type Options struct {
+	Rows    RowSpec
+	Cols    ColSpec
+	Where   string /* Conditions, as for Where.AddAll, may be empty */
+	Ordered bool   /* Output columns in the order given */
+	Comma   rune   /* Field delimiter, ',' if 0 */
+
+	/* Limits.  0 means the default. */
+	MaxFields int /* Fields per record, DefaultMaxFields */
+	MaxOutput int /* Bytes of output, DefaultMaxOutput */
+}
+
+/* ProcessBytes selects rows and columns from the CSV in in as configured by
+opts and returns the selected rows as CSV.  It's meant for untrusted input:
+malformed CSV and invalid options give an error, as does a record with more
+than opts.MaxFields fields or output larger than opts.MaxOutput bytes, and
+ProcessBytes never panics.  Memory use is bounded by a small multiple of the
+sizes of in and the output.  Output written before an error is returned along
+with the error. */
+func ProcessBytes(in []byte, opts Options) ([]byte, error) {
+	/* Work out what to select */
+	if 0 == opts.Comma {
+		opts.Comma = ','
+	}
+	if 0 == opts.MaxFields {
+		opts.MaxFields = DefaultMaxFields
+	}
+	if 0 == opts.MaxOutput {
+		opts.MaxOutput = DefaultMaxOutput
+	}
+	if 0 > opts.MaxFields || 0 > opts.MaxOutput {
+		return nil, fmt.Errorf("negative limit")
+	}
+	s, err := NewSelector(opts.Rows, opts.Cols)
+	if nil != err {
+		return nil, err
+	}
+	s.Ordered = opts.Ordered
+	s.Comma = opts.Comma
+	if "" != opts.Where {
+		if err := s.Where.AddAll(opts.Where); nil != err {
+			return nil, fmt.Errorf("where: %w", err)
+		}
+	}
+
+	/* Select from the input */
+	r := csv.NewReader(bytes.NewReader(in))
+	r.Comma = opts.Comma
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+	read := func() ([]string, error) {
+		rec, err := r.Read()
+		if nil == err && len(rec) > opts.MaxFields {
+			line, _ := r.FieldPos(0)
+			return nil, fmt.Errorf(
+				"line %d: %w: more than %d fields",
+				line,
+				ErrLimit,
+				opts.MaxFields,
+			)
+		}
+		return rec, err
+	}
+	buf := limitedBuffer{max: opts.MaxOutput}
+	w := csv.NewWriter(&buf)
+	w.Comma = opts.Comma
+	err = s.process(read, r.ReuseRecord, w)
+	return buf.Bytes(), err
+}
+
+/* limitedBuffer is a bytes.Buffer which refuses to grow past max bytes */
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+/* Write writes p to the buffer if it fits */
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		return 0, fmt.Errorf("%w: more than %d bytes of output",
+			ErrLimit, b.max)
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,147 @@
+/*
+ * process_test.go
+ * Tests for process.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProcessBytes(t *testing.T) {
+	in := []byte("a,b,c\nd,e,f\ng,h,i\n")
+	for _, c := range []struct {
+		name string
+		opts Options
+		want string
+		err  bool
+	}{{
+		name: "all",
+		want: "a,b,c\nd,e,f\ng,h,i\n",
+	}, {
+		name: "rows_cols",
+		opts: Options{Rows: "2-3", Cols: "1,3"},
+		want: "d,f\ng,i\n",
+	}, {
+		name: "ordered",
+		opts: Options{Rows: "1", Cols: "3,1", Ordered: true},
+		want: "c,a\n",
+	}, {
+		name: "where",
+		opts: Options{Where: `c2 == "e"`},
+		want: "d,e,f\n",
+	}, {
+		name: "bad_rows",
+		opts: Options{Rows: "x"},
+		err:  true,
+	}, {
+		name: "bad_where",
+		opts: Options{Where: "("},
+		err:  true,
+	}, {
+		name: "negative_limit",
+		opts: Options{MaxOutput: -1},
+		err:  true,
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ProcessBytes(in, c.opts)
+			if c.err {
+				if nil == err {
+					t.Errorf("No error, got %q", got)
+				}
+				return
+			}
+			if nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			if string(got) != c.want {
+				t.Errorf("got: %q\nwant: %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestProcessBytesLimits(t *testing.T) {
+	if _, err := ProcessBytes(
+		[]byte("a,b,c\n"),
+		Options{MaxFields: 2},
+	); !errors.Is(err, ErrLimit) {
+		t.Errorf("Too many fields gave %v", err)
+	}
+	out, err := ProcessBytes(
+		[]byte(strings.Repeat("abc\n", 10)),
+		Options{MaxOutput: 10},
+	)
+	if !errors.Is(err, ErrLimit) {
+		t.Errorf("Too much output gave %v", err)
+	}
+	if 10 < len(out) {
+		t.Errorf("Got %d bytes of output, more than 10", len(out))
+	}
+}
+
+func FuzzProcessBytes(f *testing.F) {
+	for _, s := range []struct {
+		in, rows, cols, where string
+	}{
+		{"a,b,c\nd,e,f\n", "", "", ""},
+		{"a,b,c\nd,e,f\n", "2", "3,1", ""},
+		{"a,b,c\nd,e,f\n", "-1", "-2-", "c1 == d"},
+		{"x,1\ny,2\nz,3\n", "1-2,$", "2", "c2 > 1 || c1 < y"},
+		{"\"a\"\"b\",\"c\nd\"\n", "1", "1-2", ""},
+		{"a,\"b\n", "", "", ""},
+		{"", "", "", ""},
+		{"a;b\n", "", "", "len(c1) >= 3"},
+	} {
+		f.Add([]byte(s.in), s.rows, s.cols, s.where, false)
+		f.Add([]byte(s.in), s.rows, s.cols, s.where, true)
+	}
+	f.Fuzz(func(
+		t *testing.T,
+		in []byte,
+		rows, cols, where string,
+		ordered bool,
+	) {
+		opts := Options{
+			Rows:      RowSpec(rows),
+			Cols:      ColSpec(cols),
+			Where:     where,
+			Ordered:   ordered,
+			MaxFields: 100,
+			MaxOutput: 1 << 16,
+		}
+		out, err := ProcessBytes(in, opts)
+		if len(out) > opts.MaxOutput {
+			t.Fatalf("Output is %d bytes, more than %d",
+				len(out), opts.MaxOutput)
+		}
+		if nil != err {
+			return
+		}
+		/* Selecting everything from the output shouldn't fail */
+		if _, err := ProcessBytes(out, Options{}); nil != err {
+			t.Fatalf("Reprocessing %q: %v", out, err)
+		}
+	})
+}
@@ -0,0 +1,663 @@
+/*
+ * selection.go
+ * Pick rows and columns out of records
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/magisterquis/ranges"
+)
+
+/* Filter allows numbers, i.e. row or column numbers, allowed by in, anchors,
+fromEnd, or stepped but not by out.  The zero Filter allows all numbers. */
+type Filter struct {
+	in      ranges.Filter
+	haveIn  bool /* Ranges have been added */
+	anchors []*anchor
+	fromEnd []numRange     /* Ranges counted from the end */
+	stepped []numRange     /* Ranges with a step, counted from the start */
+	out     *ranges.Filter /* May be nil */
+	order   []numRange     /* Non-anchored ranges, in the order given */
+}
+
+/* numRange is a range of numbers.  A hi of 0 means there's no upper limit.
+If fromEnd is true, lo and hi count back from the last number, which is 1.
+If step is larger than 1, only every step'th number starting at lo is in the
+range. */
+type numRange struct {
+	lo, hi  int
+	fromEnd bool
+	step    int
+}
+
+/* resolve returns the first and last numbers in the range, given the last
+number.  A fromEnd range which starts before 1 is truncated. */
+func (r numRange) resolve(last int) (lo, hi int) {
+	if !r.fromEnd {
+		if 0 == r.hi {
+			return r.lo, last
+		}
+		return r.lo, r.hi
+	}
+	lo, hi = last-r.lo+1, last-r.hi+1
+	if 1 > lo {
+		lo = 1
+	}
+	return lo, hi
+}
+
+/* contains returns whether n is in the range, given the last number.  For
+ranges not counted from the end, last may be 0 if it's not yet known. */
+func (r numRange) contains(n, last int) bool {
+	if r.fromEnd && 0 == last {
+		return false
+	}
+	lo, hi := r.resolve(last)
+	if !r.fromEnd && 0 == r.hi {
+		hi = n
+	}
+	if n < lo || hi < n {
+		return false
+	}
+	return 1 >= r.step || 0 == (n-lo)%r.step
+}
+
+/* NewFilter returns a Filter which allows the ranges in spec, as for Add.  An
+empty spec allows all numbers. */
+func NewFilter(spec string) (Filter, error) {
+	var f Filter
+	_, err := f.Add(spec)
+	return f, err
+}
+
+/* Add adds the ranges in spec, a comma-separated list of ranges, to those
+the filter allows.  If spec starts with a !, the ranges are instead added to
+those the filter doesn't allow.  Ranges are of the form n, m-n, -n, or n-;
+-n- and -n--m count back from the end, after:/REGEX/OFFSETS are relative to
+the first row matching REGEX, and a range may be followed by /s or :s to
+allow every s'th number.  Add returns the pieces of spec which are simple
+ranges of the form n, m-n, -n, or n-, joined with commas. */
+func (f *Filter) Add(spec string) (string, error) {
+	t := strings.TrimSpace(spec)
+	if strings.HasPrefix(t, "!") {
+		return "", f.Exclude(t[1:])
+	}
+	if "" == t {
+		return "", nil
+	}
+	if !f.haveIn {
+		f.in = ranges.New(verbose, debug)
+		f.haveIn = true
+	}
+	/* Anchored and from-the-end ranges are handled separately */
+	rest, err := f.addSpec(spec)
+	if nil != err {
+		return "", err
+	}
+	if "" == strings.TrimSpace(rest) {
+		return "", nil
+	}
+	return rest, f.in.Update(rest)
+}
+
+/* Anchored returns true if any ranges relative to a matching row have been
+added to f.  Such ranges only make sense for rows. */
+func (f *Filter) Anchored() bool {
+	return 0 != len(f.anchors)
+}
+
+/* allowsAll returns true if f allows all numbers not excluded */
+func (f *Filter) allowsAll() bool {
+	return !f.haveIn || f.in.All
+}
+
+/* addSpec parses the comma-separated pieces of spec, adds anchored ranges to
+f.anchors, ranges counted from the end to f.fromEnd, other ranges with steps
+to f.stepped, and all but anchored ranges to f.order.  It returns the pieces
+which may be passed to f.in.Update, joined with commas. */
+func (f *Filter) addSpec(spec string) (string, error) {
+	var rest []string
+	for _, p := range splitSpec(spec) {
+		t := strings.TrimSpace(p)
+		if "" == t {
+			continue
+		}
+		/* Relative to a matching row */
+		if strings.HasPrefix(t, anchorPrefix) {
+			a, err := parseAnchor(t)
+			if nil != err {
+				return "", fmt.Errorf("%v: %v", t, err)
+			}
+			debug("Anchored range %q: %#v", t, a)
+			f.anchors = append(f.anchors, a)
+			continue
+		}
+		r, err := parseNumRange(t)
+		if nil != err {
+			return "", err
+		}
+		f.order = append(f.order, r)
+		if r.fromEnd {
+			f.fromEnd = append(f.fromEnd, r)
+			continue
+		}
+		if 1 < r.step {
+			f.stepped = append(f.stepped, r)
+			continue
+		}
+		/* A step of 1 is no step at all */
+		rest = append(rest, stripStep(t))
+	}
+	return strings.Join(rest, ","), nil
+}
+
+/* Window returns the number of numbers from the end which the filter needs
+to know about, i.e. the largest N in a -N- or -N--M.  When filtering rows,
+this many rows must be read past a row before it's known whether the row is
+allowed. */
+func (f *Filter) Window() int {
+	w := 0
+	for _, r := range f.fromEnd {
+		if r.lo > w {
+			w = r.lo
+		}
+	}
+	return w
+}
+
+/* parseNumRange parses a single n, m-n, -n, n-, -, -n-, or -n--m, any of
+which may be followed by /s or :s to take every s'th number. */
+func parseNumRange(p string) (numRange, error) {
+	r := numRange{lo: 1, step: 1}
+	/* Every so many */
+	if i := strings.LastIndexAny(p, "/:"); -1 != i {
+		var err error
+		if r.step, err = strconv.Atoi(p[i+1:]); nil != err ||
+			1 > r.step {
+			return r, fmt.Errorf("invalid step %q", p[i+1:])
+		}
+		p = stripStep(p)
+	}
+	/* Counted from the end */
+	if strings.HasPrefix(p, "-") && strings.Contains(p[1:], "-") {
+		lo, hi, _ := strings.Cut(p[1:], "-")
+		r.fromEnd = true
+		r.hi = 1
+		var err error
+		if r.lo, err = strconv.Atoi(lo); nil != err || 1 > r.lo {
+			return r, fmt.Errorf("invalid number %q", lo)
+		}
+		if "" == hi {
+			return r, nil
+		}
+		if !strings.HasPrefix(hi, "-") {
+			return r, fmt.Errorf("invalid range %q", p)
+		}
+		if r.hi, err = strconv.Atoi(hi[1:]); nil != err ||
+			1 > r.hi || r.hi > r.lo {
+			return r, fmt.Errorf("invalid range %q", p)
+		}
+		return r, nil
+	}
+	/* Counted from the start */
+	lo, hi, isRange := strings.Cut(p, "-")
+	var err error
+	if "" != lo {
+		if r.lo, err = strconv.Atoi(lo); nil != err || 1 > r.lo {
+			return r, fmt.Errorf("invalid number %q", lo)
+		}
+	}
+	switch {
+	case !isRange:
+		r.hi = r.lo
+	case "" != hi:
+		if r.hi, err = strconv.Atoi(hi); nil != err || r.lo > r.hi {
+			return r, fmt.Errorf("invalid range %q", p)
+		}
+	}
+	return r, nil
+}
+
+/* stripStep returns p without the /s or :s at its end, if any */
+func stripStep(p string) string {
+	if i := strings.LastIndexAny(p, "/:"); -1 != i {
+		return p[:i]
+	}
+	return p
+}
+
+/* Exclude adds the simple ranges in spec to the numbers the filter doesn't
+allow. */
+func (f *Filter) Exclude(spec string) error {
+	if nil == f.out {
+		o := ranges.New(verbose, debug)
+		f.out = &o
+	}
+	return f.out.Update(spec)
+}
+
+/* allows returns whether the filter allows n.  If final is true, the filter
+will return the same for all numbers larger than n.  The record numbered n is
+needed for anchored ranges, and may be nil for columns; its fields are joined
+with sep for matching.  Last is the last number, used for ranges counted from
+the end, or 0 if it's not yet known. */
+func (f *Filter) allows(
+	n int,
+	last int,
+	record []string,
+	sep string,
+) (ok, final bool) {
+	a, y := true, ranges.AllMatch
+	if f.haveIn {
+		a, y = f.in.AllowsOut(n)
+	}
+	final = ranges.AllMatch == y || ranges.Above == y
+	for _, an := range f.anchors {
+		aa, af := an.allows(n, record, sep)
+		a = a || aa
+		final = final && af
+	}
+	for _, r := range f.fromEnd {
+		final = false
+		if r.contains(n, last) {
+			a = true
+		}
+	}
+	for _, r := range f.stepped {
+		if 0 == r.hi || n <= r.hi {
+			final = false
+		}
+		if r.contains(n, last) {
+			a = true
+		}
+	}
+	if nil == f.out {
+		return a, final
+	}
+	na, ny := f.out.AllowsOut(n)
+	return a && !na, final && (ranges.AllMatch == ny || ranges.Above == ny)
+}
+
+/* Selector holds the filters used to select rows and columns as well as the
+state needed to apply them to a stream of records.  The zero Selector selects
+all rows and columns.  The exported fields should be set before the Selector
+is first used. */
+type Selector struct {
+	Rows    Filter
+	Cols    Filter
+	Where   Where
+	Ordered bool /* Output columns in the order given */
+	Comma   rune /* Joins fields for anchored ranges, ',' if 0 */
+
+	names   []string         /* Column names, resolved from the first row */
+	nameREs []*regexp.Regexp /* Column name regexes, likewise */
+
+	firstPer []int           /* Key columns for SetFirstPerGroup */
+	seen     map[string]bool /* Keys seen for SetFirstPerGroup */
+
+	header    []string /* First row, if it's a header */
+	sepHeader bool     /* Header isn't counted as a row, for -header */
+	ocols     []int    /* Input columns of the last record from columns */
+
+	row   int  /* Number of the last row passed to Select */
+	total int  /* Total number of rows, if known */
+	rdone bool /* All remaining rows are allowed by number */
+	rpast bool /* No remaining rows are allowed by number */
+	osize int  /* Size of previous output record */
+}
+
+/* SetColumnNames selects the columns with the given names, in addition to
+any selected by s.Cols.  Names are looked up in the first record passed to
+Select or SelectHeader.  A name which isn't in that record may be a range of
+the form FIRST-LAST, for the columns from the one named FIRST to the one named
+LAST; either may be omitted to mean the first or last column. */
+func (s *Selector) SetColumnNames(names []string) {
+	s.names = names
+}
+
+/* SetColumnRegexps selects the columns with names matching any of the given
+regular expressions, in addition to any selected by s.Cols or
+SetColumnNames.  Names are looked up as for SetColumnNames. */
+func (s *Selector) SetColumnRegexps(res []*regexp.Regexp) {
+	s.nameREs = res
+}
+
+/* SetFirstPerGroup causes only the first selected row with each distinct
+combination of values in the given 1-indexed columns to be selected. */
+func (s *Selector) SetFirstPerGroup(cols []int) {
+	s.firstPer = cols
+	s.seen = make(map[string]bool)
+}
+
+/* Row returns the number of the last row passed to Select */
+func (s *Selector) Row() int {
+	return s.row
+}
+
+/* SetRow sets the number of the last row passed to Select, as if n rows had
+been passed to it. */
+func (s *Selector) SetRow(n int) {
+	s.row = n
+}
+
+/* Reset prepares s for another stream of records, as if no records had been
+passed to it.  The row counter and total number of rows are cleared and
+anchored ranges look for a new matching row.  Column names, once resolved,
+and the groups seen for SetFirstPerGroup are kept. */
+func (s *Selector) Reset() {
+	s.row = 0
+	s.total = 0
+	s.rdone = false
+	s.rpast = false
+	for _, a := range s.Rows.anchors {
+		a.at = 0
+	}
+}
+
+/* SetTotal sets the total number of rows, which is needed to select rows
+counted from the end.  Until it's set, such rows won't be selected. */
+func (s *Selector) SetTotal(n int) {
+	s.total = n
+}
+
+/* Exhausted returns true if no row after the last passed to Select can be
+selected by number, e.g. if rows 5-100 are selected and row 101 has been
+passed to Select.  Filters with rows counted from the end are never
+exhausted. */
+func (s *Selector) Exhausted() bool {
+	return s.rpast
+}
+
+/* IsHeaderRow returns true if the last record passed to Select was the first
+row and column names were looked up in it. */
+func (s *Selector) IsHeaderRow() bool {
+	return 1 == s.row && nil != s.header && !s.sepHeader
+}
+
+/* Select counts record as the next row and returns the selected columns of
+record and true if the row is selected.  If the row isn't selected, Select
+returns nil and false.  An error is returned if column names were given
+which aren't in the first row. */
+func (s *Selector) Select(record []string) ([]string, bool, error) {
+	s.row++
+	/* The first row may be a header with column names */
+	if nil != s.names || nil != s.nameREs {
+		if err := s.resolveNames(record); nil != err {
+			return nil, false, err
+		}
+	}
+	/* Work out whether to ignore it */
+	if !s.rdone {
+		a, final := s.Rows.allows(
+			s.row,
+			s.total,
+			record,
+			s.sep(),
+		)
+		/* Read the next one if not allowed, noting if there's
+		no point */
+		if !a {
+			s.rpast = final
+			return nil, false, nil
+		}
+		/* Done checking lines if all are allowed or
+		if we're past the upper limit */
+		if final {
+			s.rdone = true
+		}
+	}
+	/* Make sure the contents are acceptable */
+	if !s.Where.Matches(record) {
+		return nil, false, nil
+	}
+	/* Make sure it's the first in its group */
+	if nil != s.firstPer {
+		k := GroupKey(s.firstPer, record)
+		if s.seen[k] {
+			return nil, false, nil
+		}
+		s.seen[k] = true
+	}
+	return s.Columns(record), true, nil
+}
+
+/* SelectHeader notes record as the header, looking up column names if
+needed, and returns its selected columns.  Unlike with Select, record isn't
+counted as a row. */
+func (s *Selector) SelectHeader(record []string) ([]string, error) {
+	s.sepHeader = true
+	if nil != s.names || nil != s.nameREs {
+		if err := s.resolveNames(record); nil != err {
+			return nil, err
+		}
+	} else {
+		s.header = append(s.header[:0], record...)
+	}
+	return s.Columns(record), nil
+}
+
+/* sep returns the string used to join fields for anchored ranges */
+func (s *Selector) sep() string {
+	if 0 == s.Comma {
+		return ","
+	}
+	return string(s.Comma)
+}
+
+/* Columns returns the selected columns of record, regardless of whether the
+row is selected. */
+func (s *Selector) Columns(record []string) []string {
+	/* Roll an output slice */
+	if 0 == s.osize {
+		s.osize = 1
+	}
+	orec := make([]string, 0, s.osize)
+	s.ocols = s.ocols[:0]
+	if s.Ordered {
+		return s.orderedColumns(record, orec)
+	}
+	cdone := false /* Done worrying about columns */
+	/* Add the right columns */
+	for i := 1; i <= len(record); i++ {
+		/* Work out whether to add this column */
+		if !cdone {
+			a, final := s.Cols.allows(i, len(record), nil, "")
+			if !a {
+				continue
+			}
+			/* Done checking if upper limit or
+			all allowed */
+			if final {
+				cdone = true
+			}
+		}
+		orec = append(orec, record[i-1])
+		s.ocols = append(s.ocols, i)
+	}
+	s.osize = len(orec)
+	return orec
+}
+
+/* orderedColumns appends the selected columns of record to orec in the order
+in which they were specified, for -ordered. */
+func (s *Selector) orderedColumns(record, orec []string) []string {
+	for _, r := range s.Cols.order {
+		lo, hi := r.resolve(len(record))
+		if hi > len(record) {
+			hi = len(record)
+		}
+		step := 1
+		if 1 < r.step {
+			step = r.step
+		}
+		/* lo <= i catches overflow with huge steps */
+		for i := lo; lo <= i && i <= hi; i += step {
+			if nil != s.Cols.out {
+				if no, _ := s.Cols.out.AllowsOut(i); no {
+					continue
+				}
+			}
+			orec = append(orec, record[i-1])
+			s.ocols = append(s.ocols, i)
+		}
+	}
+	/* No columns specified means all of them */
+	if s.Cols.allowsAll() {
+		for i := 1; i <= len(record); i++ {
+			if nil != s.Cols.out {
+				if no, _ := s.Cols.out.AllowsOut(i); no {
+					continue
+				}
+			}
+			orec = append(orec, record[i-1])
+			s.ocols = append(s.ocols, i)
+		}
+	}
+	s.osize = len(orec)
+	return orec
+}
+
+/* nameRange parses n as a range of column names of the form FIRST-LAST and
+returns the 1-indexed numbers of the first column named FIRST and the first
+named LAST.  Either name may be omitted to mean the first or last column.
+Names may contain hyphens; each hyphen is tried in turn until both names are
+found in header.  If no range is found, nameRange returns false. */
+func nameRange(header []string, n string) (lo, hi int, ok bool) {
+	for i := 0; i < len(n); i++ {
+		if '-' != n[i] {
+			continue
+		}
+		first, last := n[:i], n[i+1:]
+		if "" == first && "" == last {
+			return 0, 0, false
+		}
+		lo, hi = 1, len(header)
+		if "" != first {
+			lo = findColumn(header, first)
+		}
+		if "" != last {
+			hi = findColumn(header, last)
+		}
+		if 0 != lo && 0 != hi {
+			return lo, hi, true
+		}
+	}
+	return 0, 0, false
+}
+
+/* findColumn returns the 1-indexed number of the first column in header
+named n, or 0 if there's none. */
+func findColumn(header []string, n string) int {
+	for i, h := range header {
+		if n == h || n == strings.TrimSpace(h) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+/* InputColumn returns the 1-indexed input column of the ith field of the last
+record returned by Columns, Select, or SelectHeader, or 0 if there's no ith
+field. */
+func (s *Selector) InputColumn(i int) int {
+	if i >= len(s.ocols) {
+		return 0
+	}
+	return s.ocols[i]
+}
+
+/* ColumnName returns a name for the ith field of the last record returned
+by Columns, Select, or SelectHeader.  This is the field's column's name from
+the header if there is one, or cN, where N is the 1-indexed input column
+number, if not. */
+func (s *Selector) ColumnName(i int) string {
+	if i >= len(s.ocols) {
+		return "c" + strconv.Itoa(i+1)
+	}
+	c := s.ocols[i]
+	if c <= len(s.header) {
+		return s.header[c-1]
+	}
+	return "c" + strconv.Itoa(c)
+}
+
+/* resolveNames adds the columns in header named in s.names or with names
+matching s.nameREs to s.Cols.  A name or regex may match more than one
+column.  s.names and s.nameREs are set to nil after the names are resolved and
+s.header is set to a copy of header. */
+func (s *Selector) resolveNames(header []string) error {
+	names, res := s.names, s.nameREs
+	s.names, s.nameREs = nil, nil
+	s.header = append([]string{}, header...)
+	for _, n := range names {
+		found := false
+		for i, h := range header {
+			if n != h && n != strings.TrimSpace(h) {
+				continue
+			}
+			found = true
+			c := i + 1
+			debug("Column %q is column %v", n, c)
+			if _, err := s.Cols.Add(strconv.Itoa(c)); nil != err {
+				return err
+			}
+		}
+		if found {
+			continue
+		}
+		/* Maybe it's a range of names */
+		lo, hi, ok := nameRange(header, n)
+		if !ok {
+			return fmt.Errorf("no column named %q", n)
+		}
+		if lo > hi {
+			return fmt.Errorf("range %q ends before it starts", n)
+		}
+		debug("Columns %q are columns %v-%v", n, lo, hi)
+		r := fmt.Sprintf("%d-%d", lo, hi)
+		if _, err := s.Cols.Add(r); nil != err {
+			return err
+		}
+	}
+	for _, re := range res {
+		found := false
+		for i, h := range header {
+			if !re.MatchString(strings.TrimSpace(h)) {
+				continue
+			}
+			found = true
+			c := i + 1
+			debug("Column %q matches %v", h, re)
+			if _, err := s.Cols.Add(strconv.Itoa(c)); nil != err {
+				return err
+			}
+		}
+		if !found {
+			return fmt.Errorf("no column name matches %v", re)
+		}
+	}
+	return nil
+}
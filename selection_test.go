@@ -0,0 +1,207 @@
+/*
+ * selection_test.go
+ * Tests for selection.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+/* selectTest is a selection and what it should select */
+type selectTest struct {
+	name string
+	rows RowSpec
+	cols ColSpec
+	in   string
+	want string
+}
+
+/* runSelectTests runs each of tests with SelectBytes as a subtest */
+func runSelectTests(t *testing.T, tests []selectTest) {
+	t.Helper()
+	for _, c := range tests {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s, err := NewSelector(c.rows, c.cols)
+			if nil != err {
+				t.Fatalf("NewSelector: %v", err)
+			}
+			got, err := s.SelectBytes([]byte(c.in))
+			if nil != err {
+				t.Fatalf("SelectBytes: %v", err)
+			}
+			if c.want != string(got) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFromEnd(t *testing.T) {
+	five := "1\n2\n3\n4\n5\n"
+	runSelectTests(t, []selectTest{
+		{name: "last_two", rows: "-2-", in: five, want: "4\n5\n"},
+		{name: "last", rows: "-1-", in: five, want: "5\n"},
+		{name: "middle", rows: "-3--2", in: five, want: "3\n4\n"},
+		{name: "mixed", rows: "1,-1-", in: five, want: "1\n5\n"},
+		{name: "overlap", rows: "4-,-2-", in: five, want: "4\n5\n"},
+		{name: "too_many", rows: "-10-", in: five, want: five},
+		{name: "empty", rows: "-2-", in: "", want: ""},
+		{
+			name: "last_column",
+			cols: "-1-",
+			in:   "a,b,c\nd,e\n",
+			want: "c\ne\n",
+		}, {
+			name: "last_two_columns",
+			cols: "-2-",
+			in:   "a,b,c\nd\n",
+			want: "b,c\nd\n",
+		}, {
+			name: "both",
+			rows: "-1-",
+			cols: "-3--2",
+			in:   "a,b,c,d\ne,f,g,h\n",
+			want: "f,g\n",
+		},
+	})
+
+	/* Only the rows counted from the end should be held */
+	s, err := NewSelector("-2-", "")
+	if nil != err {
+		t.Fatalf("NewSelector: %v", err)
+	}
+	if w := s.Rows.Window(); 2 != w {
+		t.Errorf("Window %d, want 2", w)
+	}
+	if _, err := NewSelector("-0-", ""); nil == err ||
+		!strings.Contains(err.Error(), "rows") {
+		t.Errorf("Invalid range gave %v", err)
+	}
+}
+
+func TestSteps(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+	in := sb.String()
+	runSelectTests(t, []selectTest{
+		{name: "slash", rows: "2-30/10", in: in, want: "2\n12\n22\n"},
+		{name: "colon", rows: "25-:2", in: in, want: "25\n27\n29\n"},
+		{name: "open_start", rows: "-5/2", in: in, want: "1\n3\n5\n"},
+		{name: "one", rows: "28-/1", in: in, want: "28\n29\n30\n"},
+		{
+			name: "with_plain",
+			rows: "1-9/4,3",
+			in:   in,
+			want: "1\n3\n5\n9\n",
+		}, {
+			name: "columns",
+			cols: "1-/2",
+			in:   "a,b,c,d,e\n",
+			want: "a,c,e\n",
+		}, {
+			name: "columns_from_2",
+			cols: "2-:3",
+			in:   "a,b,c,d,e,f,g,h\n",
+			want: "b,e,h\n",
+		},
+	})
+	for _, r := range []RowSpec{"1-10/0", "1-10/x", "1-10/-2", "1-10:"} {
+		if _, err := NewSelector(r, ""); nil == err {
+			t.Errorf("Invalid step %q didn't fail", r)
+		}
+	}
+}
+
+func TestNameRange(t *testing.T) {
+	header := []string{"id", "timestamp", "a-b", " status ", "z"}
+	for _, c := range []struct {
+		n      string
+		lo, hi int
+		ok     bool
+	}{
+		{"timestamp-status", 2, 4, true},
+		{"status-timestamp", 4, 2, true},
+		{"-a-b", 1, 3, true},
+		{"a-b-", 3, 5, true},
+		{"a-b-z", 3, 5, true},
+		{"id-a-b", 1, 3, true},
+		{"-", 0, 0, false},
+		{"id", 0, 0, false},
+		{"id-nope", 0, 0, false},
+		{"nope-", 0, 0, false},
+	} {
+		lo, hi, ok := nameRange(header, c.n)
+		if c.ok != ok || c.lo != lo || c.hi != hi {
+			t.Errorf(
+				"%q: got %v, %v, %v, want %v, %v, %v",
+				c.n, lo, hi, ok, c.lo, c.hi, c.ok,
+			)
+		}
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	for _, c := range []struct {
+		rows RowSpec
+		want int /* Row after which the selector's exhausted, or 0 */
+	}{
+		{rows: "2-3", want: 4},
+		{rows: "1,3", want: 4},
+		{rows: "2-", want: 0},
+		{rows: "1,-1-", want: 0},
+		{rows: "", want: 0},
+	} {
+		s, err := NewSelector(c.rows, "")
+		if nil != err {
+			t.Fatalf("%q: NewSelector: %v", c.rows, err)
+		}
+		got := 0
+		for i := 1; i <= 10; i++ {
+			if _, _, err := s.Select([]string{"x"}); nil != err {
+				t.Fatalf("%q: Select: %v", c.rows, err)
+			}
+			if s.Exhausted() {
+				got = i
+				break
+			}
+		}
+		if got != c.want {
+			t.Errorf(
+				"%q: exhausted after row %d, want %d",
+				c.rows,
+				got,
+				c.want,
+			)
+		}
+
+		/* Resetting should start over */
+		s.Reset()
+		if s.Exhausted() {
+			t.Errorf("%q: exhausted after Reset", c.rows)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * selector.go
+ * Select rows and columns from CSV
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+/* Package csvcol selects rows and columns from CSV.  It's the engine behind
+the csvcol command, which lives in cmd/csvcol.
+
+Selection is deterministic: a Selector uses no randomness, doesn't depend on
+the order in which maps are iterated, and doesn't look at the time or the
+environment, so the same settings and input always give the same output.
+This makes it suitable for golden tests, in which output is compared to
+output saved from an earlier run; SelectBytes, CompareCSV, and CheckGolden
+help with that. */
+package csvcol
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+/* Verbose and Debug, if not nil, are called with verbose and debugging
+messages, respectively. */
+var (
+	Verbose func(f string, a ...interface{})
+	Debug   func(f string, a ...interface{})
+)
+
+/* verbose calls Verbose if it's not nil */
+func verbose(f string, a ...interface{}) {
+	if nil != Verbose {
+		Verbose(f, a...)
+	}
+}
+
+/* debug calls Debug if it's not nil */
+func debug(f string, a ...interface{}) {
+	if nil != Debug {
+		Debug(f, a...)
+	}
+}
+
+/* RowSpec is a comma-separated list of row numbers or ranges, as given to
+csvcol's -rows.  An empty RowSpec selects all rows. */
+type RowSpec string
+
+/* ColSpec is a comma-separated list of column numbers or ranges, as given to
+csvcol's -cols.  An empty ColSpec selects all columns. */
+type ColSpec string
+
+/* NewSelector returns a Selector which selects the given rows and columns.
+Further rows and columns may be added to its Rows and Cols. */
+func NewSelector(rows RowSpec, cols ColSpec) (*Selector, error) {
+	r, err := NewFilter(string(rows))
+	if nil != err {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	c, err := NewFilter(string(cols))
+	if nil != err {
+		return nil, fmt.Errorf("columns: %w", err)
+	}
+	if c.Anchored() {
+		return nil, fmt.Errorf("columns: anchored ranges may only " +
+			"be used for rows")
+	}
+	return &Selector{Rows: r, Cols: c, Comma: ','}, nil
+}
+
+/* Process reads records from r until EOF and writes the selected rows and
+columns to w, which is flushed before Process returns.  If rows are counted
+from the end, the last few rows are held in memory until r is exhausted. */
+func (s *Selector) Process(r *csv.Reader, w *csv.Writer) error {
+	return s.process(r.Read, r.ReuseRecord, w)
+}
+
+/* process is Process, but reads records with read.  If reused is true, read
+may reuse the slice it returns. */
+func (s *Selector) process(
+	read func() ([]string, error),
+	reused bool,
+	w *csv.Writer,
+) error {
+	/* write writes record, if selected */
+	write := func(record []string) error {
+		orec, ok, err := s.Select(record)
+		if nil != err || !ok {
+			return err
+		}
+		return w.Write(orec)
+	}
+
+	/* Rows counted from the end need the last few rows held back */
+	window := s.Rows.Window()
+	var held [][]string
+	for {
+		record, err := read()
+		if io.EOF == err {
+			break
+		} else if nil != err {
+			return err
+		}
+		if 0 != window {
+			if reused {
+				record = append([]string(nil), record...)
+			}
+			held = append(held, record)
+			if len(held) <= window {
+				continue
+			}
+			record, held = held[0], held[1:]
+		}
+		if err := write(record); nil != err {
+			return err
+		}
+	}
+
+	/* Now we know how many rows there are, deal with the held rows */
+	s.SetTotal(s.row + len(held))
+	for _, record := range held {
+		if err := write(record); nil != err {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
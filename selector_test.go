@@ -0,0 +1,127 @@
+/*
+ * selector_test.go
+ * Tests for selector.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestNewSelector(t *testing.T) {
+	for _, c := range []struct {
+		rows RowSpec
+		cols ColSpec
+	}{
+		{"x", ""},
+		{"", "1-x"},
+		{"", "/foo/-"},
+	} {
+		if _, err := NewSelector(c.rows, c.cols); nil == err {
+			t.Errorf(
+				"NewSelector(%q, %q) didn't fail",
+				c.rows,
+				c.cols,
+			)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	in := "a,b,c\nd,e,f\ng,h,i\nj,k,l\n"
+	for _, c := range []struct {
+		name   string
+		rows   RowSpec
+		cols   ColSpec
+		reuse  bool
+		modify func(s *Selector) error
+		want   string
+	}{{
+		name: "all",
+		want: in,
+	}, {
+		name: "rows_cols",
+		rows: "2-3",
+		cols: "1,3",
+		want: "d,f\ng,i\n",
+	}, {
+		name:  "from_end_reused",
+		rows:  "-2-",
+		cols:  "2",
+		reuse: true,
+		want:  "h\nk\n",
+	}, {
+		name: "added",
+		rows: "1",
+		cols: "2-3",
+		modify: func(s *Selector) error {
+			if _, err := s.Rows.Add("4"); nil != err {
+				return err
+			}
+			return s.Cols.Exclude("3")
+		},
+		want: "b\nk\n",
+	}, {
+		name: "where",
+		modify: func(s *Selector) error {
+			return s.Where.AddAll(`c2 != "e"`)
+		},
+		cols: "1",
+		want: "a\ng\nj\n",
+	}} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			s, err := NewSelector(c.rows, c.cols)
+			if nil != err {
+				t.Fatalf("NewSelector: %v", err)
+			}
+			if nil != c.modify {
+				if err := c.modify(s); nil != err {
+					t.Fatalf("Modifying selector: %v", err)
+				}
+			}
+			r := csv.NewReader(strings.NewReader(in))
+			r.ReuseRecord = c.reuse
+			var b bytes.Buffer
+			if err := s.Process(r, csv.NewWriter(&b)); nil != err {
+				t.Fatalf("Process: %v", err)
+			}
+			if got := b.String(); c.want != got {
+				t.Errorf("got:\n%s\nwant:\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestProcessReadError(t *testing.T) {
+	s, err := NewSelector("", "")
+	if nil != err {
+		t.Fatalf("NewSelector: %v", err)
+	}
+	r := csv.NewReader(strings.NewReader("a,b\nc\n"))
+	var b bytes.Buffer
+	if err := s.Process(r, csv.NewWriter(&b)); nil == err {
+		t.Errorf("Ragged input didn't fail")
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ * validate.go
+ * Validity checks for common identifiers
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"math/big"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+/* validators are the checks usable in conditions as NAME(col:N) */
+var validators = map[string]func(string) bool{
+	"luhn":  ValidLuhn,
+	"iban":  ValidIBAN,
+	"uuid":  ValidUUID,
+	"email": ValidEmail,
+}
+
+/* ValidLuhn returns true if s is a number which passes the Luhn check, as
+credit card numbers do.  Spaces and hyphens are ignored. */
+func ValidLuhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; 0 <= i; i-- {
+		c := s[i]
+		switch {
+		case ' ' == c, '-' == c:
+			continue
+		case '0' > c, '9' < c:
+			return false
+		}
+		d := int(c - '0')
+		if 1 == n%2 {
+			if d *= 2; 9 < d {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return 2 <= n && 0 == sum%10
+}
+
+/* ibanRE matches an IBAN, less spaces */
+var ibanRE = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+
+/* ValidIBAN returns true if s is an International Bank Account Number with a
+valid check digits.  Spaces are ignored. */
+func ValidIBAN(s string) bool {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if !ibanRE.MatchString(s) {
+		return false
+	}
+	/* Move the first four characters to the end, turn letters into
+	numbers, and check the remainder mod 97 */
+	var d strings.Builder
+	for _, r := range s[4:] + s[:4] {
+		if 'A' <= r && 'Z' >= r {
+			d.WriteString(big.NewInt(int64(r - 'A' + 10)).String())
+			continue
+		}
+		d.WriteRune(r)
+	}
+	n, ok := new(big.Int).SetString(d.String(), 10)
+	return ok && 1 == new(big.Int).Mod(n, big.NewInt(97)).Int64()
+}
+
+/* uuidRE matches a UUID */
+var uuidRE = regexp.MustCompile(
+	`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-` +
+		`[0-9a-fA-F]{12}$`,
+)
+
+/* ValidUUID returns true if s is a UUID of the form
+xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx */
+func ValidUUID(s string) bool {
+	return uuidRE.MatchString(s)
+}
+
+/* ValidEmail returns true if s is a bare email address, e.g.
+user@example.com, with a dot in the domain. */
+func ValidEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	if nil != err || a.Address != s || "" != a.Name {
+		return false
+	}
+	_, domain, _ := strings.Cut(s, "@")
+	return strings.Contains(strings.Trim(domain, "."), ".")
+}
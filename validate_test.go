@@ -0,0 +1,139 @@
+/*
+ * validate_test.go
+ * Tests for validate.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import "testing"
+
+func TestValidators(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		valid func(string) bool
+		good  []string
+		bad   []string
+	}{{
+		name:  "luhn",
+		valid: ValidLuhn,
+		good: []string{
+			"4111111111111111",
+			"4111 1111 1111 1111",
+			"5500-0000-0000-0004",
+			"79927398713",
+			"00",
+		},
+		bad: []string{
+			"4111111111111112",
+			"79927398710",
+			"0",
+			"",
+			"4111x111111111111",
+			"-",
+		},
+	}, {
+		name:  "iban",
+		valid: ValidIBAN,
+		good: []string{
+			"GB82WEST12345698765432",
+			"GB82 WEST 1234 5698 7654 32",
+			"gb82west12345698765432",
+			"DE89370400440532013000",
+		},
+		bad: []string{
+			"GB82WEST12345698765431",
+			"GB83WEST12345698765432",
+			"GB82",
+			"1282WEST12345698765432",
+			"GB82-WEST-1234-5698-7654-32",
+			"",
+		},
+	}, {
+		name:  "uuid",
+		valid: ValidUUID,
+		good: []string{
+			"123e4567-e89b-12d3-a456-426614174000",
+			"123E4567-E89B-12D3-A456-426614174000",
+		},
+		bad: []string{
+			"123e4567e89b12d3a456426614174000",
+			"123e4567-e89b-12d3-a456-42661417400",
+			"g23e4567-e89b-12d3-a456-426614174000",
+			"{123e4567-e89b-12d3-a456-426614174000}",
+		},
+	}, {
+		name:  "email",
+		valid: ValidEmail,
+		good: []string{
+			"user@example.com",
+			"a.b+c@mail.example.co.uk",
+		},
+		bad: []string{
+			"user@localhost",
+			"user@.com.",
+			"User <user@example.com>",
+			"user@@example.com",
+			"user",
+			" user@example.com",
+			"",
+		},
+	}} {
+		for _, s := range c.good {
+			if !c.valid(s) {
+				t.Errorf("%s: %q not valid", c.name, s)
+			}
+		}
+		for _, s := range c.bad {
+			if c.valid(s) {
+				t.Errorf("%s: %q valid", c.name, s)
+			}
+		}
+	}
+}
+
+func TestWhereValidators(t *testing.T) {
+	var w Where
+	if err := w.AddAll(
+		`luhn(c1) && !uuid(col:2) || email(c3)`,
+	); nil != err {
+		t.Fatalf("AddAll: %v", err)
+	}
+	checkMatches(
+		t,
+		w,
+		[]string{
+			"79927398713,x",
+			"1,x,a@example.com",
+			" 79927398713 ,x",
+		},
+		[]string{
+			"79927398710,x",
+			"79927398713,123e4567-e89b-12d3-a456-426614174000",
+			"1,x,a@localhost",
+			"",
+		},
+	)
+	for _, s := range []string{"luhn(x)", "luhn c1", "luhn(c1", "crc(c1)"} {
+		var w Where
+		if err := w.AddAll(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+/*
+ * where.go
+ * Content-based row filtering
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/* condition is a single test against one field of a record */
+type condition struct {
+	spec  string /* As given, for error messages */
+	col   int    /* 1-indexed column */
+	len   bool   /* Compare the field's length, not its value */
+	op    string
+	value string
+	other int               /* Column to compare to instead of value */
+	set   map[string]bool   /* For in and not in */
+	re    *regexp.Regexp    /* For match and not match */
+	valid func(string) bool /* For valid */
+}
+
+/* Where holds conditions on the contents of records.  A record matches if it
+satisfies every condition added with AddAll, AddIn, AddNotIn, AddMatch, and
+AddNotMatch and at least one added with AddAny, if any were.  The zero Where
+matches all records. */
+type Where struct {
+	all []expr
+	any []expr
+}
+
+/* operators which may appear in a condition, longest first so that <= isn't
+mistaken for < */
+var operators = []string{"==", "!=", "<=", ">=", "=", "<", ">"}
+
+/* AddAll adds a condition which must be true for a record to match.  The
+condition is as given to csvcol's -where, e.g. col3 > 100 && c2 != "". */
+func (w *Where) AddAll(cond string) error {
+	e, err := parseConditionExpr(cond)
+	if nil != err {
+		return err
+	}
+	w.all = append(w.all, e)
+	return nil
+}
+
+/* AddAny adds a condition of which at least one must be true for a record to
+match.  The condition is as for AddAll. */
+func (w *Where) AddAny(cond string) error {
+	e, err := parseConditionExpr(cond)
+	if nil != err {
+		return err
+	}
+	w.any = append(w.any, e)
+	return nil
+}
+
+/* AddIn adds a condition that a column's value be in a set of values, given
+as col:N=V1,V2,... */
+func (w *Where) AddIn(spec string) error {
+	return w.addCondition(parseSetCondition(spec, "in"))
+}
+
+/* AddNotIn adds a condition that a column's value not be in a set of values,
+given as for AddIn. */
+func (w *Where) AddNotIn(spec string) error {
+	return w.addCondition(parseSetCondition(spec, "not in"))
+}
+
+/* AddMatch adds a condition that a column match a regular expression, given
+as N:REGEX. */
+func (w *Where) AddMatch(spec string) error {
+	return w.addCondition(parseMatchCondition(spec, "match"))
+}
+
+/* AddNotMatch adds a condition that a column not match a regular
+expression, given as for AddMatch. */
+func (w *Where) AddNotMatch(spec string) error {
+	return w.addCondition(parseMatchCondition(spec, "not match"))
+}
+
+/* addCondition adds c to w.all, if err is nil */
+func (w *Where) addCondition(c condition, err error) error {
+	if nil != err {
+		return fmt.Errorf("%q: %v", c.spec, err)
+	}
+	w.all = append(w.all, c)
+	return nil
+}
+
+/* parseConditionExpr parses s into an expr.  If s isn't a valid expression
+but starts with col:N, it's tried as a single condition, for conditions like
+col:2 == some words which predate expressions. */
+func parseConditionExpr(s string) (expr, error) {
+	e, err := parseExpr(s)
+	if nil != err {
+		if !isLegacyCondition(s) {
+			return nil, fmt.Errorf("condition %q: %v", s, err)
+		}
+		c, cerr := parseCondition(s)
+		if nil != cerr {
+			return nil, fmt.Errorf("condition %q: %v", s, err)
+		}
+		e = c
+	}
+	debug("Condition %q: %#v", s, e)
+	return e, nil
+}
+
+/* isLegacyCondition returns true if s starts with col:N or len(col:N, as
+did conditions before expressions. */
+func isLegacyCondition(s string) bool {
+	r := strings.TrimSpace(s)
+	if strings.HasPrefix(r, "len(") {
+		r = strings.TrimSpace(r[len("len("):])
+	}
+	if !strings.HasPrefix(r, "col:") {
+		return false
+	}
+	r = r[len("col:"):]
+	return "" != r && '0' <= r[0] && '9' >= r[0]
+}
+
+/* parseCondition parses a condition of the form col:N OP VALUE, col:N is
+empty, or col:N is not empty.  VALUE may be surrounded by double quotes to
+preserve leading or trailing spaces, or may be another column, to compare two
+fields of the same record.  Instead of col:N, len(col:N) may be used to compare
+the length of the field in characters.  Columns may also be given as cN. */
+func parseCondition(s string) (condition, error) {
+	c := condition{spec: s}
+	r := strings.TrimSpace(s)
+	if strings.HasPrefix(r, "len(") {
+		c.len = true
+		r = r[len("len("):]
+	}
+	col, r, err := parseColumn(r)
+	if nil != err {
+		return c, err
+	}
+	c.col = col
+	if c.len {
+		if !strings.HasPrefix(r, ")") {
+			return c, fmt.Errorf("missing ) after len(col:%v", col)
+		}
+		r = strings.TrimLeft(r[1:], " \t")
+	}
+
+	/* Emptiness checks */
+	switch strings.Join(strings.Fields(r), " ") {
+	case "is empty":
+		c.op = "is empty"
+		return c, nil
+	case "is not empty":
+		c.op = "is not empty"
+		return c, nil
+	}
+
+	/* Operator */
+	for _, op := range operators {
+		if strings.HasPrefix(r, op) {
+			c.op = op
+			break
+		}
+	}
+	if "" == c.op {
+		return c, fmt.Errorf("missing or unknown operator")
+	}
+	if "=" == c.op {
+		c.op = "=="
+		r = r[1:]
+	} else {
+		r = r[len(c.op):]
+	}
+
+	/* Value, possibly quoted or another column */
+	c.value = strings.TrimSpace(r)
+	if o, rest, err := parseColumn(c.value); nil == err && "" == rest {
+		c.other = o
+		return c, nil
+	}
+	if 2 <= len(c.value) && strings.HasPrefix(c.value, `"`) &&
+		strings.HasSuffix(c.value, `"`) {
+		v, err := strconv.Unquote(c.value)
+		if nil != err {
+			return c, fmt.Errorf("invalid quoted value: %v", err)
+		}
+		c.value = v
+	}
+
+	return c, nil
+}
+
+/* parseSetCondition parses a condition of the form col:N=V1,V2,...  The list
+of values is parsed as a line of CSV, so values may be quoted.  Op should be
+either "in" or "not in". */
+func parseSetCondition(s, op string) (condition, error) {
+	c := condition{spec: s, op: op, set: make(map[string]bool)}
+	col, r, err := parseColumn(s)
+	if nil != err {
+		return c, err
+	}
+	c.col = col
+	if !strings.HasPrefix(r, "=") {
+		return c, fmt.Errorf("missing =")
+	}
+	vs, err := csv.NewReader(strings.NewReader(r[1:])).Read()
+	if nil != err {
+		return c, fmt.Errorf("invalid list of values: %v", err)
+	}
+	for _, v := range vs {
+		c.set[v] = true
+	}
+	return c, nil
+}
+
+/* parseMatchCondition parses a condition of the form N:REGEX, where N may
+also be col:N or cN.  Op should be either "match" or "not match". */
+func parseMatchCondition(s, op string) (condition, error) {
+	c := condition{spec: s, op: op}
+	/* Column number */
+	var (
+		r   string
+		err error
+	)
+	if t := strings.TrimSpace(s); "" != t && '0' <= t[0] && '9' >= t[0] {
+		c.col, r, err = parseColumn("c" + t)
+	} else {
+		c.col, r, err = parseColumn(s)
+	}
+	if nil != err {
+		return c, err
+	}
+	if !strings.HasPrefix(r, ":") {
+		return c, fmt.Errorf("missing : after column")
+	}
+	if c.re, err = regexp.Compile(r[1:]); nil != err {
+		return c, err
+	}
+	return c, nil
+}
+
+/* parseColumn parses the col:N, colN, or cN at the start of s and returns N
+and the rest of s with leading whitespace removed. */
+func parseColumn(s string) (int, string, error) {
+	r := strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(r, "col:"):
+		r = r[len("col:"):]
+	case strings.HasPrefix(r, "col"):
+		r = r[len("col"):]
+	case strings.HasPrefix(r, "c"):
+		r = r[len("c"):]
+	default:
+		return 0, "", fmt.Errorf("must start with a column")
+	}
+	n := 0
+	for n < len(r) && '0' <= r[n] && '9' >= r[n] {
+		n++
+	}
+	col, err := strconv.Atoi(r[:n])
+	if nil != err || 1 > col {
+		return 0, "", fmt.Errorf("invalid column number")
+	}
+	return col, strings.TrimLeft(r[n:], " \t"), nil
+}
+
+/* Matches returns true if the record satisfies w's conditions */
+func (w Where) Matches(record []string) bool {
+	for _, c := range w.all {
+		if !c.matches(record) {
+			return false
+		}
+	}
+	if 0 == len(w.any) {
+		return true
+	}
+	for _, c := range w.any {
+		if c.matches(record) {
+			return true
+		}
+	}
+	return false
+}
+
+/* matches returns true if the record satisfies the condition.  Missing fields
+are treated as empty, as are fields containing only whitespace. */
+func (c condition) matches(record []string) bool {
+	f := ""
+	if c.col <= len(record) {
+		f = record[c.col-1]
+	}
+	if c.len {
+		f = strconv.Itoa(utf8.RuneCountInString(f))
+	}
+	v := c.value
+	if 0 != c.other {
+		v = ""
+		if c.other <= len(record) {
+			v = record[c.other-1]
+		}
+	}
+	switch c.op {
+	case "in":
+		return c.set[f]
+	case "not in":
+		return !c.set[f]
+	case "valid":
+		return c.valid(strings.TrimSpace(f))
+	case "match":
+		return c.re.MatchString(f)
+	case "not match":
+		return !c.re.MatchString(f)
+	case "is empty":
+		return "" == strings.TrimSpace(f)
+	case "is not empty":
+		return "" != strings.TrimSpace(f)
+	}
+	cmp := CompareValues(f, v)
+	switch c.op {
+	case "==":
+		return 0 == cmp
+	case "!=":
+		return 0 != cmp
+	case "<":
+		return 0 > cmp
+	case "<=":
+		return 0 >= cmp
+	case ">":
+		return 0 < cmp
+	case ">=":
+		return 0 <= cmp
+	}
+	return false
+}
+
+/* compareStrings compares values which aren't both numbers */
+var compareStrings = strings.Compare
+
+/* SetStringCompare sets the function CompareValues, and so conditions and
+csvcol's sorting, uses to compare values which aren't both numbers, e.g. to
+use a locale's collation order.  The function should return -1, 0, or 1, like
+strings.Compare, which is used if f is nil.  SetStringCompare affects
+everything in the package and should be called before anything's compared. */
+func SetStringCompare(f func(a, b string) int) {
+	if nil == f {
+		f = strings.Compare
+	}
+	compareStrings = f
+}
+
+/* CompareValues compares a and b numerically if they're both finite
+numbers, or as strings if not; words like NaN and Inf aren't numbers.  It
+returns -1, 0, or 1, like strings.Compare.  Strings are compared with
+strings.Compare, unless changed with SetStringCompare. */
+func CompareValues(a, b string) int {
+	af, aerr := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	bf, berr := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if nil != aerr || nil != berr || !isFinite(af) || !isFinite(bf) {
+		return compareStrings(a, b)
+	}
+	switch {
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+/* isFinite returns true if f is neither infinite nor NaN */
+func isFinite(f float64) bool {
+	return !math.IsInf(f, 0) && !math.IsNaN(f)
+}
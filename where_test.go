@@ -0,0 +1,344 @@
+/*
+ * where_test.go
+ * Tests for where.go
+ * by J. Stuart McMurray
+ * Created 20261017
+ * Last modified 20261017
+ *
+ * Copyright (c) 2026 J. Stuart McMurray <kd5pbo@gmail.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package csvcol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWhereLegacyCondition(t *testing.T) {
+	for _, c := range []struct {
+		cond  string
+		err   bool
+		match []string /* Records which match */
+		miss  []string /* Records which don't */
+	}{{
+		cond:  "col:2 == some words",
+		match: []string{"a,some words"},
+		miss:  []string{"a,some"},
+	}, {
+		cond:  "  col:1 != x y",
+		match: []string{"x"},
+		miss:  []string{"x y"},
+	}, {
+		cond:  "len(col:2) > 3 4",
+		match: []string{"a,bcde"},
+		miss:  []string{"a,bcd"},
+	}, {
+		cond:  `c2 == "some words"`,
+		match: []string{"a,some words"},
+		miss:  []string{"a,some"},
+	}, {
+		cond: "c2 == some words",
+		err:  true,
+	}, {
+		cond: "some words",
+		err:  true,
+	}, {
+		cond: "col:x == y z",
+		err:  true,
+	}, {
+		cond: "(c1 == a",
+		err:  true,
+	}} {
+		c := c
+		t.Run(c.cond, func(t *testing.T) {
+			var w Where
+			err := w.AddAll(c.cond)
+			if c.err {
+				if nil == err {
+					t.Fatalf("No error")
+				}
+				if strings.Contains(err.Error(), "operator") {
+					t.Errorf("Legacy parser's error: %v",
+						err)
+				}
+				return
+			}
+			if nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			checkMatches(t, w, c.match, c.miss)
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"2", "10", -1},
+		{"10", "2", 1},
+		{" 1.0", "1", 0},
+		{"1e3", "999", 1},
+		{"abc", "abd", -1},
+		{"2", "abc", -1},
+		/* Not numbers */
+		{"nan", "1", 1},
+		{"NaN", "NaN", 0},
+		{"inf", "1", 1},
+		{"-inf", "-1", 1},
+		{"infinity", "10", 1},
+		{"+Infinity", "+Infinity", 0},
+		{"1e400", "1", 1},
+	} {
+		if got := CompareValues(c.a, c.b); got != c.want {
+			t.Errorf("CompareValues(%q, %q) = %d, want %d",
+				c.a, c.b, got, c.want)
+		}
+	}
+}
+
+/* checkMatches checks that w matches the records, given as comma-separated
+fields, in match and none of those in miss. */
+func checkMatches(t *testing.T, w Where, match, miss []string) {
+	t.Helper()
+	for _, r := range match {
+		if !w.Matches(strings.Split(r, ",")) {
+			t.Errorf("%q didn't match", r)
+		}
+	}
+	for _, r := range miss {
+		if w.Matches(strings.Split(r, ",")) {
+			t.Errorf("%q matched", r)
+		}
+	}
+}
+
+func TestWhereIn(t *testing.T) {
+	var in Where
+	if err := in.AddIn(`col:2=red,"green, light",blue`); nil != err {
+		t.Fatalf("AddIn: %v", err)
+	}
+	checkMatches(
+		t,
+		in,
+		[]string{"a,red", "b,blue"},
+		[]string{"c,green", "d,", "e", "f,Red"},
+	)
+	if got := in.Matches([]string{"g", "green, light"}); !got {
+		t.Errorf("Quoted value with a comma didn't match")
+	}
+
+	var notIn Where
+	if err := notIn.AddNotIn("c2=red,blue"); nil != err {
+		t.Fatalf("AddNotIn: %v", err)
+	}
+	checkMatches(
+		t,
+		notIn,
+		[]string{"c,green", "d,", "e"},
+		[]string{"a,red", "b,blue"},
+	)
+
+	for _, s := range []string{"2=red", "col:2", "col:2=\"red"} {
+		var w Where
+		if err := w.AddIn(s); nil == err {
+			t.Errorf("AddIn(%q) didn't fail", s)
+		}
+	}
+}
+
+func TestWhereEmpty(t *testing.T) {
+	for _, c := range []struct {
+		cond        string
+		match, miss []string
+	}{{
+		cond:  "col:2 is empty",
+		match: []string{"a,", "b,  ", "c", "d,\t,x"},
+		miss:  []string{"e,x", "f, x "},
+	}, {
+		cond:  "c2 is not empty",
+		match: []string{"e,x", "f, x "},
+		miss:  []string{"a,", "b,  ", "c"},
+	}, {
+		cond:  "c1 is empty || c3 is not empty",
+		match: []string{",x", "a,b,c"},
+		miss:  []string{"a,b", "a,b, "},
+	}} {
+		c := c
+		t.Run(c.cond, func(t *testing.T) {
+			var w Where
+			if err := w.AddAll(c.cond); nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			checkMatches(t, w, c.match, c.miss)
+		})
+	}
+	var w Where
+	if err := w.AddAll("c1 is full"); nil == err {
+		t.Errorf("Invalid emptiness check didn't fail")
+	}
+}
+
+func TestWhereLen(t *testing.T) {
+	for _, c := range []struct {
+		cond        string
+		match, miss []string
+	}{{
+		cond:  "len(col:3) > 5",
+		match: []string{"a,b,abcdef", "a,b,ééééé€"},
+		miss:  []string{"a,b,abcde", "a,b,éééé€", "a,b"},
+	}, {
+		cond:  "len(c1) == 0",
+		match: []string{",x", ""},
+		miss:  []string{"a,x"},
+	}, {
+		cond:  "3 <= len(c1)",
+		match: []string{"abc", "abcd"},
+		miss:  []string{"ab"},
+	}, {
+		cond:  "len(c1) != c2",
+		match: []string{"abc,2"},
+		miss:  []string{"abc,3"},
+	}} {
+		c := c
+		t.Run(c.cond, func(t *testing.T) {
+			var w Where
+			if err := w.AddAll(c.cond); nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			checkMatches(t, w, c.match, c.miss)
+		})
+	}
+	for _, s := range []string{"len(c1 > 3", "len(x) > 3", "c1 > len(c2)"} {
+		var w Where
+		if err := w.AddAll(s); nil == err {
+			t.Errorf("%q didn't fail", s)
+		}
+	}
+}
+
+func TestWhereColumns(t *testing.T) {
+	for _, c := range []struct {
+		cond        string
+		match, miss []string
+	}{{
+		cond:  "c3 > c4",
+		match: []string{"a,b,10,9", "a,b,b,a"},
+		miss:  []string{"a,b,9,10", "a,b,5,5"},
+	}, {
+		cond:  "col:1 != col:2",
+		match: []string{"a,b", "a,"},
+		miss:  []string{"a,a", "1,1.0"},
+	}, {
+		cond:  "c1 <= c2 && c2 < c3",
+		match: []string{"1,1,2", "1,2,3"},
+		miss:  []string{"1,2,2", "2,1,3"},
+	}, {
+		cond:  "c2 == c9",
+		match: []string{"a,", "a"},
+		miss:  []string{"a,b"},
+	}, {
+		cond:  `c1 == "c2"`,
+		match: []string{"c2,x"},
+		miss:  []string{"x,x"},
+	}} {
+		c := c
+		t.Run(c.cond, func(t *testing.T) {
+			var w Where
+			if err := w.AddAll(c.cond); nil != err {
+				t.Fatalf("Error: %v", err)
+			}
+			checkMatches(t, w, c.match, c.miss)
+		})
+	}
+}
+
+func TestWhereMatch(t *testing.T) {
+	var m Where
+	for _, s := range []string{"3:^(GET|POST) /admin", "c1:^10\\."} {
+		if err := m.AddMatch(s); nil != err {
+			t.Fatalf("AddMatch(%q): %v", s, err)
+		}
+	}
+	checkMatches(
+		t,
+		m,
+		[]string{"10.0.0.1,x,GET /admin/", "10.1.2.3,,POST /admin"},
+		[]string{
+			"10.0.0.1,x,PUT /admin",
+			"100.0.0.1,x,GET /admin",
+			"10.0.0.1,x, GET /admin",
+			"10.0.0.1,x",
+		},
+	)
+
+	var v Where
+	if err := v.AddNotMatch("col:2:a.c"); nil != err {
+		t.Fatalf("AddNotMatch: %v", err)
+	}
+	checkMatches(t, v, []string{"x,abd", "x,", "x"}, []string{"x,zabcz"})
+
+	/* Missing fields are empty, so can still match. */
+	var e Where
+	if err := e.AddMatch("2:^$"); nil != err {
+		t.Fatalf("AddMatch: %v", err)
+	}
+	checkMatches(t, e, []string{"x", "x,"}, []string{"x,y"})
+
+	for _, s := range []string{"3", "3^a", "0:a", "x:a", "3:(", ":a"} {
+		var w Where
+		if err := w.AddMatch(s); nil == err {
+			t.Errorf("AddMatch(%q) didn't fail", s)
+		}
+	}
+}
+
+func TestSetStringCompare(t *testing.T) {
+	defer SetStringCompare(nil)
+	SetStringCompare(func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"abc", "ABC", 0},
+		{"B", "a", 1},
+		{"2", "10", -1}, /* Numbers are still numbers */
+	} {
+		if got := CompareValues(c.a, c.b); got != c.want {
+			t.Errorf("CompareValues(%q, %q) = %d, want %d",
+				c.a, c.b, got, c.want)
+		}
+	}
+	var w Where
+	if err := w.AddAll(`c1 == abc && c2 < b`); nil != err {
+		t.Fatalf("AddAll: %v", err)
+	}
+	checkMatches(
+		t,
+		w,
+		[]string{"ABC,A", "aBc,a"},
+		[]string{"abd,a", "abc,B"},
+	)
+
+	/* And back to normal */
+	SetStringCompare(nil)
+	if got := CompareValues("B", "a"); -1 != got {
+		t.Errorf("Reset CompareValues(B, a) = %d", got)
+	}
+}